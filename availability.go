@@ -0,0 +1,144 @@
+package go_ical
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Available returns the instances of the AVAILABLE blocks of a which overlap
+// [from,to),sorted by start. Recurring blocks are expanded like events,see
+// Expand. Instances are not cut to the DTSTART/DTEND range of a.
+func (a *VAvailability) Available(from, to time.Time) ([]Occurrence, error) {
+	return expandSubComponents(&a.ComponentObj, from, to, CompAvailable)
+}
+
+// availabilityRange is the time a VAVAILABILITY covers,zero times stand for
+// an open start or end,RFC 7953 3.1
+func (a *VAvailability) availabilityRange() (start, end time.Time, err error) {
+	p := a.GetProperty(PropDatetimeStart)
+	if p == nil {
+		return
+	}
+	if start, err = p.GetToTime(); err != nil {
+		return
+	}
+	if p := a.GetProperty(PropDatetimeEnd); p != nil {
+		end, err = p.GetToTime()
+		return
+	}
+	if p := a.GetProperty(PropDuration); p != nil {
+		var d time.Duration
+		if d, err = p.GetToDuration(); err != nil {
+			return
+		}
+		end = start.Add(d)
+	}
+	return
+}
+
+// availabilityRank orders by PRIORITY,1 is the highest and 0 or no PRIORITY
+// the lowest,RFC 7953 4
+func (a *VAvailability) availabilityRank() int {
+	if p := a.GetProperty(PropPriority); p != nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(p.Value)); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 10
+}
+
+/*
+UnavailablePeriods returns the time in [from,to) which avs mark as unavailable,
+RFC 7953 4.
+
+Inside its DTSTART/DTEND range a VAVAILABILITY is busy,with its BUSYTYPE or
+BUSY-UNAVAILABLE,except during the instances of its AVAILABLE blocks. Where
+ranges overlap the VAVAILABILITY with the highest PRIORITY decides,those with
+the same PRIORITY are free where any of them has an AVAILABLE block. The
+periods are merged as by MergeFreeBusy.
+*/
+func UnavailablePeriods(avs []*VAvailability, from, to time.Time) ([]FreeBusyPeriod, error) {
+	type span struct {
+		start, end time.Time
+	}
+	type layer struct {
+		span
+		rank      int
+		busyType  string
+		available []span
+	}
+	var layers []layer
+	cuts := []time.Time{from, to}
+	clip := func(start, end time.Time) (span, bool) {
+		if start.IsZero() || start.Before(from) {
+			start = from
+		}
+		if end.IsZero() || end.After(to) {
+			end = to
+		}
+		cuts = append(cuts, start, end)
+		return span{start, end}, end.After(start)
+	}
+	for _, a := range avs {
+		start, end, err := a.availabilityRange()
+		if err != nil {
+			return nil, err
+		}
+		r, ok := clip(start, end)
+		if !ok {
+			continue
+		}
+		l := layer{span: r, rank: a.availabilityRank(), busyType: FBTypeBusyUnavailable}
+		if p := a.GetProperty(PropBusyType); p != nil && fbRank[strings.ToUpper(p.Value)] > 0 {
+			l.busyType = strings.ToUpper(p.Value)
+		}
+		occs, err := a.Available(r.start, r.end)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range occs {
+			if s, ok := clip(o.Start, o.End); ok {
+				l.available = append(l.available, s)
+			}
+		}
+		layers = append(layers, l)
+	}
+
+	//every piece between two cuts is either wholly covered by a range or
+	//block,or not at all
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i].Before(cuts[j]) })
+	inside := func(t time.Time, s span) bool { return !t.Before(s.start) && t.Before(s.end) }
+	var fbs []FreeBusyPeriod
+	for i := 0; i+1 < len(cuts); i++ {
+		t, next := cuts[i], cuts[i+1]
+		if !next.After(t) {
+			continue
+		}
+		best := 11
+		for _, l := range layers {
+			if inside(t, l.span) && l.rank < best {
+				best = l.rank
+			}
+		}
+		typ, free := "", false
+		for _, l := range layers {
+			if l.rank != best || !inside(t, l.span) {
+				continue
+			}
+			for _, s := range l.available {
+				if inside(t, s) {
+					free = true
+				}
+			}
+			if fbRank[l.busyType] > fbRank[typ] {
+				typ = l.busyType
+			}
+		}
+		if typ != "" && !free {
+			fbs = append(fbs, FreeBusyPeriod{Period: Period{Start: t, End: next}, Type: typ})
+		}
+	}
+	return MergeFreeBusy(fbs, from, to), nil
+}
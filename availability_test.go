@@ -0,0 +1,143 @@
+package go_ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const availabilityCalendarStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VAVAILABILITY
+UID:office@example.com
+DTSTAMP:20200201T000000Z
+DTSTART:20200301T000000Z
+DTEND:20200401T000000Z
+PRIORITY:5
+BEGIN:AVAILABLE
+UID:office-hours@example.com
+DTSTAMP:20200201T000000Z
+DTSTART:20200302T090000Z
+DTEND:20200302T170000Z
+RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR
+SUMMARY:Office hours
+END:AVAILABLE
+BEGIN:AVAILABLE
+UID:office-hours@example.com
+DTSTAMP:20200201T000000Z
+RECURRENCE-ID:20200304T090000Z
+DTSTART:20200304T130000Z
+DTEND:20200304T170000Z
+END:AVAILABLE
+END:VAVAILABILITY
+BEGIN:VAVAILABILITY
+UID:trip@example.com
+DTSTAMP:20200201T000000Z
+DTSTART:20200303T000000Z
+DURATION:P1D
+PRIORITY:1
+BUSYTYPE:BUSY
+END:VAVAILABILITY
+END:VCALENDAR
+`
+
+func TestValidateAvailability(t *testing.T) {
+	cal := decodeString(t, availabilityCalendarStr)
+	if findings := Validate(cal); len(findings) != 0 {
+		t.Errorf("Validate() found problems in a valid calendar:\n%v", findings)
+	}
+
+	cal = decodeString(t, `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VAVAILABILITY
+UID:bad@example.com
+DTSTAMP:20200201T000000Z
+DURATION:P1D
+BUSYTYPE:AWAY
+BEGIN:AVAILABLE
+UID:bad-block@example.com
+DTSTAMP:20200201T000000Z
+DTSTART:20200302T090000Z
+DTEND:20200302T080000Z
+END:AVAILABLE
+END:VAVAILABILITY
+END:VCALENDAR
+`)
+	findings := Validate(cal)
+	want := []struct {
+		path, message string
+		severity      Severity
+	}{
+		{"VCALENDAR/VAVAILABILITY[0]", "DURATION in VAVAILABILITY needs DTSTART", SeverityError},
+		{"VCALENDAR/VAVAILABILITY[0]/BUSYTYPE", `unknown BUSYTYPE "AWAY"`, SeverityWarning},
+		{"VCALENDAR/VAVAILABILITY[0]/AVAILABLE[0]/DTEND", "DTEND MUST be later than DTSTART", SeverityError},
+	}
+	for _, w := range want {
+		found := false
+		for _, f := range findings {
+			if f.Path == w.path && strings.Contains(f.Message, w.message) && f.Severity == w.severity {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("no finding at %s containing %q", w.path, w.message)
+		}
+	}
+	if t.Failed() {
+		for _, f := range findings {
+			t.Log(f)
+		}
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	cal := decodeString(t, availabilityCalendarStr)
+	av := &VAvailability{*cal.SubComponents()[0].obj()}
+	occs, err := av.Available(time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC), time.Date(2020, 3, 6, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Available() err: %v", err)
+	}
+	var got []string
+	for _, o := range occs {
+		got = append(got, Period{Start: o.Start, End: o.End}.String())
+	}
+	want := []string{
+		"20200303T090000Z/20200303T170000Z",
+		"20200304T130000Z/20200304T170000Z",
+		"20200305T090000Z/20200305T170000Z",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Available():\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestAvailabilityFreeBusy(t *testing.T) {
+	from := time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 5, 0, 0, 0, 0, time.UTC)
+	fb, err := ComputeFreeBusy([]Calendar{decodeString(t, availabilityCalendarStr)}, from, to, itipNow)
+	if err != nil {
+		t.Fatalf("ComputeFreeBusy() err: %v", err)
+	}
+	fbs, err := fb.Periods()
+	if err != nil {
+		t.Fatalf("Periods() err: %v", err)
+	}
+	var got []string
+	for _, p := range MergeFreeBusy(fbs, from, to) {
+		got = append(got, p.Type+":"+p.String())
+	}
+	//the trip outranks the office hours on the 3rd,on the 4th the override
+	//starts the office hours at 13:00
+	want := []string{
+		"BUSY-UNAVAILABLE:20200302T000000Z/20200302T090000Z",
+		"BUSY-UNAVAILABLE:20200302T170000Z/20200303T000000Z",
+		"BUSY:20200303T000000Z/20200304T000000Z",
+		"BUSY-UNAVAILABLE:20200304T000000Z/20200304T130000Z",
+		"BUSY-UNAVAILABLE:20200304T170000Z/20200305T000000Z",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("periods:\n got %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package go_ical
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// CalDAV,RFC 4791,is WebDAV,RFC 4918,with calendar collections holding one
+// iCalendar object per resource. Requests and responses are XML documents in
+// the "DAV:" and CalDAV namespaces. The types below write them with the
+// prefixes "D:" and "C:" declared on the root element,and read them by
+// namespace whatever prefixes the peer chose.
+
+const (
+	davNS    = "DAV:"
+	caldavNS = "urn:ietf:params:xml:ns:caldav"
+)
+
+// davMultistatus is a written DAV:multistatus,RFC 4918 14.16
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"D:multistatus"`
+	NSDAV     string        `xml:"xmlns:D,attr"`
+	NSCalDAV  string        `xml:"xmlns:C,attr"`
+	Responses []davResponse `xml:"D:response"`
+}
+
+type davResponse struct {
+	Href      string        `xml:"D:href"`
+	Status    string        `xml:"D:status,omitempty"`
+	Propstats []davPropstat `xml:"D:propstat"`
+}
+
+type davPropstat struct {
+	Props  []davProperty `xml:"D:prop>any"`
+	Status string        `xml:"D:status"`
+}
+
+// davProperty is a property with its content as raw XML,its name is either
+// prefixed,e.g. "D:getetag",or a namespace and a local name
+type davProperty struct {
+	XMLName xml.Name
+	Inner   string `xml:",innerxml"`
+}
+
+// davName is the name of a read element
+type davName struct {
+	XMLName xml.Name
+}
+
+// davNames reads the property names of a DAV:prop
+type davNames struct {
+	Names []davName `xml:",any"`
+}
+
+// davPrefixed returns the name of the element name in the namespace ns as
+// it is written
+func davPrefixed(name xml.Name) xml.Name {
+	switch name.Space {
+	case davNS:
+		return xml.Name{Local: "D:" + name.Local}
+	case caldavNS:
+		return xml.Name{Local: "C:" + name.Local}
+	}
+	return name
+}
+
+// davStatus formats an HTTP status line for DAV:status
+func davStatus(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
+
+// davHref escapes a path for DAV:href
+func davHref(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+// davText escapes s as XML character data
+func davText(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// writeDAVXML writes v as an XML response body with the status code
+func writeDAVXML(w http.ResponseWriter, code int, v interface{}) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	w.WriteHeader(code)
+	w.Write([]byte(xml.Header))
+	w.Write(b)
+}
+
+// davError is a DAV:error body naming the failed precondition,RFC 4918 16
+type davError struct {
+	XMLName   xml.Name `xml:"D:error"`
+	NSDAV     string   `xml:"xmlns:D,attr"`
+	NSCalDAV  string   `xml:"xmlns:C,attr"`
+	Condition davProperty
+}
+
+// writeDAVError answers with code and the precondition cond,e.g.
+// {caldavNS,"valid-calendar-data"}
+func writeDAVError(w http.ResponseWriter, code int, cond xml.Name) {
+	writeDAVXML(w, code, &davError{
+		NSDAV:     davNS,
+		NSCalDAV:  caldavNS,
+		Condition: davProperty{XMLName: davPrefixed(cond)},
+	})
+}
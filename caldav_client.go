@@ -0,0 +1,515 @@
+package go_ical
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	//ErrCalDAVInvalidSyncToken is returned by SyncCollection when the server
+	//no longer knows the token,the collection must be read again in full
+	ErrCalDAVInvalidSyncToken = errors.New("ical:CalDAV sync token is invalid")
+)
+
+/*
+CalDAVClient talks to a CalDAV server,RFC 4791.
+
+Paths passed to and returned by the client are URL paths on the server of
+Endpoint,as the server writes them in DAV:href. A typical session finds the
+principal,its calendar home and the calendars,reads every calendar once with
+Objects and keeps the sync token,then calls SyncCollection with it.
+*/
+type CalDAVClient struct {
+	//Endpoint is the server URL,e.g. "https://cal.example.com/"
+	Endpoint *url.URL
+	//HTTPClient sends the requests,http.DefaultClient when nil
+	HTTPClient *http.Client
+	//Username and Password are sent with basic authentication when set
+	Username string
+	Password string
+}
+
+// NewCalDAVClient returns a client for the server at endpoint
+func NewCalDAVClient(endpoint string) (*CalDAVClient, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("ical:CalDAV endpoint %q is not an absolute URL", endpoint)
+	}
+	return &CalDAVClient{Endpoint: u}, nil
+}
+
+// davStatusError is the error for an unexpected response
+func davStatusError(req *http.Request, resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return ErrCalDAVNotFound
+	case http.StatusPreconditionFailed:
+		return ErrCalDAVPreconditionFailed
+	}
+	return fmt.Errorf("ical:CalDAV %s %s: %s", req.Method, req.URL.Path, resp.Status)
+}
+
+func (c *CalDAVClient) request(method, p string, body []byte) (*http.Request, error) {
+	u := c.Endpoint.ResolveReference(&url.URL{Path: p})
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	return req, nil
+}
+
+// do sends req and reads the response body,statuses other than ok fail
+func (c *CalDAVClient) do(req *http.Request, ok ...int) (*http.Response, []byte, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, code := range ok {
+		if resp.StatusCode == code {
+			return resp, b, nil
+		}
+	}
+	return resp, b, davStatusError(req, resp)
+}
+
+// davPropValues are the properties the client reads from a DAV:prop
+type davPropValues struct {
+	ResourceType struct {
+		Collection *struct{} `xml:"DAV: collection"`
+		Calendar   *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+	} `xml:"DAV: resourcetype"`
+	DisplayName          string      `xml:"DAV: displayname"`
+	ETag                 string      `xml:"DAV: getetag"`
+	LastModified         string      `xml:"DAV: getlastmodified"`
+	CurrentUserPrincipal davHrefProp `xml:"DAV: current-user-principal"`
+	CalendarHomeSet      davHrefProp `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	Description          string      `xml:"urn:ietf:params:xml:ns:caldav calendar-description"`
+	Components           []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set>comp"`
+	CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+type davHrefProp struct {
+	Href string `xml:"DAV: href"`
+}
+
+// davReadMultistatus is a DAV:multistatus as the client reads it
+type davReadMultistatus struct {
+	XMLName   xml.Name `xml:"DAV: multistatus"`
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Status    string `xml:"DAV: status"`
+		Propstats []struct {
+			Prop   davPropValues `xml:"DAV: prop"`
+			Status string        `xml:"DAV: status"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+	SyncToken string `xml:"DAV: sync-token"`
+}
+
+// davResult is one response of a multistatus
+type davResult struct {
+	path   string
+	status int
+	props  davPropValues
+}
+
+// davStatusCode returns the code of a DAV:status line,200 when it is empty
+func davStatusCode(s string) int {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return http.StatusOK
+	}
+	code, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0
+	}
+	return code
+}
+
+// parseMultistatus returns the responses of b with the properties found,
+// the paths are resolved against the request URL. Responses for another
+// scheme or host are skipped,they are no resources of this server.
+func parseMultistatus(req *http.Request, b []byte) ([]davResult, string, error) {
+	var ms davReadMultistatus
+	if err := xml.Unmarshal(b, &ms); err != nil {
+		return nil, "", fmt.Errorf("ical:CalDAV %s %s: invalid multistatus: %v", req.Method, req.URL.Path, err)
+	}
+	res := make([]davResult, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		u, err := url.Parse(strings.TrimSpace(r.Href))
+		if err != nil {
+			return nil, "", fmt.Errorf("ical:CalDAV invalid href %q", r.Href)
+		}
+		ref := req.URL.ResolveReference(u)
+		if !strings.EqualFold(ref.Scheme, req.URL.Scheme) || !strings.EqualFold(ref.Host, req.URL.Host) {
+			continue
+		}
+		dr := davResult{path: ref.Path, status: davStatusCode(r.Status)}
+		for _, ps := range r.Propstats {
+			if davStatusCode(ps.Status) == http.StatusOK {
+				dr.props = ps.Prop
+			}
+		}
+		res = append(res, dr)
+	}
+	return res, ms.SyncToken, nil
+}
+
+func (c *CalDAVClient) propfindRequest(p, depth string, names []xml.Name) (*http.Request, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header + `<D:propfind xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><D:prop>`)
+	for _, n := range names {
+		buf.WriteString("<" + davPrefixed(n).Local + "/>")
+	}
+	buf.WriteString("</D:prop></D:propfind>")
+	req, err := c.request("PROPFIND", p, buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", depth)
+	req.Header.Set("Content-Type", `application/xml; charset="utf-8"`)
+	return req, nil
+}
+
+// propfind asks for the properties names of p and its members to depth
+func (c *CalDAVClient) propfind(p, depth string, names ...xml.Name) ([]davResult, error) {
+	req, err := c.propfindRequest(p, depth, names)
+	if err != nil {
+		return nil, err
+	}
+	_, b, err := c.do(req, http.StatusMultiStatus)
+	if err != nil {
+		return nil, err
+	}
+	res, _, err := parseMultistatus(req, b)
+	return res, err
+}
+
+// FindPrincipal returns the path of the current user's principal. It asks
+// the endpoint,then /.well-known/caldav,RFC 6764 5.
+func (c *CalDAVClient) FindPrincipal() (string, error) {
+	name := xml.Name{Space: davNS, Local: "current-user-principal"}
+	principal := func(res []davResult) string {
+		for _, r := range res {
+			if href := r.props.CurrentUserPrincipal.Href; href != "" {
+				return href
+			}
+		}
+		return ""
+	}
+	if res, err := c.propfind(c.Endpoint.Path, "0", name); err == nil && principal(res) != "" {
+		return c.resolve(principal(res))
+	}
+
+	//redirects are followed here,an http.Client turns PROPFIND into GET
+	client := http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	if c.HTTPClient != nil {
+		client.Transport, client.Jar, client.Timeout = c.HTTPClient.Transport, c.HTTPClient.Jar, c.HTTPClient.Timeout
+	}
+	noRedirect := &CalDAVClient{HTTPClient: &client}
+	p := "/.well-known/caldav"
+	for i := 0; i < 10; i++ {
+		req, err := c.propfindRequest(p, "0", []xml.Name{name})
+		if err != nil {
+			return "", err
+		}
+		resp, b, err := noRedirect.do(req, http.StatusMultiStatus, http.StatusMovedPermanently, http.StatusFound,
+			http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode == http.StatusMultiStatus {
+			res, _, err := parseMultistatus(req, b)
+			if err != nil {
+				return "", err
+			}
+			if href := principal(res); href != "" {
+				return c.resolve(href)
+			}
+			break
+		}
+		u, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil {
+			return "", err
+		}
+		p = req.URL.ResolveReference(u).Path
+	}
+	return "", fmt.Errorf("ical:CalDAV server %s names no current-user-principal", c.Endpoint)
+}
+
+func (c *CalDAVClient) resolve(href string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", fmt.Errorf("ical:CalDAV invalid href %q", href)
+	}
+	return c.Endpoint.ResolveReference(u).Path, nil
+}
+
+// FindCalendarHome returns the calendar home of the principal,RFC 4791 6.2.1
+func (c *CalDAVClient) FindCalendarHome(principal string) (string, error) {
+	res, err := c.propfind(principal, "0", xml.Name{Space: caldavNS, Local: "calendar-home-set"})
+	if err != nil {
+		return "", err
+	}
+	for _, r := range res {
+		if href := r.props.CalendarHomeSet.Href; href != "" {
+			return c.resolve(href)
+		}
+	}
+	return "", fmt.Errorf("ical:CalDAV principal %s has no calendar-home-set", principal)
+}
+
+// Calendars returns the calendar collections in home
+func (c *CalDAVClient) Calendars(home string) ([]CalDAVCalendar, error) {
+	res, err := c.propfind(home, "1",
+		xml.Name{Space: davNS, Local: "resourcetype"},
+		xml.Name{Space: davNS, Local: "displayname"},
+		xml.Name{Space: caldavNS, Local: "calendar-description"},
+		xml.Name{Space: caldavNS, Local: "supported-calendar-component-set"})
+	if err != nil {
+		return nil, err
+	}
+	var cals []CalDAVCalendar
+	for _, r := range res {
+		if r.props.ResourceType.Calendar == nil {
+			continue
+		}
+		cal := CalDAVCalendar{Path: r.path, Name: r.props.DisplayName, Description: r.props.Description}
+		for _, comp := range r.props.Components {
+			cal.Components = append(cal.Components, comp.Name)
+		}
+		cals = append(cals, cal)
+	}
+	return cals, nil
+}
+
+// unquoteETag returns the ETag of a header or DAV:getetag without quotes
+func unquoteETag(s string) string {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "W/")
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// object decodes the CalDAV object of a multistatus response
+func (r *davResult) object() (*CalDAVObject, error) {
+	cal, err := NewDecoder(strings.NewReader(r.props.CalendarData)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("ical:CalDAV object %s: %v", r.path, err)
+	}
+	o := &CalDAVObject{Path: r.path, ETag: unquoteETag(r.props.ETag), Data: &cal}
+	if t, err := http.ParseTime(r.props.LastModified); err == nil {
+		o.ModTime = t
+	}
+	return o, nil
+}
+
+// MultiGet fetches the objects at paths from the calendar calPath with a
+// calendar-multiget REPORT,RFC 4791 7.9. Objects the server does not have
+// are left out.
+func (c *CalDAVClient) MultiGet(calPath string, paths []string) ([]CalDAVObject, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header + `<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">` +
+		`<D:prop><D:getetag/><D:getlastmodified/><C:calendar-data/></D:prop>`)
+	for _, p := range paths {
+		buf.WriteString("<D:href>" + davText(davHref(p)) + "</D:href>")
+	}
+	buf.WriteString("</C:calendar-multiget>")
+	req, err := c.request("REPORT", calPath, buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", `application/xml; charset="utf-8"`)
+	_, b, err := c.do(req, http.StatusMultiStatus)
+	if err != nil {
+		return nil, err
+	}
+	res, _, err := parseMultistatus(req, b)
+	if err != nil {
+		return nil, err
+	}
+	var objs []CalDAVObject
+	for i := range res {
+		if res[i].status != http.StatusOK || res[i].props.CalendarData == "" {
+			continue
+		}
+		o, err := res[i].object()
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, *o)
+	}
+	return objs, nil
+}
+
+// Objects lists the calendar calPath and fetches all its objects
+func (c *CalDAVClient) Objects(calPath string) ([]CalDAVObject, error) {
+	res, err := c.propfind(calPath, "1",
+		xml.Name{Space: davNS, Local: "resourcetype"},
+		xml.Name{Space: davNS, Local: "getetag"})
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, r := range res {
+		if r.props.ResourceType.Collection == nil && r.status == http.StatusOK {
+			paths = append(paths, r.path)
+		}
+	}
+	return c.MultiGet(calPath, paths)
+}
+
+// GetObject fetches one object with GET
+func (c *CalDAVClient) GetObject(p string) (*CalDAVObject, error) {
+	req, err := c.request(http.MethodGet, p, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, b, err := c.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := NewDecoder(bytes.NewReader(b)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("ical:CalDAV object %s: %v", p, err)
+	}
+	o := &CalDAVObject{Path: p, ETag: unquoteETag(resp.Header.Get("ETag")), Data: &cal}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		o.ModTime = t
+	}
+	return o, nil
+}
+
+/*
+PutObject stores cal at p and returns its new ETag. With an etag the object
+is only replaced while it still has that ETag,without one it is only created
+if it does not exist yet,otherwise ErrCalDAVPreconditionFailed is returned.
+The ETag is empty when the server changed the data while storing it,GetObject
+fetches what it stored.
+*/
+func (c *CalDAVClient) PutObject(p string, cal *Calendar, etag string) (string, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(cal); err != nil {
+		return "", err
+	}
+	req, err := c.request(http.MethodPut, p, buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	if etag != "" {
+		req.Header.Set("If-Match", strconv.Quote(etag))
+	} else {
+		req.Header.Set("If-None-Match", "*")
+	}
+	resp, _, err := c.do(req, http.StatusCreated, http.StatusNoContent, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+	return unquoteETag(resp.Header.Get("ETag")), nil
+}
+
+// DeleteObject removes the object at p,only while it has etag unless etag
+// is empty
+func (c *CalDAVClient) DeleteObject(p, etag string) error {
+	req, err := c.request(http.MethodDelete, p, nil)
+	if err != nil {
+		return err
+	}
+	if etag != "" {
+		req.Header.Set("If-Match", strconv.Quote(etag))
+	}
+	_, _, err = c.do(req, http.StatusNoContent, http.StatusOK)
+	return err
+}
+
+// CalDAVSync is the result of a sync-collection REPORT
+type CalDAVSync struct {
+	//Token is the sync token for the next call
+	Token string
+	//Changed are the objects created or changed since the last token
+	Changed []CalDAVObject
+	//Deleted are the paths of the objects removed since the last token
+	Deleted []string
+	//Truncated tells that the server sent part of the changes,call again
+	//with Token for the rest
+	Truncated bool
+}
+
+/*
+SyncCollection returns the changes of the calendar calPath since token with
+a sync-collection REPORT,RFC 6578,and fetches the changed objects with
+MultiGet. An empty token asks for every object. When the server no longer
+knows token ErrCalDAVInvalidSyncToken is returned and the calendar has to be
+read again with an empty token.
+*/
+func (c *CalDAVClient) SyncCollection(calPath, token string) (*CalDAVSync, error) {
+	body := xml.Header + `<D:sync-collection xmlns:D="DAV:"><D:sync-token>` + davText(token) +
+		`</D:sync-token><D:sync-level>1</D:sync-level><D:prop><D:getetag/></D:prop></D:sync-collection>`
+	req, err := c.request("REPORT", calPath, []byte(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `application/xml; charset="utf-8"`)
+	resp, b, err := c.do(req, http.StatusMultiStatus)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusConflict) &&
+			bytes.Contains(b, []byte("valid-sync-token")) {
+			return nil, ErrCalDAVInvalidSyncToken
+		}
+		return nil, err
+	}
+	res, next, err := parseMultistatus(req, b)
+	if err != nil {
+		return nil, err
+	}
+	sync := &CalDAVSync{Token: next}
+	collection := strings.TrimSuffix(req.URL.Path, "/")
+	var changed []string
+	for _, r := range res {
+		switch {
+		case strings.TrimSuffix(r.path, "/") == collection:
+			//the collection itself reports a truncated result,RFC 6578 3.6
+			if r.status == http.StatusInsufficientStorage {
+				sync.Truncated = true
+			}
+		case r.status == http.StatusNotFound:
+			sync.Deleted = append(sync.Deleted, r.path)
+		default:
+			changed = append(changed, r.path)
+		}
+	}
+	if sync.Changed, err = c.MultiGet(calPath, changed); err != nil {
+		return nil, err
+	}
+	return sync, nil
+}
@@ -0,0 +1,180 @@
+package go_ical
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// syncStandIn answers sync-collection REPORTs,RFC 6578,with canned changes
+// and passes every other request to next
+func syncStandIn(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(b))
+		if r.Method != "REPORT" || !bytes.Contains(b, []byte("sync-collection")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		switch {
+		case bytes.Contains(b, []byte("<D:sync-token></D:sync-token>")):
+			w.WriteHeader(http.StatusMultiStatus)
+			w.Write([]byte(`<?xml version="1.0"?><multistatus xmlns="DAV:">
+<response><href>/calendars/alice/work/standup.ics</href><propstat><prop><getetag>"x"</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>
+<sync-token>tok-1</sync-token></multistatus>`))
+		case bytes.Contains(b, []byte("<D:sync-token>tok-1</D:sync-token>")):
+			w.WriteHeader(http.StatusMultiStatus)
+			//an absolute href on this server counts,one on another host does not
+			w.Write([]byte(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">
+<d:response><d:href>http://` + r.Host + `/calendars/alice/work/report.ics</d:href><d:propstat><d:prop><d:getetag>"y"</d:getetag></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+<d:response><d:href>http://elsewhere.example.com/calendars/alice/work/standup.ics</d:href><d:propstat><d:prop><d:getetag>"z"</d:getetag></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+<d:response><d:href>http://elsewhere.example.com/calendars/alice/work/report.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>
+<d:response><d:href>/calendars/alice/work/gone.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>
+<d:response><d:href>/calendars/alice/work/</d:href><d:status>HTTP/1.1 507 Insufficient Storage</d:status></d:response>
+<d:sync-token>tok-2</d:sync-token></d:multistatus>`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<?xml version="1.0"?><D:error xmlns:D="DAV:"><D:valid-sync-token/></D:error>`))
+		}
+	})
+}
+
+func newCalDAVTestClient(t *testing.T, endpoint string) (*CalDAVClient, *memCalDAVStore, func()) {
+	store := newMemCalDAVStore(CalDAVCalendar{Path: "/calendars/alice/work/", Name: "Work"})
+	srv := httptest.NewServer(syncStandIn(&CalDAVHandler{Store: store, Principal: "/principals/alice/", Home: "/calendars/alice/"}))
+	c, err := NewCalDAVClient(srv.URL + endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HTTPClient = srv.Client()
+	return c, store, srv.Close
+}
+
+func TestCalDAVClientDiscovery(t *testing.T) {
+	//the endpoint is not a DAV resource,the principal is found through
+	//the redirect of /.well-known/caldav
+	c, _, done := newCalDAVTestClient(t, "/nothing/here")
+	defer done()
+	principal, err := c.FindPrincipal()
+	if err != nil || principal != "/principals/alice/" {
+		t.Fatalf("FindPrincipal() = %q, %v", principal, err)
+	}
+	home, err := c.FindCalendarHome(principal)
+	if err != nil || home != "/calendars/alice/" {
+		t.Fatalf("FindCalendarHome() = %q, %v", home, err)
+	}
+	cals, err := c.Calendars(home)
+	if err != nil {
+		t.Fatalf("Calendars() err: %v", err)
+	}
+	if len(cals) != 1 || cals[0].Path != "/calendars/alice/work/" || cals[0].Name != "Work" ||
+		strings.Join(cals[0].Components, ",") != "VEVENT,VTODO" {
+		t.Errorf("Calendars() = %+v", cals)
+	}
+
+	if _, err := NewCalDAVClient("/no/host"); err == nil {
+		t.Error("NewCalDAVClient() of a relative URL err = nil")
+	}
+}
+
+func TestCalDAVClientObjects(t *testing.T) {
+	c, store, done := newCalDAVTestClient(t, "/")
+	defer done()
+	const work = "/calendars/alice/work/"
+
+	ev := decodeString(t, caldavEventStr)
+	etag, err := c.PutObject(work+"standup.ics", &ev, "")
+	if err != nil || etag == "" {
+		t.Fatalf("PutObject() = %q, %v", etag, err)
+	}
+	if _, err := c.PutObject(work+"standup.ics", &ev, ""); err != ErrCalDAVPreconditionFailed {
+		t.Errorf("PutObject() of an existing object err = %v", err)
+	}
+	todo := decodeString(t, caldavTodoStr)
+	if _, err := c.PutObject(work+"report.ics", &todo, ""); err != nil {
+		t.Fatalf("PutObject() err: %v", err)
+	}
+
+	objs, err := c.Objects(work)
+	if err != nil {
+		t.Fatalf("Objects() err: %v", err)
+	}
+	if len(objs) != 2 || objs[0].Path != work+"report.ics" || objs[1].Path != work+"standup.ics" || objs[1].ETag != etag {
+		t.Fatalf("Objects() = %+v", objs)
+	}
+	if got := objs[1].Data.SubComponents()[0].obj().GetProperty(PropSummary).Value; got != "Standup" {
+		t.Errorf("SUMMARY = %s", got)
+	}
+	if objs[1].ModTime.IsZero() {
+		t.Error("ModTime is zero")
+	}
+
+	objs, err = c.MultiGet(work, []string{work + "standup.ics", work + "gone.ics"})
+	if err != nil || len(objs) != 1 || objs[0].Path != work+"standup.ics" {
+		t.Errorf("MultiGet() = %+v, %v", objs, err)
+	}
+
+	ev.SubComponents()[0].obj().PutProperty(newPropertyValue(PropSummary, "Standup!"))
+	next, err := c.PutObject(work+"standup.ics", &ev, etag)
+	if err != nil || next == etag {
+		t.Fatalf("PutObject() with If-Match = %q, %v", next, err)
+	}
+	if _, err := c.PutObject(work+"standup.ics", &ev, etag); err != ErrCalDAVPreconditionFailed {
+		t.Errorf("PutObject() with a stale ETag err = %v", err)
+	}
+	o, err := c.GetObject(work + "standup.ics")
+	if err != nil || o.ETag != next || o.Data.SubComponents()[0].obj().GetProperty(PropSummary).Value != "Standup!" {
+		t.Errorf("GetObject() = %+v, %v", o, err)
+	}
+
+	if err := c.DeleteObject(work+"standup.ics", etag); err != ErrCalDAVPreconditionFailed {
+		t.Errorf("DeleteObject() with a stale ETag err = %v", err)
+	}
+	if err := c.DeleteObject(work+"standup.ics", next); err != nil {
+		t.Errorf("DeleteObject() err: %v", err)
+	}
+	if _, err := c.GetObject(work + "standup.ics"); err != ErrCalDAVNotFound {
+		t.Errorf("GetObject() of a deleted object err = %v", err)
+	}
+	if _, err := store.Object(work + "report.ics"); err != nil {
+		t.Errorf("report.ics is gone: %v", err)
+	}
+}
+
+func TestCalDAVClientSync(t *testing.T) {
+	c, store, done := newCalDAVTestClient(t, "/")
+	defer done()
+	const work = "/calendars/alice/work/"
+	ev, todo := decodeString(t, caldavEventStr), decodeString(t, caldavTodoStr)
+	store.PutObject(work+"standup.ics", &ev, CalDAVCondition{})
+	store.PutObject(work+"report.ics", &todo, CalDAVCondition{})
+
+	sync, err := c.SyncCollection(work, "")
+	if err != nil {
+		t.Fatalf("SyncCollection() err: %v", err)
+	}
+	if sync.Token != "tok-1" || len(sync.Changed) != 1 || sync.Changed[0].Path != work+"standup.ics" ||
+		len(sync.Deleted) != 0 || sync.Truncated {
+		t.Errorf("first SyncCollection() = %+v", sync)
+	}
+
+	sync, err = c.SyncCollection(work, sync.Token)
+	if err != nil {
+		t.Fatalf("SyncCollection() err: %v", err)
+	}
+	if sync.Token != "tok-2" || len(sync.Changed) != 1 || sync.Changed[0].Path != work+"report.ics" ||
+		len(sync.Deleted) != 1 || sync.Deleted[0] != work+"gone.ics" || !sync.Truncated {
+		t.Errorf("second SyncCollection() = %+v", sync)
+	}
+	if got := sync.Changed[0].Data.SubComponents()[0].Name(); got != CompTodo {
+		t.Errorf("changed object is a %s", got)
+	}
+
+	if _, err := c.SyncCollection(work, "expired"); err != ErrCalDAVInvalidSyncToken {
+		t.Errorf("SyncCollection() with an expired token err = %v", err)
+	}
+}
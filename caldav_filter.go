@@ -0,0 +1,589 @@
+package go_ical
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A calendar-query,RFC 4791 7.8,selects calendar objects with a filter: a
+// tree of comp-filters naming components,prop-filters and param-filters
+// naming properties and parameters,each with a test on time or text or on
+// the absence of what it names. The types below hold such a filter,Match
+// evaluates it against a calendar the way a CalDAV server does.
+
+// CollationASCIICasemap is the default collation of a TextMatch,
+// CollationOctet compares bytes and CollationUnicodeCasemap ignores the case
+// of any letter,RFC 4790 and RFC 5051
+const (
+	CollationASCIICasemap   = "i;ascii-casemap"
+	CollationOctet          = "i;octet"
+	CollationUnicodeCasemap = "i;unicode-casemap"
+)
+
+// CompFilter is a CALDAV:comp-filter,RFC 4791 9.7.1. A component matches
+// when it has the name,overlaps TimeRange and matches all PropFilters and
+// CompFilters. With IsNotDefined the filter matches when no such component
+// exists and the other tests are ignored.
+type CompFilter struct {
+	Name         string
+	IsNotDefined bool
+	TimeRange    *TimeRange
+	PropFilters  []PropFilter
+	CompFilters  []CompFilter
+}
+
+// PropFilter is a CALDAV:prop-filter,RFC 4791 9.7.2. It matches when one of
+// the properties with the name passes all the tests,or with IsNotDefined when
+// the component has no such property.
+type PropFilter struct {
+	Name         string
+	IsNotDefined bool
+	TimeRange    *TimeRange
+	TextMatch    *TextMatch
+	ParamFilters []ParamFilter
+}
+
+// ParamFilter is a CALDAV:param-filter,RFC 4791 9.7.3
+type ParamFilter struct {
+	Name         string
+	IsNotDefined bool
+	TextMatch    *TextMatch
+}
+
+// TextMatch is a CALDAV:text-match,RFC 4791 9.7.5: a substring test with a
+// collation,CollationASCIICasemap when empty
+type TextMatch struct {
+	Text            string
+	Collation       string
+	NegateCondition bool
+}
+
+// TimeRange is a CALDAV:time-range,RFC 4791 9.9. A zero Start or End leaves
+// that side open.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// collationError is returned for a collation the matcher does not know
+type collationError string
+
+func (e collationError) Error() string {
+	return fmt.Sprintf("ical:unsupported collation %q", string(e))
+}
+
+/*
+Match reports whether cal is selected by f,which must be the comp-filter of
+VCALENDAR.
+
+A time-range on VEVENT,VTODO and VJOURNAL matches when an instance overlaps
+it,recurring components are expanded with ExpandComponent together with the
+overrides of the same UID,on VALARM it matches when the alarm triggers in
+the range,on VFREEBUSY when a FREEBUSY period or DTSTART and DTEND overlap
+it. A VTODO without DTSTART and DUE is tested on COMPLETED and CREATED,
+RFC 4791 9.9. Text is compared unescaped.
+*/
+func (f *CompFilter) Match(cal Calendar) (bool, error) {
+	if !strings.EqualFold(f.Name, CompCalendar) {
+		return false, fmt.Errorf("ical:a calendar filter starts with VCALENDAR,not %q", f.Name)
+	}
+	if f.IsNotDefined {
+		return false, nil
+	}
+	return f.matches(&filterTarget{com: &cal.ComponentObj})
+}
+
+// filterTarget is a component under test with the group it expands with
+type filterTarget struct {
+	com       *ComponentObj
+	parent    *filterTarget
+	master    *ComponentObj
+	overrides []*ComponentObj
+}
+
+func newFilterTarget(com *ComponentObj, parent *filterTarget) *filterTarget {
+	t := &filterTarget{com: com, parent: parent}
+	uid := com.GetProperty(PropUID)
+	if uid == nil {
+		if com.GetProperty(PropRecurrenceId) == nil {
+			t.master = com
+		} else {
+			t.overrides = []*ComponentObj{com}
+		}
+		return t
+	}
+	for _, sub := range parent.com.SubComponents() {
+		s := sub.obj()
+		if s.Name() != com.Name() {
+			continue
+		}
+		if p := s.GetProperty(PropUID); p == nil || p.Value != uid.Value {
+			continue
+		}
+		if s.GetProperty(PropRecurrenceId) == nil {
+			t.master = s
+		} else {
+			t.overrides = append(t.overrides, s)
+		}
+	}
+	return t
+}
+
+// occurrences returns the instances of t.com overlapping [from,to)
+func (t *filterTarget) occurrences(from, to time.Time) ([]Occurrence, error) {
+	occs, err := ExpandComponent(t.master, t.overrides, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var own []Occurrence
+	for _, o := range occs {
+		if o.Component == t.com {
+			own = append(own, o)
+		}
+	}
+	return own, nil
+}
+
+// unbounded reports whether t.com is a master recurring forever
+func (t *filterTarget) unbounded() (bool, error) {
+	if t.com != t.master {
+		return false, nil
+	}
+	for _, p := range t.com.GetProperties(PropRecurrenceRule) {
+		r, err := ParseRecur(p.Value)
+		if err != nil {
+			return false, err
+		}
+		if r.Count == 0 && r.Until.IsZero() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *CompFilter) matches(t *filterTarget) (bool, error) {
+	if f.TimeRange != nil {
+		if ok, err := f.TimeRange.matchComponent(t); err != nil || !ok {
+			return false, err
+		}
+	}
+	for i := range f.PropFilters {
+		if ok, err := f.PropFilters[i].matches(t.com); err != nil || !ok {
+			return false, err
+		}
+	}
+	for i := range f.CompFilters {
+		if ok, err := f.CompFilters[i].matchesIn(t); err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchesIn applies f to the sub-components of parent
+func (f *CompFilter) matchesIn(parent *filterTarget) (bool, error) {
+	found := false
+	for _, sub := range parent.com.SubComponents() {
+		if !strings.EqualFold(sub.Name(), f.Name) {
+			continue
+		}
+		found = true
+		if f.IsNotDefined {
+			return false, nil
+		}
+		if ok, err := f.matches(newFilterTarget(sub.obj(), parent)); err != nil || ok {
+			return ok, err
+		}
+	}
+	return f.IsNotDefined && !found, nil
+}
+
+// farFuture closes an open time range
+var farFuture = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+func (tr *TimeRange) bounds() (time.Time, time.Time) {
+	if tr.End.IsZero() {
+		return tr.Start, farFuture
+	}
+	return tr.Start, tr.End
+}
+
+func (tr *TimeRange) contains(t time.Time) bool {
+	from, to := tr.bounds()
+	return !t.Before(from) && t.Before(to)
+}
+
+func (tr *TimeRange) matchComponent(t *filterTarget) (bool, error) {
+	com := t.com
+	switch com.Name() {
+	case CompAlarm:
+		return tr.matchAlarm(t)
+	case CompFreebusy:
+		fbs, err := freeBusyPeriods(com)
+		if err != nil {
+			return false, err
+		}
+		from, to := tr.bounds()
+		for _, fb := range fbs {
+			if overlaps(fb.Start, fb.End, from, to) {
+				return true, nil
+			}
+		}
+		start, end, ok, err := componentSpan(com)
+		return ok && overlaps(start, end, from, to), err
+	case CompTodo:
+		if com.GetProperty(PropDatetimeStart) == nil && com.GetProperty(PropDatetimeDue) == nil {
+			return tr.matchUndatedTodo(com)
+		}
+	}
+	if tr.End.IsZero() {
+		//an infinite rule has instances after any start
+		if ok, err := t.unbounded(); err != nil || ok {
+			return ok, err
+		}
+	}
+	from, to := tr.bounds()
+	occs, err := t.occurrences(from, to)
+	return len(occs) > 0, err
+}
+
+// matchUndatedTodo tests a VTODO without DTSTART and DUE,RFC 4791 9.9
+func (tr *TimeRange) matchUndatedTodo(com *ComponentObj) (bool, error) {
+	from, to := tr.bounds()
+	var completed, created *time.Time
+	for _, c := range []struct {
+		name string
+		t    **time.Time
+	}{{PropDatetimeCompleted, &completed}, {PropDatetimeCreated, &created}} {
+		if p := com.GetProperty(c.name); p != nil {
+			v, err := p.GetToTime()
+			if err != nil {
+				return false, err
+			}
+			*c.t = &v
+		}
+	}
+	switch {
+	case completed != nil && created != nil:
+		return (!from.After(*created) || !from.After(*completed)) &&
+			(!to.Before(*created) || !to.Before(*completed)), nil
+	case completed != nil:
+		return !from.After(*completed) && !to.Before(*completed), nil
+	case created != nil:
+		return to.After(*created), nil
+	}
+	return true, nil
+}
+
+// matchAlarm reports whether the VALARM t.com triggers in tr,a relative
+// trigger is taken from every instance of the parent component
+func (tr *TimeRange) matchAlarm(t *filterTarget) (bool, error) {
+	trigger := t.com.GetProperty(PropTrigger)
+	if trigger == nil {
+		return false, nil
+	}
+	repeat := 0
+	var every time.Duration
+	if p := t.com.GetProperty(PropRepeatCount); p != nil {
+		n, err := strconv.Atoi(p.Value)
+		if err != nil {
+			return false, fmt.Errorf("ical:invalid REPEAT %q", p.Value)
+		}
+		repeat = n
+		if d := t.com.GetProperty(PropDuration); d != nil {
+			if every, err = d.GetToDuration(); err != nil {
+				return false, err
+			}
+		}
+	}
+	fires := func(at time.Time) bool {
+		for k := 0; k <= repeat; k++ {
+			if tr.contains(at.Add(time.Duration(k) * every)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if trigger.GetParamValue() == VDTdatetime {
+		at, err := trigger.GetToTime()
+		return err == nil && fires(at), err
+	}
+	offset, err := trigger.GetToDuration()
+	if err != nil || t.parent == nil {
+		return false, err
+	}
+	if tr.End.IsZero() {
+		if ok, err := t.parent.unbounded(); err != nil || ok {
+			return ok, err
+		}
+	}
+	//the instances whose start or end lies where a trigger can fire,
+	//widened by a second to keep instances which end right at from
+	from, to := tr.bounds()
+	lo := from.Add(-offset - time.Duration(repeat)*every - time.Second)
+	if from.IsZero() {
+		lo = time.Time{}
+	}
+	hi := to
+	if !to.Equal(farFuture) {
+		hi = to.Add(-offset + time.Second)
+	}
+	occs, err := t.parent.occurrences(lo, hi)
+	if err != nil {
+		return false, err
+	}
+	related := strings.ToUpper(trigger.Params.Get(Paramtrigrel))
+	for _, o := range occs {
+		ref := o.Start
+		if related == "END" {
+			ref = o.End
+		}
+		if fires(ref.Add(offset)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *PropFilter) matches(com *ComponentObj) (bool, error) {
+	found := false
+	for _, p := range com.Properties() {
+		if !strings.EqualFold(p.Name, f.Name) {
+			continue
+		}
+		found = true
+		if f.IsNotDefined {
+			return false, nil
+		}
+		if ok, err := f.matchesProperty(&p); err != nil || ok {
+			return ok, err
+		}
+	}
+	return f.IsNotDefined && !found, nil
+}
+
+func (f *PropFilter) matchesProperty(p *Property) (bool, error) {
+	if f.TimeRange != nil {
+		t, err := p.GetToTime()
+		if err != nil || !f.TimeRange.contains(t) {
+			//a property without a time value is not in any range
+			return false, nil
+		}
+	}
+	if f.TextMatch != nil {
+		text := p.Value
+		if vt := p.GetParamValue(); vt == VDTtext || vt == VDTdefault {
+			text = FromText(text)
+		}
+		if ok, err := f.TextMatch.Matches(text); err != nil || !ok {
+			return false, err
+		}
+	}
+	for i := range f.ParamFilters {
+		if ok, err := f.ParamFilters[i].matches(p); err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (f *ParamFilter) matches(p *Property) (bool, error) {
+	var values []string
+	found := false
+	for name, vs := range p.Params {
+		if strings.EqualFold(name, f.Name) {
+			values, found = vs, true
+		}
+	}
+	if f.IsNotDefined || !found {
+		return f.IsNotDefined != found, nil
+	}
+	if f.TextMatch == nil {
+		return true, nil
+	}
+	for _, v := range values {
+		if ok, err := f.TextMatch.Matches(v); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// Matches reports whether s contains m.Text under the collation of m,or
+// does not with NegateCondition
+func (m *TextMatch) Matches(s string) (bool, error) {
+	text := m.Text
+	switch m.Collation {
+	case CollationOctet:
+	case "", CollationASCIICasemap:
+		s, text = asciiLower(s), asciiLower(text)
+	case CollationUnicodeCasemap:
+		//simple case folding,without the decomposition of RFC 5051
+		s, text = strings.ToLower(strings.ToUpper(s)), strings.ToLower(strings.ToUpper(text))
+	default:
+		return false, collationError(m.Collation)
+	}
+	return strings.Contains(s, text) != m.NegateCondition, nil
+}
+
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// ParseCalDAVFilter reads a CALDAV:filter element,RFC 4791 9.7
+func ParseCalDAVFilter(b []byte) (*CompFilter, error) {
+	var x calDAVFilterXML
+	if err := xml.Unmarshal(b, &x); err != nil {
+		return nil, err
+	}
+	return x.filter()
+}
+
+type calDAVFilterXML struct {
+	XMLName    xml.Name              `xml:"urn:ietf:params:xml:ns:caldav filter"`
+	CompFilter []calDAVCompFilterXML `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type calDAVCompFilterXML struct {
+	Name         string                `xml:"name,attr"`
+	IsNotDefined *struct{}             `xml:"urn:ietf:params:xml:ns:caldav is-not-defined"`
+	TimeRange    *calDAVTimeRangeXML   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	PropFilters  []calDAVPropFilterXML `xml:"urn:ietf:params:xml:ns:caldav prop-filter"`
+	CompFilters  []calDAVCompFilterXML `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type calDAVPropFilterXML struct {
+	Name         string                 `xml:"name,attr"`
+	IsNotDefined *struct{}              `xml:"urn:ietf:params:xml:ns:caldav is-not-defined"`
+	TimeRange    *calDAVTimeRangeXML    `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	TextMatch    *calDAVTextMatchXML    `xml:"urn:ietf:params:xml:ns:caldav text-match"`
+	ParamFilters []calDAVParamFilterXML `xml:"urn:ietf:params:xml:ns:caldav param-filter"`
+}
+
+type calDAVParamFilterXML struct {
+	Name         string              `xml:"name,attr"`
+	IsNotDefined *struct{}           `xml:"urn:ietf:params:xml:ns:caldav is-not-defined"`
+	TextMatch    *calDAVTextMatchXML `xml:"urn:ietf:params:xml:ns:caldav text-match"`
+}
+
+type calDAVTimeRangeXML struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+type calDAVTextMatchXML struct {
+	Text      string `xml:",chardata"`
+	Collation string `xml:"collation,attr"`
+	Negate    string `xml:"negate-condition,attr"`
+}
+
+// filterError wraps the problems of a filter which breaks
+// CALDAV:valid-filter
+type filterError string
+
+func (e filterError) Error() string {
+	return "ical:invalid CalDAV filter: " + string(e)
+}
+
+func (x *calDAVFilterXML) filter() (*CompFilter, error) {
+	if len(x.CompFilter) != 1 || !strings.EqualFold(x.CompFilter[0].Name, CompCalendar) {
+		return nil, filterError("a filter has one comp-filter of VCALENDAR")
+	}
+	return x.CompFilter[0].filter()
+}
+
+func (x *calDAVCompFilterXML) filter() (*CompFilter, error) {
+	if x.Name == "" {
+		return nil, filterError("comp-filter without name")
+	}
+	f := &CompFilter{Name: strings.ToUpper(x.Name), IsNotDefined: x.IsNotDefined != nil}
+	var err error
+	if f.TimeRange, err = x.TimeRange.timeRange(); err != nil {
+		return nil, err
+	}
+	for i := range x.PropFilters {
+		pf, err := x.PropFilters[i].filter()
+		if err != nil {
+			return nil, err
+		}
+		f.PropFilters = append(f.PropFilters, *pf)
+	}
+	for i := range x.CompFilters {
+		cf, err := x.CompFilters[i].filter()
+		if err != nil {
+			return nil, err
+		}
+		f.CompFilters = append(f.CompFilters, *cf)
+	}
+	return f, nil
+}
+
+func (x *calDAVPropFilterXML) filter() (*PropFilter, error) {
+	if x.Name == "" {
+		return nil, filterError("prop-filter without name")
+	}
+	f := &PropFilter{Name: strings.ToUpper(x.Name), IsNotDefined: x.IsNotDefined != nil}
+	var err error
+	if f.TimeRange, err = x.TimeRange.timeRange(); err != nil {
+		return nil, err
+	}
+	if f.TextMatch, err = x.TextMatch.textMatch(); err != nil {
+		return nil, err
+	}
+	for _, pfx := range x.ParamFilters {
+		if pfx.Name == "" {
+			return nil, filterError("param-filter without name")
+		}
+		pf := ParamFilter{Name: strings.ToUpper(pfx.Name), IsNotDefined: pfx.IsNotDefined != nil}
+		if pf.TextMatch, err = pfx.TextMatch.textMatch(); err != nil {
+			return nil, err
+		}
+		f.ParamFilters = append(f.ParamFilters, pf)
+	}
+	return f, nil
+}
+
+func (x *calDAVTimeRangeXML) timeRange() (*TimeRange, error) {
+	if x == nil {
+		return nil, nil
+	}
+	if x.Start == "" && x.End == "" {
+		return nil, filterError("time-range without start and end")
+	}
+	tr := &TimeRange{}
+	for _, b := range []struct {
+		s string
+		t *time.Time
+	}{{x.Start, &tr.Start}, {x.End, &tr.End}} {
+		if b.s == "" {
+			continue
+		}
+		t, err := time.Parse(DatetimeFormat2, b.s)
+		if err != nil {
+			return nil, filterError(fmt.Sprintf("time-range value %q is not a UTC DATE-TIME", b.s))
+		}
+		*b.t = t
+	}
+	return tr, nil
+}
+
+func (x *calDAVTextMatchXML) textMatch() (*TextMatch, error) {
+	if x == nil {
+		return nil, nil
+	}
+	m := &TextMatch{Text: x.Text, Collation: x.Collation, NegateCondition: x.Negate == "yes"}
+	if _, err := m.Matches(""); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
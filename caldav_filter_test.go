@@ -0,0 +1,151 @@
+package go_ical
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const filterCalendarStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+DTSTART:20210301T090000Z
+DTEND:20210301T093000Z
+RRULE:FREQ=WEEKLY
+EXDATE:20210308T090000Z
+SUMMARY:Team Standup
+CATEGORIES:WORK
+ATTENDEE;CN=Ann;PARTSTAT=ACCEPTED:mailto:ann@example.com
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+DESCRIPTION:Standup
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+RECURRENCE-ID:20210315T090000Z
+DTSTART:20210316T100000Z
+DTEND:20210316T103000Z
+SUMMARY:Moved standup
+END:VEVENT
+BEGIN:VTODO
+UID:review@example.com
+DTSTAMP:20210301T080000Z
+COMPLETED:20210310T120000Z
+SUMMARY:Überprüfung
+END:VTODO
+END:VCALENDAR
+`
+
+func calendarFilter(t *testing.T, inner string) *CompFilter {
+	t.Helper()
+	f, err := ParseCalDAVFilter([]byte(`<C:filter xmlns:C="urn:ietf:params:xml:ns:caldav"><C:comp-filter name="VCALENDAR">` +
+		inner + `</C:comp-filter></C:filter>`))
+	if err != nil {
+		t.Fatalf("ParseCalDAVFilter(%s) err: %v", inner, err)
+	}
+	return f
+}
+
+func TestCompFilterMatch(t *testing.T) {
+	cal := decodeString(t, filterCalendarStr)
+	for _, c := range []struct {
+		filter string
+		want   bool
+	}{
+		{``, true},
+		{`<C:comp-filter name="VEVENT"/>`, true},
+		{`<C:comp-filter name="VEVENT"><C:is-not-defined/></C:comp-filter>`, false},
+		{`<C:comp-filter name="VJOURNAL"><C:is-not-defined/></C:comp-filter>`, true},
+		//the instance of 3/8 is excluded and the one of 3/15 moved to 3/16
+		{`<C:comp-filter name="VEVENT"><C:time-range start="20210308T000000Z" end="20210309T000000Z"/></C:comp-filter>`, false},
+		{`<C:comp-filter name="VEVENT"><C:time-range start="20210315T000000Z" end="20210316T000000Z"/></C:comp-filter>`, false},
+		{`<C:comp-filter name="VEVENT"><C:time-range start="20210316T000000Z" end="20210317T000000Z"/></C:comp-filter>`, true},
+		{`<C:comp-filter name="VEVENT"><C:time-range start="20210322T092900Z" end="20210322T093000Z"/></C:comp-filter>`, true},
+		{`<C:comp-filter name="VEVENT"><C:time-range start="20210322T093000Z" end="20210322T100000Z"/></C:comp-filter>`, false},
+		{`<C:comp-filter name="VEVENT"><C:time-range end="20210301T000000Z"/></C:comp-filter>`, false},
+		{`<C:comp-filter name="VEVENT"><C:time-range start="20300101T000000Z"/></C:comp-filter>`, true},
+		//the tests of one comp-filter hold for the same component
+		{`<C:comp-filter name="VEVENT"><C:time-range start="20210316T000000Z" end="20210317T000000Z"/>
+			<C:prop-filter name="SUMMARY"><C:text-match>team</C:text-match></C:prop-filter></C:comp-filter>`, false},
+		{`<C:comp-filter name="VEVENT"><C:prop-filter name="SUMMARY"><C:text-match>STANDUP</C:text-match></C:prop-filter></C:comp-filter>`, true},
+		{`<C:comp-filter name="VEVENT"><C:prop-filter name="SUMMARY"><C:text-match collation="i;octet">STANDUP</C:text-match></C:prop-filter></C:comp-filter>`, false},
+		{`<C:comp-filter name="VEVENT"><C:prop-filter name="CATEGORIES"><C:text-match negate-condition="yes">WORK</C:text-match></C:prop-filter></C:comp-filter>`, false},
+		{`<C:comp-filter name="VEVENT"><C:prop-filter name="LOCATION"><C:is-not-defined/></C:prop-filter></C:comp-filter>`, true},
+		{`<C:comp-filter name="VEVENT"><C:prop-filter name="DTSTART"><C:time-range start="20210301T000000Z" end="20210302T000000Z"/></C:prop-filter></C:comp-filter>`, true},
+		{`<C:comp-filter name="VEVENT"><C:prop-filter name="SUMMARY"><C:time-range start="20210301T000000Z"/></C:prop-filter></C:comp-filter>`, false},
+		{`<C:comp-filter name="VEVENT"><C:prop-filter name="ATTENDEE"><C:param-filter name="PARTSTAT"><C:text-match>accepted</C:text-match></C:param-filter></C:prop-filter></C:comp-filter>`, true},
+		{`<C:comp-filter name="VEVENT"><C:prop-filter name="ATTENDEE"><C:param-filter name="PARTSTAT"><C:text-match>DECLINED</C:text-match></C:param-filter></C:prop-filter></C:comp-filter>`, false},
+		{`<C:comp-filter name="VEVENT"><C:prop-filter name="ATTENDEE"><C:param-filter name="RSVP"><C:is-not-defined/></C:param-filter></C:prop-filter></C:comp-filter>`, true},
+		{`<C:comp-filter name="VEVENT"><C:prop-filter name="ATTENDEE"><C:param-filter name="CN"><C:is-not-defined/></C:param-filter></C:prop-filter></C:comp-filter>`, false},
+		//the alarm fires 15 minutes before each instance
+		{`<C:comp-filter name="VEVENT"><C:comp-filter name="VALARM"><C:time-range start="20210322T084500Z" end="20210322T084600Z"/></C:comp-filter></C:comp-filter>`, true},
+		{`<C:comp-filter name="VEVENT"><C:comp-filter name="VALARM"><C:time-range start="20210308T084500Z" end="20210308T084600Z"/></C:comp-filter></C:comp-filter>`, false},
+		//a VTODO without DTSTART and DUE is tested on COMPLETED
+		{`<C:comp-filter name="VTODO"><C:time-range start="20210301T000000Z" end="20210311T000000Z"/></C:comp-filter>`, true},
+		{`<C:comp-filter name="VTODO"><C:time-range start="20210311T000000Z" end="20210320T000000Z"/></C:comp-filter>`, false},
+		{`<C:comp-filter name="VTODO"><C:prop-filter name="SUMMARY"><C:text-match collation="i;unicode-casemap">überprüfung</C:text-match></C:prop-filter></C:comp-filter>`, true},
+		{`<C:comp-filter name="VTODO"><C:prop-filter name="SUMMARY"><C:text-match>überprüfung</C:text-match></C:prop-filter></C:comp-filter>`, false},
+	} {
+		got, err := calendarFilter(t, c.filter).Match(cal)
+		if err != nil {
+			t.Errorf("Match(%s) err: %v", c.filter, err)
+		} else if got != c.want {
+			t.Errorf("Match(%s) = %v, want %v", c.filter, got, c.want)
+		}
+	}
+}
+
+func TestParseCalDAVFilterErrors(t *testing.T) {
+	for _, s := range []string{
+		`<C:filter xmlns:C="urn:ietf:params:xml:ns:caldav"><C:comp-filter name="VEVENT"/></C:filter>`,
+		`<C:filter xmlns:C="urn:ietf:params:xml:ns:caldav"><C:comp-filter name="VCALENDAR"><C:comp-filter/></C:comp-filter></C:filter>`,
+		`<C:filter xmlns:C="urn:ietf:params:xml:ns:caldav"><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT"><C:time-range start="2021-03-01"/></C:comp-filter></C:comp-filter></C:filter>`,
+		`<C:filter xmlns:C="urn:ietf:params:xml:ns:caldav"><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT"><C:time-range/></C:comp-filter></C:comp-filter></C:filter>`,
+		`<C:filter xmlns:C="urn:ietf:params:xml:ns:caldav"><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT"><C:prop-filter name="SUMMARY"><C:text-match collation="i;bogus">x</C:text-match></C:prop-filter></C:comp-filter></C:comp-filter></C:filter>`,
+		`<filter/>`,
+	} {
+		if _, err := ParseCalDAVFilter([]byte(s)); err == nil {
+			t.Errorf("ParseCalDAVFilter(%s) err = nil", s)
+		}
+	}
+	if _, err := (&CompFilter{Name: CompEvent}).Match(Calendar{}); err == nil {
+		t.Error("Match() of a VEVENT root err = nil")
+	}
+}
+
+func TestCalDAVQueryFilter(t *testing.T) {
+	srv, store := newCalDAVTestServer()
+	defer srv.Close()
+	cal, todo := decodeString(t, filterCalendarStr), decodeString(t, caldavTodoStr)
+	store.PutObject("/calendars/alice/work/standup.ics", &cal, CalDAVCondition{})
+	store.PutObject("/calendars/alice/work/report.ics", &todo, CalDAVCondition{})
+
+	query := func(filter string) (*http.Response, string) {
+		return caldavDo(t, srv, "REPORT", "/calendars/alice/work/", `<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+ <D:prop><D:getetag/></D:prop>
+ <C:filter><C:comp-filter name="VCALENDAR">`+filter+`</C:comp-filter></C:filter>
+</C:calendar-query>`, "Depth", "1")
+	}
+	_, body := query(`<C:comp-filter name="VTODO"><C:time-range start="20210305T000000Z" end="20210306T000000Z"/></C:comp-filter>`)
+	expectContains(t, "calendar-query", body, "/calendars/alice/work/report.ics")
+	if strings.Contains(body, "standup.ics") {
+		t.Errorf("calendar-query returned standup.ics\n%s", body)
+	}
+	_, body = query(`<C:comp-filter name="VEVENT"><C:time-range start="20210405T000000Z" end="20210406T000000Z"/></C:comp-filter>`)
+	expectContains(t, "calendar-query", body, "/calendars/alice/work/standup.ics")
+
+	resp, body := query(`<C:comp-filter name="VEVENT"><C:prop-filter name="SUMMARY"><C:text-match collation="i;bogus">x</C:text-match></C:prop-filter></C:comp-filter>`)
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(body, "<C:supported-collation") {
+		t.Errorf("unknown collation status = %d\n%s", resp.StatusCode, body)
+	}
+	resp, body = query(`<C:comp-filter name="VEVENT"><C:time-range start="tomorrow"/></C:comp-filter>`)
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(body, "<C:valid-filter") {
+		t.Errorf("invalid time-range status = %d\n%s", resp.StatusCode, body)
+	}
+}
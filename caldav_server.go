@@ -0,0 +1,680 @@
+package go_ical
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrCalDAVNotFound is returned by a CalDAVStore for a missing resource
+var ErrCalDAVNotFound = errors.New("ical:CalDAV resource not found")
+
+// ErrCalDAVPreconditionFailed is returned when an If-Match or If-None-Match
+// ETag does not hold,someone else changed the resource
+var ErrCalDAVPreconditionFailed = errors.New("ical:CalDAV precondition failed")
+
+// CalDAVCalendar is a calendar collection
+type CalDAVCalendar struct {
+	//Path is the URL path of the collection,ending with "/"
+	Path        string
+	Name        string
+	Description string
+	//Components are the component names the calendar accepts,
+	//VEVENT and VTODO when empty
+	Components []string
+}
+
+// CalDAVObject is a calendar object resource,one .ics file
+type CalDAVObject struct {
+	Path    string
+	ETag    string
+	ModTime time.Time
+	Data    *Calendar
+}
+
+/*
+CalDAVStore keeps the calendars served by a CalDAVHandler.
+
+Paths are URL paths. The ETag of an object is an opaque string without
+quotes which changes whenever its data changes. PutObject and DeleteObject
+get the If-Match and If-None-Match headers of the request as a
+CalDAVCondition. The store must check it and write in one step,with
+CalDAVCondition.Check under the lock which guards the object,and return
+ErrCalDAVPreconditionFailed when it fails,so that two concurrent requests
+with the same If-Match can not both succeed.
+*/
+type CalDAVStore interface {
+	//Calendars returns the calendars of the calendar home
+	Calendars() ([]CalDAVCalendar, error)
+	//CreateCalendar creates the calendar collection c.Path
+	CreateCalendar(c *CalDAVCalendar) error
+	//Objects returns the objects of the calendar at calPath
+	Objects(calPath string) ([]CalDAVObject, error)
+	//Object returns the object at path,or ErrCalDAVNotFound
+	Object(path string) (*CalDAVObject, error)
+	//PutObject creates or replaces the object at path when cond holds and
+	//returns it with its new ETag
+	PutObject(path string, cal *Calendar, cond CalDAVCondition) (*CalDAVObject, error)
+	//DeleteObject removes the object at path when cond holds,or returns
+	//ErrCalDAVNotFound
+	DeleteObject(path string, cond CalDAVCondition) error
+}
+
+// CalDAVCondition is the precondition of a write,the values of the If-Match
+// and If-None-Match headers,RFC 7232 3.1 and 3.2. Empty values always hold.
+type CalDAVCondition struct {
+	IfMatch     string
+	IfNoneMatch string
+}
+
+// Check returns ErrCalDAVPreconditionFailed when c does not hold for the
+// current object o,which is nil when the resource does not exist
+func (c CalDAVCondition) Check(o *CalDAVObject) error {
+	if c.IfMatch != "" && (o == nil || !etagMatches(c.IfMatch, o.ETag)) {
+		return ErrCalDAVPreconditionFailed
+	}
+	if c.IfNoneMatch != "" && o != nil && etagMatches(c.IfNoneMatch, o.ETag) {
+		return ErrCalDAVPreconditionFailed
+	}
+	return nil
+}
+
+func requestCondition(r *http.Request) CalDAVCondition {
+	return CalDAVCondition{IfMatch: r.Header.Get("If-Match"), IfNoneMatch: r.Header.Get("If-None-Match")}
+}
+
+/*
+CalDAVHandler serves the calendars of one user over CalDAV,RFC 4791.
+
+It answers OPTIONS,PROPFIND,REPORT with calendar-query and
+calendar-multiget,GET,HEAD,PUT and DELETE of calendar objects with ETags,
+If-Match and If-None-Match,and MKCALENDAR. The filter of a calendar-query
+is evaluated with CompFilter.Match. Request paths are the paths of the
+store,the handler must not be mounted behind http.StripPrefix. Clients find
+the calendars from any path through DAV:current-user-principal and
+CALDAV:calendar-home-set,or through /.well-known/caldav,RFC 6764.
+*/
+type CalDAVHandler struct {
+	Store CalDAVStore
+	//Principal is the path of the user's principal,e.g. "/principals/alice/"
+	Principal string
+	//Home is the path of the collection holding the calendars,
+	//e.g. "/calendars/alice/"
+	Home string
+}
+
+// calDAVComponents are the default supported-calendar-component-set
+var calDAVComponents = []string{CompEvent, CompTodo}
+
+func (h *CalDAVHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Path
+	if p == "/.well-known/caldav" {
+		http.Redirect(w, r, h.Principal, http.StatusMovedPermanently)
+		return
+	}
+	var err error
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("DAV", "1, 3, calendar-access")
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT, MKCALENDAR")
+	case "PROPFIND":
+		err = h.propfind(w, r)
+	case "REPORT":
+		err = h.report(w, r)
+	case http.MethodGet, http.MethodHead:
+		err = h.get(w, r)
+	case http.MethodPut:
+		err = h.put(w, r)
+	case http.MethodDelete:
+		err = h.delete(w, r)
+	case "MKCALENDAR":
+		err = h.mkcalendar(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+	if err == ErrCalDAVNotFound {
+		http.NotFound(w, r)
+	} else if err == ErrCalDAVPreconditionFailed {
+		w.WriteHeader(http.StatusPreconditionFailed)
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// calendar returns the calendar at p,with or without the final slash
+func (h *CalDAVHandler) calendar(p string) (*CalDAVCalendar, error) {
+	if !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	cals, err := h.Store.Calendars()
+	if err != nil {
+		return nil, err
+	}
+	for i := range cals {
+		if cals[i].Path == p {
+			return &cals[i], nil
+		}
+	}
+	return nil, ErrCalDAVNotFound
+}
+
+// davResource is a resource with the properties it has,mapped to their XML
+type davResource struct {
+	href  string
+	props map[xml.Name]string
+}
+
+func (h *CalDAVHandler) hrefProp(p string) string {
+	return "<D:href>" + davText(davHref(p)) + "</D:href>"
+}
+
+func (h *CalDAVHandler) principalResource(p string) davResource {
+	props := map[xml.Name]string{
+		{Space: davNS, Local: "resourcetype"}:           "<D:collection/>",
+		{Space: davNS, Local: "current-user-principal"}: h.hrefProp(h.Principal),
+		{Space: davNS, Local: "principal-URL"}:          h.hrefProp(h.Principal),
+		{Space: caldavNS, Local: "calendar-home-set"}:   h.hrefProp(h.Home),
+	}
+	if p == h.Principal {
+		props[xml.Name{Space: davNS, Local: "resourcetype"}] = "<D:collection/><D:principal/>"
+	}
+	return davResource{href: p, props: props}
+}
+
+func (h *CalDAVHandler) homeResource() davResource {
+	return davResource{href: h.Home, props: map[xml.Name]string{
+		{Space: davNS, Local: "resourcetype"}:           "<D:collection/>",
+		{Space: davNS, Local: "current-user-principal"}: h.hrefProp(h.Principal),
+	}}
+}
+
+func (h *CalDAVHandler) calendarResource(c *CalDAVCalendar) davResource {
+	comps := c.Components
+	if len(comps) == 0 {
+		comps = calDAVComponents
+	}
+	set := ""
+	for _, name := range comps {
+		set += `<C:comp name="` + davText(name) + `"/>`
+	}
+	props := map[xml.Name]string{
+		{Space: davNS, Local: "resourcetype"}:                        "<D:collection/><C:calendar/>",
+		{Space: davNS, Local: "current-user-principal"}:              h.hrefProp(h.Principal),
+		{Space: caldavNS, Local: "supported-calendar-component-set"}: set,
+		{Space: davNS, Local: "supported-report-set"}: "<D:supported-report><D:report><C:calendar-query/></D:report></D:supported-report>" +
+			"<D:supported-report><D:report><C:calendar-multiget/></D:report></D:supported-report>",
+	}
+	if c.Name != "" {
+		props[xml.Name{Space: davNS, Local: "displayname"}] = davText(c.Name)
+	}
+	if c.Description != "" {
+		props[xml.Name{Space: caldavNS, Local: "calendar-description"}] = davText(c.Description)
+	}
+	return davResource{href: c.Path, props: props}
+}
+
+// objectResource returns the properties of o,with C:calendar-data when data
+// is set as it is only returned by REPORT
+func objectResource(o *CalDAVObject, data bool) (davResource, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(o.Data); err != nil {
+		return davResource{}, err
+	}
+	props := map[xml.Name]string{
+		{Space: davNS, Local: "resourcetype"}:     "",
+		{Space: davNS, Local: "getetag"}:          davText(strconv.Quote(o.ETag)),
+		{Space: davNS, Local: "getcontenttype"}:   "text/calendar; charset=utf-8",
+		{Space: davNS, Local: "getcontentlength"}: strconv.Itoa(buf.Len()),
+	}
+	if !o.ModTime.IsZero() {
+		props[xml.Name{Space: davNS, Local: "getlastmodified"}] = o.ModTime.UTC().Format(http.TimeFormat)
+	}
+	if data {
+		props[xml.Name{Space: caldavNS, Local: "calendar-data"}] = davText(buf.String())
+	}
+	return davResource{href: o.Path, props: props}, nil
+}
+
+// response answers a PROPFIND or REPORT for res,names nil asks for all
+// properties and propname for their names only
+func (res davResource) response(names []xml.Name, propname bool) davResponse {
+	found := davPropstat{Status: davStatus(http.StatusOK)}
+	missing := davPropstat{Status: davStatus(http.StatusNotFound)}
+	if names == nil {
+		for name := range res.props {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if names[i].Space != names[j].Space {
+				return names[i].Space < names[j].Space
+			}
+			return names[i].Local < names[j].Local
+		})
+	}
+	for _, name := range names {
+		inner, ok := res.props[name]
+		if !ok {
+			missing.Props = append(missing.Props, davProperty{XMLName: davPrefixed(name)})
+			continue
+		}
+		if propname {
+			inner = ""
+		}
+		found.Props = append(found.Props, davProperty{XMLName: davPrefixed(name), Inner: inner})
+	}
+	resp := davResponse{Href: davHref(res.href)}
+	for _, ps := range []davPropstat{found, missing} {
+		if len(ps.Props) > 0 {
+			resp.Propstats = append(resp.Propstats, ps)
+		}
+	}
+	return resp
+}
+
+func newMultistatus(resps []davResponse) *davMultistatus {
+	return &davMultistatus{NSDAV: davNS, NSCalDAV: caldavNS, Responses: resps}
+}
+
+// readDAVBody reads the XML body of r into v,an empty body leaves v alone
+func readDAVBody(r *http.Request, v interface{}) (bool, error) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil || len(bytes.TrimSpace(b)) == 0 {
+		return false, err
+	}
+	return true, xml.Unmarshal(b, v)
+}
+
+type davPropfindRequest struct {
+	XMLName  xml.Name  `xml:"DAV: propfind"`
+	AllProp  *struct{} `xml:"DAV: allprop"`
+	PropName *struct{} `xml:"DAV: propname"`
+	Prop     *davNames `xml:"DAV: prop"`
+}
+
+// requested returns the asked property names,nil for all of them
+func requested(prop *davNames) []xml.Name {
+	if prop == nil {
+		return nil
+	}
+	names := make([]xml.Name, 0, len(prop.Names))
+	for _, n := range prop.Names {
+		names = append(names, n.XMLName)
+	}
+	return names
+}
+
+func (h *CalDAVHandler) propfind(w http.ResponseWriter, r *http.Request) error {
+	var req davPropfindRequest
+	if _, err := readDAVBody(r, &req); err != nil {
+		http.Error(w, "invalid PROPFIND body: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	names := requested(req.Prop)
+	depth := r.Header.Get("Depth")
+	resources, err := h.resources(r.URL.Path, depth != "0")
+	if err != nil {
+		return err
+	}
+	resps := make([]davResponse, 0, len(resources))
+	for _, res := range resources {
+		resps = append(resps, res.response(names, req.PropName != nil))
+	}
+	writeDAVXML(w, http.StatusMultiStatus, newMultistatus(resps))
+	return nil
+}
+
+// resources returns the resource at p and,with children,its members
+func (h *CalDAVHandler) resources(p string, children bool) ([]davResource, error) {
+	switch {
+	case p == "/" || p == h.Principal:
+		return []davResource{h.principalResource(p)}, nil
+	case p == h.Home || p+"/" == h.Home:
+		res := []davResource{h.homeResource()}
+		if !children {
+			return res, nil
+		}
+		cals, err := h.Store.Calendars()
+		if err != nil {
+			return nil, err
+		}
+		for i := range cals {
+			res = append(res, h.calendarResource(&cals[i]))
+		}
+		return res, nil
+	}
+	c, err := h.calendar(p)
+	if err == ErrCalDAVNotFound {
+		o, err := h.Store.Object(p)
+		if err != nil {
+			return nil, err
+		}
+		res, err := objectResource(o, false)
+		return []davResource{res}, err
+	} else if err != nil {
+		return nil, err
+	}
+	res := []davResource{h.calendarResource(c)}
+	if !children {
+		return res, nil
+	}
+	objs, err := h.Store.Objects(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range objs {
+		o, err := objectResource(&objs[i], false)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, o)
+	}
+	return res, nil
+}
+
+type calDAVReport struct {
+	XMLName xml.Name
+	Prop    *davNames `xml:"DAV: prop"`
+	//Hrefs are the resources of a calendar-multiget
+	Hrefs []string `xml:"DAV: href"`
+	//Filter is the filter of a calendar-query
+	Filter *calDAVFilterXML `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+func (h *CalDAVHandler) report(w http.ResponseWriter, r *http.Request) error {
+	var req calDAVReport
+	if ok, err := readDAVBody(r, &req); err != nil || !ok {
+		http.Error(w, "invalid REPORT body", http.StatusBadRequest)
+		return nil
+	}
+	names := requested(req.Prop)
+	var objs []CalDAVObject
+	var resps []davResponse
+	switch req.XMLName {
+	case xml.Name{Space: caldavNS, Local: "calendar-multiget"}:
+		for _, href := range req.Hrefs {
+			u, err := url.Parse(href)
+			if err != nil {
+				http.Error(w, "invalid href "+href, http.StatusBadRequest)
+				return nil
+			}
+			o, err := h.Store.Object(u.Path)
+			if err == ErrCalDAVNotFound {
+				resps = append(resps, davResponse{Href: davHref(u.Path), Status: davStatus(http.StatusNotFound)})
+				continue
+			} else if err != nil {
+				return err
+			}
+			objs = append(objs, *o)
+		}
+	case xml.Name{Space: caldavNS, Local: "calendar-query"}:
+		if req.Filter == nil {
+			writeDAVError(w, http.StatusForbidden, xml.Name{Space: caldavNS, Local: "valid-filter"})
+			return nil
+		}
+		f, err := req.Filter.filter()
+		if _, ok := err.(collationError); ok {
+			writeDAVError(w, http.StatusForbidden, xml.Name{Space: caldavNS, Local: "supported-collation"})
+			return nil
+		} else if err != nil {
+			writeDAVError(w, http.StatusForbidden, xml.Name{Space: caldavNS, Local: "valid-filter"})
+			return nil
+		}
+		all, err := h.query(r.URL.Path)
+		if err != nil {
+			return err
+		}
+		for _, o := range all {
+			//an object the filter can not evaluate,e.g. with an invalid
+			//RRULE,is not returned
+			if ok, err := f.Match(*o.Data); err == nil && ok {
+				objs = append(objs, o)
+			}
+		}
+	default:
+		writeDAVError(w, http.StatusForbidden, xml.Name{Space: davNS, Local: "supported-report"})
+		return nil
+	}
+	for i := range objs {
+		res, err := objectResource(&objs[i], true)
+		if err != nil {
+			return err
+		}
+		resps = append(resps, res.response(names, false))
+	}
+	writeDAVXML(w, http.StatusMultiStatus, newMultistatus(resps))
+	return nil
+}
+
+// query returns the objects a calendar-query on p searches
+func (h *CalDAVHandler) query(p string) ([]CalDAVObject, error) {
+	c, err := h.calendar(p)
+	if err == ErrCalDAVNotFound {
+		o, err := h.Store.Object(p)
+		if err != nil {
+			return nil, err
+		}
+		return []CalDAVObject{*o}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return h.Store.Objects(c.Path)
+}
+
+func (h *CalDAVHandler) get(w http.ResponseWriter, r *http.Request) error {
+	o, err := h.Store.Object(r.URL.Path)
+	if err != nil {
+		return err
+	}
+	etag := strconv.Quote(o.ETag)
+	w.Header().Set("ETag", etag)
+	if !o.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", o.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), o.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(o.Data); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	if r.Method != http.MethodHead {
+		w.Write(buf.Bytes())
+	}
+	return nil
+}
+
+// etagMatches reports whether the If-Match or If-None-Match header lists etag
+func etagMatches(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == strconv.Quote(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *CalDAVHandler) put(w http.ResponseWriter, r *http.Request) error {
+	p := r.URL.Path
+	if strings.HasSuffix(p, "/") {
+		http.Error(w, "cannot PUT a collection", http.StatusMethodNotAllowed)
+		return nil
+	}
+	c, err := h.calendar(path.Dir(p))
+	if err == ErrCalDAVNotFound {
+		http.Error(w, "no calendar collection at "+path.Dir(p), http.StatusConflict)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	old, err := h.Store.Object(p)
+	if err == ErrCalDAVNotFound {
+		old = nil
+	} else if err != nil {
+		return err
+	}
+	//fail early before reading the body,the store checks again as it writes
+	cond := requestCondition(r)
+	if err := cond.Check(old); err != nil {
+		return err
+	}
+
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "text/calendar" {
+		writeDAVError(w, http.StatusUnsupportedMediaType, xml.Name{Space: caldavNS, Local: "supported-calendar-data"})
+		return nil
+	}
+	cal, err := NewDecoder(r.Body).Decode()
+	if err != nil {
+		writeDAVError(w, http.StatusBadRequest, xml.Name{Space: caldavNS, Local: "valid-calendar-data"})
+		return nil
+	}
+	//some input decodes but can not be written back,such as control
+	//characters in a value,the store would fail on it
+	if err := NewEncoder(ioutil.Discard).Encode(&cal); err != nil {
+		writeDAVError(w, http.StatusForbidden, xml.Name{Space: caldavNS, Local: "valid-calendar-data"})
+		return nil
+	}
+	uid, broken := calendarObjectUID(&cal, c)
+	if broken != "" {
+		writeDAVError(w, http.StatusForbidden, xml.Name{Space: caldavNS, Local: broken})
+		return nil
+	}
+	objs, err := h.Store.Objects(c.Path)
+	if err != nil {
+		return err
+	}
+	for _, o := range objs {
+		if o.Path == p {
+			continue
+		}
+		if other, _ := calendarObjectUID(o.Data, c); other == uid {
+			writeDAVError(w, http.StatusConflict, xml.Name{Space: caldavNS, Local: "no-uid-conflict"})
+			return nil
+		}
+	}
+
+	o, err := h.Store.PutObject(p, &cal, cond)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", strconv.Quote(o.ETag))
+	if old == nil {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return nil
+}
+
+// calendarObjectUID returns the UID of a calendar object resource,or the
+// CalDAV precondition it breaks,RFC 4791 4.1
+func calendarObjectUID(cal *Calendar, c *CalDAVCalendar) (string, string) {
+	if cal.GetProperty(PropMethod) != nil {
+		return "", "valid-calendar-object-resource"
+	}
+	comps := c.Components
+	if len(comps) == 0 {
+		comps = calDAVComponents
+	}
+	uid := ""
+	for _, sub := range cal.SubComponents() {
+		if sub.Name() == CompTimezone {
+			continue
+		}
+		if !contains(comps, sub.Name()) {
+			return "", "supported-calendar-component"
+		}
+		p := sub.obj().GetProperty(PropUID)
+		if p == nil || uid != "" && p.Value != uid {
+			return "", "valid-calendar-object-resource"
+		}
+		uid = p.Value
+	}
+	if uid == "" {
+		return "", "valid-calendar-object-resource"
+	}
+	return uid, ""
+}
+
+func (h *CalDAVHandler) delete(w http.ResponseWriter, r *http.Request) error {
+	o, err := h.Store.Object(r.URL.Path)
+	if err != nil {
+		return err
+	}
+	if err := h.Store.DeleteObject(o.Path, requestCondition(r)); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type calDAVMkcalendar struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:caldav mkcalendar"`
+	Prop    struct {
+		DisplayName string `xml:"DAV: displayname"`
+		Description string `xml:"urn:ietf:params:xml:ns:caldav calendar-description"`
+		Components  []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set>comp"`
+	} `xml:"DAV: set>prop"`
+}
+
+func (h *CalDAVHandler) mkcalendar(w http.ResponseWriter, r *http.Request) error {
+	p := r.URL.Path
+	if !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	if path.Dir(strings.TrimSuffix(p, "/"))+"/" != h.Home {
+		http.Error(w, "calendars are created in "+h.Home, http.StatusConflict)
+		return nil
+	}
+	if _, err := h.calendar(p); err == nil {
+		writeDAVError(w, http.StatusMethodNotAllowed, xml.Name{Space: davNS, Local: "resource-must-be-null"})
+		return nil
+	} else if err != ErrCalDAVNotFound {
+		return err
+	}
+	var req calDAVMkcalendar
+	if _, err := readDAVBody(r, &req); err != nil {
+		http.Error(w, "invalid MKCALENDAR body: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	c := &CalDAVCalendar{Path: p, Name: req.Prop.DisplayName, Description: req.Prop.Description}
+	for _, comp := range req.Prop.Components {
+		c.Components = append(c.Components, strings.ToUpper(comp.Name))
+	}
+	if err := h.Store.CreateCalendar(c); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// calendarETag returns an ETag derived from the encoded calendar
+func calendarETag(cal *Calendar) (string, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(cal); err != nil {
+		return "", err
+	}
+	return contentETag(buf.Bytes()), nil
+}
+
+// contentETag returns the ETag of an encoded calendar
+func contentETag(b []byte) string {
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,406 @@
+package go_ical
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memCalDAVStore is a CalDAVStore in memory for the tests
+type memCalDAVStore struct {
+	mu      sync.Mutex
+	cals    map[string]CalDAVCalendar
+	objects map[string]CalDAVObject
+}
+
+func newMemCalDAVStore(cals ...CalDAVCalendar) *memCalDAVStore {
+	s := &memCalDAVStore{cals: map[string]CalDAVCalendar{}, objects: map[string]CalDAVObject{}}
+	for _, c := range cals {
+		s.cals[c.Path] = c
+	}
+	return s
+}
+
+func (s *memCalDAVStore) Calendars() ([]CalDAVCalendar, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var cals []CalDAVCalendar
+	for _, c := range s.cals {
+		cals = append(cals, c)
+	}
+	sort.Slice(cals, func(i, j int) bool { return cals[i].Path < cals[j].Path })
+	return cals, nil
+}
+
+func (s *memCalDAVStore) CreateCalendar(c *CalDAVCalendar) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cals[c.Path] = *c
+	return nil
+}
+
+func (s *memCalDAVStore) Objects(calPath string) ([]CalDAVObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var objs []CalDAVObject
+	for p, o := range s.objects {
+		if strings.HasPrefix(p, calPath) {
+			objs = append(objs, o)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool { return objs[i].Path < objs[j].Path })
+	return objs, nil
+}
+
+func (s *memCalDAVStore) Object(path string) (*CalDAVObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.objects[path]
+	if !ok {
+		return nil, ErrCalDAVNotFound
+	}
+	return &o, nil
+}
+
+func (s *memCalDAVStore) PutObject(path string, cal *Calendar, cond CalDAVCondition) (*CalDAVObject, error) {
+	etag, err := calendarETag(cal)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := cond.Check(s.object(path)); err != nil {
+		return nil, err
+	}
+	o := CalDAVObject{Path: path, ETag: etag, ModTime: time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC), Data: cal}
+	s.objects[path] = o
+	return &o, nil
+}
+
+func (s *memCalDAVStore) DeleteObject(path string, cond CalDAVCondition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.object(path)
+	if o == nil {
+		return ErrCalDAVNotFound
+	}
+	if err := cond.Check(o); err != nil {
+		return err
+	}
+	delete(s.objects, path)
+	return nil
+}
+
+// object returns the object at path or nil,the caller holds mu
+func (s *memCalDAVStore) object(path string) *CalDAVObject {
+	if o, ok := s.objects[path]; ok {
+		return &o
+	}
+	return nil
+}
+
+const caldavEventStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+DTSTART:20210301T090000Z
+DTEND:20210301T093000Z
+SUMMARY:Standup
+END:VEVENT
+END:VCALENDAR
+`
+
+const caldavTodoStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VTODO
+UID:report@example.com
+DTSTAMP:20210301T080000Z
+DUE:20210305T170000Z
+SUMMARY:Report
+END:VTODO
+END:VCALENDAR
+`
+
+func newCalDAVTestServer() (*httptest.Server, *memCalDAVStore) {
+	store := newMemCalDAVStore(CalDAVCalendar{Path: "/calendars/alice/work/", Name: "Work"})
+	srv := httptest.NewServer(&CalDAVHandler{Store: store, Principal: "/principals/alice/", Home: "/calendars/alice/"})
+	return srv, store
+}
+
+func caldavDo(t *testing.T, srv *httptest.Server, method, path, body string, header ...string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func expectContains(t *testing.T, what, got string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("%s does not contain %s\n%s", what, want, got)
+		}
+	}
+}
+
+func TestCalDAVPutGetDelete(t *testing.T) {
+	srv, _ := newCalDAVTestServer()
+	defer srv.Close()
+	ics := "Content-Type"
+	const obj = "/calendars/alice/work/standup.ics"
+
+	resp, _ := caldavDo(t, srv, "PUT", obj, caldavEventStr, ics, "text/calendar")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("PUT status = %d", resp.StatusCode)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("PUT returned no ETag")
+	}
+
+	resp, body := caldavDo(t, srv, "GET", obj, "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != etag {
+		t.Fatalf("GET status = %d, ETag = %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	if body != toCRLF(caldavEventStr) {
+		t.Errorf("GET body = %s", body)
+	}
+	if resp, _ := caldavDo(t, srv, "GET", obj, "", "If-None-Match", etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional GET status = %d", resp.StatusCode)
+	}
+
+	changed := strings.Replace(caldavEventStr, "Standup", "Standup!", 1)
+	for _, c := range []struct {
+		body   string
+		header []string
+		want   int
+		cond   string
+	}{
+		{changed, []string{ics, "text/calendar", "If-Match", `"stale"`}, http.StatusPreconditionFailed, ""},
+		{changed, []string{ics, "text/calendar", "If-None-Match", "*"}, http.StatusPreconditionFailed, ""},
+		{changed, []string{ics, "text/plain"}, http.StatusUnsupportedMediaType, "<C:supported-calendar-data"},
+		{"BEGIN:VCALENDAR\n", []string{ics, "text/calendar"}, http.StatusBadRequest, "<C:valid-calendar-data"},
+		{strings.Replace(changed, "Standup!", "bad\x01ctl", 1), []string{ics, "text/calendar"}, http.StatusForbidden, "<C:valid-calendar-data"},
+		{strings.Replace(changed, "VERSION:2.0", "VERSION:2.0\nMETHOD:REQUEST", 1), []string{ics, "text/calendar"}, http.StatusForbidden, "<C:valid-calendar-object-resource"},
+		{strings.Replace(caldavEventStr, "VEVENT", "VJOURNAL", -1), []string{ics, "text/calendar"}, http.StatusForbidden, "<C:supported-calendar-component"},
+		{changed, []string{ics, "text/calendar", "If-Match", etag}, http.StatusNoContent, ""},
+	} {
+		resp, body := caldavDo(t, srv, "PUT", obj, c.body, c.header...)
+		if resp.StatusCode != c.want || !strings.Contains(body, c.cond) {
+			t.Errorf("PUT %v status = %d, want %d\n%s", c.header, resp.StatusCode, c.want, body)
+		}
+	}
+	resp, _ = caldavDo(t, srv, "PUT", "/calendars/alice/work/copy.ics", caldavEventStr, ics, "text/calendar")
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("PUT of the same UID status = %d", resp.StatusCode)
+	}
+	resp, _ = caldavDo(t, srv, "PUT", "/calendars/alice/home/standup.ics", caldavEventStr, ics, "text/calendar")
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("PUT outside a calendar status = %d", resp.StatusCode)
+	}
+
+	if resp, _ := caldavDo(t, srv, "DELETE", obj, "", "If-Match", etag); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with stale ETag status = %d", resp.StatusCode)
+	}
+	if resp, _ := caldavDo(t, srv, "DELETE", obj, ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE status = %d", resp.StatusCode)
+	}
+	if resp, _ := caldavDo(t, srv, "GET", obj, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET after DELETE status = %d", resp.StatusCode)
+	}
+}
+
+func TestCalDAVPropfind(t *testing.T) {
+	srv, store := newCalDAVTestServer()
+	defer srv.Close()
+	cal := decodeString(t, caldavEventStr)
+	o, _ := store.PutObject("/calendars/alice/work/standup.ics", &cal, CalDAVCondition{})
+
+	resp, _ := caldavDo(t, srv, "GET", "/.well-known/caldav", "")
+	if resp.Request.URL.Path != "/principals/alice/" {
+		t.Errorf("/.well-known/caldav went to %s", resp.Request.URL.Path)
+	}
+
+	resp, body := caldavDo(t, srv, "PROPFIND", "/", `<?xml version="1.0"?>
+<propfind xmlns="DAV:"><prop><current-user-principal/></prop></propfind>`, "Depth", "0")
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND status = %d", resp.StatusCode)
+	}
+	expectContains(t, "PROPFIND /", body,
+		`<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">`,
+		`<D:current-user-principal><D:href>/principals/alice/</D:href></D:current-user-principal>`)
+
+	_, body = caldavDo(t, srv, "PROPFIND", "/principals/alice/", `<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+<d:prop><c:calendar-home-set/><d:resourcetype/><d:owner/></d:prop></d:propfind>`, "Depth", "0")
+	expectContains(t, "PROPFIND principal", body,
+		`<C:calendar-home-set><D:href>/calendars/alice/</D:href></C:calendar-home-set>`,
+		`<D:resourcetype><D:collection/><D:principal/></D:resourcetype>`,
+		`<D:propstat><D:prop><D:owner></D:owner></D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>`)
+
+	_, body = caldavDo(t, srv, "PROPFIND", "/calendars/alice/", `<propfind xmlns="DAV:"><prop><resourcetype/><displayname/></prop></propfind>`, "Depth", "1")
+	expectContains(t, "PROPFIND home", body,
+		`<D:href>/calendars/alice/work/</D:href><D:propstat><D:prop><D:resourcetype><D:collection/><C:calendar/></D:resourcetype><D:displayname>Work</D:displayname>`)
+
+	_, body = caldavDo(t, srv, "PROPFIND", "/calendars/alice/work/", "", "Depth", "1")
+	expectContains(t, "PROPFIND calendar", body,
+		`<C:supported-calendar-component-set><C:comp name="VEVENT"/><C:comp name="VTODO"/></C:supported-calendar-component-set>`,
+		`<D:href>/calendars/alice/work/standup.ics</D:href>`,
+		`<D:getetag>&#34;`+o.ETag+`&#34;</D:getetag>`,
+		`<D:getlastmodified>Mon, 01 Mar 2021 08:00:00 GMT</D:getlastmodified>`)
+	if strings.Contains(body, "calendar-data") {
+		t.Errorf("PROPFIND returned calendar-data\n%s", body)
+	}
+
+	if resp, _ := caldavDo(t, srv, "PROPFIND", "/calendars/alice/none/", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("PROPFIND of a missing calendar status = %d", resp.StatusCode)
+	}
+	if resp, _ := caldavDo(t, srv, "PROPFIND", "/", "<prop/>"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("PROPFIND with invalid body status = %d", resp.StatusCode)
+	}
+}
+
+// racingCalDAVStore runs race before every write,as another request changing
+// the object between the handler's checks and the write
+type racingCalDAVStore struct {
+	*memCalDAVStore
+	race func()
+}
+
+func (s *racingCalDAVStore) PutObject(path string, cal *Calendar, cond CalDAVCondition) (*CalDAVObject, error) {
+	s.race()
+	return s.memCalDAVStore.PutObject(path, cal, cond)
+}
+
+func (s *racingCalDAVStore) DeleteObject(path string, cond CalDAVCondition) error {
+	s.race()
+	return s.memCalDAVStore.DeleteObject(path, cond)
+}
+
+func TestCalDAVConcurrentWrite(t *testing.T) {
+	const obj = "/calendars/alice/work/standup.ics"
+	mem := newMemCalDAVStore(CalDAVCalendar{Path: "/calendars/alice/work/", Name: "Work"})
+	ev := decodeString(t, caldavEventStr)
+	o, err := mem.PutObject(obj, &ev, CalDAVCondition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := strconv.Quote(o.ETag)
+	n := 0
+	store := &racingCalDAVStore{mem, func() {
+		n++
+		other := decodeString(t, strings.Replace(caldavEventStr, "Standup", "Moved standup "+strconv.Itoa(n), 1))
+		mem.PutObject(obj, &other, CalDAVCondition{})
+	}}
+	srv := httptest.NewServer(&CalDAVHandler{Store: store, Principal: "/principals/alice/", Home: "/calendars/alice/"})
+	defer srv.Close()
+
+	changed := strings.Replace(caldavEventStr, "Standup", "Standup!", 1)
+	if resp, _ := caldavDo(t, srv, "PUT", obj, changed, "Content-Type", "text/calendar", "If-Match", etag); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("PUT after a concurrent change status = %d", resp.StatusCode)
+	}
+	if o, _ := mem.Object(obj); o.Data.SubComponents()[0].obj().GetProperty(PropSummary).Value != "Moved standup 1" {
+		t.Errorf("PUT overwrote the concurrent change")
+	}
+	etag = strconv.Quote(mem.object(obj).ETag)
+	if resp, _ := caldavDo(t, srv, "DELETE", obj, "", "If-Match", etag); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("DELETE after a concurrent change status = %d", resp.StatusCode)
+	}
+}
+
+func TestCalDAVReport(t *testing.T) {
+	srv, store := newCalDAVTestServer()
+	defer srv.Close()
+	ev, todo := decodeString(t, caldavEventStr), decodeString(t, caldavTodoStr)
+	store.PutObject("/calendars/alice/work/standup.ics", &ev, CalDAVCondition{})
+	store.PutObject("/calendars/alice/work/report.ics", &todo, CalDAVCondition{})
+
+	resp, body := caldavDo(t, srv, "REPORT", "/calendars/alice/work/", `<?xml version="1.0"?>
+<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+ <D:prop><D:getetag/><C:calendar-data/></D:prop>
+ <D:href>/calendars/alice/work/standup.ics</D:href>
+ <D:href>/calendars/alice/work/gone.ics</D:href>
+</C:calendar-multiget>`, "Depth", "1")
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("REPORT status = %d", resp.StatusCode)
+	}
+	expectContains(t, "calendar-multiget", body,
+		`<D:response><D:href>/calendars/alice/work/gone.ics</D:href><D:status>HTTP/1.1 404 Not Found</D:status></D:response>`,
+		`<D:href>/calendars/alice/work/standup.ics</D:href>`,
+		`<C:calendar-data>BEGIN:VCALENDAR&#xD;&#xA;`,
+		`SUMMARY:Standup&#xD;&#xA;`)
+	if strings.Contains(body, "Report") {
+		t.Errorf("calendar-multiget returned an object not asked for\n%s", body)
+	}
+
+	_, body = caldavDo(t, srv, "REPORT", "/calendars/alice/work/", `<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+ <D:prop><D:getetag/></D:prop>
+ <C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VTODO"/></C:comp-filter></C:filter>
+</C:calendar-query>`, "Depth", "1")
+	expectContains(t, "calendar-query", body, `<D:href>/calendars/alice/work/report.ics</D:href>`)
+	if strings.Contains(body, "standup.ics") || strings.Contains(body, "calendar-data") {
+		t.Errorf("calendar-query returned too much\n%s", body)
+	}
+
+	for _, s := range []string{
+		`<C:calendar-query xmlns:C="urn:ietf:params:xml:ns:caldav"><C:filter><C:comp-filter name="VEVENT"/></C:filter></C:calendar-query>`,
+		`<D:sync-collection xmlns:D="DAV:"/>`,
+	} {
+		if resp, _ := caldavDo(t, srv, "REPORT", "/calendars/alice/work/", s); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("REPORT %s status = %d", s, resp.StatusCode)
+		}
+	}
+}
+
+func TestCalDAVMkcalendar(t *testing.T) {
+	srv, store := newCalDAVTestServer()
+	defer srv.Close()
+	resp, _ := caldavDo(t, srv, "MKCALENDAR", "/calendars/alice/tasks/", `<?xml version="1.0"?>
+<C:mkcalendar xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+ <D:set><D:prop>
+  <D:displayname>Tasks</D:displayname>
+  <C:calendar-description>Things to do</C:calendar-description>
+  <C:supported-calendar-component-set><C:comp name="VTODO"/></C:supported-calendar-component-set>
+ </D:prop></D:set>
+</C:mkcalendar>`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("MKCALENDAR status = %d", resp.StatusCode)
+	}
+	cals, _ := store.Calendars()
+	if len(cals) != 2 || cals[0].Path != "/calendars/alice/tasks/" || cals[0].Name != "Tasks" ||
+		cals[0].Description != "Things to do" || len(cals[0].Components) != 1 || cals[0].Components[0] != CompTodo {
+		t.Errorf("calendars = %+v", cals)
+	}
+	if resp, _ := caldavDo(t, srv, "MKCALENDAR", "/calendars/alice/tasks/", ""); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("second MKCALENDAR status = %d", resp.StatusCode)
+	}
+	if resp, _ := caldavDo(t, srv, "MKCALENDAR", "/elsewhere/tasks/", ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("MKCALENDAR outside the home status = %d", resp.StatusCode)
+	}
+	resp, _ = caldavDo(t, srv, "PUT", "/calendars/alice/tasks/standup.ics", caldavEventStr, "Content-Type", "text/calendar")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("PUT of a VEVENT into a VTODO calendar status = %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,216 @@
+package go_ical
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PropertyPolicy tells which calendar properties CombineCalendars keeps
+type PropertyPolicy int
+
+const (
+	//PolicyFirst keeps the properties of the first calendar which has them
+	PolicyFirst PropertyPolicy = iota
+	//PolicyLast keeps the properties of the last calendar which has them
+	PolicyLast
+	//PolicyUnion keeps the different properties of all calendars,for
+	//properties which may only occur once it is PolicyFirst
+	PolicyUnion
+	//PolicyDrop keeps none,VERSION and PRODID are those of NewCalendar
+	PolicyDrop
+)
+
+// CombinePolicy tells CombineCalendars what to do with the calendar
+// properties,the zero value keeps those of the first calendar
+type CombinePolicy struct {
+	Default PropertyPolicy
+	//Properties overrides Default by property name
+	Properties map[string]PropertyPolicy
+}
+
+func (p CombinePolicy) policy(name string) PropertyPolicy {
+	if pol, ok := p.Properties[name]; ok {
+		return pol
+	}
+	return p.Default
+}
+
+/*
+CombineCalendars returns a calendar with the components of all cals.
+
+Components with the same UID and RECURRENCE-ID are kept once: the one with
+the highest SEQUENCE,then the latest LAST-MODIFIED,then the latest DTSTAMP,
+the first of equals. VTIMEZONEs of the same TZID which only differ in
+LAST-MODIFIED are kept once,a different definition of a TZID already taken
+is renamed to TZID-2,TZID-3 and so on,together with the TZID parameters of
+its calendar. The calendar properties are kept by policy. The calendars are
+not modified.
+*/
+func CombineCalendars(policy CombinePolicy, cals ...Calendar) *Calendar {
+	combined := &Calendar{ComponentObj{NameObj: CompCalendar, PropertiesObj: []Property{}, SubComponentsObj: []Component{}}}
+	combined.PropertiesObj = combineProperties(policy, cals)
+
+	zones := map[string]*ComponentObj{}
+	var others []Component
+	byKey := map[ComponentKey]int{}
+	for _, cal := range cals {
+		rename := map[string]string{}
+		for _, sub := range cal.SubComponentsObj {
+			if sub.Name() != CompTimezone {
+				continue
+			}
+			if tz := combineTimezone(zones, sub.obj(), rename); tz != nil {
+				combined.SubComponentsObj = append(combined.SubComponentsObj, tz)
+			}
+		}
+		for _, sub := range cal.SubComponentsObj {
+			if sub.Name() == CompTimezone {
+				continue
+			}
+			com := sub.obj().Clone()
+			k, keyed := componentKey(com)
+			renameTZIDs(com, rename)
+			if !keyed {
+				others = append(others, com)
+				continue
+			}
+			if i, ok := byKey[k]; ok {
+				if mergeWinner(com, others[i].obj()) == MergeLocal {
+					others[i] = com
+				}
+				continue
+			}
+			byKey[k] = len(others)
+			others = append(others, com)
+		}
+	}
+	combined.SubComponentsObj = append(combined.SubComponentsObj, others...)
+	return combined
+}
+
+// combineProperties keeps the calendar properties by policy,in the order
+// their names first appear
+func combineProperties(policy CombinePolicy, cals []Calendar) []Property {
+	var names []string
+	seen := map[string]bool{}
+	for _, cal := range cals {
+		for _, p := range cal.PropertiesObj {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				names = append(names, p.Name)
+			}
+		}
+	}
+
+	var props []Property
+	for _, name := range names {
+		pol := policy.policy(name)
+		if pol == PolicyUnion && !contains(MultiPropMap[CompCalendar], name) && !strings.HasPrefix(name, "X-") {
+			pol = PolicyFirst
+		}
+		switch pol {
+		case PolicyFirst, PolicyLast:
+			var keep []Property
+			for _, cal := range cals {
+				if ps := cal.GetProperties(name); len(ps) > 0 {
+					keep = ps
+					if pol == PolicyFirst {
+						break
+					}
+				}
+			}
+			for _, p := range keep {
+				props = append(props, p.Clone())
+			}
+		case PolicyUnion:
+			var keep []Property
+			for _, cal := range cals {
+				for _, p := range cal.GetProperties(name) {
+					dup := false
+					for i := range keep {
+						if propertyEqual(&keep[i], &p) {
+							dup = true
+							break
+						}
+					}
+					if !dup {
+						keep = append(keep, p.Clone())
+					}
+				}
+			}
+			props = append(props, keep...)
+		}
+	}
+
+	//VERSION and PRODID are required
+	defaults := NewCalendar()
+	for _, p := range defaults.PropertiesObj {
+		has := false
+		for _, q := range props {
+			has = has || q.Name == p.Name
+		}
+		if !has {
+			props = append(props, p)
+		}
+	}
+	return props
+}
+
+// combineTimezone adds tz to zones under its TZID,or under a new one when
+// the TZID is taken by another definition,and records that in rename. It
+// returns the VTIMEZONE to add,nil when zones has it already.
+func combineTimezone(zones map[string]*ComponentObj, tz *ComponentObj, rename map[string]string) *ComponentObj {
+	p := tz.GetProperty(PropTimeZoneIdentifier)
+	if p == nil {
+		return tz.Clone()
+	}
+	tzid := p.Value
+	id := tzid
+	for n := 2; ; n++ {
+		have, ok := zones[id]
+		if !ok {
+			break
+		}
+		if sameTimezone(have, tz) {
+			if id != tzid {
+				rename[tzid] = id
+			}
+			return nil
+		}
+		id = fmt.Sprintf("%s-%d", tzid, n)
+	}
+	c := tz.Clone()
+	if id != tzid {
+		rename[tzid] = id
+		c.GetProperty(PropTimeZoneIdentifier).Value = id
+	}
+	zones[id] = c
+	return c
+}
+
+// sameTimezone reports whether a and b only differ in TZID and LAST-MODIFIED
+func sameTimezone(a, b *ComponentObj) bool {
+	a, b = a.Clone(), b.Clone()
+	for _, c := range []*ComponentObj{a, b} {
+		c.DelProperty(PropTimeZoneIdentifier)
+		c.DelProperty(PropLastModified)
+	}
+	return DiffComponents(a, b) == nil
+}
+
+// renameTZIDs changes the TZID parameters of com and its sub-components
+func renameTZIDs(com *ComponentObj, rename map[string]string) {
+	if len(rename) == 0 {
+		return
+	}
+	for _, p := range com.PropertiesObj {
+		if vs := p.Params[Paramtzid]; len(vs) > 0 {
+			if to, ok := rename[vs[0]]; ok {
+				p.Params[Paramtzid] = []string{to}
+			}
+		}
+	}
+	for _, sub := range com.SubComponentsObj {
+		renameTZIDs(sub.obj(), rename)
+	}
+}
@@ -0,0 +1,145 @@
+package go_ical
+
+import (
+	"strings"
+	"testing"
+)
+
+const combineWorkStr = `BEGIN:VCALENDAR
+PRODID:-//Work//EN
+VERSION:2.0
+METHOD:PUBLISH
+X-WR-CALNAME:Work
+BEGIN:VTIMEZONE
+TZID:Office
+LAST-MODIFIED:20200101T000000Z
+BEGIN:STANDARD
+DTSTART:19700101T000000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0100
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+SEQUENCE:1
+DTSTART;TZID=Office:20210301T090000
+SUMMARY:Standup
+END:VEVENT
+BEGIN:VEVENT
+UID:review@example.com
+DTSTAMP:20210301T080000Z
+DTSTART;TZID=Office:20210302T090000
+SUMMARY:Review
+END:VEVENT
+END:VCALENDAR
+`
+
+const combineHomeStr = `BEGIN:VCALENDAR
+PRODID:-//Home//EN
+VERSION:2.0
+X-WR-CALNAME:Home
+BEGIN:VTIMEZONE
+TZID:Office
+LAST-MODIFIED:20210101T000000Z
+BEGIN:STANDARD
+DTSTART:19700101T000000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0100
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+SEQUENCE:2
+DTSTART;TZID=Office:20210301T100000
+SUMMARY:Standup moved
+END:VEVENT
+END:VCALENDAR
+`
+
+const combineTravelStr = `BEGIN:VCALENDAR
+PRODID:-//Travel//EN
+VERSION:2.0
+X-WR-CALNAME:Travel
+BEGIN:VTIMEZONE
+TZID:Office
+BEGIN:STANDARD
+DTSTART:19700101T000000
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0500
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210305T080000Z
+SEQUENCE:2
+DTSTART;TZID=Office:20210301T040000
+SUMMARY:Standup abroad
+END:VEVENT
+BEGIN:VEVENT
+UID:flight@example.com
+DTSTAMP:20210301T080000Z
+DTSTART;TZID=Office:20210310T070000
+SUMMARY:Flight
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER;RELATED=START:-PT2H
+DESCRIPTION:Flight
+END:VALARM
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestCombineCalendars(t *testing.T) {
+	work, home, travel := decodeString(t, combineWorkStr), decodeString(t, combineHomeStr), decodeString(t, combineTravelStr)
+	cal := CombineCalendars(CombinePolicy{}, work, home, travel)
+
+	var got []string
+	for _, sub := range cal.SubComponents() {
+		com := sub.obj()
+		switch sub.Name() {
+		case CompTimezone:
+			got = append(got, "VTIMEZONE "+com.GetProperty(PropTimeZoneIdentifier).Value)
+		default:
+			start := com.GetProperty(PropDatetimeStart)
+			got = append(got, com.GetProperty(PropSummary).Value+" "+start.Params.Get(Paramtzid))
+		}
+	}
+	//the standup of travel has the same SEQUENCE,but the later DTSTAMP
+	want := "VTIMEZONE Office,VTIMEZONE Office-2,Standup abroad Office-2,Review Office,Flight Office-2"
+	if strings.Join(got, ",") != want {
+		t.Errorf("components = %s, want %s", strings.Join(got, ","), want)
+	}
+	if tz := cal.SubComponents()[0].obj(); tz.GetProperty(PropLastModified).Value != "20200101T000000Z" {
+		t.Errorf("first VTIMEZONE is not the one of work")
+	}
+
+	props := func(cal *Calendar) string {
+		var ps []string
+		for _, p := range cal.Properties() {
+			ps = append(ps, p.Name+":"+p.Value)
+		}
+		return strings.Join(ps, ",")
+	}
+	if got := props(cal); got != "PRODID:-//Work//EN,VERSION:2.0,METHOD:PUBLISH,X-WR-CALNAME:Work" {
+		t.Errorf("PolicyFirst properties = %s", got)
+	}
+	cal = CombineCalendars(CombinePolicy{Default: PolicyLast, Properties: map[string]PropertyPolicy{
+		"X-WR-CALNAME": PolicyUnion, PropProductIdentifier: PolicyUnion, PropMethod: PolicyDrop,
+	}}, work, home, travel)
+	if got := props(cal); got != "PRODID:-//Work//EN,VERSION:2.0,X-WR-CALNAME:Work,X-WR-CALNAME:Home,X-WR-CALNAME:Travel" {
+		t.Errorf("policy properties = %s", got)
+	}
+	cal = CombineCalendars(CombinePolicy{Default: PolicyDrop}, work)
+	if got := props(cal); got != "VERSION:2.0,PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN" {
+		t.Errorf("PolicyDrop properties = %s", got)
+	}
+
+	if got := work.SubComponents()[1].obj().GetProperty(PropSummary).Value; got != "Standup" {
+		t.Errorf("work was modified: SUMMARY = %s", got)
+	}
+	if got := travel.SubComponents()[2].obj().GetProperty(PropDatetimeStart).Params.Get(Paramtzid); got != "Office" {
+		t.Errorf("travel was modified: TZID = %s", got)
+	}
+}
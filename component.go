@@ -2,6 +2,7 @@ package go_ical
 
 import (
 	"fmt"
+	"strconv"
 )
 
 /*
@@ -21,6 +22,7 @@ type Component interface {
 	SubComponents() []Component
 	IsAvailable() error
 	encode(enc *Encoder) error
+	obj() *ComponentObj
 }
 
 type ComponentObj struct {
@@ -41,22 +43,104 @@ func (com *ComponentObj) SubComponents() []Component {
 	return com.SubComponentsObj
 }
 
+func (com *ComponentObj) obj() *ComponentObj {
+	return com
+}
+
+//GetProperty returns the first property named name,or nil.
+//The pointer refers into the component,changes to it are kept
+func (com *ComponentObj) GetProperty(name string) *Property {
+	for i := range com.PropertiesObj{
+		if com.PropertiesObj[i].Name == name{
+			return &com.PropertiesObj[i]
+		}
+	}
+	return nil
+}
+
+//GetProperties returns all properties named name
+func (com *ComponentObj) GetProperties(name string) []Property {
+	var ps []Property
+	for _,p := range com.PropertiesObj{
+		if p.Name == name{
+			ps = append(ps,p)
+		}
+	}
+	return ps
+}
+
+//AddProperty appends p,other properties with the same name are kept
+func (com *ComponentObj) AddProperty(p Property)  {
+	com.PropertiesObj = append(com.PropertiesObj,p)
+}
+
+//PutProperty replaces all properties named p.Name with p,keeping the position of the first one
+func (com *ComponentObj) PutProperty(p Property)  {
+	ps := com.PropertiesObj[:0]
+	done := false
+	for _,old := range com.PropertiesObj{
+		if old.Name != p.Name{
+			ps = append(ps,old)
+		}else if !done{
+			ps = append(ps,p)
+			done = true
+		}
+	}
+	if !done{
+		ps = append(ps,p)
+	}
+	com.PropertiesObj = ps
+}
+
+//DelProperty removes all properties named name
+func (com *ComponentObj) DelProperty(name string)  {
+	ps := com.PropertiesObj[:0]
+	for _,p := range com.PropertiesObj{
+		if p.Name != name{
+			ps = append(ps,p)
+		}
+	}
+	com.PropertiesObj = ps
+}
+
+//Clone returns a deep copy,sub-components are copied as *ComponentObj
+func (com *ComponentObj) Clone() *ComponentObj {
+	c := &ComponentObj{NameObj:com.NameObj}
+	if com.PropertiesObj != nil{
+		c.PropertiesObj = make([]Property,len(com.PropertiesObj))
+		for i,p := range com.PropertiesObj{
+			c.PropertiesObj[i] = p.Clone()
+		}
+	}
+	if com.SubComponentsObj != nil{
+		c.SubComponentsObj = make([]Component,len(com.SubComponentsObj))
+		for i,sub := range com.SubComponentsObj{
+			c.SubComponentsObj[i] = sub.obj().Clone()
+		}
+	}
+	return c
+}
+
 //changed from Encoder's encodeComponent
 func (com *ComponentObj) encode(enc *Encoder) error {
 	if err := com.IsAvailable();err != nil{
 		return err
 	}
-	fmt.Fprint(enc.w,"BEGIN:"+com.Name(),"\r\n")
-	for _,p := range com.Properties(){
-		enc.encodeProperty(&p)
+	if err := enc.writeLine("BEGIN:"+com.Name());err != nil{
+		return err
+	}
+	for i := range com.Properties(){
+		if err := enc.encodeProperty(&com.PropertiesObj[i]);err != nil{
+			return err
+		}
 	}
 
 	for _,c := range com.SubComponents(){
-		c.encode(enc)
+		if err := c.encode(enc);err != nil{
+			return err
+		}
 	}
-	fmt.Fprint(enc.w,"END:"+com.Name()+"\r\n")
-
-	return nil
+	return enc.writeLine("END:"+com.Name())
 }
 
 //to check componet
@@ -66,26 +150,26 @@ func (com *ComponentObj) IsAvailable() error {
 		if len(com.SubComponents()) == 0{
 			return fmt.Errorf("ical: VCALENDAR is empty!,can not encode")
 		}
-		//if VEVENT has no Prop METHOD,VCALENDAR Must have DTSTART
+		//if VCALENDAR has no METHOD,every VEVENT Must have DTSTART
+		isMethod := false
+		for _,p := range com.Properties(){
+			if p.Name == PropMethod{
+				isMethod = true
+			}
+		}
+		if isMethod{
+			break
+		}
 		for _,sub := range com.SubComponents(){
 			if sub.Name() == CompEvent{
-				isMethod := false
-				for _,p := range sub.Properties(){
-					if p.Name == PropMethod{
-						isMethod = true
-					}
-				}
-				if isMethod{
-					continue
-				}
 				isDTSTART := false
-				for _,p := range com.Properties(){
+				for _,p := range sub.Properties(){
 					if p.Name == PropDatetimeStart{
 						isDTSTART = true
 					}
 				}
 				if !isDTSTART{
-					return fmt.Errorf("ical: DTSTART is required,when VEVENT has no Prop METHOD")
+					return fmt.Errorf("ical: DTSTART is required in VEVENT,when VCALENDAR has no METHOD")
 				}
 			}
 		}
@@ -144,6 +228,24 @@ func (com *ComponentObj) IsAvailable() error {
 				return fmt.Errorf("ical:STANDARD and DAYLIGHT are allowed in TIMEZONE,but got %q",sub.Name())
 			}
 		}
+	case CompAvailability,CompAvailable:
+		for _,sub := range com.SubComponents(){
+			if com.Name() == CompAvailable || sub.Name() != CompAvailable{
+				return fmt.Errorf("ical:In %s only AVAILABLE is allowed,but got %q",com.Name(),sub.Name())
+			}
+		}
+		isEnd := false
+		isDuration := false
+		for _,p := range com.Properties(){
+			if p.Name == PropDatetimeEnd{
+				isEnd = true
+			} else if p.Name == PropDuration{
+				isDuration = true
+			}
+		}
+		if isEnd && isDuration{
+			return fmt.Errorf("ical:in %s can not use DTEND and DURATION at same time",com.Name())
+		}
 	//case CompAlarm:
 	}
 	for _,pn := range OnlyOnePropMap[com.Name()]{
@@ -154,7 +256,7 @@ func (com *ComponentObj) IsAvailable() error {
 			}
 		}
 		if n != 1{
-			return fmt.Errorf("ical:%q MUST have only one prop %q,but got %q",com.Name(),pn,n)
+			return fmt.Errorf("ical:%q MUST have only one prop %q,but got %d",com.Name(),pn,n)
 		}
 	}
 	for _,pn := range OneOrZeroPropMap[com.Name()]{
@@ -165,7 +267,7 @@ func (com *ComponentObj) IsAvailable() error {
 			}
 		}
 		if n > 1{
-			return fmt.Errorf("ical:%q SHOULD have one or zero prop %q,but got %q",com.Name(),pn,n)
+			return fmt.Errorf("ical:%q SHOULD have one or zero prop %q,but got %d",com.Name(),pn,n)
 		}
 	}
 	return nil
@@ -178,7 +280,7 @@ type VEvent struct {
 }
 
 func NewEvent() *VEvent {
-	return &VEvent{ComponentObj{}}
+	return &VEvent{ComponentObj{NameObj:CompEvent}}
 }
 
 func (ev *VEvent) SetProperty(pname string,val string,pis ...ParamItem)  {
@@ -191,6 +293,17 @@ func (ev *VEvent) SetProperty(pname string,val string,pis ...ParamItem)  {
 	ev.PropertiesObj = append(ev.PropertiesObj,p)
 }
 
+//BumpSequence increments SEQUENCE,a missing SEQUENCE counts as 0,and returns the new value
+func (ev *VEvent) BumpSequence() int {
+	n := 0
+	if p := ev.GetProperty(PropSequenceNumber);p != nil{
+		n,_ = strconv.Atoi(p.Value)
+	}
+	n++
+	ev.PutProperty(newPropertyValue(PropSequenceNumber,strconv.Itoa(n)))
+	return n
+}
+
 type Attendee struct {
 	ComponentObj
 }
@@ -226,5 +339,15 @@ type VTZDAYLIGHT struct {
 	ComponentObj
 }
 
+//VAvailability is a VAVAILABILITY,RFC 7953 3.1
+type VAvailability struct {
+	ComponentObj
+}
+
+//VAvailable is an AVAILABLE block of a VAVAILABILITY
+type VAvailable struct {
+	ComponentObj
+}
+
 
 
@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"regexp"
 	"strings"
 )
 
@@ -16,17 +15,11 @@ func decodeProperty(line ContentLine) (*Property,error) {
 		Params: map[string][]string{},
 	}
 
-	proNameReg, err := regexp.Compile("[A-Za-z0-9-]{1,}")
-	if err != nil {
-		return nil,fmt.Errorf("ical:Failed to build regex: %v", err)
-	}
-	namepos := proNameReg.FindIndex([]byte(line))
-	if namepos == nil {
+	n := scanName(line,0)
+	if n == 0 {
 		return nil,fmt.Errorf("ical:property name format error or no property name")
 	}
-	n := 0
-	p.Name = string(line[n+namepos[0]:n+namepos[1]])
-	n += namepos[1]
+	p.Name = strings.ToUpper(string(line[:n]))
 	for{
 		if n>=len(line){
 			return nil,fmt.Errorf("ical:content line format error,can not only have property name")
@@ -46,67 +39,53 @@ func decodeProperty(line ContentLine) (*Property,error) {
 	}
 }
 
+//the value is everything after the first ':' which is not inside a quoted parameter value
 func decodePropertyValue(p *Property,line ContentLine,n int) (*Property,error) {
-	paramValueReg, err := regexp.Compile("^(?:\"(?:[^\"\\\\]|\\[\"nrt])*\"|[^,;\\\\:\"]*)")
-	if err != nil {
-		return nil,fmt.Errorf("ical: Failed to build regex: %v", err)
-	}
-	vpos := paramValueReg.FindIndex([]byte(line[n:]))
-	if vpos == nil {
-		return nil,fmt.Errorf("ical:Can not decode PropertyValue:format may error")
-	}
-	val := string(line[n:n+vpos[1]])
-	if val[0] == '"'{
-		val = val[1:]
-		var another rune
-		var end int
-		for i,c := range val{
-			if c == '"'{
-				another = c
-				end = i
-			}
-		}
-		if another != '"'{
-			return nil,fmt.Errorf("ical:parameter format error,only one quote string")
-		}else {
-			val = val[:end]
-		}
-	}
-	p.Value = val
+	p.Value = string(line[n:])
 	return p,nil
 }
 
 func decodePropertyParam(p *Property,line ContentLine,n int) (*Property,error,int) {
-	paraNameReg, err := regexp.Compile("[A-Za-z0-9-]{1,}")
-	if err != nil {
-		return nil,fmt.Errorf("Failed to build regex: %v", err),-1
-	}
-	npos := paraNameReg.FindIndex([]byte(line[n:]))
-	if npos == nil {
+	end := scanName(line,n)
+	if end == n {
 		return nil,fmt.Errorf("ical:can not decode property parameter name,no parameter name or format error"),-1
 	}
-	key := string(line[n:n+npos[1]])
-	n += npos[1]
-	if rune(line[n]) != '='{
+	key := strings.ToUpper(string(line[n:end]))
+	n = end
+	if n >= len(line) || rune(line[n]) != '='{
 		return nil,fmt.Errorf("ical:param format need '='"),-1
 	}else{
 		n += 1
 	}
-	paraValueReg,err :=  regexp.Compile("^(?:\"(?:[^\"\\\\]|\\[\"nrt])*\"|[^,;\\\\:\"]*)")
-	if err != nil {
-		return nil,fmt.Errorf("Failed to build regex: %v", err),-1
-	}
 	for{
 		if n >= len(line){
 			return nil,fmt.Errorf("ical:property format error,need value"),-1
 		}
-		npos = paraValueReg.FindIndex([]byte(line[n:]))
-		if npos == nil{
-			return nil,fmt.Errorf("ical:can not decode property param,param value format error or no value"),-1
+		var val string
+		if line[n] == '"'{
+			//quoted-string = DQUOTE *QSAFE-CHAR DQUOTE
+			end := strings.IndexByte(string(line[n+1:]),'"')
+			if end < 0{
+				return nil,fmt.Errorf("ical:parameter format error,only one quote string"),-1
+			}
+			val = string(line[n+1:n+1+end])
+			n += end+2
+		}else{
+			//paramtext = *SAFE-CHAR
+			end := strings.IndexAny(string(line[n:]),"\",;:")
+			if end < 0{
+				return nil,fmt.Errorf("ical:property format error,need value"),-1
+			}
+			if line[n+end] == '"'{
+				return nil,fmt.Errorf("ical:can not decode property param,DQUOTE inside param value"),-1
+			}
+			val = string(line[n:n+end])
+			n += end
 		}
-		val := string(line[n+npos[0]:n+npos[1]])
-		n += npos[1]
 		p.Params[key]=append(p.Params[key],val)
+		if n >= len(line){
+			return nil,fmt.Errorf("ical:property format error,need value"),-1
+		}
 		if rune(line[n]) == ','{
 			n += 1
 		}else {
@@ -115,6 +94,18 @@ func decodePropertyParam(p *Property,line ContentLine,n int) (*Property,error,in
 	}
 }
 
+//scanName returns the end of the name (ALPHA, DIGIT and "-") starting at n
+func scanName(line ContentLine,n int) int {
+	for n < len(line){
+		c := line[n]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-'){
+			break
+		}
+		n++
+	}
+	return n
+}
+
 type Decoder struct {
 	r *bufio.Reader
 }
@@ -123,6 +114,9 @@ func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{bufio.NewReader(r)}
 }
 
+//Decode reads the next calendar. Names are case-insensitive,property,parameter
+//and component names are upper-cased so they compare equal to the Prop*,Param*
+//and Comp* constants,values are kept as they are.
 func (dec *Decoder) Decode() (Calendar,error) {
 	com,err := dec.decodeComponent()
 	if err != nil {
@@ -159,7 +153,7 @@ func (dec *Decoder) generalDecodeComponent(first *Property) (*ComponentObj,error
 		}
 		switch prop.Name {
 		case "END":
-			if prop.Value != first.Value{
+			if !strings.EqualFold(prop.Value,first.Value){
 				return nil,fmt.Errorf("ical:malformed component,expect END property %q,but got %q",first.Value,prop.Value)
 			}else{
 				isContinued = false
@@ -179,7 +173,7 @@ func (dec *Decoder) generalDecodeComponent(first *Property) (*ComponentObj,error
 		return nil,fmt.Errorf("ical:expect END property")
 	}
 	return &ComponentObj{
-		NameObj:first.Value,
+		NameObj:strings.ToUpper(first.Value),
 		PropertiesObj:props,
 		SubComponentsObj:subComs,
 	},nil
@@ -199,7 +193,7 @@ func (dec *Decoder) decodeContentline() (*Property,error) {
 }
 
 func (dec *Decoder) readContentline() (ContentLine,error) {
-	bs,err := dec.r.ReadSlice('\n')
+	bs,err := dec.r.ReadBytes('\n')
 	if err == io.EOF && len(bs)>0{
 		err = nil
 	}
@@ -223,7 +217,7 @@ func (dec *Decoder) readContentline() (ContentLine,error) {
 			break
 		}
 
-		bs,err = dec.r.ReadSlice('\n')
+		bs,err = dec.r.ReadBytes('\n')
 		if err == io.EOF && len(bs)>0{
 			err = nil
 		}
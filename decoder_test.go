@@ -1,5 +1,10 @@
 package go_ical
 
+import (
+	"strings"
+	"testing"
+)
+
 /*
 func TestDecodeProperty(t *testing.T) {
 	tests := []struct {
@@ -50,3 +55,35 @@ func TestDecoder_Decode(t *testing.T) {
 
 
  */
+
+// names are case-insensitive (RFC 5545 3.1),the decoder upper-cases property,
+// parameter and component names but keeps values as they are
+func TestDecodeNameCase(t *testing.T) {
+	cal := decodeString(t, `begin:vcalendar
+prodid:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+Version:2.0
+Begin:VEvent
+uid:Lunch@Example.com
+dtstamp:20210301T080000Z
+dtstart;tzid=Europe/Berlin:20210301T120000
+summary;Language=en:Lunch
+end:VEVENT
+END:vcalendar
+`)
+	if cal.Name() != CompCalendar || len(cal.SubComponents()) != 1 || cal.SubComponents()[0].Name() != CompEvent {
+		t.Fatalf("decoded components = %s %v", cal.Name(), cal.SubComponents())
+	}
+	ev := cal.SubComponents()[0].obj()
+	if p := ev.GetProperty(PropUID); p == nil || p.Value != "Lunch@Example.com" {
+		t.Errorf("UID = %v", p)
+	}
+	if p := ev.GetProperty(PropDatetimeStart); p == nil || p.Params.Get(Paramtzid) != "Europe/Berlin" {
+		t.Errorf("DTSTART = %v", p)
+	}
+	if p := ev.GetProperty(PropSummary); p == nil || p.Params.Get(Paramlanguage) != "en" || p.Value != "Lunch" {
+		t.Errorf("SUMMARY = %v", p)
+	}
+	if _, err := NewDecoder(strings.NewReader(toCRLF("BEGIN:VCALENDAR\nVERSION:2.0\nEND:VEVENT\n"))).Decode(); err == nil {
+		t.Error("Decode() of mismatched END err = nil")
+	}
+}
@@ -5,7 +5,7 @@ const (
 	Paramaltrep = "ALTREP"
 	Paramcn = "CN"
 	Paramcutype = "CUTYPE"
-	Paramdelfrom = "DELEGATED-FROMx"
+	Paramdelfrom = "DELEGATED-FROM"
 	Paramdelto = "DELEGATED-TO"
 	Paramdir = "DIR"
 	Paramencoding = "ENCODING"
@@ -24,11 +24,25 @@ const (
 	Paramvaluetypeparam = "VALUE"
 )
 
+//Parameters defined in RFC 6638 7,used by a scheduling organizer
+const (
+	Paramscheduleagent = "SCHEDULE-AGENT"
+	Paramschedulestatus = "SCHEDULE-STATUS"
+	Paramscheduleforcesend = "SCHEDULE-FORCE-SEND"
+)
+
+//values of SCHEDULE-AGENT,RFC 6638 7.1
+const (
+	ScheduleAgentServer = "SERVER"
+	ScheduleAgentClient = "CLIENT"
+	ScheduleAgentNone = "NONE"
+)
+
 //Value types defined in RFC 5545 3.3
 const (
 	VDTdefault = ""
 	VDTbinary = "BINARY"
-	VDTbool = "BOOL"
+	VDTbool = "BOOLEAN"
 	VDTcalendaraddress = "CAL-ADDRESS"
 	VDTdate = "DATE"
 	VDTdatetime = "DATE-TIME"
@@ -50,6 +64,19 @@ const  (
 	PropProductIdentifier = "PRODID"
 	PropVersion = "VERSION"
 )
+//calendar properties defined in RFC 7986 5
+const (
+	PropName = "NAME"
+	PropRefreshInterval = "REFRESH-INTERVAL"
+	PropSource = "SOURCE"
+	PropColor = "COLOR"
+	PropImage = "IMAGE"
+	PropConference = "CONFERENCE"
+)
+//availability property defined in RFC 7953 3.2,its values are the FBType constants
+const (
+	PropBusyType = "BUSYTYPE"
+)
 //component properties defined in RFC 3.8
 const (
 	//Descriptive Component Properties
@@ -202,8 +229,71 @@ var DefaultVDT = map[string]string{
 	PropLastModified:VDTdatetime,
 	PropSequenceNumber:VDTint,
 	PropRequestStatus:VDTtext,
+	PropName:VDTtext,
+	PropRefreshInterval:VDTduration,
+	PropSource:VDTuri,
+	PropColor:VDTtext,
+	PropImage:VDTuri,//can use binary
+	PropConference:VDTuri,
+	PropBusyType:VDTtext,
 }
 
+//iTIP methods,RFC 5546 1.4
+const (
+	MethodPublish = "PUBLISH"
+	MethodRequest = "REQUEST"
+	MethodReply = "REPLY"
+	MethodAdd = "ADD"
+	MethodCancel = "CANCEL"
+	MethodRefresh = "REFRESH"
+	MethodCounter = "COUNTER"
+	MethodDeclineCounter = "DECLINECOUNTER"
+)
+
+//values of STATUS,RFC 5545 3.8.1.11
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+	StatusNeedsAction = "NEEDS-ACTION"
+	StatusCompleted = "COMPLETED"
+	StatusInProcess = "IN-PROCESS"
+	StatusDraft = "DRAFT"
+	StatusFinal = "FINAL"
+)
+
+//values of TRANSP,RFC 5545 3.8.2.7
+const (
+	TranspOpaque = "OPAQUE"
+	TranspTransparent = "TRANSPARENT"
+)
+
+//values of PARTSTAT,RFC 5545 3.2.12
+const (
+	PartstatNeedsAction = "NEEDS-ACTION"
+	PartstatAccepted = "ACCEPTED"
+	PartstatDeclined = "DECLINED"
+	PartstatTentative = "TENTATIVE"
+	PartstatDelegated = "DELEGATED"
+	PartstatCompleted = "COMPLETED"
+	PartstatInProcess = "IN-PROCESS"
+)
+
+//values of FBTYPE,RFC 5545 3.2.9
+const (
+	FBTypeFree = "FREE"
+	FBTypeBusy = "BUSY"
+	FBTypeBusyUnavailable = "BUSY-UNAVAILABLE"
+	FBTypeBusyTentative = "BUSY-TENTATIVE"
+)
+
+//values of ACTION,RFC 5545 3.8.6.1
+const (
+	ActionAudio = "AUDIO"
+	ActionDisplay = "DISPLAY"
+	ActionEmail = "EMAIL"
+)
+
 const (
 	DateFormat = "20060102"
 	DatetimeFormat = "20060102T150405"
@@ -221,6 +311,9 @@ const (
 	CompTimezoneStandard = "STANDARD"
 	CompTimezoneDaylight = "DAYLIGHT"
 	CompAlarm = "VALARM"
+	//RFC 7953 3.1
+	CompAvailability = "VAVAILABILITY"
+	CompAvailable = "AVAILABLE"
 
 
 )
@@ -235,16 +328,51 @@ var OnlyOnePropMap = map[string][]string{
 	CompTimezoneStandard:[]string{PropDatetimeStart,PropTimeZoneOffsetTo,PropTimeZoneOffsetFrom},
 	CompTimezoneDaylight:[]string{PropDatetimeStart,PropTimeZoneOffsetTo,PropTimeZoneOffsetFrom},
 	CompAlarm:[]string{PropAction,PropTrigger},
+	CompAvailability:[]string{PropDatetimeStamp,PropUID},
+	CompAvailable:[]string{PropDatetimeStamp,PropDatetimeStart,PropUID},
 }
 
 var OneOrZeroPropMap = map[string][]string{
-	CompCalendar:[]string{PropCalendarScale,PropMethod},
+	CompCalendar:[]string{PropCalendarScale,PropMethod,PropUID,PropURL,PropLastModified,PropRefreshInterval,PropSource,PropColor},
 	CompEvent:[]string{PropDatetimeStart,PropClassification,PropDatetimeCreated,PropDescription,PropGeographicPosition,PropLastModified,PropLocation,
-		PropOrganizer,PropPriority,PropSequenceNumber,PropStatus,PropSummary,PropTimeTransparency,PropURL,PropRecurrenceId,PropDatetimeEnd,PropDuration},
+		PropOrganizer,PropPriority,PropSequenceNumber,PropStatus,PropSummary,PropTimeTransparency,PropURL,PropRecurrenceId,PropDatetimeEnd,PropDuration,PropColor},
 	CompTodo:[]string{PropClassification,PropDatetimeCompleted,PropDatetimeCreated,PropDescription,PropDatetimeStart,PropGeographicPosition,
-		PropLastModified,PropLocation,PropOrganizer,PropPercentComplete,PropPriority,PropRecurrenceId,PropSequenceNumber,PropStatus,PropSummary,PropURL,PropDatetimeDue,PropDuration},
-	CompJournal:[]string{PropClassification,PropDatetimeCreated,PropDatetimeStart,PropLastModified,PropOrganizer,PropRecurrenceId,PropSequenceNumber,PropStatus,PropSummary,PropURL},
+		PropLastModified,PropLocation,PropOrganizer,PropPercentComplete,PropPriority,PropRecurrenceId,PropSequenceNumber,PropStatus,PropSummary,PropURL,PropDatetimeDue,PropDuration,PropColor},
+	CompJournal:[]string{PropClassification,PropDatetimeCreated,PropDatetimeStart,PropLastModified,PropOrganizer,PropRecurrenceId,PropSequenceNumber,PropStatus,PropSummary,PropURL,PropColor},
 	CompFreebusy:[]string{PropContact,PropDatetimeStart,PropDatetimeEnd,PropOrganizer,PropURL},
 	CompTimezone:[]string{PropLastModified,PropTimeZoneURL},
-	//alarm is special
+	CompTimezoneStandard:[]string{PropRecurrenceRule},
+	CompTimezoneDaylight:[]string{PropRecurrenceRule},
+	CompAvailability:[]string{PropBusyType,PropClassification,PropDatetimeCreated,PropDescription,PropDatetimeStart,PropLastModified,
+		PropLocation,PropOrganizer,PropPriority,PropSequenceNumber,PropSummary,PropURL,PropDatetimeEnd,PropDuration},
+	CompAvailable:[]string{PropDatetimeCreated,PropDescription,PropGeographicPosition,PropLastModified,PropLocation,PropRecurrenceId,
+		PropRecurrenceRule,PropSummary,PropDatetimeEnd,PropDuration},
+	//ACTION,TRIGGER and the properties each ACTION needs are checked by the validator
+	CompAlarm:[]string{PropDuration,PropRepeatCount,PropDescription,PropSummary},
+}
+
+//properties which MAY occur more than once
+var MultiPropMap = map[string][]string{
+	CompCalendar:[]string{PropName,PropDescription,PropCategories,PropImage},
+	CompEvent:[]string{PropAttachment,PropAttendee,PropCategories,PropComment,PropContact,PropExceptionDatetime,PropRequestStatus,
+		PropRelatedTo,PropResources,PropRecurrenceDatetime,PropRecurrenceRule,PropConference,PropImage},
+	CompTodo:[]string{PropAttachment,PropAttendee,PropCategories,PropComment,PropContact,PropExceptionDatetime,PropRequestStatus,
+		PropRelatedTo,PropResources,PropRecurrenceDatetime,PropRecurrenceRule,PropConference,PropImage},
+	CompJournal:[]string{PropAttachment,PropAttendee,PropCategories,PropComment,PropContact,PropDescription,PropExceptionDatetime,
+		PropRelatedTo,PropRecurrenceDatetime,PropRequestStatus,PropRecurrenceRule,PropImage},
+	CompFreebusy:[]string{PropAttendee,PropComment,PropFreeBusy,PropRequestStatus},
+	CompTimezoneStandard:[]string{PropComment,PropRecurrenceDatetime,PropTimeZoneName},
+	CompTimezoneDaylight:[]string{PropComment,PropRecurrenceDatetime,PropTimeZoneName},
+	CompAlarm:[]string{PropAttachment,PropAttendee},
+	CompAvailability:[]string{PropCategories,PropComment,PropContact},
+	CompAvailable:[]string{PropCategories,PropComment,PropContact,PropExceptionDatetime,PropRecurrenceDatetime},
+}
+
+//sub-components each component can contain
+var SubComponentMap = map[string][]string{
+	CompCalendar:[]string{CompEvent,CompTodo,CompJournal,CompFreebusy,CompTimezone,CompAvailability},
+	CompEvent:[]string{CompAlarm},
+	CompTodo:[]string{CompAlarm},
+	CompTimezone:[]string{CompTimezoneStandard,CompTimezoneDaylight},
+	CompAvailability:[]string{CompAvailable},
 }
@@ -0,0 +1,385 @@
+package go_ical
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DiffKind tells how a component or property changed
+type DiffKind int
+
+const (
+	DiffAdded DiffKind = iota
+	DiffRemoved
+	DiffModified
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "+"
+	case DiffRemoved:
+		return "-"
+	}
+	return "~"
+}
+
+// ParamChange is a parameter of a property which changed,Old or New is nil
+// when the parameter was added or removed
+type ParamChange struct {
+	Name string
+	Old  []string
+	New  []string
+}
+
+/*
+PropertyChange is a changed property of a component.
+
+Old is nil for an added property and New for a removed one. A modified
+property has both,and Params lists its changed parameters.
+
+Properties which may occur more than once,such as ATTENDEE,are matched by
+value,so a changed PARTSTAT is a modified ATTENDEE and another address an
+added and a removed one. The values of list properties,such as CATEGORIES
+and EXDATE,are compared one by one: each change holds a property with a
+single value.
+*/
+type PropertyChange struct {
+	Kind   DiffKind
+	Name   string
+	Old    *Property
+	New    *Property
+	Params []ParamChange
+}
+
+/*
+ComponentDiff is a changed component.
+
+Old is nil for an added component and New for a removed one. A modified
+component has both,and lists its changed properties and sub-components.
+Sub-components without UID,such as VALARM,are matched by their position
+among the sub-components of the same name.
+*/
+type ComponentDiff struct {
+	Kind       DiffKind
+	Key        ComponentKey
+	Old        *ComponentObj
+	New        *ComponentObj
+	Properties []PropertyChange
+	Components []ComponentDiff
+}
+
+// CalendarDiff is the difference of two calendars: the changed calendar
+// properties and the changed components,keyed by UID and RECURRENCE-ID
+type CalendarDiff struct {
+	Old        *ComponentObj
+	New        *ComponentObj
+	Properties []PropertyChange
+	Components []ComponentDiff
+}
+
+// multiProperties are the properties which may occur more than once in a
+// component and are matched by value
+var multiProperties = []string{
+	PropAttachment, PropAttendee, PropCategories, PropComment, PropContact,
+	PropExceptionDatetime, PropRelatedTo, PropResources, PropRecurrenceDatetime,
+	PropRequestStatus, PropFreeBusy, PropImage, PropConference,
+}
+
+// listProperties are the properties whose comma separated values are
+// compared one by one
+var listProperties = []string{
+	PropCategories, PropResources, PropExceptionDatetime, PropRecurrenceDatetime, PropFreeBusy,
+}
+
+// DiffCalendars returns the changes which turn old into new
+func DiffCalendars(old, new Calendar) *CalendarDiff {
+	return &CalendarDiff{
+		Old:        &old.ComponentObj,
+		New:        &new.ComponentObj,
+		Properties: diffProperties(old.PropertiesObj, new.PropertiesObj),
+		Components: diffComponents(old.SubComponentsObj, new.SubComponentsObj),
+	}
+}
+
+// Empty reports whether the calendars are the same
+func (d *CalendarDiff) Empty() bool {
+	return len(d.Properties) == 0 && len(d.Components) == 0
+}
+
+// DiffComponents returns the changes which turn the component old into new,
+// nil when they are the same
+func DiffComponents(old, new Component) *ComponentDiff {
+	d := ComponentDiff{Kind: DiffModified, Old: old.obj(), New: new.obj()}
+	d.Key, _ = diffKey(d.New)
+	d.Properties = diffProperties(d.Old.PropertiesObj, d.New.PropertiesObj)
+	d.Components = diffComponents(d.Old.SubComponentsObj, d.New.SubComponentsObj)
+	if len(d.Properties) == 0 && len(d.Components) == 0 {
+		return nil
+	}
+	return &d
+}
+
+// diffKey is componentKey,a VTIMEZONE is keyed by its TZID
+func diffKey(com *ComponentObj) (ComponentKey, bool) {
+	if com.Name() == CompTimezone {
+		if tzid := com.GetProperty(PropTimeZoneIdentifier); tzid != nil {
+			return ComponentKey{Name: CompTimezone, UID: tzid.Value}, true
+		}
+	}
+	if k, ok := componentKey(com); ok {
+		return k, true
+	}
+	return ComponentKey{Name: com.Name()}, false
+}
+
+// componentIDs returns the ids of coms in order and the component of each id:
+// the key,or the name and position among the components of the same name
+// without key. Duplicate ids keep the first component.
+func componentIDs(coms []Component) ([]string, map[string]*ComponentObj) {
+	var order []string
+	byID := map[string]*ComponentObj{}
+	seen := map[string]int{}
+	for _, c := range coms {
+		k, ok := diffKey(c.obj())
+		id := k.String()
+		if !ok {
+			id = fmt.Sprintf("%s#%d", id, seen[id])
+			seen[k.String()]++
+		}
+		if _, dup := byID[id]; dup {
+			continue
+		}
+		order = append(order, id)
+		byID[id] = c.obj()
+	}
+	return order, byID
+}
+
+// diffComponents matches old and new by componentIDs
+func diffComponents(old, new []Component) []ComponentDiff {
+	oldOrder, oldCom := componentIDs(old)
+	newOrder, newCom := componentIDs(new)
+
+	var diffs []ComponentDiff
+	for _, id := range oldOrder {
+		o := oldCom[id]
+		n, ok := newCom[id]
+		if !ok {
+			k, _ := diffKey(o)
+			diffs = append(diffs, ComponentDiff{Kind: DiffRemoved, Key: k, Old: o})
+		} else if d := DiffComponents(o, n); d != nil {
+			diffs = append(diffs, *d)
+		}
+	}
+	for _, id := range newOrder {
+		if _, ok := oldCom[id]; !ok {
+			k, _ := diffKey(newCom[id])
+			diffs = append(diffs, ComponentDiff{Kind: DiffAdded, Key: k, New: newCom[id]})
+		}
+	}
+	return diffs
+}
+
+// diffProperties compares the properties by name in the order they first
+// appear
+func diffProperties(old, new []Property) []PropertyChange {
+	var names []string
+	byName := func(ps []Property) map[string][]Property {
+		m := map[string][]Property{}
+		for _, p := range ps {
+			if _, ok := m[p.Name]; !ok {
+				names = append(names, p.Name)
+			}
+			m[p.Name] = append(m[p.Name], p)
+		}
+		return m
+	}
+	oldBy, newBy := byName(old), byName(new)
+
+	var changes []PropertyChange
+	done := map[string]bool{}
+	for _, name := range names {
+		if done[name] {
+			continue
+		}
+		done[name] = true
+		o, n := oldBy[name], newBy[name]
+		if len(o) == 1 && len(n) == 1 && !contains(multiProperties, name) {
+			if c, ok := diffProperty(o[0], n[0]); ok {
+				changes = append(changes, c)
+			}
+			continue
+		}
+		changes = append(changes, diffPropertyValues(splitListProperties(o), splitListProperties(n))...)
+	}
+	return changes
+}
+
+// splitListProperties returns a property per value of the list properties
+func splitListProperties(ps []Property) []Property {
+	var split []Property
+	for _, p := range ps {
+		if !contains(listProperties, p.Name) {
+			split = append(split, p)
+			continue
+		}
+		for _, v := range splitValues(p.Value) {
+			c := p.Clone()
+			c.Value = v
+			split = append(split, c)
+		}
+	}
+	return split
+}
+
+// diffPropertyValues matches properties of the same name by value
+func diffPropertyValues(old, new []Property) []PropertyChange {
+	var changes []PropertyChange
+	used := make([]bool, len(new))
+	for i := range old {
+		found := false
+		for j := range new {
+			if used[j] || propertyIdentity(old[i]) != propertyIdentity(new[j]) {
+				continue
+			}
+			used[j], found = true, true
+			if c, ok := diffProperty(old[i], new[j]); ok {
+				changes = append(changes, c)
+			}
+			break
+		}
+		if !found {
+			changes = append(changes, PropertyChange{Kind: DiffRemoved, Name: old[i].Name, Old: &old[i]})
+		}
+	}
+	for j := range new {
+		if !used[j] {
+			changes = append(changes, PropertyChange{Kind: DiffAdded, Name: new[j].Name, New: &new[j]})
+		}
+	}
+	return changes
+}
+
+// propertyIdentity is the value properties are matched by,calendar
+// addresses are not case sensitive in their mailto: scheme
+func propertyIdentity(p Property) string {
+	if p.Name == PropAttendee || p.Name == PropOrganizer {
+		return strings.ToLower(p.Value)
+	}
+	return p.Value
+}
+
+// diffProperty compares two properties of the same name,ok is false when
+// they are the same
+func diffProperty(old, new Property) (PropertyChange, bool) {
+	c := PropertyChange{Kind: DiffModified, Name: new.Name, Old: &old, New: &new}
+	for _, k := range sortedParamNames(old.Params, new.Params) {
+		o, n := old.Params[k], new.Params[k]
+		if !equalStrings(o, n) {
+			c.Params = append(c.Params, ParamChange{Name: k, Old: o, New: n})
+		}
+	}
+	if old.Value == new.Value && len(c.Params) == 0 {
+		return c, false
+	}
+	return c, true
+}
+
+func sortedParamNames(a, b Parameters) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, ps := range []Parameters{a, b} {
+		for k := range ps {
+			if !seen[k] {
+				seen[k] = true
+				names = append(names, k)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+String renders the diff as a report,a line per change:
+
+	~ VEVENT standup@example.com
+	  ~ SUMMARY: Standup -> Team Standup
+	  ~ ATTENDEE mailto:ann@example.com: PARTSTAT NEEDS-ACTION -> ACCEPTED
+	  + CATEGORIES: WORK
+	  - VALARM
+	+ VTODO review@example.com
+*/
+func (d *CalendarDiff) String() string {
+	var b strings.Builder
+	if len(d.Properties) > 0 {
+		b.WriteString("~ " + CompCalendar + "\n")
+		writePropertyChanges(&b, d.Properties, "  ")
+	}
+	for i := range d.Components {
+		d.Components[i].report(&b, "")
+	}
+	return b.String()
+}
+
+func (d *ComponentDiff) String() string {
+	var b strings.Builder
+	d.report(&b, "")
+	return b.String()
+}
+
+func (d *ComponentDiff) report(b *strings.Builder, indent string) {
+	b.WriteString(indent + d.Kind.String() + " " + d.Key.String() + "\n")
+	writePropertyChanges(b, d.Properties, indent+"  ")
+	for i := range d.Components {
+		d.Components[i].report(b, indent+"  ")
+	}
+}
+
+func writePropertyChanges(b *strings.Builder, changes []PropertyChange, indent string) {
+	for _, c := range changes {
+		b.WriteString(indent + c.String() + "\n")
+	}
+}
+
+func (c PropertyChange) String() string {
+	switch c.Kind {
+	case DiffAdded:
+		return "+ " + c.Name + ": " + c.New.Value
+	case DiffRemoved:
+		return "- " + c.Name + ": " + c.Old.Value
+	}
+	var params []string
+	for _, p := range c.Params {
+		params = append(params, p.Name+" "+reportParam(p.Old)+" -> "+reportParam(p.New))
+	}
+	if c.Old.Value == c.New.Value {
+		return "~ " + c.Name + " " + c.New.Value + ": " + strings.Join(params, ", ")
+	}
+	s := "~ " + c.Name + ": " + c.Old.Value + " -> " + c.New.Value
+	if len(params) > 0 {
+		s += " (" + strings.Join(params, ", ") + ")"
+	}
+	return s
+}
+
+func reportParam(vs []string) string {
+	if vs == nil {
+		return "none"
+	}
+	return strings.Join(vs, ",")
+}
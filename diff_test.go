@@ -0,0 +1,141 @@
+package go_ical
+
+import (
+	"strings"
+	"testing"
+)
+
+const diffOldStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+DTSTART:20210301T090000Z
+RRULE:FREQ=WEEKLY
+SUMMARY:Standup
+CATEGORIES:WORK,DAILY
+ATTENDEE;PARTSTAT=NEEDS-ACTION:mailto:ann@example.com
+ATTENDEE:mailto:bob@example.com
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+DESCRIPTION:Standup
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+RECURRENCE-ID:20210308T090000Z
+DTSTART:20210308T100000Z
+SUMMARY:Late standup
+END:VEVENT
+BEGIN:VTODO
+UID:review@example.com
+DTSTAMP:20210301T080000Z
+SUMMARY:Review
+END:VTODO
+END:VCALENDAR
+`
+
+const diffNewStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+METHOD:PUBLISH
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+DTSTART:20210301T090000Z
+RRULE:FREQ=WEEKLY
+SUMMARY:Team Standup
+CATEGORIES:DAILY
+CATEGORIES:MEETING
+ATTENDEE;PARTSTAT=ACCEPTED:MAILTO:ann@example.com
+ATTENDEE:mailto:carl@example.com
+END:VEVENT
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+RECURRENCE-ID;TZID=Europe/Berlin:20210308T100000
+DTSTART:20210308T100000Z
+SUMMARY:Late standup
+END:VEVENT
+BEGIN:VJOURNAL
+UID:notes@example.com
+DTSTAMP:20210301T080000Z
+SUMMARY:Notes
+END:VJOURNAL
+END:VCALENDAR
+`
+
+func TestDiffCalendars(t *testing.T) {
+	old, new := decodeString(t, diffOldStr), decodeString(t, diffNewStr)
+	d := DiffCalendars(old, new)
+	if d.Empty() {
+		t.Fatal("Empty() = true")
+	}
+	if len(d.Properties) != 1 || d.Properties[0].Kind != DiffAdded || d.Properties[0].New.Value != "PUBLISH" {
+		t.Errorf("calendar properties = %+v", d.Properties)
+	}
+	//the RECURRENCE-ID of the override is in another zone,but the same time
+	if len(d.Components) != 4 {
+		t.Fatalf("components = %s", d)
+	}
+	ev, override, todo, journal := d.Components[0], d.Components[1], d.Components[2], d.Components[3]
+	if override.Kind != DiffModified || override.Key.RecurrenceID != "20210308T090000Z" {
+		t.Errorf("override = %s", override.String())
+	}
+	if ev.Kind != DiffModified || ev.Key != (ComponentKey{CompEvent, "standup@example.com", ""}) ||
+		todo.Kind != DiffRemoved || todo.Key.UID != "review@example.com" ||
+		journal.Kind != DiffAdded || journal.New.GetProperty(PropSummary).Value != "Notes" {
+		t.Errorf("components = %s", d)
+	}
+
+	want := `~ VCALENDAR
+  + METHOD: PUBLISH
+~ VEVENT standup@example.com
+  ~ SUMMARY: Standup -> Team Standup
+  - CATEGORIES: WORK
+  + CATEGORIES: MEETING
+  ~ ATTENDEE: mailto:ann@example.com -> MAILTO:ann@example.com (PARTSTAT NEEDS-ACTION -> ACCEPTED)
+  - ATTENDEE: mailto:bob@example.com
+  + ATTENDEE: mailto:carl@example.com
+  - VALARM
+~ VEVENT standup@example.com 20210308T090000Z
+  ~ RECURRENCE-ID: 20210308T090000Z -> 20210308T100000 (TZID none -> Europe/Berlin)
+- VTODO review@example.com
++ VJOURNAL notes@example.com
+`
+	if got := d.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	attendee := ev.Properties[3]
+	if len(attendee.Params) != 1 || attendee.Params[0].Name != Parampartstat ||
+		strings.Join(attendee.Params[0].New, ",") != "ACCEPTED" {
+		t.Errorf("ATTENDEE change = %+v", attendee)
+	}
+	if !DiffCalendars(old, old).Empty() {
+		t.Error("DiffCalendars() of the same calendar is not empty")
+	}
+}
+
+func TestDiffComponents(t *testing.T) {
+	cal := decodeString(t, diffOldStr)
+	old := cal.SubComponents()[0].obj()
+	new := old.Clone()
+	if d := DiffComponents(old, new); d != nil {
+		t.Errorf("DiffComponents() of a clone = %s", d)
+	}
+	new.SubComponentsObj[0].obj().PutProperty(newPropertyValue(PropTrigger, "-PT5M"))
+	new.GetProperty(PropSummary).Params.Set(Paramlanguage, "en")
+	d := DiffComponents(old, new)
+	want := `~ VEVENT standup@example.com
+  ~ SUMMARY Standup: LANGUAGE none -> en
+  ~ VALARM
+    ~ TRIGGER: -PT15M -> -PT5M
+`
+	if d == nil || d.String() != want {
+		t.Errorf("DiffComponents() =\n%s\nwant\n%s", d, want)
+	}
+}
@@ -1,3 +1,140 @@
 package go_ical
 
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
 
+func TestEncodeContentLine(t *testing.T) {
+	tests := []struct {
+		prop    Property
+		want    string
+		wantErr bool
+	}{
+		{
+			prop: Property{Name: PropSummary, Params: Parameters{}, Value: "Lunch"},
+			want: "SUMMARY:Lunch",
+		},
+		{
+			prop: Property{Name: PropDescription, Params: Parameters{Paramaltrep: {"cid:part1.0001@example.org"}}, Value: "Test"},
+			want: `DESCRIPTION;ALTREP="cid:part1.0001@example.org":Test`,
+		},
+		{
+			prop: Property{Name: PropAttendee, Params: Parameters{Paramrole: {"REQ-PARTICIPANT"}, Paramcn: {"Doe; John", "x,y"}}, Value: "mailto:jdoe@example.com"},
+			want: `ATTENDEE;CN="Doe; John","x,y";ROLE=REQ-PARTICIPANT:mailto:jdoe@example.com`,
+		},
+		{
+			prop:    Property{Name: PropAttendee, Params: Parameters{Paramcn: {`John "JD" Doe`}}, Value: "mailto:jdoe@example.com"},
+			wantErr: true,
+		},
+		{
+			prop:    Property{Name: PropAttendee, Params: Parameters{Paramcn: {"John\nDoe"}}, Value: "mailto:jdoe@example.com"},
+			wantErr: true,
+		},
+		{
+			prop:    Property{Name: PropSummary, Params: Parameters{}, Value: "two\r\nlines"},
+			wantErr: true,
+		},
+		{
+			prop:    Property{Name: "X SPACE", Params: Parameters{}, Value: "x"},
+			wantErr: true,
+		},
+	}
+	for i, test := range tests {
+		got, err := encodeContentLine(&test.prop)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%d: encodeContentLine() = %q, want error", i, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: encodeContentLine() err: %v", i, err)
+		} else if got != test.want {
+			t.Errorf("%d: encodeContentLine() = %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("0123456789", 20) + strings.Repeat("日本語", 30)
+	folded := foldLine(line)
+	if !strings.HasSuffix(folded, "\r\n") {
+		t.Fatalf("folded line does not end with CRLF: %q", folded)
+	}
+	lines := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+	for i, l := range lines {
+		if len(l) > maxLineOctets {
+			t.Errorf("line %d is %d octets, want at most %d", i, len(l), maxLineOctets)
+		}
+		if i > 0 && l[0] != ' ' {
+			t.Errorf("continuation line %d does not start with a space", i)
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("line %d splits a UTF-8 sequence: %q", i, l)
+		}
+	}
+	if len(lines[0]) != maxLineOctets {
+		t.Errorf("first line is %d octets, want %d", len(lines[0]), maxLineOctets)
+	}
+	if unfolded := strings.Replace(folded[:len(folded)-2], "\r\n ", "", -1); unfolded != line {
+		t.Errorf("unfolded line = %q, want %q", unfolded, line)
+	}
+}
+
+var roundTripCalendarStr = toCRLF(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+BEGIN:VTODO
+DTSTAMP:19960704T120000Z
+UID:uid2@example.com
+ATTENDEE;CN="Doe, Jane";ROLE=REQ-PARTICIPANT:mailto:jane@example.com
+DESCRIPTION:Ünïcödé text that is long enough to be folded more than onc
+ e\, so the continuation lines have to be split without cutting a multi-oct
+ et sequence in half: 日本語日本語日本語日本語
+SUMMARY;LANGUAGE=en:Submit report
+END:VTODO
+END:VCALENDAR
+`)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	cal := NewCalendar()
+	todo := &ComponentObj{NameObj: CompTodo, SubComponentsObj: []Component{}}
+	todo.PropertiesObj = []Property{
+		{Name: PropDatetimeStamp, Params: Parameters{}, Value: "19960704T120000Z"},
+		{Name: PropUID, Params: Parameters{}, Value: "uid2@example.com"},
+		{Name: PropAttendee, Params: Parameters{Paramcn: {"Doe, Jane"}, Paramrole: {"REQ-PARTICIPANT"}}, Value: "mailto:jane@example.com"},
+		{Name: PropDescription, Params: Parameters{}, Value: ToText("Ünïcödé text that is long enough to be folded more than once, " +
+			"so the continuation lines have to be split without cutting a multi-octet sequence in half: 日本語日本語日本語日本語")},
+		{Name: PropSummary, Params: Parameters{Paramlanguage: {"en"}}, Value: "Submit report"},
+	}
+	cal.AddComponent(todo)
+	for i := range cal.PropertiesObj {
+		cal.PropertiesObj[i].Params = Parameters{}
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	if buf.String() != roundTripCalendarStr {
+		t.Errorf("Encode() = \n%s\nwant\n%s", buf.String(), roundTripCalendarStr)
+	}
+
+	got, err := NewDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatalf("Decode() err: %v", err)
+	}
+	if !reflect.DeepEqual(got.PropertiesObj, cal.PropertiesObj) {
+		t.Errorf("decoded VCALENDAR properties = %#v, want %#v", got.PropertiesObj, cal.PropertiesObj)
+	}
+	if len(got.SubComponents()) != 1 {
+		t.Fatalf("decoded %d components, want 1", len(got.SubComponents()))
+	}
+	if !reflect.DeepEqual(got.SubComponents()[0], Component(todo)) {
+		t.Errorf("decoded VTODO = \n%#v\nwant\n%#v", got.SubComponents()[0], todo)
+	}
+}
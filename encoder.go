@@ -1,91 +1,167 @@
 package go_ical
 
 import (
-	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"unicode/utf8"
 )
 
+//https://tools.ietf.org/html/rfc5545#section-3.1
+//lines SHOULD NOT be longer than 75 octets, excluding the line break
+const maxLineOctets = 75
+
 type Encoder struct {
 	w io.Writer
 }
 
-func (enc *Encoder) encodeProperty(p *Property)  {
-	b := bytes.NewBufferString("")
-	fmt.Fprintln(b,p.Name)
-	for key,vals := range p.Params{
-		fmt.Fprint(b,';')
-		fmt.Fprint(b,key)
-		fmt.Fprint(b,'=')
-		//process values
-		for i,val := range vals{
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w}
+}
+
+func (enc *Encoder) Encode(com Component) error {
+	if err := com.encode(enc); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (enc *Encoder) encodeProperty(p *Property) error {
+	line, err := encodeContentLine(p)
+	if err != nil {
+		return err
+	}
+	return enc.writeLine(line)
+}
+
+//writeLine folds one unfolded content line and writes it with CRLF line breaks
+func (enc *Encoder) writeLine(line string) error {
+	_, err := io.WriteString(enc.w, foldLine(line))
+	return err
+}
+
+/*
+contentline   = name *(";" param ) ":" value CRLF
+param         = param-name "=" param-value *("," param-value)
+*/
+func encodeContentLine(p *Property) (string, error) {
+	if !isValidName(p.Name) {
+		return "", fmt.Errorf("ical:invalid property name %q", p.Name)
+	}
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(p.Name))
+
+	for _, key := range sortedParamKeys(p.Params) {
+		if !isValidName(key) {
+			return "", fmt.Errorf("ical:property %q has invalid parameter name %q", p.Name, key)
+		}
+		b.WriteByte(';')
+		b.WriteString(strings.ToUpper(key))
+		b.WriteByte('=')
+		for i, val := range p.Params[key] {
 			//seperate with ','
-			if i > 0{
-				fmt.Fprint(b,',')
+			if i > 0 {
+				b.WriteByte(',')
 			}
-			if strings.ContainsAny(val,";:\\\","){
-				val = strings.Replace(val,"\"","\\\"",-1)
-				val = strings.Replace(val,"\\","\\\\",-1)
+			ev, err := encodeParamValue(val)
+			if err != nil {
+				return "", fmt.Errorf("ical:property %q parameter %q: %v", p.Name, key, err)
 			}
-			fmt.Fprint(b,val)
+			b.WriteString(ev)
 		}
 	}
-	fmt.Fprint(b,":")
-	fmt.Fprint(b,p.Value)
-	st := b.String()
-	//https://tools.ietf.org/html/rfc5545#section-3.1
-	if len(st) > 75 {
-		for len(st) > 74{
-			ts := trimUTF8(74,st)
-			fmt.Fprint(enc.w,ts,"\r\n")//CRLF CR and LF
-			fmt.Fprint(enc.w," ")
-			st = st[len(ts):]
-		}
+	b.WriteByte(':')
+	if err := checkValueChars(p.Value); err != nil {
+		return "", fmt.Errorf("ical:property %q: %v", p.Name, err)
 	}
-	fmt.Fprint(enc.w,st,"\r\n")
+	b.WriteString(p.Value)
+	return b.String(), nil
 }
 
-func trimUTF8(max int,st string) string {
-	l := 0
-	for _,c := range st {
-		newl := l + utf8.RuneLen(c)
-		if newl > max {
-			break
-		}
-		l = newl
+//sortedParamKeys returns the parameter names of params in order,map order is
+//random and output must be stable
+func sortedParamKeys(params Parameters) []string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
 	}
-	return st[:l]
+	sort.Strings(keys)
+	return keys
 }
+
 /*
-//This method lead to cmp be copied ,but I don't know how to use index with Interface,so chang Encoder's Method to Component's method
-func (enc *Encoder) endcodeComponent(cmp Component) error {
-	if err := cmp.IsAvailable();err != nil{
-		return err
+param-value   = paramtext / quoted-string
+paramtext     = *SAFE-CHAR
+quoted-string = DQUOTE *QSAFE-CHAR DQUOTE
+
+QSAFE-CHAR is any character except CONTROL and DQUOTE,so a value containing
+DQUOTE or a control character can not be represented at all.
+*/
+func encodeParamValue(val string) (string, error) {
+	if strings.ContainsRune(val, '"') {
+		return "", fmt.Errorf("value %q contains DQUOTE", val)
 	}
-	fmt.Fprint(enc.w,"BEGIN:"+cmp.Name(),"\r\n")
-	for _,p := range cmp.Properties(){
-		enc.encodeProperty(&p)
+	if err := checkValueChars(val); err != nil {
+		return "", err
 	}
-
-	for _,c := range cmp.SubComponents(){
-		enc.endcodeComponent(c)
+	if strings.ContainsAny(val, ":;,") {
+		return `"` + val + `"`, nil
 	}
-	fmt.Fprint(enc.w,"END:"+cmp.Name()+"\r\n")
+	return val, nil
+}
 
+//CONTROL = %x00-08 / %x0A-1F / %x7F ,HTAB is allowed
+func checkValueChars(val string) error {
+	if !utf8.ValidString(val) {
+		return fmt.Errorf("value %q is not valid UTF-8", val)
+	}
+	for _, c := range val {
+		if (c < 0x20 && c != '\t') || c == 0x7f {
+			return fmt.Errorf("value %q contains control character %U", val, c)
+		}
+	}
 	return nil
 }
 
- */
+//name = iana-token / x-name ,both only use ALPHA, DIGIT and "-"
+func isValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
 
-func (enc *Encoder) Encode(com Component) error {
-	if err := com.encode(enc);err != nil{
-		return err
+//foldLine splits a content line into lines of at most 75 octets,the leading
+//space of a continuation line is counted,and UTF-8 sequences are never split
+func foldLine(st string) string {
+	var b strings.Builder
+	max := maxLineOctets
+	for len(st) > max {
+		ts := trimUTF8(max, st)
+		b.WriteString(ts)
+		b.WriteString("\r\n ")
+		st = st[len(ts):]
+		max = maxLineOctets - 1
 	}
-	return nil
+	b.WriteString(st)
+	b.WriteString("\r\n")
+	return b.String()
 }
 
-func NewEncoder(w io.Writer) *Encoder {
-	return &Encoder{w}
+func trimUTF8(max int, st string) string {
+	l := 0
+	for _, c := range st {
+		newl := l + utf8.RuneLen(c)
+		if newl > max {
+			break
+		}
+		l = newl
+	}
+	return st[:l]
 }
@@ -0,0 +1,285 @@
+package go_ical
+
+import (
+	"sort"
+	"time"
+)
+
+// Occurrence is one instance of a VEVENT,VTODO or VJOURNAL
+type Occurrence struct {
+	Start time.Time
+	End   time.Time
+	//RecurrenceID is the start the recurrence set gave the instance,before
+	//an override moved it
+	RecurrenceID time.Time
+	//Component is the master,or the override which replaced the instance
+	Component *ComponentObj
+}
+
+/*
+Expand returns the instances of the VEVENT,VTODO and VJOURNAL components of
+cal which overlap [from,to),sorted by start.
+
+Components are grouped by UID: RRULE,RDATE and EXDATE of the master build the
+recurrence set,RFC 5545 3.8.5,and a component with RECURRENCE-ID replaces the
+instance it names. Rules without COUNT or UNTIL are only expanded up to to.
+TZID is resolved with time.LoadLocation and floating times are taken as UTC,
+the same as Property.GetToTime. RANGE=THISANDFUTURE is not supported,such an
+override only replaces its own instance.
+*/
+func Expand(cal Calendar, from, to time.Time) ([]Occurrence, error) {
+	return expandSubComponents(&cal.ComponentObj, from, to, CompEvent, CompTodo, CompJournal)
+}
+
+// expandSubComponents expands the sub-components of com with one of names,
+// grouped by name and UID
+func expandSubComponents(com *ComponentObj, from, to time.Time, names ...string) ([]Occurrence, error) {
+	type group struct {
+		master    *ComponentObj
+		overrides []*ComponentObj
+	}
+	groups := map[string]*group{}
+	var order []string
+	var occs []Occurrence
+	for _, sub := range com.SubComponents() {
+		if !contains(names, sub.Name()) {
+			continue
+		}
+		c := sub.obj()
+		p := c.GetProperty(PropUID)
+		if p == nil {
+			//a component without UID can not be matched with others
+			o, err := expandAlone(c, from, to)
+			if err != nil {
+				return nil, err
+			}
+			occs = append(occs, o...)
+			continue
+		}
+		key := sub.Name() + "/" + p.Value
+		g := groups[key]
+		if g == nil {
+			g = &group{}
+			groups[key] = g
+			order = append(order, key)
+		}
+		if c.GetProperty(PropRecurrenceId) == nil {
+			g.master = c
+		} else {
+			g.overrides = append(g.overrides, c)
+		}
+	}
+	for _, key := range order {
+		g := groups[key]
+		o, err := ExpandComponent(g.master, g.overrides, from, to)
+		if err != nil {
+			return nil, err
+		}
+		occs = append(occs, o...)
+	}
+	sortOccurrences(occs)
+	return occs, nil
+}
+
+func expandAlone(com *ComponentObj, from, to time.Time) ([]Occurrence, error) {
+	if com.GetProperty(PropRecurrenceId) != nil {
+		return ExpandComponent(nil, []*ComponentObj{com}, from, to)
+	}
+	return ExpandComponent(com, nil, from, to)
+}
+
+/*
+ExpandComponent returns the instances of master overlapping [from,to),with
+the overrides replacing the instances they name. master may be nil when only
+overrides are known. A component without DTSTART has no instance,except a
+VTODO with DUE which has one at DUE.
+*/
+func ExpandComponent(master *ComponentObj, overrides []*ComponentObj, from, to time.Time) ([]Occurrence, error) {
+	var occs []Occurrence
+	var rids []time.Time
+	for _, o := range overrides {
+		rid, err := o.GetProperty(PropRecurrenceId).GetToTime()
+		if err != nil {
+			return nil, err
+		}
+		rids = append(rids, rid)
+		start, end, ok, err := componentSpan(o)
+		if err != nil {
+			return nil, err
+		}
+		if ok && overlaps(start, end, from, to) {
+			occs = append(occs, Occurrence{Start: start, End: end, RecurrenceID: rid, Component: o})
+		}
+	}
+	if master == nil {
+		sortOccurrences(occs)
+		return occs, nil
+	}
+
+	start, end, ok, err := componentSpan(master)
+	if err != nil || !ok {
+		return occs, err
+	}
+	dur := end.Sub(start)
+	add := func(s, e time.Time) {
+		for _, rid := range rids {
+			if rid.Equal(s) {
+				return
+			}
+		}
+		if overlaps(s, e, from, to) {
+			occs = append(occs, Occurrence{Start: s, End: e, RecurrenceID: s, Component: master})
+		}
+	}
+
+	var exdates []time.Time
+	for _, p := range master.GetProperties(PropExceptionDatetime) {
+		ps, err := periodValues(&p)
+		if err != nil {
+			return nil, err
+		}
+		for _, pe := range ps {
+			exdates = append(exdates, pe.Start)
+		}
+	}
+	excluded := func(t time.Time) bool {
+		for _, ex := range exdates {
+			if ex.Equal(t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	seen := map[int64]bool{}
+	emit := func(s, e time.Time) {
+		if seen[s.UnixNano()] || excluded(s) {
+			return
+		}
+		seen[s.UnixNano()] = true
+		add(s, e)
+	}
+	emit(start, end)
+	for _, p := range master.GetProperties(PropRecurrenceDatetime) {
+		ps, err := periodValues(&p)
+		if err != nil {
+			return nil, err
+		}
+		for _, pe := range ps {
+			if pe.End.IsZero() {
+				pe.End = pe.Start.Add(dur)
+			}
+			emit(pe.Start, pe.End)
+		}
+	}
+	//instances which start before from may still overlap the window
+	earliest := from.Add(-dur)
+	for _, p := range master.GetProperties(PropRecurrenceRule) {
+		r, err := ParseRecur(p.Value)
+		if err != nil {
+			return nil, err
+		}
+		it := r.Iterator(start)
+		for {
+			t, ok := it.Next()
+			if !ok || !t.Before(to) {
+				break
+			}
+			if t.Before(earliest) {
+				continue
+			}
+			emit(t, t.Add(dur))
+		}
+	}
+	sortOccurrences(occs)
+	return occs, nil
+}
+
+/*
+componentSpan returns the start and end of a single instance of com.
+The end comes from DTEND,DUE or DURATION,it defaults to one day after a
+DATE start and to the start itself otherwise,RFC 5545 3.6.1 .ok is false
+when com has no start.
+*/
+func componentSpan(com *ComponentObj) (start, end time.Time, ok bool, err error) {
+	ds := com.GetProperty(PropDatetimeStart)
+	if ds == nil {
+		if due := com.GetProperty(PropDatetimeDue); due != nil && com.Name() == CompTodo {
+			t, err := due.GetToTime()
+			return t, t, err == nil, err
+		}
+		return time.Time{}, time.Time{}, false, nil
+	}
+	if start, err = ds.GetToTime(); err != nil {
+		return
+	}
+	ok = true
+	endName := PropDatetimeEnd
+	if com.Name() == CompTodo {
+		endName = PropDatetimeDue
+	}
+	if p := com.GetProperty(endName); p != nil {
+		end, err = p.GetToTime()
+		return
+	}
+	if p := com.GetProperty(PropDuration); p != nil {
+		var d time.Duration
+		if d, err = p.GetToDuration(); err != nil {
+			return
+		}
+		end = start.Add(d)
+		return
+	}
+	if ds.IsDate() {
+		end = start.AddDate(0, 0, 1)
+		return
+	}
+	end = start
+	return
+}
+
+// periodValues parses the list of DATE,DATE-TIME or PERIOD values of an
+// RDATE or EXDATE,End is zero unless the value is a PERIOD
+func periodValues(p *Property) ([]Period, error) {
+	var ps []Period
+	for _, v := range splitValues(p.Value) {
+		q := p.Clone()
+		q.Value = v
+		if q.GetParamValue() == VDTperiod {
+			pe, err := parsePeriod(v)
+			if err != nil {
+				return nil, err
+			}
+			if tz := q.Params.Get(Paramtzid); tz != "" && len(v) > 0 && v[len(v)-1] != 'Z' {
+				loc, err := time.LoadLocation(tz)
+				if err != nil {
+					return nil, err
+				}
+				d := pe.Duration()
+				pe.Start = wallClock(pe.Start, loc)
+				pe.End = pe.Start.Add(d)
+			}
+			ps = append(ps, pe)
+			continue
+		}
+		t, err := q.GetToTime()
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, Period{Start: t})
+	}
+	return ps, nil
+}
+
+// overlaps reports whether [start,end) meets [from,to),an instance without
+// length meets the window when it starts inside it
+func overlaps(start, end, from, to time.Time) bool {
+	if !end.After(start) {
+		return !start.Before(from) && start.Before(to)
+	}
+	return start.Before(to) && end.After(from)
+}
+
+func sortOccurrences(occs []Occurrence) {
+	sort.SliceStable(occs, func(i, j int) bool { return occs[i].Start.Before(occs[j].Start) })
+}
@@ -0,0 +1,205 @@
+package go_ical
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// FreeBusyPeriod is one period of a FREEBUSY property with its FBTYPE
+type FreeBusyPeriod struct {
+	Period
+	Type string
+}
+
+// fbRank orders the busy types: where periods overlap the highest wins
+var fbRank = map[string]int{
+	FBTypeBusyTentative:   1,
+	FBTypeBusyUnavailable: 2,
+	FBTypeBusy:            3,
+}
+
+// Periods returns the FREEBUSY periods of fb,in UTC and in the order they
+// are written. A FREEBUSY without FBTYPE is BUSY,RFC 5545 3.2.9
+func (fb *VFreeBusy) Periods() ([]FreeBusyPeriod, error) {
+	return freeBusyPeriods(&fb.ComponentObj)
+}
+
+// SetPeriods replaces the FREEBUSY properties of fb,one property is written
+// for each FBTYPE
+func (fb *VFreeBusy) SetPeriods(fbs []FreeBusyPeriod) {
+	fb.DelProperty(PropFreeBusy)
+	var types []string
+	byType := map[string][]Period{}
+	for _, p := range fbs {
+		typ := strings.ToUpper(p.Type)
+		if typ == "" {
+			typ = FBTypeBusy
+		}
+		if _, ok := byType[typ]; !ok {
+			types = append(types, typ)
+		}
+		byType[typ] = append(byType[typ], p.Period)
+	}
+	for _, typ := range types {
+		p := NewProperty(PropFreeBusy)
+		p.SetFromPeriods(byType[typ])
+		p.Params.Set(Paramfbtype, typ)
+		fb.AddProperty(*p)
+	}
+}
+
+func freeBusyPeriods(com *ComponentObj) ([]FreeBusyPeriod, error) {
+	var fbs []FreeBusyPeriod
+	for _, p := range com.GetProperties(PropFreeBusy) {
+		ps, err := p.GetToPeriods()
+		if err != nil {
+			return nil, err
+		}
+		typ := strings.ToUpper(p.Params.Get(Paramfbtype))
+		if typ == "" {
+			typ = FBTypeBusy
+		}
+		for _, pe := range ps {
+			fbs = append(fbs, FreeBusyPeriod{Period: pe, Type: typ})
+		}
+	}
+	return fbs, nil
+}
+
+/*
+ComputeFreeBusy returns a VFREEBUSY with the busy time of cals in [from,to),
+RFC 5545 3.6.4.
+
+Every VEVENT instance is busy time,recurring events are expanded with Expand.
+Instances with TRANSP:TRANSPARENT or STATUS:CANCELLED are skipped,STATUS
+TENTATIVE gives BUSY-TENTATIVE and other instances BUSY. The FREEBUSY periods
+of VFREEBUSY components in cals are added with their FBTYPE,and the time the
+VAVAILABILITY components of each calendar mark as unavailable is added as
+BUSY-UNAVAILABLE,or its BUSYTYPE,see UnavailablePeriods. Periods are cut
+to the window and merged,where types overlap BUSY wins over
+BUSY-UNAVAILABLE,which wins over BUSY-TENTATIVE. All values are in UTC,
+DTSTAMP is now.
+*/
+func ComputeFreeBusy(cals []Calendar, from, to time.Time, now time.Time) (*VFreeBusy, error) {
+	var fbs []FreeBusyPeriod
+	for _, cal := range cals {
+		occs, err := Expand(cal, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range occs {
+			if o.Component.Name() != CompEvent || !o.End.After(o.Start) {
+				continue
+			}
+			if p := o.Component.GetProperty(PropTimeTransparency); p != nil && strings.EqualFold(p.Value, TranspTransparent) {
+				continue
+			}
+			typ := FBTypeBusy
+			if p := o.Component.GetProperty(PropStatus); p != nil {
+				switch strings.ToUpper(p.Value) {
+				case StatusCancelled:
+					continue
+				case StatusTentative:
+					typ = FBTypeBusyTentative
+				}
+			}
+			fbs = append(fbs, FreeBusyPeriod{Period: Period{Start: o.Start, End: o.End}, Type: typ})
+		}
+		var avs []*VAvailability
+		for _, sub := range cal.SubComponents() {
+			switch sub.Name() {
+			case CompFreebusy:
+				ps, err := freeBusyPeriods(sub.obj())
+				if err != nil {
+					return nil, err
+				}
+				fbs = append(fbs, ps...)
+			case CompAvailability:
+				avs = append(avs, &VAvailability{*sub.obj()})
+			}
+		}
+		ps, err := UnavailablePeriods(avs, from, to)
+		if err != nil {
+			return nil, err
+		}
+		fbs = append(fbs, ps...)
+	}
+
+	fb := &VFreeBusy{ComponentObj{NameObj: CompFreebusy}}
+	uid, err := newUID()
+	if err != nil {
+		return nil, err
+	}
+	fb.AddProperty(newPropertyValue(PropUID, uid))
+	for _, x := range []struct {
+		name string
+		t    time.Time
+	}{{PropDatetimeStamp, now}, {PropDatetimeStart, from}, {PropDatetimeEnd, to}} {
+		p := NewProperty(x.name)
+		p.setFromUTC(x.t.Truncate(time.Second))
+		fb.AddProperty(*p)
+	}
+	fb.SetPeriods(MergeFreeBusy(fbs, from, to))
+	return fb, nil
+}
+
+/*
+MergeFreeBusy cuts the busy periods to [from,to) and merges them into
+sorted periods which do not overlap,each with the highest busy type covering
+it. FREE periods are dropped.
+*/
+func MergeFreeBusy(fbs []FreeBusyPeriod, from, to time.Time) []FreeBusyPeriod {
+	type edge struct {
+		t     time.Time
+		typ   string
+		delta int
+	}
+	var edges []edge
+	for _, p := range fbs {
+		typ := strings.ToUpper(p.Type)
+		if typ == "" {
+			typ = FBTypeBusy
+		}
+		if fbRank[typ] == 0 {
+			continue
+		}
+		start, end := p.Start, p.End
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !end.After(start) {
+			continue
+		}
+		edges = append(edges, edge{start.UTC(), typ, 1}, edge{end.UTC(), typ, -1})
+	}
+	sort.SliceStable(edges, func(i, j int) bool { return edges[i].t.Before(edges[j].t) })
+
+	var out []FreeBusyPeriod
+	counts := map[string]int{}
+	for i, e := range edges {
+		counts[e.typ] += e.delta
+		if i+1 == len(edges) || edges[i+1].t.Equal(e.t) {
+			continue
+		}
+		typ := ""
+		for t, n := range counts {
+			if n > 0 && fbRank[t] > fbRank[typ] {
+				typ = t
+			}
+		}
+		if typ == "" {
+			continue
+		}
+		next := edges[i+1].t
+		if n := len(out); n > 0 && out[n-1].Type == typ && out[n-1].End.Equal(e.t) {
+			out[n-1].End = next
+			continue
+		}
+		out = append(out, FreeBusyPeriod{Period: Period{Start: e.t, End: next}, Type: typ})
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package go_ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const busyCalendarStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20200101T000000Z
+DTSTART;TZID=Europe/Berlin:20200302T090000
+DTEND;TZID=Europe/Berlin:20200302T093000
+RRULE:FREQ=DAILY;COUNT=3
+END:VEVENT
+BEGIN:VEVENT
+UID:lunch@example.com
+DTSTAMP:20200101T000000Z
+DTSTART:20200302T081500Z
+DTEND:20200302T100000Z
+STATUS:TENTATIVE
+END:VEVENT
+BEGIN:VEVENT
+UID:holiday@example.com
+DTSTAMP:20200101T000000Z
+DTSTART;VALUE=DATE:20200304
+TRANSP:TRANSPARENT
+END:VEVENT
+BEGIN:VEVENT
+UID:cancelled@example.com
+DTSTAMP:20200101T000000Z
+DTSTART:20200303T120000Z
+DTEND:20200303T130000Z
+STATUS:CANCELLED
+END:VEVENT
+END:VCALENDAR
+`
+
+const publishedFreeBusyStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VFREEBUSY
+UID:fb@example.com
+DTSTAMP:20200101T000000Z
+DTSTART:20200301T000000Z
+DTEND:20200310T000000Z
+FREEBUSY;FBTYPE=BUSY-UNAVAILABLE:20200303T160000Z/PT2H
+FREEBUSY;FBTYPE=FREE:20200303T000000Z/PT8H
+END:VFREEBUSY
+END:VCALENDAR
+`
+
+func TestComputeFreeBusy(t *testing.T) {
+	from := time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 4, 17, 0, 0, 0, time.UTC)
+	fb, err := ComputeFreeBusy([]Calendar{decodeString(t, busyCalendarStr), decodeString(t, publishedFreeBusyStr)}, from, to, itipNow)
+	if err != nil {
+		t.Fatalf("ComputeFreeBusy() err: %v", err)
+	}
+	if got := propValue(&fb.ComponentObj, PropDatetimeStamp); got != "20200301T090000Z" {
+		t.Errorf("DTSTAMP = %q, want 20200301T090000Z", got)
+	}
+	fbs, err := fb.Periods()
+	if err != nil {
+		t.Fatalf("Periods() err: %v", err)
+	}
+	var got []string
+	for _, p := range MergeFreeBusy(fbs, from, to) {
+		got = append(got, p.Type+":"+p.String())
+	}
+	want := []string{
+		"BUSY:20200302T080000Z/20200302T083000Z",
+		"BUSY-TENTATIVE:20200302T083000Z/20200302T100000Z",
+		"BUSY:20200303T080000Z/20200303T083000Z",
+		"BUSY-UNAVAILABLE:20200303T160000Z/20200303T180000Z",
+		"BUSY:20200304T080000Z/20200304T083000Z",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("periods:\n got %v\nwant %v", got, want)
+	}
+	if got := propValue(&fb.ComponentObj, PropDatetimeStart); got != "20200302T000000Z" {
+		t.Errorf("DTSTART = %q", got)
+	}
+
+	cal := NewCalendar()
+	cal.SetMethod(MethodPublish)
+	fb.AddProperty(newPropertyValue(PropOrganizer, "mailto:a@example.com"))
+	cal.AddComponent(fb)
+	if findings := Validate(*cal); HasErrors(findings) {
+		t.Errorf("Validate() of the VFREEBUSY: %v", findings)
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	if !strings.Contains(buf.String(), "FREEBUSY;FBTYPE=BUSY:20200302T080000Z/20200302T083000Z,") {
+		t.Errorf("encoded VFREEBUSY:\n%s", buf.String())
+	}
+}
+
+func TestMergeFreeBusy(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2020, 3, 2, h, 0, 0, 0, time.UTC) }
+	fbs := []FreeBusyPeriod{
+		{Period{at(9), at(11)}, FBTypeBusyTentative},
+		{Period{at(10), at(12)}, FBTypeBusy},
+		{Period{at(12), at(13)}, FBTypeBusy},
+		{Period{at(5), at(8)}, ""},
+	}
+	var got []string
+	for _, p := range MergeFreeBusy(fbs, at(7), at(20)) {
+		got = append(got, p.Type+":"+p.String())
+	}
+	want := "BUSY:20200302T070000Z/20200302T080000Z BUSY-TENTATIVE:20200302T090000Z/20200302T100000Z " +
+		"BUSY:20200302T100000Z/20200302T130000Z"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("MergeFreeBusy():\n got %s\nwant %s", s, want)
+	}
+}
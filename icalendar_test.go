@@ -58,7 +58,8 @@ func TestCalendar(t *testing.T) {
 	var sm,desc string
 	var ds,start,end time.Time
 
-	for _,p := range exampleCalendar.Properties(){
+	//the event properties,the calendar itself only has VERSION and PRODID
+	for _,p := range events[0].Properties(){
 		switch p.Name {
 		case PropSummary:
 
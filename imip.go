@@ -0,0 +1,291 @@
+package go_ical
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// iMIP,RFC 6047,carries iTIP messages in email. An invitation is a
+// multipart/alternative message with a readable text/plain part and a
+// text/calendar part whose "method" parameter repeats the METHOD of the
+// calendar.
+
+// IMIPMessage holds the email headers and parts which WriteIMIP writes around
+// an iTIP calendar
+type IMIPMessage struct {
+	From    string
+	To      []string
+	Subject string
+	Date    time.Time
+	//Text is the text/plain part,a summary of the events is made when it is empty
+	Text string
+	//Attachment also attaches the calendar as a file with this name,e.g. "invite.ics"
+	Attachment string
+}
+
+// WriteIMIP writes cal,which must have METHOD,as a RFC 5322 message with the
+// headers and parts of msg.
+func WriteIMIP(w io.Writer, msg IMIPMessage, cal *Calendar) error {
+	method := methodOf(*cal)
+	if method == "" {
+		return fmt.Errorf("ical:iMIP needs a calendar with METHOD")
+	}
+	var ics bytes.Buffer
+	if err := NewEncoder(&ics).Encode(cal); err != nil {
+		return err
+	}
+	text := msg.Text
+	if text == "" {
+		text = imipSummary(cal)
+	}
+
+	h := textproto.MIMEHeader{}
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("ical:invalid From %q: %v", msg.From, err)
+	}
+	h.Set("From", from.String())
+	to := make([]string, len(msg.To))
+	for i, s := range msg.To {
+		a, err := mail.ParseAddress(s)
+		if err != nil {
+			return fmt.Errorf("ical:invalid To %q: %v", s, err)
+		}
+		to[i] = a.String()
+	}
+	h.Set("To", strings.Join(to, ", "))
+	h.Set("Subject", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	date := msg.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	h.Set("Date", date.Format(time.RFC1123Z))
+	h.Set("MIME-Version", "1.0")
+
+	calType := mime.FormatMediaType("text/calendar", map[string]string{"method": method, "charset": "UTF-8"})
+	alt := func(mw *multipart.Writer) error {
+		if err := writeQPPart(mw, "text/plain; charset=UTF-8", []byte(text)); err != nil {
+			return err
+		}
+		return writeQPPart(mw, calType, ics.Bytes())
+	}
+
+	bw := bufio.NewWriter(w)
+	if msg.Attachment == "" {
+		mw := multipart.NewWriter(bw)
+		h.Set("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+		if err := writeHeader(bw, h); err != nil {
+			return err
+		}
+		if err := alt(mw); err != nil {
+			return err
+		}
+		if err := mw.Close(); err != nil {
+			return err
+		}
+		return bw.Flush()
+	}
+
+	mixed := multipart.NewWriter(bw)
+	h.Set("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	if err := writeHeader(bw, h); err != nil {
+		return err
+	}
+	var inner bytes.Buffer
+	mw := multipart.NewWriter(&inner)
+	if err := alt(mw); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	pw, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + mw.Boundary()},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := pw.Write(inner.Bytes()); err != nil {
+		return err
+	}
+	pw, err = mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {mime.FormatMediaType("text/calendar",
+			map[string]string{"method": method, "charset": "UTF-8", "name": msg.Attachment})},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": msg.Attachment})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeBase64(pw, ics.Bytes()); err != nil {
+		return err
+	}
+	if err := mixed.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+/*
+ReadIMIP reads a RFC 5322 message and decodes every text/calendar or
+application/ics part found in it,at any depth of multipart nesting. Parts
+with the same content,such as the inline part and the attachment of an
+invitation,give one Calendar. It is an error when the message holds no
+calendar at all.
+*/
+func ReadIMIP(r io.Reader) ([]Calendar, error) {
+	m, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	var raws [][]byte
+	err = walkIMIPPart(textproto.MIMEHeader(m.Header), m.Body, func(b []byte) {
+		for _, raw := range raws {
+			if bytes.Equal(raw, b) {
+				return
+			}
+		}
+		raws = append(raws, b)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(raws) == 0 {
+		return nil, fmt.Errorf("ical:the message has no text/calendar part")
+	}
+	cals := make([]Calendar, 0, len(raws))
+	for _, raw := range raws {
+		cal, err := NewDecoder(bytes.NewReader(raw)).Decode()
+		if err != nil {
+			return nil, err
+		}
+		cals = append(cals, cal)
+	}
+	return cals, nil
+}
+
+func walkIMIPPart(h textproto.MIMEHeader, body io.Reader, found func([]byte)) error {
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		ct = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return fmt.Errorf("ical:invalid Content-Type %q: %v", ct, err)
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := walkIMIPPart(p.Header, p, found); err != nil {
+				return err
+			}
+		}
+	}
+	if mediaType != "text/calendar" && mediaType != "application/ics" {
+		return nil
+	}
+	switch cs := strings.ToLower(params["charset"]); cs {
+	case "", "utf-8", "us-ascii":
+	default:
+		return fmt.Errorf("ical:unsupported charset %q of a calendar part", cs)
+	}
+	switch cte := strings.ToLower(h.Get("Content-Transfer-Encoding")); cte {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	found(b)
+	return nil
+}
+
+// imipSummary is the text/plain part for a calendar
+func imipSummary(cal *Calendar) string {
+	var sb strings.Builder
+	for _, sub := range cal.SubComponents() {
+		com := sub.obj()
+		if com.Name() == CompTimezone {
+			continue
+		}
+		if p := com.GetProperty(PropSummary); p != nil {
+			sb.WriteString(FromText(p.Value) + "\r\n")
+		}
+		if p := com.GetProperty(PropDatetimeStart); p != nil {
+			if t, err := p.GetToTime(); err == nil {
+				layout := "Mon Jan 2 2006 15:04 MST"
+				if p.IsDate() {
+					layout = "Mon Jan 2 2006"
+				}
+				sb.WriteString("When: " + t.Format(layout) + "\r\n")
+			}
+		}
+		if p := com.GetProperty(PropLocation); p != nil {
+			sb.WriteString("Where: " + FromText(p.Value) + "\r\n")
+		}
+		if p := com.GetProperty(PropOrganizer); p != nil {
+			sb.WriteString("Organizer: " + strings.TrimPrefix(strings.ToLower(p.Value), "mailto:") + "\r\n")
+		}
+		sb.WriteString("\r\n")
+	}
+	return sb.String()
+}
+
+func writeHeader(w io.Writer, h textproto.MIMEHeader) error {
+	for _, k := range []string{"From", "To", "Subject", "Date", "MIME-Version", "Content-Type"} {
+		if _, err := fmt.Fprintf(w, "%s: %s\r\n", k, h.Get(k)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+func writeQPPart(mw *multipart.Writer, contentType string, b []byte) error {
+	pw, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qw := quotedprintable.NewWriter(pw)
+	if _, err := qw.Write(b); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// writeBase64 writes b in base64 lines of 76 characters
+func writeBase64(w io.Writer, b []byte) error {
+	s := base64.StdEncoding.EncodeToString(b)
+	for len(s) > 76 {
+		if _, err := io.WriteString(w, s[:76]+"\r\n"); err != nil {
+			return err
+		}
+		s = s[76:]
+	}
+	_, err := io.WriteString(w, s+"\r\n")
+	return err
+}
@@ -0,0 +1,119 @@
+package go_ical
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIMIPRoundTrip(t *testing.T) {
+	for _, attachment := range []string{"", "invite.ics"} {
+		cal, err := BuildRequest(prepareOrganizerEvent(), itipNow)
+		if err != nil {
+			t.Fatalf("BuildRequest() err: %v", err)
+		}
+		cal.PutProperty(newPropertyValue(PropDescription, ToText("Réunion,"+strings.Repeat(" long text", 20))))
+
+		var buf bytes.Buffer
+		msg := IMIPMessage{
+			From:       "Alice <a@example.com>",
+			To:         []string{"b@example.com"},
+			Subject:    "Invitation: Weekly sync",
+			Date:       time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC),
+			Attachment: attachment,
+		}
+		if err := WriteIMIP(&buf, msg, cal); err != nil {
+			t.Fatalf("WriteIMIP() err: %v", err)
+		}
+		raw := buf.String()
+		for _, want := range []string{
+			"Subject: Invitation: Weekly sync\r\n",
+			"Content-Type: text/calendar; charset=UTF-8; method=REQUEST",
+			"Content-Type: text/plain; charset=UTF-8",
+			"Weekly sync",
+		} {
+			if !strings.Contains(raw, want) {
+				t.Errorf("attachment %q: message has no %q:\n%s", attachment, want, raw)
+			}
+		}
+		if attachment != "" && !strings.Contains(raw, `filename=invite.ics`) {
+			t.Errorf("message has no attachment:\n%s", raw)
+		}
+
+		cals, err := ReadIMIP(strings.NewReader(raw))
+		if err != nil {
+			t.Fatalf("ReadIMIP() err: %v", err)
+		}
+		if len(cals) != 1 {
+			t.Fatalf("ReadIMIP() got %d calendars, want 1", len(cals))
+		}
+		if got := methodOf(cals[0]); got != MethodRequest {
+			t.Errorf("METHOD = %q, want REQUEST", got)
+		}
+		if got := FromText(cals[0].GetProperty(PropDescription).Value); !strings.HasPrefix(got, "Réunion,") {
+			t.Errorf("DESCRIPTION = %q", got)
+		}
+		if got := propValue(cals[0].GetEvents()[0].obj(), PropUID); got != "weekly@example.com" {
+			t.Errorf("UID = %q", got)
+		}
+	}
+}
+
+func TestReadIMIPBase64(t *testing.T) {
+	raw := "From: b@example.com\r\n" +
+		"To: a@example.com\r\n" +
+		"Subject: Accepted\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/calendar; method=REPLY; charset=UTF-8\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n"
+	var b bytes.Buffer
+	writeBase64(&b, []byte(toCRLF(strings.Replace(requestCalendarStr, "METHOD:REQUEST", "METHOD:REPLY", 1))))
+	cals, err := ReadIMIP(strings.NewReader(raw + b.String()))
+	if err != nil {
+		t.Fatalf("ReadIMIP() err: %v", err)
+	}
+	if len(cals) != 1 || methodOf(cals[0]) != MethodReply {
+		t.Errorf("ReadIMIP() = %v", cals)
+	}
+
+	noCal := "From: b@example.com\r\nContent-Type: text/plain\r\n\r\nhello\r\n"
+	if _, err := ReadIMIP(strings.NewReader(noCal)); err == nil {
+		t.Errorf("ReadIMIP() of a message without calendar succeeded")
+	}
+}
+
+// failingWriter accepts n bytes,then fails
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if len(b) > w.n {
+		k := w.n
+		w.n = 0
+		return k, errors.New("disk full")
+	}
+	w.n -= len(b)
+	return len(b), nil
+}
+
+func TestWriteIMIPError(t *testing.T) {
+	cal, err := BuildRequest(prepareOrganizerEvent(), itipNow)
+	if err != nil {
+		t.Fatalf("BuildRequest() err: %v", err)
+	}
+	msg := IMIPMessage{From: "a@example.com", To: []string{"b@example.com"}, Subject: "Weekly sync", Date: itipNow}
+	for _, attachment := range []string{"", "invite.ics"} {
+		msg.Attachment = attachment
+		var buf bytes.Buffer
+		if err := WriteIMIP(&buf, msg, cal); err != nil {
+			t.Fatalf("WriteIMIP() err: %v", err)
+		}
+		for _, n := range []int{0, 20, buf.Len() / 2, buf.Len() - 1} {
+			if err := WriteIMIP(&failingWriter{n}, msg, cal); err == nil {
+				t.Errorf("attachment %q: WriteIMIP() after %d of %d bytes err = nil", attachment, n, buf.Len())
+			}
+		}
+	}
+}
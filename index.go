@@ -0,0 +1,433 @@
+package go_ical
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	//indexBucket is the span of a bucket of the occurrence index
+	indexBucket = 24 * time.Hour
+	//indexLongBuckets is the number of buckets above which an occurrence
+	//is kept in the list of long ones instead
+	indexLongBuckets = 32
+)
+
+/*
+CalendarIndex keeps components in memory,indexed by UID and by the time of
+their occurrences,for agenda queries over large calendars.
+
+Occurrences are expanded and indexed within the window [From,To) given to
+NewCalendarIndex,so rules without COUNT or UNTIL stay bounded. Queries
+inside the window only look at the occurrences which may overlap,queries
+reaching outside it expand every object. Changes re-expand the UID they
+concern only. The index can be saved to and restored from an iCalendar
+snapshot. A CalendarIndex is safe for concurrent use,the components it
+returns must not be modified.
+*/
+type CalendarIndex struct {
+	//From and To are the window,they must not be changed
+	From time.Time
+	To   time.Time
+
+	mu      sync.RWMutex
+	objects map[string]*indexObject
+	buckets map[int64][]*indexEntry
+	long    []*indexEntry
+}
+
+// indexObject is the components of a UID and their indexed occurrences
+type indexObject struct {
+	components []*ComponentObj
+	entries    []*indexEntry
+}
+
+type indexEntry struct {
+	uid string
+	occ Occurrence
+}
+
+// NewCalendarIndex returns an empty index with the window [from,to)
+func NewCalendarIndex(from, to time.Time) (*CalendarIndex, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("ical:index window %v to %v is empty", from, to)
+	}
+	return &CalendarIndex{
+		From:    from,
+		To:      to,
+		objects: map[string]*indexObject{},
+		buckets: map[int64][]*indexEntry{},
+	}, nil
+}
+
+// Len returns the number of UIDs in the index
+func (idx *CalendarIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.objects)
+}
+
+// UIDs returns the UIDs in the index,sorted
+func (idx *CalendarIndex) UIDs() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	uids := make([]string, 0, len(idx.objects))
+	for uid := range idx.objects {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+// Lookup returns the components of uid,the master and its overrides,nil
+// when the index does not have it
+func (idx *CalendarIndex) Lookup(uid string) []*ComponentObj {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if o := idx.objects[uid]; o != nil {
+		return append([]*ComponentObj(nil), o.components...)
+	}
+	return nil
+}
+
+// Put adds a copy of com,or replaces the component with the same UID and
+// RECURRENCE-ID. com needs a UID.
+func (idx *CalendarIndex) Put(com Component) error {
+	c := com.obj().Clone()
+	k, ok := componentKey(c)
+	if !ok {
+		return fmt.Errorf("ical:cannot index a %s without UID", c.Name())
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var coms []*ComponentObj
+	replaced := false
+	if o := idx.objects[k.UID]; o != nil {
+		for _, have := range o.components {
+			if hk, _ := componentKey(have); hk == k {
+				have, replaced = c, true
+			}
+			coms = append(coms, have)
+		}
+	}
+	if !replaced {
+		coms = append(coms, c)
+	}
+	return idx.set(k.UID, coms)
+}
+
+// AddCalendar puts the components of cal,VTIMEZONEs are left out as TZIDs
+// are resolved with time.LoadLocation. Nothing is added when a component
+// can not be indexed.
+func (idx *CalendarIndex) AddCalendar(cal Calendar) error {
+	groups, order, err := indexGroups(cal)
+	if err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	//expand first so that an error leaves the index as it is
+	objects := map[string]*indexObject{}
+	for _, uid := range order {
+		coms := groups[uid]
+		if o := idx.objects[uid]; o != nil {
+			coms = mergeIndexComponents(o.components, coms)
+		}
+		if objects[uid], err = idx.expand(uid, coms); err != nil {
+			return err
+		}
+	}
+	for _, uid := range order {
+		idx.replace(uid, objects[uid])
+	}
+	return nil
+}
+
+// indexGroups returns copies of the components of cal by UID
+func indexGroups(cal Calendar) (map[string][]*ComponentObj, []string, error) {
+	groups := map[string][]*ComponentObj{}
+	var order []string
+	for _, sub := range cal.SubComponentsObj {
+		if sub.Name() == CompTimezone {
+			continue
+		}
+		c := sub.obj().Clone()
+		k, ok := componentKey(c)
+		if !ok {
+			return nil, nil, fmt.Errorf("ical:cannot index a %s without UID", c.Name())
+		}
+		if _, ok := groups[k.UID]; !ok {
+			order = append(order, k.UID)
+		}
+		groups[k.UID] = append(groups[k.UID], c)
+	}
+	return groups, order, nil
+}
+
+// mergeIndexComponents replaces the components of have by those of add
+// with the same key and appends the others
+func mergeIndexComponents(have, add []*ComponentObj) []*ComponentObj {
+	coms := append([]*ComponentObj(nil), have...)
+	for _, c := range add {
+		k, _ := componentKey(c)
+		i := 0
+		for ; i < len(coms); i++ {
+			if hk, _ := componentKey(coms[i]); hk == k {
+				coms[i] = c
+				break
+			}
+		}
+		if i == len(coms) {
+			coms = append(coms, c)
+		}
+	}
+	return coms
+}
+
+// Remove removes the component with key k,it reports whether there was one
+func (idx *CalendarIndex) Remove(k ComponentKey) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	o := idx.objects[k.UID]
+	if o == nil {
+		return false
+	}
+	var coms []*ComponentObj
+	for _, c := range o.components {
+		if ck, _ := componentKey(c); ck != k {
+			coms = append(coms, c)
+		}
+	}
+	if len(coms) == len(o.components) {
+		return false
+	}
+	//fewer components expand without error as they did before
+	idx.set(k.UID, coms)
+	return true
+}
+
+// RemoveUID removes all components of uid,it reports whether there were any
+func (idx *CalendarIndex) RemoveUID(uid string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.objects[uid] == nil {
+		return false
+	}
+	idx.replace(uid, nil)
+	return true
+}
+
+// set expands coms and replaces the object of uid. The caller holds mu.
+func (idx *CalendarIndex) set(uid string, coms []*ComponentObj) error {
+	o, err := idx.expand(uid, coms)
+	if err != nil {
+		return err
+	}
+	idx.replace(uid, o)
+	return nil
+}
+
+// expand returns the object of coms with its occurrences in the window
+func (idx *CalendarIndex) expand(uid string, coms []*ComponentObj) (*indexObject, error) {
+	if len(coms) == 0 {
+		return nil, nil
+	}
+	occs, err := expandObject(coms, idx.From, idx.To)
+	if err != nil {
+		return nil, fmt.Errorf("ical:indexing %s: %v", uid, err)
+	}
+	o := &indexObject{components: coms}
+	for _, occ := range occs {
+		o.entries = append(o.entries, &indexEntry{uid: uid, occ: occ})
+	}
+	return o, nil
+}
+
+func expandObject(coms []*ComponentObj, from, to time.Time) ([]Occurrence, error) {
+	holder := &ComponentObj{NameObj: CompCalendar}
+	for _, c := range coms {
+		holder.SubComponentsObj = append(holder.SubComponentsObj, c)
+	}
+	return expandSubComponents(holder, from, to, CompEvent, CompTodo, CompJournal)
+}
+
+// replace drops the entries of the object of uid and adds those of o,which
+// is nil to remove the object. The caller holds mu.
+func (idx *CalendarIndex) replace(uid string, o *indexObject) {
+	if old := idx.objects[uid]; old != nil {
+		drop := map[*indexEntry]bool{}
+		for _, e := range old.entries {
+			drop[e] = true
+		}
+		for _, e := range old.entries {
+			if b, n, long := indexBuckets(e.occ); !long {
+				for i := int64(0); i < n; i++ {
+					idx.buckets[b+i] = dropEntries(idx.buckets[b+i], drop)
+					if len(idx.buckets[b+i]) == 0 {
+						delete(idx.buckets, b+i)
+					}
+				}
+			}
+		}
+		idx.long = dropEntries(idx.long, drop)
+		delete(idx.objects, uid)
+	}
+	if o == nil {
+		return
+	}
+	idx.objects[uid] = o
+	for _, e := range o.entries {
+		b, n, long := indexBuckets(e.occ)
+		if long {
+			idx.long = append(idx.long, e)
+			continue
+		}
+		for i := int64(0); i < n; i++ {
+			idx.buckets[b+i] = append(idx.buckets[b+i], e)
+		}
+	}
+}
+
+func dropEntries(es []*indexEntry, drop map[*indexEntry]bool) []*indexEntry {
+	kept := es[:0]
+	for _, e := range es {
+		if !drop[e] {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// bucketOf returns the bucket of t
+func bucketOf(t time.Time) int64 {
+	b := t.Unix() / int64(indexBucket/time.Second)
+	if t.Unix() < 0 && t.Unix()%int64(indexBucket/time.Second) != 0 {
+		b--
+	}
+	return b
+}
+
+// indexBuckets returns the first bucket occ is in and the number of them,
+// long is set when there are more than indexLongBuckets
+func indexBuckets(occ Occurrence) (first, n int64, long bool) {
+	first = bucketOf(occ.Start)
+	last := first
+	if occ.End.After(occ.Start) {
+		//the end is not part of the occurrence
+		last = bucketOf(occ.End.Add(-time.Nanosecond))
+	}
+	n = last - first + 1
+	return first, n, n > indexLongBuckets
+}
+
+/*
+Query returns the occurrences which overlap [from,to),sorted by start,as
+Expand does for a calendar holding all components of the index.
+*/
+func (idx *CalendarIndex) Query(from, to time.Time) ([]Occurrence, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if from.Before(idx.From) || to.After(idx.To) {
+		return idx.expandAll(from, to)
+	}
+	var occs []Occurrence
+	seen := map[*indexEntry]bool{}
+	collect := func(es []*indexEntry) {
+		for _, e := range es {
+			if !seen[e] && overlaps(e.occ.Start, e.occ.End, from, to) {
+				seen[e] = true
+				occs = append(occs, e.occ)
+			}
+		}
+	}
+	if to.After(from) {
+		for b, last := bucketOf(from), bucketOf(to.Add(-time.Nanosecond)); b <= last; b++ {
+			collect(idx.buckets[b])
+		}
+	}
+	collect(idx.long)
+	sortOccurrences(occs)
+	return occs, nil
+}
+
+// expandAll expands every object,for queries outside the window. The
+// caller holds mu.
+func (idx *CalendarIndex) expandAll(from, to time.Time) ([]Occurrence, error) {
+	var occs []Occurrence
+	for uid, o := range idx.objects {
+		found, err := expandObject(o.components, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("ical:expanding %s: %v", uid, err)
+		}
+		occs = append(occs, found...)
+	}
+	sortOccurrences(occs)
+	return occs, nil
+}
+
+// Snapshot writes all components of the index as a calendar,in UID order
+func (idx *CalendarIndex) Snapshot(w io.Writer) error {
+	cal := NewCalendar()
+	idx.mu.RLock()
+	for _, uid := range idx.sortedUIDs() {
+		for _, c := range idx.objects[uid].components {
+			cal.AddComponent(c)
+		}
+	}
+	idx.mu.RUnlock()
+	return NewEncoder(w).Encode(cal)
+}
+
+func (idx *CalendarIndex) sortedUIDs() []string {
+	uids := make([]string, 0, len(idx.objects))
+	for uid := range idx.objects {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+// Restore replaces the contents of the index by the calendar r holds,as
+// written by Snapshot. The index is unchanged on error.
+func (idx *CalendarIndex) Restore(r io.Reader) error {
+	cal, err := NewDecoder(r).Decode()
+	if err != nil {
+		return err
+	}
+	fresh, err := NewCalendarIndex(idx.From, idx.To)
+	if err != nil {
+		return err
+	}
+	if err := fresh.AddCalendar(cal); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.objects, idx.buckets, idx.long = fresh.objects, fresh.buckets, fresh.long
+	return nil
+}
+
+// SaveSnapshot writes a snapshot to the file path,atomically by a
+// temporary file renamed to path
+func (idx *CalendarIndex) SaveSnapshot(path string) error {
+	var buf bytes.Buffer
+	if err := idx.Snapshot(&buf); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// LoadSnapshot restores the index from the file path
+func (idx *CalendarIndex) LoadSnapshot(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return idx.Restore(f)
+}
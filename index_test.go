@@ -0,0 +1,168 @@
+package go_ical
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestIndex(t *testing.T) *CalendarIndex {
+	t.Helper()
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	idx, err := NewCalendarIndex(from, from.AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.AddCalendar(decodeString(t, filterCalendarStr)); err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+// indexStarts describes occs by UID and start
+func indexStarts(occs []Occurrence) string {
+	var got []string
+	for _, o := range occs {
+		got = append(got, o.Component.GetProperty(PropUID).Value+" "+o.Start.UTC().Format(DatetimeFormat2))
+	}
+	return strings.Join(got, ",")
+}
+
+func TestCalendarIndexQuery(t *testing.T) {
+	idx := newTestIndex(t)
+	if uids := strings.Join(idx.UIDs(), ","); uids != "review@example.com,standup@example.com" {
+		t.Errorf("UIDs() = %s", uids)
+	}
+	if coms := idx.Lookup("standup@example.com"); len(coms) != 2 {
+		t.Errorf("Lookup() = %d components", len(coms))
+	}
+	if coms := idx.Lookup("missing@example.com"); coms != nil {
+		t.Errorf("Lookup() of a missing UID = %v", coms)
+	}
+
+	from := time.Date(2021, 3, 7, 0, 0, 0, 0, time.UTC)
+	occs, err := idx.Query(from, from.AddDate(0, 0, 14))
+	if err != nil {
+		t.Fatalf("Query() err: %v", err)
+	}
+	//3/8 is excluded,3/15 is moved to 3/16
+	if got, want := indexStarts(occs), "standup@example.com 20210316T100000Z"; got != want {
+		t.Errorf("Query() = %s, want %s", got, want)
+	}
+
+	//the rule has no end,the index stops at the window but queries past it
+	//still expand
+	late := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	occs, err = idx.Query(late, late.AddDate(0, 0, 7))
+	if got, want := indexStarts(occs), "standup@example.com 20220307T090000Z"; err != nil || got != want {
+		t.Errorf("Query() outside the window = %s, %v, want %s", got, err, want)
+	}
+	want := indexStarts(mustExpand(t, decodeString(t, filterCalendarStr), idx.From, idx.To))
+	if occs, _ := idx.Query(idx.From, idx.To); indexStarts(occs) != want {
+		t.Errorf("Query() of the window = %s, want %s", indexStarts(occs), want)
+	}
+}
+
+func mustExpand(t *testing.T, cal Calendar, from, to time.Time) []Occurrence {
+	t.Helper()
+	occs, err := Expand(cal, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return occs
+}
+
+func TestCalendarIndexUpdate(t *testing.T) {
+	idx := newTestIndex(t)
+	from := time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC)
+	query := func() string {
+		occs, err := idx.Query(from, from.AddDate(0, 0, 7))
+		if err != nil {
+			t.Fatalf("Query() err: %v", err)
+		}
+		return indexStarts(occs)
+	}
+
+	//moving the override again replaces it
+	override := idx.Lookup("standup@example.com")[1].Clone()
+	override.PutProperty(newPropertyValue(PropDatetimeStart, "20210317T110000Z"))
+	override.PutProperty(newPropertyValue(PropDatetimeEnd, "20210317T113000Z"))
+	if err := idx.Put(override); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := query(), "standup@example.com 20210317T110000Z"; got != want {
+		t.Errorf("Query() after Put() = %s, want %s", got, want)
+	}
+	if n := len(idx.Lookup("standup@example.com")); n != 2 {
+		t.Errorf("Put() of an override left %d components", n)
+	}
+
+	//removing the override brings back the instance of the rule
+	if !idx.Remove(ComponentKey{Name: CompEvent, UID: "standup@example.com", RecurrenceID: "20210315T090000Z"}) {
+		t.Fatal("Remove() = false")
+	}
+	if got, want := query(), "standup@example.com 20210315T090000Z"; got != want {
+		t.Errorf("Query() after Remove() = %s, want %s", got, want)
+	}
+
+	//a new object with a long event lands in the index too
+	conf := &ComponentObj{NameObj: CompEvent}
+	conf.AddProperty(newPropertyValue(PropUID, "retreat@example.com"))
+	conf.AddProperty(newPropertyValue(PropDatetimeStamp, "20210301T080000Z"))
+	conf.AddProperty(newPropertyValue(PropDatetimeStart, "20210201T000000Z"))
+	conf.AddProperty(newPropertyValue(PropDatetimeEnd, "20210401T000000Z"))
+	if err := idx.Put(conf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := query(), "retreat@example.com 20210201T000000Z,standup@example.com 20210315T090000Z"; got != want {
+		t.Errorf("Query() after Put() of a new UID = %s, want %s", got, want)
+	}
+
+	if !idx.RemoveUID("standup@example.com") || idx.RemoveUID("standup@example.com") {
+		t.Error("RemoveUID() did not report the removal")
+	}
+	if got, want := query(), "retreat@example.com 20210201T000000Z"; got != want {
+		t.Errorf("Query() after RemoveUID() = %s, want %s", got, want)
+	}
+
+	bad := &ComponentObj{NameObj: CompEvent}
+	bad.AddProperty(newPropertyValue(PropSummary, "No UID"))
+	if err := idx.Put(bad); err == nil {
+		t.Error("Put() without UID err = nil")
+	}
+	if idx.Len() != 2 {
+		t.Errorf("Len() = %d", idx.Len())
+	}
+}
+
+func TestCalendarIndexSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.ics")
+
+	idx := newTestIndex(t)
+	if err := idx.SaveSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := NewCalendarIndex(idx.From, idx.To)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.LoadSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := idx.Query(idx.From, idx.To)
+	b, _ := restored.Query(idx.From, idx.To)
+	if indexStarts(a) != indexStarts(b) || strings.Join(idx.UIDs(), ",") != strings.Join(restored.UIDs(), ",") {
+		t.Errorf("restored index = %s, want %s", indexStarts(b), indexStarts(a))
+	}
+	if err := restored.LoadSnapshot(filepath.Join(dir, "missing.ics")); err == nil || restored.Len() != 2 {
+		t.Errorf("LoadSnapshot() of a missing file = %v, Len() = %d", err, restored.Len())
+	}
+}
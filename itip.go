@@ -0,0 +1,375 @@
+package go_ical
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// presence is how often a property or component may appear in an iTIP message,
+// written in the RFC 5546 tables as "1", "1+", "0 or 1", "0+" and "0"
+type presence int
+
+const (
+	presenceOne presence = iota
+	presenceOneOrMore
+	presenceOptional
+	presenceAny
+	presenceNever
+)
+
+// methodRule is one restriction table of RFC 5546 for a method and a component
+type methodRule struct {
+	section string
+	//number of these components allowed in the message
+	count presence
+	props map[string]presence
+	//properties which are not listed are forbidden
+	strict bool
+	//values STATUS may take,nil means any value of RFC 5545
+	status []string
+	alarms presence
+}
+
+/*
+parsePresence builds the property table of a methodRule from a list like
+"DTSTAMP:1 ATTENDEE:+ CLASS:? COMMENT:* REQUEST-STATUS:0"
+which follows the notation of the RFC 5546 tables.
+*/
+func parsePresence(spec string) map[string]presence {
+	m := map[string]presence{}
+	for _, f := range strings.Fields(spec) {
+		i := strings.LastIndexByte(f, ':')
+		var pr presence
+		switch f[i+1:] {
+		case "1":
+			pr = presenceOne
+		case "+":
+			pr = presenceOneOrMore
+		case "?":
+			pr = presenceOptional
+		case "*":
+			pr = presenceAny
+		case "0":
+			pr = presenceNever
+		default:
+			panic("ical: invalid presence " + f)
+		}
+		m[f[:i]] = pr
+	}
+	return m
+}
+
+// properties which are "0 or 1" or "0+" in most VEVENT tables
+const eventDescriptive = "ATTACH:* CATEGORIES:* CLASS:? COMMENT:* CONTACT:* CREATED:? DESCRIPTION:? DTEND:? DURATION:? " +
+	"GEO:? LAST-MODIFIED:? LOCATION:? PRIORITY:? RELATED-TO:* RESOURCES:? TRANSP:? URL:? "
+
+const todoDescriptive = "ATTACH:* CATEGORIES:* CLASS:? COMMENT:* CONTACT:* CREATED:? DESCRIPTION:? DUE:? DURATION:? " +
+	"GEO:? LAST-MODIFIED:? LOCATION:? PERCENT-COMPLETE:? RELATED-TO:* RESOURCES:? URL:? "
+
+// methodRules[METHOD][component] is the restriction table of RFC 5546 3.2 - 3.5
+var methodRules = map[string]map[string]methodRule{
+	MethodPublish: {
+		CompEvent: {section: "3.2.1", count: presenceOneOrMore, alarms: presenceAny,
+			status: []string{StatusTentative, StatusConfirmed, StatusCancelled},
+			props: parsePresence(eventDescriptive + "DTSTAMP:1 DTSTART:1 ORGANIZER:1 SUMMARY:1 UID:1 " +
+				"RECURRENCE-ID:? SEQUENCE:? EXDATE:* RDATE:* RRULE:? STATUS:? ATTENDEE:0 REQUEST-STATUS:0")},
+		CompFreebusy: {section: "3.3.1", count: presenceOne, alarms: presenceNever,
+			props: parsePresence("DTSTAMP:1 DTSTART:1 DTEND:1 FREEBUSY:+ ORGANIZER:1 UID:? COMMENT:* CONTACT:? URL:? " +
+				"ATTENDEE:0 DURATION:0 REQUEST-STATUS:0")},
+		CompTodo: {section: "3.4.1", count: presenceOneOrMore, alarms: presenceAny,
+			status: []string{StatusCompleted, StatusNeedsAction, StatusInProcess, StatusCancelled},
+			props: parsePresence(todoDescriptive + "DTSTAMP:1 DTSTART:1 ORGANIZER:1 PRIORITY:1 SUMMARY:1 UID:1 " +
+				"RECURRENCE-ID:? SEQUENCE:? COMPLETED:? EXDATE:* RDATE:* RRULE:? STATUS:? ATTENDEE:0 REQUEST-STATUS:0")},
+		CompJournal: {section: "3.5.1", count: presenceOneOrMore, alarms: presenceNever,
+			status: []string{StatusDraft, StatusFinal, StatusCancelled},
+			props: parsePresence("DESCRIPTION:1 DTSTAMP:1 DTSTART:1 ORGANIZER:1 UID:1 ATTACH:* CATEGORIES:* CLASS:? " +
+				"COMMENT:* CONTACT:* CREATED:? EXDATE:* LAST-MODIFIED:? RDATE:* RECURRENCE-ID:? RELATED-TO:* RRULE:? " +
+				"SEQUENCE:? STATUS:? SUMMARY:? URL:? ATTENDEE:0 REQUEST-STATUS:0")},
+	},
+	MethodRequest: {
+		CompEvent: {section: "3.2.2", count: presenceOneOrMore, alarms: presenceAny,
+			status: []string{StatusTentative, StatusConfirmed},
+			props: parsePresence(eventDescriptive + "ATTENDEE:+ DTSTAMP:1 DTSTART:1 ORGANIZER:1 SUMMARY:1 UID:1 " +
+				"SEQUENCE:? EXDATE:* RDATE:* RECURRENCE-ID:? REQUEST-STATUS:* RRULE:? STATUS:?")},
+		CompFreebusy: {section: "3.3.2", count: presenceOne, alarms: presenceNever,
+			props: parsePresence("ATTENDEE:+ DTEND:1 DTSTAMP:1 DTSTART:1 ORGANIZER:1 UID:1 COMMENT:0 CONTACT:0 " +
+				"FREEBUSY:0 DURATION:0 REQUEST-STATUS:0 URL:0")},
+		CompTodo: {section: "3.4.2", count: presenceOneOrMore, alarms: presenceAny,
+			status: []string{StatusNeedsAction, StatusInProcess, StatusCompleted},
+			props: parsePresence(todoDescriptive + "ATTENDEE:+ DTSTAMP:1 DTSTART:1 ORGANIZER:1 PRIORITY:1 SUMMARY:1 UID:1 " +
+				"SEQUENCE:? COMPLETED:? EXDATE:* RDATE:* RECURRENCE-ID:? REQUEST-STATUS:* RRULE:? STATUS:?")},
+	},
+	MethodReply: {
+		CompEvent: {section: "3.2.3", count: presenceOneOrMore, alarms: presenceNever,
+			props: parsePresence(eventDescriptive + "ATTENDEE:1 DTSTAMP:1 ORGANIZER:1 UID:1 RECURRENCE-ID:? SEQUENCE:? " +
+				"DTSTART:? EXDATE:* RDATE:* REQUEST-STATUS:* RRULE:? STATUS:? SUMMARY:?")},
+		CompFreebusy: {section: "3.3.3", count: presenceOne, alarms: presenceNever,
+			props: parsePresence("ATTENDEE:1 DTSTAMP:1 DTEND:1 DTSTART:1 FREEBUSY:* ORGANIZER:1 UID:1 COMMENT:* " +
+				"CONTACT:? REQUEST-STATUS:* URL:? DURATION:0 SEQUENCE:0")},
+		CompTodo: {section: "3.4.3", count: presenceOneOrMore, alarms: presenceNever,
+			props: parsePresence(todoDescriptive + "ATTENDEE:+ DTSTAMP:1 ORGANIZER:1 REQUEST-STATUS:+ UID:1 " +
+				"COMPLETED:? DTSTART:? EXDATE:* PRIORITY:? RDATE:* RECURRENCE-ID:? RRULE:? SEQUENCE:? STATUS:? SUMMARY:?")},
+	},
+	MethodAdd: {
+		CompEvent: {section: "3.2.4", count: presenceOne, alarms: presenceAny,
+			status: []string{StatusTentative, StatusConfirmed},
+			props: parsePresence(eventDescriptive + "DTSTAMP:1 DTSTART:1 ORGANIZER:1 SEQUENCE:1 SUMMARY:1 UID:1 " +
+				"ATTENDEE:* STATUS:? EXDATE:0 RDATE:0 RECURRENCE-ID:0 REQUEST-STATUS:0 RRULE:0")},
+		CompTodo: {section: "3.4.4", count: presenceOne, alarms: presenceAny,
+			props: parsePresence(todoDescriptive + "DTSTAMP:1 ORGANIZER:1 PRIORITY:1 SEQUENCE:1 SUMMARY:1 UID:1 " +
+				"ATTENDEE:* DTSTART:? STATUS:? EXDATE:0 RDATE:0 RECURRENCE-ID:0 REQUEST-STATUS:0 RRULE:0")},
+		CompJournal: {section: "3.5.2", count: presenceOne, alarms: presenceNever,
+			props: parsePresence("DESCRIPTION:1 DTSTAMP:1 DTSTART:1 ORGANIZER:1 SEQUENCE:1 UID:1 ATTACH:* CATEGORIES:* " +
+				"CLASS:? COMMENT:* CONTACT:* CREATED:? LAST-MODIFIED:? RELATED-TO:* STATUS:? SUMMARY:? URL:? " +
+				"ATTENDEE:0 EXDATE:0 RDATE:0 RECURRENCE-ID:0 REQUEST-STATUS:0 RRULE:0")},
+	},
+	MethodCancel: {
+		CompEvent: {section: "3.2.5", count: presenceOneOrMore, alarms: presenceNever,
+			status: []string{StatusCancelled},
+			props: parsePresence(eventDescriptive + "ATTENDEE:* DTSTAMP:1 ORGANIZER:1 SEQUENCE:1 UID:1 RECURRENCE-ID:? " +
+				"STATUS:? DTSTART:? EXDATE:* RDATE:* RRULE:? SUMMARY:? REQUEST-STATUS:0")},
+		CompTodo: {section: "3.4.5", count: presenceOneOrMore, alarms: presenceNever,
+			status: []string{StatusCancelled},
+			props: parsePresence(todoDescriptive + "ATTENDEE:* DTSTAMP:1 ORGANIZER:1 SEQUENCE:1 UID:1 RECURRENCE-ID:? " +
+				"STATUS:? DTSTART:? EXDATE:* PRIORITY:? RDATE:* RRULE:? SUMMARY:? REQUEST-STATUS:0")},
+		CompJournal: {section: "3.5.3", count: presenceOneOrMore, alarms: presenceNever,
+			status: []string{StatusCancelled},
+			props: parsePresence("ATTENDEE:* DTSTAMP:1 ORGANIZER:1 SEQUENCE:1 UID:1 ATTACH:* CATEGORIES:* CLASS:? " +
+				"COMMENT:* CONTACT:* CREATED:? DESCRIPTION:? DTSTART:? EXDATE:* LAST-MODIFIED:? RDATE:* " +
+				"RECURRENCE-ID:? RELATED-TO:* RRULE:? STATUS:? SUMMARY:? URL:? REQUEST-STATUS:0")},
+	},
+	MethodRefresh: {
+		CompEvent: {section: "3.2.6", count: presenceOne, alarms: presenceNever, strict: true,
+			props: parsePresence("ATTENDEE:1 DTSTAMP:1 ORGANIZER:1 UID:1 COMMENT:? RECURRENCE-ID:?")},
+		CompTodo: {section: "3.4.6", count: presenceOne, alarms: presenceNever, strict: true,
+			props: parsePresence("ATTENDEE:1 DTSTAMP:1 ORGANIZER:1 UID:1 RECURRENCE-ID:?")},
+	},
+	MethodCounter: {
+		CompEvent: {section: "3.2.7", count: presenceOne, alarms: presenceAny,
+			status: []string{StatusTentative, StatusConfirmed},
+			props: parsePresence(eventDescriptive + "DTSTAMP:1 DTSTART:1 ORGANIZER:1 SUMMARY:1 UID:1 ATTENDEE:* " +
+				"RECURRENCE-ID:? SEQUENCE:? EXDATE:* RDATE:* REQUEST-STATUS:* RRULE:? STATUS:?")},
+		CompTodo: {section: "3.4.7", count: presenceOne, alarms: presenceAny,
+			props: parsePresence(todoDescriptive + "ATTENDEE:+ DTSTAMP:1 ORGANIZER:1 PRIORITY:1 SUMMARY:1 UID:1 " +
+				"COMPLETED:? DTSTART:? EXDATE:* RDATE:* RECURRENCE-ID:? REQUEST-STATUS:* RRULE:? SEQUENCE:? STATUS:?")},
+	},
+	MethodDeclineCounter: {
+		CompEvent: {section: "3.2.8", count: presenceOne, alarms: presenceNever, strict: true,
+			props: parsePresence("ATTENDEE:* COMMENT:? DTSTAMP:1 ORGANIZER:1 RECURRENCE-ID:? REQUEST-STATUS:* SEQUENCE:? UID:1")},
+		CompTodo: {section: "3.4.8", count: presenceOne, alarms: presenceNever, strict: true,
+			props: parsePresence("ATTENDEE:+ COMMENT:? DTSTAMP:1 ORGANIZER:1 RECURRENCE-ID:? REQUEST-STATUS:* SEQUENCE:1 UID:1")},
+	},
+}
+
+// allowed reports whether a property may appear in a component sent with method
+func (r methodRule) allowed(prop string) bool {
+	if strings.HasPrefix(prop, "X-") {
+		return true
+	}
+	pr, listed := r.props[prop]
+	if !listed {
+		return !r.strict
+	}
+	return pr != presenceNever
+}
+
+/*
+ValidateMethod checks a calendar against the restriction tables of RFC 5546
+for the method given in its METHOD property (PUBLISH,REQUEST,REPLY,ADD,
+CANCEL,REFRESH,COUNTER or DECLINECOUNTER).
+
+It reports the properties each component requires or forbids for that
+method,the number of components and the STATUS values the method allows,
+in the same Finding form as Validate. The general RFC 5545 rules are not
+repeated,callers wanting both run Validate and ValidateMethod.
+*/
+func ValidateMethod(cal Calendar) []Finding {
+	v := &validator{}
+	path := cal.Name()
+	method := ""
+	for _, p := range cal.Properties() {
+		if p.Name == PropMethod {
+			method = strings.ToUpper(p.Value)
+		}
+	}
+	if method == "" {
+		v.add(SeverityError, path, "RFC 5546 3.1", "an iTIP message MUST have METHOD")
+		return v.findings
+	}
+	rules, ok := methodRules[method]
+	if !ok {
+		v.add(SeverityError, path+"/"+PropMethod, "RFC 5546 1.4", "unknown METHOD %q", method)
+		return v.findings
+	}
+
+	//an iTIP message carries one type of calendar component,besides VTIMEZONE
+	compType := ""
+	counts := map[string]int{}
+	uids := map[string]bool{}
+	for _, sub := range cal.SubComponents() {
+		name := sub.Name()
+		if name == CompTimezone || strings.HasPrefix(name, "X-") {
+			continue
+		}
+		counts[name]++
+		if compType == "" {
+			compType = name
+		} else if compType != name {
+			v.add(SeverityError, path, "RFC 5546 3.1", "an iTIP message MUST NOT mix %s and %s", compType, name)
+		}
+		for _, p := range sub.Properties() {
+			if p.Name == PropUID {
+				uids[p.Value] = true
+			}
+		}
+	}
+	if compType == "" {
+		v.add(SeverityError, path, "RFC 5546 3.1", "%s message has no calendar component", method)
+		return v.findings
+	}
+	rule, ok := rules[compType]
+	if !ok {
+		v.add(SeverityError, path, "RFC 5546 3.1", "%s can not be used with %s", method, compType)
+		return v.findings
+	}
+	section := "RFC 5546 " + rule.section
+	if n := counts[compType]; !presenceOK(rule.count, n) {
+		v.add(SeverityError, path, section, "%s MUST contain %s %s,but got %d", method, presenceText(rule.count), compType, n)
+	}
+	if method != MethodPublish && len(uids) > 1 {
+		v.add(SeverityError, path, section, "all components of a %s message MUST have the same UID", method)
+	}
+
+	seen := 0
+	for _, sub := range cal.SubComponents() {
+		if sub.Name() != compType {
+			continue
+		}
+		cpath := path + "/" + compType + "[" + strconv.Itoa(seen) + "]"
+		seen++
+		v.methodComponent(method, rule, sub, cpath, section)
+	}
+	return v.findings
+}
+
+func (v *validator) methodComponent(method string, rule methodRule, com Component, path, section string) {
+	counts := map[string]int{}
+	for _, p := range com.Properties() {
+		counts[p.Name]++
+	}
+	names := make([]string, 0, len(rule.props))
+	for name := range rule.props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pr := rule.props[name]
+		if n := counts[name]; !presenceOK(pr, n) {
+			if pr == presenceNever {
+				v.add(SeverityError, path+"/"+name, section, "%s MUST NOT be present in a %s %s", name, method, com.Name())
+			} else {
+				v.add(SeverityError, path, section, "a %s %s MUST have %s %s,but got %d", method, com.Name(), presenceText(pr), name, n)
+			}
+		}
+	}
+	for _, p := range com.Properties() {
+		if _, listed := rule.props[p.Name]; !listed && !rule.allowed(p.Name) {
+			v.add(SeverityError, path+"/"+p.Name, section, "%s MUST NOT be present in a %s %s", p.Name, method, com.Name())
+		}
+		if p.Name == PropStatus && rule.status != nil && !contains(rule.status, p.Value) {
+			v.add(SeverityError, path+"/"+p.Name, section, "STATUS %q is not allowed in a %s %s", p.Value, method, com.Name())
+		}
+		if p.Name == PropSequenceNumber && method == MethodAdd {
+			if n, err := strconv.Atoi(p.Value); err == nil && n <= 0 {
+				v.add(SeverityError, path+"/"+p.Name, section, "SEQUENCE of an ADD MUST be greater than 0")
+			}
+		}
+	}
+	alarms := 0
+	for _, sub := range com.SubComponents() {
+		if sub.Name() == CompAlarm {
+			alarms++
+		}
+	}
+	if !presenceOK(rule.alarms, alarms) {
+		v.add(SeverityError, path, section, "VALARM MUST NOT be present in a %s %s", method, com.Name())
+	}
+}
+
+// stripForMethod removes the properties and alarms a component MUST NOT carry
+// when it is sent with method
+func stripForMethod(method string, com *ComponentObj) {
+	rule, ok := methodRules[method][com.Name()]
+	if !ok {
+		return
+	}
+	ps := com.PropertiesObj[:0]
+	for _, p := range com.PropertiesObj {
+		if rule.allowed(p.Name) {
+			ps = append(ps, p)
+		}
+	}
+	com.PropertiesObj = ps
+	if rule.alarms == presenceNever {
+		subs := com.SubComponentsObj[:0]
+		for _, sub := range com.SubComponentsObj {
+			if sub.Name() != CompAlarm {
+				subs = append(subs, sub)
+			}
+		}
+		com.SubComponentsObj = subs
+	}
+}
+
+// methodOf returns the upper-cased METHOD of cal,or "" when it has none
+func methodOf(cal Calendar) string {
+	if p := cal.GetProperty(PropMethod); p != nil {
+		return strings.ToUpper(p.Value)
+	}
+	return ""
+}
+
+// newMethodCalendar wraps the components in a VCALENDAR with METHOD set
+func newMethodCalendar(method string, coms ...Component) *Calendar {
+	cal := NewCalendar()
+	cal.SetMethod(method)
+	cal.AddComponent(coms...)
+	return cal
+}
+
+// checkMessage returns a *ValidationError when cal breaks the table of its method
+func checkMessage(cal *Calendar) (*Calendar, error) {
+	if findings := ValidateMethod(*cal); HasErrors(findings) {
+		return nil, &ValidationError{Findings: findings}
+	}
+	return cal, nil
+}
+
+func presenceOK(pr presence, n int) bool {
+	switch pr {
+	case presenceOne:
+		return n == 1
+	case presenceOneOrMore:
+		return n >= 1
+	case presenceOptional:
+		return n <= 1
+	case presenceNever:
+		return n == 0
+	}
+	return true
+}
+
+func presenceText(pr presence) string {
+	switch pr {
+	case presenceOne:
+		return "exactly one"
+	case presenceOneOrMore:
+		return "at least one"
+	case presenceOptional:
+		return "at most one"
+	case presenceNever:
+		return "no"
+	}
+	return "any number of"
+}
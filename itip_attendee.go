@@ -0,0 +1,233 @@
+package go_ical
+
+import (
+	"fmt"
+	"time"
+)
+
+// Builders for the iTIP messages an attendee sends,RFC 5546 3.2.
+// request is the REQUEST the attendee received and attendee the calendar
+// address of the user,as listed in its ATTENDEE properties. The messages carry
+// only the properties the table of their method permits,request itself is
+// never changed.
+
+// BuildReply builds a REPLY answering request for the whole series with
+// partstat,one of the Partstat constants except PartstatDelegated,see
+// BuildDelegate. comment is sent as COMMENT when it is not empty.
+func BuildReply(request Calendar, attendee, partstat, comment string, now time.Time) (*Calendar, error) {
+	com, err := requestTarget(request, attendee, nil)
+	if err != nil {
+		return nil, err
+	}
+	return buildReply(com, attendee, partstat, comment, now)
+}
+
+// BuildReplyInstance builds a REPLY answering with partstat for the single
+// occurrence of the event in request which starts at recurrenceID.
+func BuildReplyInstance(request Calendar, attendee, partstat string, recurrenceID time.Time, comment string, now time.Time) (*Calendar, error) {
+	com, err := requestTarget(request, attendee, &recurrenceID)
+	if err != nil {
+		return nil, err
+	}
+	return buildReply(com, attendee, partstat, comment, now)
+}
+
+func buildReply(target *ComponentObj, attendee, partstat, comment string, now time.Time) (*Calendar, error) {
+	switch partstat {
+	case PartstatNeedsAction, PartstatAccepted, PartstatDeclined, PartstatTentative:
+	case PartstatDelegated:
+		return nil, fmt.Errorf("ical:a delegation must name the delegate,use BuildDelegate")
+	default:
+		return nil, fmt.Errorf("ical:PARTSTAT %q is not allowed for a VEVENT", partstat)
+	}
+	return replyMessage(target, attendee, partstat, nil, comment, now)
+}
+
+// replyMessage builds the REPLY of attendee to target
+func replyMessage(target *ComponentObj, attendee, partstat string, delegates []string, comment string, now time.Time) (*Calendar, error) {
+	com := &ComponentObj{NameObj: CompEvent}
+	copyProperties(com, target, PropUID, PropOrganizer, PropRecurrenceId, PropSequenceNumber)
+	p := findAttendee(target, attendee).Clone()
+	p.Params.Set(Parampartstat, partstat)
+	p.Params.Del(Paramrsvp)
+	if len(delegates) > 0 {
+		p.Params[Paramdelto] = append([]string(nil), delegates...)
+	}
+	com.AddProperty(p)
+	if comment != "" {
+		com.AddProperty(newPropertyValue(PropComment, ToText(comment)))
+	}
+	setStamp(com, now)
+	return checkMessage(newMethodCalendar(MethodReply, com))
+}
+
+/*
+BuildDelegate builds the messages of attendee delegating the event in request
+to delegate,RFC 5546 3.2.2.3: reply is the REPLY to the organizer with
+PARTSTAT=DELEGATED and DELEGATED-TO,forward the REQUEST for delegate to answer,
+listing delegate with DELEGATED-FROM and RSVP=TRUE. recurrenceID,when not
+nil,limits the delegation to that occurrence.
+*/
+func BuildDelegate(request Calendar, attendee, delegate string, recurrenceID *time.Time, comment string, now time.Time) (reply, forward *Calendar, err error) {
+	if delegate == "" || sameAddress(delegate, attendee) {
+		return nil, nil, fmt.Errorf("ical:%s can not delegate to %q", attendee, delegate)
+	}
+	target, err := requestTarget(request, attendee, recurrenceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	reply, err = replyMessage(target, attendee, PartstatDelegated, []string{delegate}, comment, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var coms []*ComponentObj
+	if recurrenceID != nil {
+		coms = []*ComponentObj{target.Clone()}
+	} else {
+		for _, sub := range request.SubComponents() {
+			if sub.Name() == CompEvent && findAttendee(sub.obj(), attendee) != nil {
+				coms = append(coms, sub.obj().Clone())
+			}
+		}
+	}
+	forward = newMethodCalendar(MethodRequest)
+	for _, com := range coms {
+		a := findAttendee(com, attendee)
+		a.Params.Set(Parampartstat, PartstatDelegated)
+		a.Params.Del(Paramrsvp)
+		a.Params[Paramdelto] = []string{delegate}
+		if findAttendee(com, delegate) == nil {
+			com.AddProperty(Property{Name: PropAttendee, Params: Parameters{
+				Paramdelfrom:  {attendee},
+				Paramrsvp:     {"TRUE"},
+				Parampartstat: {PartstatNeedsAction},
+			}, Value: delegate})
+		}
+		forward.AddComponent(com)
+	}
+	if forward, err = checkMessage(forward); err != nil {
+		return nil, nil, err
+	}
+	return reply, forward, nil
+}
+
+// BuildCounter builds a COUNTER proposing to move the event in request to start
+// and end. A zero end keeps the length of the event. recurrenceID,when not
+// nil,limits the proposal to that occurrence.
+func BuildCounter(request Calendar, attendee string, recurrenceID *time.Time, start, end time.Time, comment string, now time.Time) (*Calendar, error) {
+	target, err := requestTarget(request, attendee, recurrenceID)
+	if err != nil {
+		return nil, err
+	}
+	com := target.Clone()
+	if recurrenceID != nil {
+		com.DelProperty(PropRecurrenceRule)
+		com.DelProperty(PropRecurrenceDatetime)
+		com.DelProperty(PropExceptionDatetime)
+	}
+	ds := com.GetProperty(PropDatetimeStart)
+	if ds == nil {
+		return nil, fmt.Errorf("ical:can not counter an event without DTSTART")
+	}
+	oldStart, err := ds.GetToTime()
+	if err != nil {
+		return nil, err
+	}
+	if end.IsZero() {
+		if de := com.GetProperty(PropDatetimeEnd); de != nil {
+			t, err := de.GetToTime()
+			if err != nil {
+				return nil, err
+			}
+			end = start.Add(t.Sub(oldStart))
+		}
+	}
+	if !end.IsZero() {
+		de := NewProperty(PropDatetimeEnd)
+		if err := de.SetFromTimeLike(ds, end); err != nil {
+			return nil, err
+		}
+		if err := ds.SetFromTimeLike(ds, start); err != nil {
+			return nil, err
+		}
+		com.DelProperty(PropDuration)
+		com.PutProperty(*de)
+	} else if err := ds.SetFromTimeLike(ds, start); err != nil {
+		return nil, err
+	}
+	if comment != "" {
+		com.AddProperty(newPropertyValue(PropComment, ToText(comment)))
+	}
+	setStamp(com, now)
+	stripForMethod(MethodCounter, com)
+	return checkMessage(newMethodCalendar(MethodCounter, com))
+}
+
+// BuildRefresh builds a REFRESH asking the organizer to send the latest
+// version of the event in request,or of one occurrence when recurrenceID is
+// not nil.
+func BuildRefresh(request Calendar, attendee string, recurrenceID *time.Time, now time.Time) (*Calendar, error) {
+	target, err := requestTarget(request, attendee, recurrenceID)
+	if err != nil {
+		return nil, err
+	}
+	com := &ComponentObj{NameObj: CompEvent}
+	copyProperties(com, target, PropUID, PropOrganizer, PropRecurrenceId)
+	p := findAttendee(target, attendee).Clone()
+	p.Params = Parameters{}
+	com.AddProperty(p)
+	setStamp(com, now)
+	return checkMessage(newMethodCalendar(MethodRefresh, com))
+}
+
+/*
+requestTarget returns the VEVENT of request an attendee answers. With a nil
+recurrenceID it is the master of the series,or the only instance the REQUEST
+holds. Otherwise it is the override for recurrenceID,or a copy of the master
+with RECURRENCE-ID set when the REQUEST has no such override.
+attendee must be an ATTENDEE of the returned component.
+*/
+func requestTarget(request Calendar, attendee string, recurrenceID *time.Time) (*ComponentObj, error) {
+	if got := methodOf(request); got != MethodRequest {
+		return nil, fmt.Errorf("ical:expect a %s message,but got METHOD %q", MethodRequest, got)
+	}
+	uid := ""
+	for _, sub := range request.SubComponents() {
+		if p := sub.obj().GetProperty(PropUID); sub.Name() == CompEvent && p != nil {
+			uid = p.Value
+			break
+		}
+	}
+	master, overrides := findEvent(&request.ComponentObj, uid)
+
+	var target *ComponentObj
+	switch {
+	case recurrenceID == nil && master != nil:
+		target = master
+	case recurrenceID == nil && len(overrides) == 1:
+		target = overrides[0]
+	case recurrenceID == nil:
+		return nil, fmt.Errorf("ical:REQUEST %q has no VEVENT for the whole series", uid)
+	default:
+		for _, o := range overrides {
+			if t, err := o.GetProperty(PropRecurrenceId).GetToTime(); err == nil && t.Equal(*recurrenceID) {
+				target = o
+			}
+		}
+		if target == nil && master == nil {
+			return nil, fmt.Errorf("ical:REQUEST %q has no VEVENT for the occurrence %s", uid, recurrenceID.Format(DatetimeFormat2))
+		}
+		if target == nil {
+			o, err := newOverride(master, *recurrenceID)
+			if err != nil {
+				return nil, err
+			}
+			target = o
+		}
+	}
+	if findAttendee(target, attendee) == nil {
+		return nil, fmt.Errorf("ical:%s is not an attendee of %q", attendee, uid)
+	}
+	return target, nil
+}
@@ -0,0 +1,154 @@
+package go_ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func prepareRequest(t *testing.T) Calendar {
+	return decodeString(t, strings.Replace(storedCalendarStr, "VERSION:2.0\n", "VERSION:2.0\nMETHOD:REQUEST\n", 1))
+}
+
+func TestBuildReply(t *testing.T) {
+	request := prepareRequest(t)
+	cal, err := BuildReply(request, "mailto:b@example.com", PartstatAccepted, "See you", itipNow)
+	if err != nil {
+		t.Fatalf("BuildReply() err: %v", err)
+	}
+	com := messageEvent(t, cal, MethodReply)
+	if got := propValue(com, PropSequenceNumber); got != "2" {
+		t.Errorf("SEQUENCE = %q, want 2", got)
+	}
+	if got := propValue(com, PropSummary); got != "" {
+		t.Errorf("REPLY carries SUMMARY %q", got)
+	}
+	a := attendeeOf(t, com, "mailto:b@example.com")
+	if got := a.Params.Get(Parampartstat); got != PartstatAccepted {
+		t.Errorf("PARTSTAT = %q, want ACCEPTED", got)
+	}
+	if a.Params.Get(Paramrsvp) != "" {
+		t.Errorf("REPLY keeps RSVP")
+	}
+	if got := attendeeOf(t, request.SubComponents()[0].obj(), "mailto:b@example.com").Params.Get(Parampartstat); got != PartstatNeedsAction {
+		t.Errorf("BuildReply() changed the REQUEST")
+	}
+
+	//the organizer can apply the reply
+	stored := decodeString(t, storedCalendarStr)
+	if err := ApplyReply(&stored, *cal); err != nil {
+		t.Errorf("ApplyReply() err: %v", err)
+	}
+
+	if _, err := BuildReply(request, "mailto:c@example.com", PartstatAccepted, "", itipNow); err == nil {
+		t.Errorf("BuildReply() for someone not invited succeeded")
+	}
+	if _, err := BuildReply(request, "mailto:b@example.com", PartstatCompleted, "", itipNow); err == nil {
+		t.Errorf("BuildReply() with PARTSTAT=COMPLETED succeeded")
+	}
+}
+
+func TestBuildReplyInstance(t *testing.T) {
+	rid := time.Date(2020, 3, 16, 10, 0, 0, 0, time.UTC)
+	cal, err := BuildReplyInstance(prepareRequest(t), "mailto:b@example.com", PartstatDeclined, rid, "", itipNow)
+	if err != nil {
+		t.Fatalf("BuildReplyInstance() err: %v", err)
+	}
+	com := messageEvent(t, cal, MethodReply)
+	if got := propValue(com, PropRecurrenceId); got != "20200316T100000Z" {
+		t.Errorf("RECURRENCE-ID = %q, want 20200316T100000Z", got)
+	}
+}
+
+func TestBuildDelegate(t *testing.T) {
+	request := prepareRequest(t)
+	if _, err := BuildReply(request, "mailto:b@example.com", PartstatDelegated, "", itipNow); err == nil {
+		t.Errorf("BuildReply() with PARTSTAT=DELEGATED succeeded")
+	}
+	reply, forward, err := BuildDelegate(request, "mailto:b@example.com", "mailto:c@example.com", nil, "", itipNow)
+	if err != nil {
+		t.Fatalf("BuildDelegate() err: %v", err)
+	}
+	b := attendeeOf(t, messageEvent(t, reply, MethodReply), "mailto:b@example.com")
+	if b.Params.Get(Parampartstat) != PartstatDelegated || b.Params.Get(Paramdelto) != "mailto:c@example.com" {
+		t.Errorf("delegator in the REPLY = %v", b)
+	}
+	fwd := messageEvent(t, forward, MethodRequest)
+	if c := attendeeOf(t, fwd, "mailto:c@example.com"); c.Params.Get(Paramdelfrom) != "mailto:b@example.com" || c.Params.Get(Paramrsvp) != "TRUE" {
+		t.Errorf("delegate in the forwarded REQUEST = %v", c)
+	}
+	if request.SubComponents()[0].obj().GetProperties(PropAttendee)[1].Params.Get(Paramdelto) != "" {
+		t.Errorf("BuildDelegate() changed the REQUEST")
+	}
+
+	//the organizer records the delegation,then the delegate's answer
+	stored := decodeString(t, storedCalendarStr)
+	if err := ApplyReply(&stored, *reply); err != nil {
+		t.Fatalf("ApplyReply() of the delegation err: %v", err)
+	}
+	answer, err := BuildReply(*forward, "mailto:c@example.com", PartstatAccepted, "", itipNow)
+	if err != nil {
+		t.Fatalf("BuildReply() of the delegate err: %v", err)
+	}
+	if err := ApplyReply(&stored, *answer); err != nil {
+		t.Fatalf("ApplyReply() of the delegate err: %v", err)
+	}
+	if got := attendeeOf(t, stored.SubComponents()[0].obj(), "mailto:c@example.com").Params.Get(Parampartstat); got != PartstatAccepted {
+		t.Errorf("delegate PARTSTAT = %q, want ACCEPTED", got)
+	}
+
+	if _, _, err := BuildDelegate(request, "mailto:b@example.com", "", nil, "", itipNow); err == nil {
+		t.Errorf("BuildDelegate() without delegate succeeded")
+	}
+}
+
+func TestBuildCounter(t *testing.T) {
+	start := time.Date(2020, 3, 2, 14, 0, 0, 0, time.UTC)
+	cal, err := BuildCounter(prepareRequest(t), "mailto:b@example.com", nil, start, time.Time{}, "Afternoon?", itipNow)
+	if err != nil {
+		t.Fatalf("BuildCounter() err: %v", err)
+	}
+	com := messageEvent(t, cal, MethodCounter)
+	for name, want := range map[string]string{
+		PropDatetimeStart:  "20200302T140000Z",
+		PropDatetimeEnd:    "20200302T150000Z",
+		PropRecurrenceRule: "FREQ=WEEKLY;COUNT=10",
+		PropComment:        "Afternoon?",
+	} {
+		if got := propValue(com, name); got != want {
+			t.Errorf("COUNTER %s = %q, want %q", name, got, want)
+		}
+	}
+
+	rid := time.Date(2020, 3, 9, 10, 0, 0, 0, time.UTC)
+	cal, err = BuildCounter(prepareRequest(t), "mailto:b@example.com", &rid, rid.Add(time.Hour), rid.Add(3*time.Hour), "", itipNow)
+	if err != nil {
+		t.Fatalf("BuildCounter() of an instance err: %v", err)
+	}
+	com = messageEvent(t, cal, MethodCounter)
+	for name, want := range map[string]string{
+		PropRecurrenceId:   "20200309T100000Z",
+		PropDatetimeStart:  "20200309T110000Z",
+		PropDatetimeEnd:    "20200309T130000Z",
+		PropRecurrenceRule: "",
+	} {
+		if got := propValue(com, name); got != want {
+			t.Errorf("COUNTER %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildRefresh(t *testing.T) {
+	cal, err := BuildRefresh(prepareRequest(t), "mailto:b@example.com", nil, itipNow)
+	if err != nil {
+		t.Fatalf("BuildRefresh() err: %v", err)
+	}
+	com := messageEvent(t, cal, MethodRefresh)
+	if n := len(com.Properties()); n != 4 {
+		t.Errorf("REFRESH has %d properties, want UID, ORGANIZER, ATTENDEE and DTSTAMP", n)
+	}
+
+	if _, err := BuildRefresh(decodeString(t, storedCalendarStr), "mailto:b@example.com", nil, itipNow); err == nil {
+		t.Errorf("BuildRefresh() from a calendar without METHOD succeeded")
+	}
+}
@@ -0,0 +1,180 @@
+package go_ical
+
+import (
+	"fmt"
+	"time"
+)
+
+// Builders for the iTIP messages an organizer sends,RFC 5546 3.2.
+// ev is always the organizer's own copy of the event. Each builder returns a
+// new Calendar with METHOD set,ready for an Encoder,and never shares
+// properties with ev. The builders for CANCEL and ADD also record the change
+// in ev,bumping its SEQUENCE as RFC 5546 requires,so that the next message
+// built from ev carries the right SEQUENCE. ev is only changed when the
+// message is built without error.
+
+// BuildRequest builds a REQUEST inviting the attendees of ev,or sending them
+// an update. It never bumps SEQUENCE: the first invitation and updates which
+// are not significant keep it,so call ev.BumpSequence first when the update
+// is significant. Schedule does this for the changes RFC 6638 calls significant.
+func BuildRequest(ev *VEvent, now time.Time) (*Calendar, error) {
+	com := ev.Clone()
+	setStamp(com, now)
+	stripForMethod(MethodRequest, com)
+	return checkMessage(newMethodCalendar(MethodRequest, com))
+}
+
+// BuildCancel builds a CANCEL for the whole event. ev gets STATUS:CANCELLED
+// and a new SEQUENCE.
+func BuildCancel(ev *VEvent, now time.Time) (*Calendar, error) {
+	upd := &VEvent{*ev.Clone()}
+	upd.BumpSequence()
+	upd.PutProperty(newPropertyValue(PropStatus, StatusCancelled))
+	touch(&upd.ComponentObj, now)
+
+	com := upd.Clone()
+	setStamp(com, now)
+	stripForMethod(MethodCancel, com)
+	return commitMessage(ev, upd, newMethodCalendar(MethodCancel, com))
+}
+
+// BuildCancelInstance builds a CANCEL for the occurrence of ev which starts at
+// recurrenceID. The occurrence is excluded from ev with an EXDATE.
+func BuildCancelInstance(ev *VEvent, recurrenceID time.Time, now time.Time) (*Calendar, error) {
+	start := ev.GetProperty(PropDatetimeStart)
+	if start == nil {
+		return nil, fmt.Errorf("ical:can not cancel an instance of an event without DTSTART")
+	}
+	rid := NewProperty(PropRecurrenceId)
+	if err := rid.SetFromTimeLike(start, recurrenceID); err != nil {
+		return nil, err
+	}
+	exdate := NewProperty(PropExceptionDatetime)
+	if err := exdate.SetFromTimeLike(start, recurrenceID); err != nil {
+		return nil, err
+	}
+
+	upd := &VEvent{*ev.Clone()}
+	seq := upd.BumpSequence()
+	upd.AddProperty(*exdate)
+	touch(&upd.ComponentObj, now)
+
+	com := &ComponentObj{NameObj: CompEvent}
+	copyProperties(com, &upd.ComponentObj, PropUID, PropOrganizer, PropAttendee, PropSummary)
+	com.AddProperty(*rid)
+	com.AddProperty(newPropertyValue(PropSequenceNumber, fmt.Sprint(seq)))
+	com.AddProperty(newPropertyValue(PropStatus, StatusCancelled))
+	setStamp(com, now)
+	return commitMessage(ev, upd, newMethodCalendar(MethodCancel, com))
+}
+
+// BuildAdd builds an ADD for a new instance of the recurring event ev,which
+// starts at start and lasts as long as the other instances. ev gets an
+// RDATE for the new instance.
+func BuildAdd(ev *VEvent, start time.Time, now time.Time) (*Calendar, error) {
+	dtstart := ev.GetProperty(PropDatetimeStart)
+	if dtstart == nil {
+		return nil, fmt.Errorf("ical:can not add an instance to an event without DTSTART")
+	}
+	oldStart, err := dtstart.GetToTime()
+	if err != nil {
+		return nil, err
+	}
+	rdate := NewProperty(PropRecurrenceDatetime)
+	if err := rdate.SetFromTimeLike(dtstart, start); err != nil {
+		return nil, err
+	}
+
+	upd := &VEvent{*ev.Clone()}
+	upd.BumpSequence()
+	upd.AddProperty(*rdate)
+	touch(&upd.ComponentObj, now)
+
+	com := upd.Clone()
+	ds := com.GetProperty(PropDatetimeStart)
+	if err := ds.SetFromTimeLike(ds, start); err != nil {
+		return nil, err
+	}
+	if end := com.GetProperty(PropDatetimeEnd); end != nil {
+		t, err := end.GetToTime()
+		if err != nil {
+			return nil, err
+		}
+		if err := end.SetFromTimeLike(end, start.Add(t.Sub(oldStart))); err != nil {
+			return nil, err
+		}
+	}
+	setStamp(com, now)
+	stripForMethod(MethodAdd, com)
+	return commitMessage(ev, upd, newMethodCalendar(MethodAdd, com))
+}
+
+// commitMessage checks msg and only then stores upd,the changed copy of ev,
+// in ev
+func commitMessage(ev, upd *VEvent, msg *Calendar) (*Calendar, error) {
+	msg, err := checkMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	ev.ComponentObj = upd.ComponentObj
+	return msg, nil
+}
+
+// BuildDeclineCounter builds a DECLINECOUNTER answering counter,a COUNTER an
+// attendee sent for ev. comment is sent as COMMENT when it is not empty.
+func BuildDeclineCounter(ev *VEvent, counter Calendar, comment string, now time.Time) (*Calendar, error) {
+	proposal, err := findMethodComponent(counter, MethodCounter, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	com := &ComponentObj{NameObj: CompEvent}
+	copyProperties(com, &ev.ComponentObj, PropUID, PropOrganizer, PropSequenceNumber)
+	copyProperties(com, proposal, PropAttendee, PropRecurrenceId)
+	if comment != "" {
+		com.AddProperty(newPropertyValue(PropComment, ToText(comment)))
+	}
+	setStamp(com, now)
+	return checkMessage(newMethodCalendar(MethodDeclineCounter, com))
+}
+
+// findMethodComponent checks that msg was sent with method and returns its
+// component for the event ev
+func findMethodComponent(msg Calendar, method string, ev *VEvent) (*ComponentObj, error) {
+	if got := methodOf(msg); got != method {
+		return nil, fmt.Errorf("ical:expect a %s message,but got METHOD %q", method, got)
+	}
+	uid := ""
+	if p := ev.GetProperty(PropUID); p != nil {
+		uid = p.Value
+	}
+	for _, sub := range msg.SubComponents() {
+		if p := sub.obj().GetProperty(PropUID); sub.Name() == CompEvent && p != nil && p.Value == uid {
+			return sub.obj(), nil
+		}
+	}
+	return nil, fmt.Errorf("ical:%s message has no VEVENT with UID %q", method, uid)
+}
+
+// copyProperties appends to dst a copy of the properties of src with the given names
+func copyProperties(dst, src *ComponentObj, names ...string) {
+	for _, p := range src.PropertiesObj {
+		if contains(names, p.Name) {
+			dst.AddProperty(p.Clone())
+		}
+	}
+}
+
+// setStamp sets DTSTAMP,the time the message was created
+func setStamp(com *ComponentObj, now time.Time) {
+	p := NewProperty(PropDatetimeStamp)
+	p.setFromUTC(now)
+	com.PutProperty(*p)
+}
+
+// touch records a change of the organizer's copy in LAST-MODIFIED
+func touch(com *ComponentObj, now time.Time) {
+	p := NewProperty(PropLastModified)
+	p.setFromUTC(now)
+	com.PutProperty(*p)
+}
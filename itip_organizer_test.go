@@ -0,0 +1,188 @@
+package go_ical
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+var itipNow = time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func prepareOrganizerEvent() *VEvent {
+	ev := NewEvent()
+	ev.SetProperty(PropUID, "weekly@example.com")
+	ev.SetProperty(PropDatetimeStamp, "20200201T090000Z")
+	ev.SetProperty(PropOrganizer, "mailto:a@example.com")
+	ev.SetProperty(PropAttendee, "mailto:a@example.com", NewParamItem(Parampartstat, []string{PartstatAccepted}))
+	ev.SetProperty(PropAttendee, "mailto:b@example.com", NewParamRSVP(true))
+	ev.SetProperty(PropSummary, "Weekly sync")
+	ev.SetProperty(PropDatetimeStart, "20200302T100000Z")
+	ev.SetProperty(PropDatetimeEnd, "20200302T110000Z")
+	ev.SetProperty(PropRecurrenceRule, "FREQ=WEEKLY;COUNT=10")
+	alarm := &ComponentObj{NameObj: CompAlarm}
+	alarm.AddProperty(newPropertyValue(PropAction, ActionDisplay))
+	alarm.AddProperty(newPropertyValue(PropDescription, "Reminder"))
+	alarm.AddProperty(newPropertyValue(PropTrigger, "-PT15M"))
+	ev.SubComponentsObj = append(ev.SubComponentsObj, alarm)
+	return ev
+}
+
+func messageEvent(t *testing.T, cal *Calendar, method string) *ComponentObj {
+	t.Helper()
+	if p := cal.GetProperty(PropMethod); p == nil || p.Value != method {
+		t.Fatalf("METHOD = %v, want %s", p, method)
+	}
+	if findings := ValidateMethod(*cal); len(findings) > 0 {
+		t.Errorf("ValidateMethod() of the %s message: %v", method, findings)
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(cal); err != nil {
+		t.Errorf("Encode() of the %s message err: %v", method, err)
+	}
+	events := cal.GetEvents()
+	if len(events) != 1 {
+		t.Fatalf("%s message has %d VEVENT, want 1", method, len(events))
+	}
+	return events[0].obj()
+}
+
+func propValue(com *ComponentObj, name string) string {
+	if p := com.GetProperty(name); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+func TestBuildRequest(t *testing.T) {
+	ev := prepareOrganizerEvent()
+	cal, err := BuildRequest(ev, itipNow)
+	if err != nil {
+		t.Fatalf("BuildRequest() err: %v", err)
+	}
+	com := messageEvent(t, cal, MethodRequest)
+	if got := propValue(com, PropDatetimeStamp); got != "20200301T090000Z" {
+		t.Errorf("DTSTAMP = %q, want 20200301T090000Z", got)
+	}
+	if len(com.SubComponents()) != 1 {
+		t.Errorf("REQUEST lost its VALARM")
+	}
+	if got := propValue(&ev.ComponentObj, PropDatetimeStamp); got != "20200201T090000Z" {
+		t.Errorf("BuildRequest() changed the organizer's DTSTAMP to %q", got)
+	}
+
+	noAttendee := prepareOrganizerEvent()
+	noAttendee.DelProperty(PropAttendee)
+	if _, err := BuildRequest(noAttendee, itipNow); err == nil {
+		t.Errorf("BuildRequest() without ATTENDEE succeeded")
+	} else if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("BuildRequest() err = %T, want *ValidationError", err)
+	}
+}
+
+func TestBuildCancel(t *testing.T) {
+	ev := prepareOrganizerEvent()
+	cal, err := BuildCancel(ev, itipNow)
+	if err != nil {
+		t.Fatalf("BuildCancel() err: %v", err)
+	}
+	com := messageEvent(t, cal, MethodCancel)
+	if got := propValue(com, PropSequenceNumber); got != "1" {
+		t.Errorf("SEQUENCE = %q, want 1", got)
+	}
+	if got := propValue(com, PropStatus); got != StatusCancelled {
+		t.Errorf("STATUS = %q, want CANCELLED", got)
+	}
+	if len(com.SubComponents()) != 0 {
+		t.Errorf("CANCEL kept the VALARM")
+	}
+	if got := propValue(&ev.ComponentObj, PropSequenceNumber); got != "1" {
+		t.Errorf("organizer SEQUENCE = %q, want 1", got)
+	}
+
+	//a message which fails validation leaves the organizer's copy as it was
+	rid := time.Date(2020, 3, 9, 10, 0, 0, 0, time.UTC)
+	for method, build := range map[string]func(*VEvent) (*Calendar, error){
+		"BuildCancel":         func(ev *VEvent) (*Calendar, error) { return BuildCancel(ev, itipNow) },
+		"BuildCancelInstance": func(ev *VEvent) (*Calendar, error) { return BuildCancelInstance(ev, rid, itipNow) },
+		"BuildAdd":            func(ev *VEvent) (*Calendar, error) { return BuildAdd(ev, rid, itipNow) },
+	} {
+		noOrganizer := prepareOrganizerEvent()
+		noOrganizer.DelProperty(PropOrganizer)
+		want := noOrganizer.Clone()
+		if _, err := build(noOrganizer); err == nil {
+			t.Errorf("%s() without ORGANIZER succeeded", method)
+		}
+		if d := DiffComponents(want, &noOrganizer.ComponentObj); d != nil {
+			t.Errorf("%s() changed the organizer's copy on error:\n%s", method, d)
+		}
+	}
+}
+
+func TestBuildCancelInstance(t *testing.T) {
+	ev := prepareOrganizerEvent()
+	cal, err := BuildCancelInstance(ev, time.Date(2020, 3, 9, 10, 0, 0, 0, time.UTC), itipNow)
+	if err != nil {
+		t.Fatalf("BuildCancelInstance() err: %v", err)
+	}
+	com := messageEvent(t, cal, MethodCancel)
+	if got := propValue(com, PropRecurrenceId); got != "20200309T100000Z" {
+		t.Errorf("RECURRENCE-ID = %q, want 20200309T100000Z", got)
+	}
+	if got := propValue(com, PropRecurrenceRule); got != "" {
+		t.Errorf("CANCEL of one instance has RRULE %q", got)
+	}
+	if got := propValue(&ev.ComponentObj, PropExceptionDatetime); got != "20200309T100000Z" {
+		t.Errorf("organizer EXDATE = %q, want 20200309T100000Z", got)
+	}
+}
+
+func TestBuildAdd(t *testing.T) {
+	ev := prepareOrganizerEvent()
+	cal, err := BuildAdd(ev, time.Date(2020, 3, 5, 15, 0, 0, 0, time.UTC), itipNow)
+	if err != nil {
+		t.Fatalf("BuildAdd() err: %v", err)
+	}
+	com := messageEvent(t, cal, MethodAdd)
+	for name, want := range map[string]string{
+		PropDatetimeStart:  "20200305T150000Z",
+		PropDatetimeEnd:    "20200305T160000Z",
+		PropSequenceNumber: "1",
+		PropRecurrenceRule: "",
+	} {
+		if got := propValue(com, name); got != want {
+			t.Errorf("ADD %s = %q, want %q", name, got, want)
+		}
+	}
+	if got := propValue(&ev.ComponentObj, PropRecurrenceDatetime); got != "20200305T150000Z" {
+		t.Errorf("organizer RDATE = %q, want 20200305T150000Z", got)
+	}
+	if got := propValue(&ev.ComponentObj, PropDatetimeStart); got != "20200302T100000Z" {
+		t.Errorf("BuildAdd() changed the organizer's DTSTART to %q", got)
+	}
+}
+
+func TestBuildDeclineCounter(t *testing.T) {
+	ev := prepareOrganizerEvent()
+	counter := &ComponentObj{NameObj: CompEvent}
+	copyProperties(counter, &ev.ComponentObj, PropUID, PropOrganizer, PropSummary, PropDatetimeStamp)
+	counter.AddProperty(newPropertyValue(PropAttendee, "mailto:b@example.com"))
+	counter.AddProperty(newPropertyValue(PropDatetimeStart, "20200302T140000Z"))
+	msg := newMethodCalendar(MethodCounter, counter)
+
+	cal, err := BuildDeclineCounter(ev, *msg, "Sorry, the time can not change", itipNow)
+	if err != nil {
+		t.Fatalf("BuildDeclineCounter() err: %v", err)
+	}
+	com := messageEvent(t, cal, MethodDeclineCounter)
+	if got := propValue(com, PropAttendee); got != "mailto:b@example.com" {
+		t.Errorf("ATTENDEE = %q, want mailto:b@example.com", got)
+	}
+	if got := propValue(com, PropDatetimeStart); got != "" {
+		t.Errorf("DECLINECOUNTER has DTSTART %q", got)
+	}
+
+	msg.PutProperty(newPropertyValue(PropMethod, MethodReply))
+	if _, err := BuildDeclineCounter(ev, *msg, "", itipNow); err == nil {
+		t.Errorf("BuildDeclineCounter() of a REPLY succeeded")
+	}
+}
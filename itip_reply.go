@@ -0,0 +1,304 @@
+package go_ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaleReplyError is returned by ApplyReply for a REPLY which answers an older
+// version of the event than the one the organizer has stored.
+type StaleReplyError struct {
+	UID          string
+	RecurrenceID string
+	Attendee     string
+	//SEQUENCE of the reply and of the stored event
+	Sequence int
+	Current  int
+}
+
+func (e *StaleReplyError) Error() string {
+	target := e.UID
+	if e.RecurrenceID != "" {
+		target += " RECURRENCE-ID " + e.RecurrenceID
+	}
+	return fmt.Sprintf("ical: REPLY of %s for %s has SEQUENCE %d,but the event is at SEQUENCE %d",
+		e.Attendee, target, e.Sequence, e.Current)
+}
+
+// UnauthorizedReplyError is returned by ApplyReply for a REPLY from a calendar
+// user who is not an ATTENDEE of the stored event.
+type UnauthorizedReplyError struct {
+	UID          string
+	RecurrenceID string
+	Attendee     string
+}
+
+func (e *UnauthorizedReplyError) Error() string {
+	target := e.UID
+	if e.RecurrenceID != "" {
+		target += " RECURRENCE-ID " + e.RecurrenceID
+	}
+	return fmt.Sprintf("ical: %s replied to %s,but is not an attendee of it", e.Attendee, target)
+}
+
+/*
+ApplyReply records the answers of a REPLY,RFC 5546 3.2.3,in stored,the
+organizer's copy of the events.
+
+Every VEVENT of reply is matched with the stored event of the same UID. A reply
+without RECURRENCE-ID answers for the whole series: PARTSTAT of the attendee is
+changed in the master and in every override listing the attendee. A reply with
+RECURRENCE-ID answers for one occurrence: its override is changed,and when
+there is none yet,one is made from the master. The RECURRENCE-ID must then be
+an occurrence of the master's DTSTART,RRULE and RDATE which EXDATE does not
+exclude.
+
+An attendee may delegate,RFC 5546 3.2.2.3: a reply with DELEGATED-FROM from a
+delegate the organizer's copy lists in DELEGATED-TO of the delegator is
+accepted,and the delegate is added as ATTENDEE when it is not one yet.
+
+A reply with a SEQUENCE lower than the stored one gives a *StaleReplyError,a
+reply from someone who is neither an ATTENDEE nor a delegate an
+*UnauthorizedReplyError and a message breaking the REPLY table a
+*ValidationError. stored is not changed at all when an error is returned.
+*/
+func ApplyReply(stored *Calendar, reply Calendar) error {
+	if got := methodOf(reply); got != MethodReply {
+		return fmt.Errorf("ical:expect a %s message,but got METHOD %q", MethodReply, got)
+	}
+	if _, err := checkMessage(&reply); err != nil {
+		return err
+	}
+
+	//try on a copy first,so that one bad component leaves stored untouched
+	if err := applyReply(stored.Clone(), reply); err != nil {
+		return err
+	}
+	return applyReply(&stored.ComponentObj, reply)
+}
+
+func applyReply(cal *ComponentObj, reply Calendar) error {
+	for _, sub := range reply.SubComponents() {
+		if sub.Name() != CompEvent {
+			continue
+		}
+		if err := applyEventReply(cal, sub.obj()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyEventReply applies one VEVENT of a REPLY to cal
+func applyEventReply(cal *ComponentObj, answer *ComponentObj) error {
+	uid := answer.GetProperty(PropUID).Value
+	attendee := answer.GetProperty(PropAttendee)
+	master, overrides := findEvent(cal, uid)
+	if master == nil && len(overrides) == 0 {
+		return fmt.Errorf("ical:REPLY for unknown event %q", uid)
+	}
+
+	rid := answer.GetProperty(PropRecurrenceId)
+	if rid == nil {
+		if master == nil {
+			return fmt.Errorf("ical:REPLY for the series of %q,but only overrides are stored", uid)
+		}
+		if err := checkReply(master, answer, uid, ""); err != nil {
+			return err
+		}
+		setPartstat(master, attendee)
+		for _, o := range overrides {
+			if mayReply(o, attendee) {
+				setPartstat(o, attendee)
+			}
+		}
+		return nil
+	}
+
+	ridTime, err := rid.GetToTime()
+	if err != nil {
+		return err
+	}
+	for _, o := range overrides {
+		if t, err := o.GetProperty(PropRecurrenceId).GetToTime(); err == nil && t.Equal(ridTime) {
+			if err := checkReply(o, answer, uid, rid.Value); err != nil {
+				return err
+			}
+			setPartstat(o, attendee)
+			return nil
+		}
+	}
+	if master == nil {
+		return fmt.Errorf("ical:REPLY for %q RECURRENCE-ID %s,but the master is not stored", uid, rid.Value)
+	}
+	if err := checkReply(master, answer, uid, rid.Value); err != nil {
+		return err
+	}
+	if ok, err := isOccurrence(master, ridTime); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("ical:REPLY for %q RECURRENCE-ID %s,which is not an occurrence of the event", uid, rid.Value)
+	}
+	o, err := newOverride(master, ridTime)
+	if err != nil {
+		return err
+	}
+	setPartstat(o, attendee)
+	cal.SubComponentsObj = append(cal.SubComponentsObj, o)
+	return nil
+}
+
+// findEvent returns the master and the overrides of the event uid
+func findEvent(cal *ComponentObj, uid string) (master *ComponentObj, overrides []*ComponentObj) {
+	for _, sub := range cal.SubComponents() {
+		com := sub.obj()
+		if com.Name() != CompEvent {
+			continue
+		}
+		if p := com.GetProperty(PropUID); p == nil || p.Value != uid {
+			continue
+		}
+		if com.GetProperty(PropRecurrenceId) == nil {
+			master = com
+		} else {
+			overrides = append(overrides, com)
+		}
+	}
+	return master, overrides
+}
+
+// checkReply checks that answer may change the stored component target
+func checkReply(target, answer *ComponentObj, uid, rid string) error {
+	attendee := answer.GetProperty(PropAttendee)
+	if !mayReply(target, attendee) {
+		return &UnauthorizedReplyError{UID: uid, RecurrenceID: rid, Attendee: attendee.Value}
+	}
+	seq, current := sequenceOf(answer), sequenceOf(target)
+	if seq < current {
+		return &StaleReplyError{UID: uid, RecurrenceID: rid, Attendee: attendee.Value, Sequence: seq, Current: current}
+	}
+	return nil
+}
+
+// mayReply reports whether attendee is an ATTENDEE of target,or a delegate of
+// one: the reply names the delegator in DELEGATED-FROM and target lists the
+// attendee in DELEGATED-TO of the delegator
+func mayReply(target *ComponentObj, attendee *Property) bool {
+	if findAttendee(target, attendee.Value) != nil {
+		return true
+	}
+	for _, from := range attendee.Params[Paramdelfrom] {
+		delegator := findAttendee(target, from)
+		if delegator == nil {
+			continue
+		}
+		for _, to := range delegator.Params[Paramdelto] {
+			if sameAddress(to, attendee.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isOccurrence reports whether master has an occurrence which starts at rid
+func isOccurrence(master *ComponentObj, rid time.Time) (bool, error) {
+	occs, err := ExpandComponent(master, nil, rid, rid.Add(time.Second))
+	if err != nil {
+		return false, err
+	}
+	for _, o := range occs {
+		if o.RecurrenceID.Equal(rid) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setPartstat copies PARTSTAT and DELEGATED-TO of the replying attendee into com,
+// a delegate who is not an ATTENDEE yet is added with DELEGATED-FROM.
+// RSVP is dropped,the answer has been given
+func setPartstat(com *ComponentObj, answer *Property) {
+	p := findAttendee(com, answer.Value)
+	if p == nil {
+		com.AddProperty(Property{Name: PropAttendee, Params: Parameters{
+			Paramdelfrom: append([]string(nil), answer.Params[Paramdelfrom]...),
+		}, Value: answer.Value})
+		p = findAttendee(com, answer.Value)
+	}
+	if p.Params == nil {
+		p.Params = Parameters{}
+	}
+	partstat := answer.Params.Get(Parampartstat)
+	if partstat == "" {
+		partstat = PartstatNeedsAction
+	}
+	p.Params.Set(Parampartstat, partstat)
+	p.Params.Del(Paramrsvp)
+	if to, ok := answer.Params[Paramdelto]; ok {
+		p.Params[Paramdelto] = append([]string(nil), to...)
+	}
+}
+
+// newOverride makes the override of master for the occurrence at rid
+func newOverride(master *ComponentObj, rid time.Time) (*ComponentObj, error) {
+	o := master.Clone()
+	o.DelProperty(PropRecurrenceRule)
+	o.DelProperty(PropRecurrenceDatetime)
+	o.DelProperty(PropExceptionDatetime)
+
+	start := o.GetProperty(PropDatetimeStart)
+	if start == nil {
+		return nil, fmt.Errorf("ical:can not make an override of an event without DTSTART")
+	}
+	oldStart, err := start.GetToTime()
+	if err != nil {
+		return nil, err
+	}
+	p := NewProperty(PropRecurrenceId)
+	if err := p.SetFromTimeLike(start, rid); err != nil {
+		return nil, err
+	}
+	if end := o.GetProperty(PropDatetimeEnd); end != nil {
+		t, err := end.GetToTime()
+		if err != nil {
+			return nil, err
+		}
+		if err := end.SetFromTimeLike(end, rid.Add(t.Sub(oldStart))); err != nil {
+			return nil, err
+		}
+	}
+	if err := start.SetFromTimeLike(start, rid); err != nil {
+		return nil, err
+	}
+	o.AddProperty(*p)
+	return o, nil
+}
+
+// findAttendee returns the ATTENDEE of com with the calendar address addr
+func findAttendee(com *ComponentObj, addr string) *Property {
+	for i := range com.PropertiesObj {
+		p := &com.PropertiesObj[i]
+		if p.Name == PropAttendee && sameAddress(p.Value, addr) {
+			return p
+		}
+	}
+	return nil
+}
+
+// sameAddress compares two calendar user addresses,the scheme and the mail
+// address are case-insensitive
+func sameAddress(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// sequenceOf returns SEQUENCE of com,a missing SEQUENCE is 0
+func sequenceOf(com *ComponentObj) int {
+	n := 0
+	if p := com.GetProperty(PropSequenceNumber); p != nil {
+		n, _ = strconv.Atoi(p.Value)
+	}
+	return n
+}
@@ -0,0 +1,167 @@
+package go_ical
+
+import (
+	"strings"
+	"testing"
+)
+
+const storedCalendarStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VEVENT
+UID:weekly@example.com
+SEQUENCE:2
+DTSTAMP:20200201T090000Z
+DTSTART:20200302T100000Z
+DTEND:20200302T110000Z
+RRULE:FREQ=WEEKLY;COUNT=10
+ORGANIZER:mailto:a@example.com
+ATTENDEE;PARTSTAT=ACCEPTED:mailto:a@example.com
+ATTENDEE;RSVP=TRUE;PARTSTAT=NEEDS-ACTION:mailto:b@example.com
+SUMMARY:Weekly sync
+END:VEVENT
+END:VCALENDAR
+`
+
+const replyCalendarStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+METHOD:REPLY
+BEGIN:VEVENT
+UID:weekly@example.com
+SEQUENCE:2
+DTSTAMP:20200202T090000Z
+ORGANIZER:mailto:a@example.com
+ATTENDEE;PARTSTAT=DECLINED:MAILTO:b@example.com
+END:VEVENT
+END:VCALENDAR
+`
+
+func attendeeOf(t *testing.T, com *ComponentObj, addr string) *Property {
+	t.Helper()
+	p := findAttendee(com, addr)
+	if p == nil {
+		t.Fatalf("no ATTENDEE %s", addr)
+	}
+	return p
+}
+
+func TestApplyReplySeries(t *testing.T) {
+	stored := decodeString(t, storedCalendarStr)
+	if err := ApplyReply(&stored, decodeString(t, replyCalendarStr)); err != nil {
+		t.Fatalf("ApplyReply() err: %v", err)
+	}
+	b := attendeeOf(t, stored.SubComponents()[0].obj(), "mailto:b@example.com")
+	if got := b.Params.Get(Parampartstat); got != PartstatDeclined {
+		t.Errorf("PARTSTAT = %q, want DECLINED", got)
+	}
+	if _, ok := b.Params[Paramrsvp]; ok {
+		t.Errorf("RSVP is kept after the reply")
+	}
+	if n := len(stored.SubComponents()); n != 1 {
+		t.Errorf("a series reply made %d components, want 1", n)
+	}
+}
+
+func TestApplyReplyInstance(t *testing.T) {
+	stored := decodeString(t, storedCalendarStr)
+	s := strings.Replace(replyCalendarStr, "SEQUENCE:2", "SEQUENCE:2\nRECURRENCE-ID:20200309T100000Z", 1)
+	s = strings.Replace(s, "DECLINED", "TENTATIVE", 1)
+	if err := ApplyReply(&stored, decodeString(t, s)); err != nil {
+		t.Fatalf("ApplyReply() err: %v", err)
+	}
+	if n := len(stored.SubComponents()); n != 2 {
+		t.Fatalf("got %d components, want the master and one override", n)
+	}
+	master, o := stored.SubComponents()[0].obj(), stored.SubComponents()[1].obj()
+	if got := attendeeOf(t, master, "mailto:b@example.com").Params.Get(Parampartstat); got != PartstatNeedsAction {
+		t.Errorf("master PARTSTAT = %q, want NEEDS-ACTION", got)
+	}
+	if got := attendeeOf(t, o, "mailto:b@example.com").Params.Get(Parampartstat); got != PartstatTentative {
+		t.Errorf("override PARTSTAT = %q, want TENTATIVE", got)
+	}
+	for name, want := range map[string]string{
+		PropRecurrenceId:   "20200309T100000Z",
+		PropDatetimeStart:  "20200309T100000Z",
+		PropDatetimeEnd:    "20200309T110000Z",
+		PropRecurrenceRule: "",
+	} {
+		if got := propValue(o, name); got != want {
+			t.Errorf("override %s = %q, want %q", name, got, want)
+		}
+	}
+
+	//a second reply for the same occurrence changes the override
+	s = strings.Replace(s, "TENTATIVE", "ACCEPTED", 1)
+	if err := ApplyReply(&stored, decodeString(t, s)); err != nil {
+		t.Fatalf("ApplyReply() err: %v", err)
+	}
+	if n := len(stored.SubComponents()); n != 2 {
+		t.Errorf("got %d components after the second reply, want 2", n)
+	}
+	if got := attendeeOf(t, o, "mailto:b@example.com").Params.Get(Parampartstat); got != PartstatAccepted {
+		t.Errorf("override PARTSTAT = %q, want ACCEPTED", got)
+	}
+}
+
+func TestApplyReplyErrors(t *testing.T) {
+	stored := decodeString(t, storedCalendarStr)
+
+	err := ApplyReply(&stored, decodeString(t, strings.Replace(replyCalendarStr, "SEQUENCE:2", "SEQUENCE:1", 1)))
+	if e, ok := err.(*StaleReplyError); !ok {
+		t.Errorf("stale reply err = %v, want *StaleReplyError", err)
+	} else if e.Sequence != 1 || e.Current != 2 {
+		t.Errorf("StaleReplyError = %+v", e)
+	}
+
+	err = ApplyReply(&stored, decodeString(t, strings.Replace(replyCalendarStr, "b@example.com", "c@example.com", 1)))
+	if e, ok := err.(*UnauthorizedReplyError); !ok {
+		t.Errorf("unauthorized reply err = %v, want *UnauthorizedReplyError", err)
+	} else if e.Attendee != "MAILTO:c@example.com" {
+		t.Errorf("UnauthorizedReplyError = %+v", e)
+	}
+
+	err = ApplyReply(&stored, decodeString(t, strings.Replace(replyCalendarStr, "ORGANIZER:mailto:a@example.com\n", "", 1)))
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("reply without ORGANIZER err = %v, want *ValidationError", err)
+	}
+
+	if got := attendeeOf(t, stored.SubComponents()[0].obj(), "mailto:b@example.com").Params.Get(Parampartstat); got != PartstatNeedsAction {
+		t.Errorf("refused replies changed PARTSTAT to %q", got)
+	}
+}
+
+func TestApplyReplyNotAnOccurrence(t *testing.T) {
+	stored := decodeString(t, storedCalendarStr)
+	//the event is weekly on Mondays,3/10 is a Tuesday and 5/11 is after COUNT
+	for _, rid := range []string{"20200310T100000Z", "20200511T100000Z"} {
+		s := strings.Replace(replyCalendarStr, "SEQUENCE:2", "SEQUENCE:2\nRECURRENCE-ID:"+rid, 1)
+		if err := ApplyReply(&stored, decodeString(t, s)); err == nil {
+			t.Errorf("ApplyReply() for RECURRENCE-ID %s succeeded", rid)
+		}
+	}
+	if n := len(stored.SubComponents()); n != 1 {
+		t.Errorf("refused replies made %d components, want 1", n)
+	}
+}
+
+func TestApplyReplyDelegate(t *testing.T) {
+	stored := decodeString(t, strings.Replace(storedCalendarStr,
+		"ATTENDEE;RSVP=TRUE;PARTSTAT=NEEDS-ACTION:mailto:b@example.com",
+		`ATTENDEE;PARTSTAT=DELEGATED;DELEGATED-TO="mailto:c@example.com":mailto:b@example.com`, 1))
+	reply := strings.Replace(replyCalendarStr, "ATTENDEE;PARTSTAT=DECLINED:MAILTO:b@example.com",
+		`ATTENDEE;PARTSTAT=ACCEPTED;DELEGATED-FROM="mailto:b@example.com":mailto:c@example.com`, 1)
+	if err := ApplyReply(&stored, decodeString(t, reply)); err != nil {
+		t.Fatalf("ApplyReply() of the delegate err: %v", err)
+	}
+	c := attendeeOf(t, stored.SubComponents()[0].obj(), "mailto:c@example.com")
+	if c.Params.Get(Parampartstat) != PartstatAccepted || c.Params.Get(Paramdelfrom) != "mailto:b@example.com" {
+		t.Errorf("delegate ATTENDEE = %v", c)
+	}
+
+	//someone the delegator did not delegate to
+	other := strings.Replace(reply, "mailto:c@example.com", "mailto:d@example.com", 1)
+	if _, ok := ApplyReply(&stored, decodeString(t, other)).(*UnauthorizedReplyError); !ok {
+		t.Errorf("reply of an undeclared delegate was not refused")
+	}
+}
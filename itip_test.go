@@ -0,0 +1,97 @@
+package go_ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const requestCalendarStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+METHOD:REQUEST
+BEGIN:VEVENT
+UID:uid1@example.com
+SEQUENCE:0
+DTSTAMP:19970613T190000Z
+DTSTART:19970701T200000Z
+DTEND:19970701T203000Z
+ORGANIZER:mailto:a@example.com
+ATTENDEE;ROLE=CHAIR;PARTSTAT=ACCEPTED:mailto:a@example.com
+ATTENDEE;RSVP=TRUE:mailto:b@example.com
+SUMMARY:Conference
+STATUS:CONFIRMED
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestValidateMethodValid(t *testing.T) {
+	cal := decodeString(t, requestCalendarStr)
+	if findings := ValidateMethod(cal); len(findings) != 0 {
+		t.Errorf("ValidateMethod() found problems in a valid REQUEST:\n%v", findings)
+	}
+}
+
+func TestValidateMethodFindings(t *testing.T) {
+	tests := []struct {
+		method  string
+		replace [2]string
+		path    string
+		section string
+		message string
+	}{
+		{MethodReply, [2]string{"SUMMARY:Conference", "BEGIN:VALARM\nACTION:DISPLAY\nDESCRIPTION:x\nTRIGGER:-PT5M\nEND:VALARM"},
+			"VCALENDAR/VEVENT[0]", "RFC 5546 3.2.3", "exactly one ATTENDEE"},
+		{MethodReply, [2]string{"SUMMARY:Conference", "BEGIN:VALARM\nACTION:DISPLAY\nDESCRIPTION:x\nTRIGGER:-PT5M\nEND:VALARM"},
+			"VCALENDAR/VEVENT[0]", "RFC 5546 3.2.3", "VALARM MUST NOT be present"},
+		{MethodRefresh, [2]string{}, "VCALENDAR/VEVENT[0]/SUMMARY", "RFC 5546 3.2.6", "SUMMARY MUST NOT be present"},
+		{MethodCancel, [2]string{}, "VCALENDAR/VEVENT[0]/STATUS", "RFC 5546 3.2.5", `STATUS "CONFIRMED" is not allowed`},
+		{MethodAdd, [2]string{}, "VCALENDAR/VEVENT[0]/SEQUENCE", "RFC 5546 3.2.4", "greater than 0"},
+		{MethodPublish, [2]string{}, "VCALENDAR/VEVENT[0]/ATTENDEE", "RFC 5546 3.2.1", "ATTENDEE MUST NOT be present"},
+		{"", [2]string{}, "VCALENDAR", "RFC 5546 3.1", "MUST have METHOD"},
+	}
+	for _, test := range tests {
+		s := requestCalendarStr
+		if test.method == "" {
+			s = strings.Replace(s, "METHOD:REQUEST\n", "", 1)
+		} else {
+			s = strings.Replace(s, "METHOD:REQUEST", "METHOD:"+test.method, 1)
+		}
+		if test.replace[0] != "" {
+			s = strings.Replace(s, test.replace[0], test.replace[1], 1)
+		}
+		findings := ValidateMethod(decodeString(t, s))
+		found := false
+		for _, f := range findings {
+			if f.Path == test.path && f.Section == test.section && strings.Contains(f.Message, test.message) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: no finding at %s (%s) containing %q in %v", test.method, test.path, test.section, test.message, findings)
+		}
+	}
+}
+
+func TestIsAvailableWithoutMethod(t *testing.T) {
+	cal, err := NewDecoder(strings.NewReader(exampleCalendarStr)).Decode()
+	if err != nil {
+		t.Fatalf("Decode() err: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(&cal); err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+
+	noStart, err := NewDecoder(strings.NewReader(strings.Replace(exampleCalendarStr, "DTSTART:19960918T143000Z\r\n", "", 1))).Decode()
+	if err != nil {
+		t.Fatalf("Decode() err: %v", err)
+	}
+	if err := NewEncoder(&buf).Encode(&noStart); err == nil {
+		t.Errorf("Encode() of a VEVENT without DTSTART and no METHOD succeeded")
+	}
+	noStart.PropertiesObj = append(noStart.PropertiesObj, Property{Name: PropMethod, Params: Parameters{}, Value: MethodCancel})
+	if err := NewEncoder(&buf).Encode(&noStart); err != nil {
+		t.Errorf("Encode() of a CANCEL without DTSTART err: %v", err)
+	}
+}
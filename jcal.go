@@ -0,0 +1,571 @@
+package go_ical
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jCal,RFC 7265,is the JSON form of iCalendar. A component is the array
+// [name,[properties],[components]] and a property is the array
+// [name,{parameters},type,value...]. Names are lower case,the VALUE parameter
+// becomes the type and values are typed JSON: numbers,booleans,RECUR objects,
+// PERIOD arrays and dates in the extended ISO 8601 format.
+
+// jcalUnknown is the type of a property whose value type is not known,its
+// value is kept as the raw iCalendar text,RFC 7265 5
+const jcalUnknown = "unknown"
+
+// jcalLists are the value types whose values are split on commas,each value
+// becomes one element of the property array
+var jcalLists = map[string]bool{
+	VDTtext:     true,
+	VDTdate:     true,
+	VDTdatetime: true,
+	VDTtime:     true,
+	VDTperiod:   true,
+	VDTduration: true,
+	VDTint:      true,
+	VDTfloat:    true,
+}
+
+// jcalRecurInts are the RECUR parts written as JSON numbers
+var jcalRecurInts = map[string]bool{
+	"COUNT": true, "INTERVAL": true, "BYSECOND": true, "BYMINUTE": true, "BYHOUR": true,
+	"BYMONTHDAY": true, "BYYEARDAY": true, "BYWEEKNO": true, "BYMONTH": true, "BYSETPOS": true,
+}
+
+// jcalFormats are the basic iCalendar and extended jCal patterns of the date
+// and time values,a 'd' stands for a digit
+var jcalFormats = map[string][][2]string{
+	VDTdate:      {{"dddddddd", "dddd-dd-dd"}},
+	VDTdatetime:  {{"ddddddddTdddddd", "dddd-dd-ddTdd:dd:dd"}, {"ddddddddTddddddZ", "dddd-dd-ddTdd:dd:ddZ"}},
+	VDTtime:      {{"dddddd", "dd:dd:dd"}, {"ddddddZ", "dd:dd:ddZ"}},
+	VDTutcoffset: {{"+dddd", "+dd:dd"}, {"-dddd", "-dd:dd"}, {"+dddddd", "+dd:dd:dd"}, {"-dddddd", "-dd:dd:dd"}},
+}
+
+type JCalEncoder struct {
+	w io.Writer
+}
+
+func NewJCalEncoder(w io.Writer) *JCalEncoder {
+	return &JCalEncoder{w}
+}
+
+// Encode writes com as one line of jCal. Values which do not match their
+// value type are an error,the same as for Encoder.
+func (enc *JCalEncoder) Encode(com Component) error {
+	v, err := jcalComponent(com.obj())
+	if err != nil {
+		return err
+	}
+	je := json.NewEncoder(enc.w)
+	je.SetEscapeHTML(false)
+	return je.Encode(v)
+}
+
+func jcalComponent(com *ComponentObj) ([]interface{}, error) {
+	if err := com.IsAvailable(); err != nil {
+		return nil, err
+	}
+	props := make([]interface{}, 0, len(com.PropertiesObj))
+	for i := range com.PropertiesObj {
+		p, err := jcalProperty(&com.PropertiesObj[i])
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, p)
+	}
+	subs := make([]interface{}, 0, len(com.SubComponentsObj))
+	for _, sub := range com.SubComponentsObj {
+		s, err := jcalComponent(sub.obj())
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return []interface{}{strings.ToLower(com.Name()), props, subs}, nil
+}
+
+func jcalProperty(p *Property) ([]interface{}, error) {
+	if !isValidName(p.Name) {
+		return nil, fmt.Errorf("ical:invalid property name %q", p.Name)
+	}
+	params := map[string]interface{}{}
+	for key, vs := range p.Params {
+		if strings.EqualFold(key, Paramvaluetypeparam) {
+			continue
+		}
+		if !isValidName(key) {
+			return nil, fmt.Errorf("ical:property %q has invalid parameter name %q", p.Name, key)
+		}
+		if len(vs) == 1 {
+			params[strings.ToLower(key)] = vs[0]
+		} else {
+			params[strings.ToLower(key)] = vs
+		}
+	}
+	typ := strings.ToUpper(p.GetParamValue())
+	if typ == VDTdefault {
+		typ = jcalUnknown
+	}
+	prop := []interface{}{strings.ToLower(p.Name), params, strings.ToLower(typ)}
+
+	var vals []string
+	switch {
+	case p.Name == PropGeographicPosition && typ == VDTfloat,
+		p.Name == PropRequestStatus && typ == VDTtext:
+		//structured values are one array
+		var parts []interface{}
+		for _, s := range splitStructured(p.Value) {
+			v, err := jcalValue(typ, s)
+			if err != nil {
+				return nil, fmt.Errorf("ical:property %q: %v", p.Name, err)
+			}
+			parts = append(parts, v)
+		}
+		return append(prop, parts), nil
+	case jcalLists[typ]:
+		vals = splitValues(p.Value)
+	default:
+		vals = []string{p.Value}
+	}
+	for _, s := range vals {
+		v, err := jcalValue(typ, s)
+		if err != nil {
+			return nil, fmt.Errorf("ical:property %q: %v", p.Name, err)
+		}
+		prop = append(prop, v)
+	}
+	return prop, nil
+}
+
+// jcalValue converts one value of type typ to its JSON form
+func jcalValue(typ, s string) (interface{}, error) {
+	switch typ {
+	case VDTtext:
+		return FromText(s), nil
+	case VDTint:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		return n, nil
+	case VDTfloat:
+		if _, err := strconv.ParseFloat(s, 64); err != nil || !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("invalid float %q", s)
+		}
+		return json.Number(s), nil
+	case VDTbool:
+		switch strings.ToUpper(s) {
+		case "TRUE":
+			return true, nil
+		case "FALSE":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid boolean %q", s)
+	case VDTdate, VDTdatetime, VDTtime, VDTutcoffset:
+		return jcalTime(typ, s)
+	case VDTperiod:
+		i := strings.IndexByte(s, '/')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid period %q", s)
+		}
+		start, err := jcalTime(VDTdatetime, s[:i])
+		if err != nil {
+			return nil, err
+		}
+		end := s[i+1:]
+		if !strings.ContainsRune(end, 'P') {
+			if end, err = jcalTime(VDTdatetime, end); err != nil {
+				return nil, err
+			}
+		}
+		return []string{start, end}, nil
+	case VDTrecurrence:
+		return jcalRecur(s)
+	}
+	return s, nil
+}
+
+// jcalTime converts a DATE,DATE-TIME,TIME or UTC-OFFSET value from the basic
+// to the extended format
+func jcalTime(typ, s string) (string, error) {
+	for _, f := range jcalFormats[typ] {
+		if v, ok := reformat(s, f[0], f[1]); ok {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid %s %q", strings.ToLower(typ), s)
+}
+
+// icalTime is the reverse of jcalTime
+func icalTime(typ, s string) (string, error) {
+	for _, f := range jcalFormats[typ] {
+		if v, ok := reformat(s, f[1], f[0]); ok {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid jCal %s %q", strings.ToLower(typ), s)
+}
+
+// reformat moves the digits of s,which must match the pattern from,into the
+// pattern to. A 'd' in a pattern stands for a digit,other characters must
+// be equal.
+func reformat(s, from, to string) (string, bool) {
+	if len(s) != len(from) {
+		return "", false
+	}
+	digits := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if from[i] == 'd' {
+			if s[i] < '0' || s[i] > '9' {
+				return "", false
+			}
+			digits = append(digits, s[i])
+		} else if s[i] != from[i] {
+			return "", false
+		}
+	}
+	out := make([]byte, 0, len(to))
+	for i := 0; i < len(to); i++ {
+		if to[i] == 'd' {
+			out = append(out, digits[0])
+			digits = digits[1:]
+		} else {
+			out = append(out, to[i])
+		}
+	}
+	return string(out), true
+}
+
+// jcalRecur writes a RECUR value as a JSON object,parts keep their order and
+// parts with several values become arrays,RFC 7265 3.6.10
+func jcalRecur(s string) (jcalObject, error) {
+	if _, err := ParseRecur(s); err != nil {
+		return nil, err
+	}
+	var obj jcalObject
+	for _, part := range strings.Split(s, ";") {
+		i := strings.IndexByte(part, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("ical:invalid recur part %q", part)
+		}
+		key := strings.ToUpper(part[:i])
+		var vals []interface{}
+		for _, v := range strings.Split(part[i+1:], ",") {
+			switch {
+			case jcalRecurInts[key]:
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					return nil, fmt.Errorf("ical:invalid %s %q", key, v)
+				}
+				vals = append(vals, n)
+			case key == "UNTIL":
+				typ := VDTdatetime
+				if len(v) == len(jcalFormats[VDTdate][0][0]) {
+					typ = VDTdate
+				}
+				t, err := jcalTime(typ, v)
+				if err != nil {
+					return nil, err
+				}
+				vals = append(vals, t)
+			default:
+				vals = append(vals, v)
+			}
+		}
+		if len(vals) == 1 {
+			obj = append(obj, jcalMember{strings.ToLower(key), vals[0]})
+		} else {
+			obj = append(obj, jcalMember{strings.ToLower(key), vals})
+		}
+	}
+	return obj, nil
+}
+
+// jcalObject is a JSON object which keeps the order of its members
+type jcalObject []jcalMember
+
+type jcalMember struct {
+	key   string
+	value interface{}
+}
+
+func (o jcalObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// splitStructured splits a structured value on the semicolons that are not
+// escaped with a backslash
+func splitStructured(s string) []string {
+	var vals []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ';':
+			vals = append(vals, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(vals, s[start:])
+}
+
+type JCalDecoder struct {
+	d *json.Decoder
+}
+
+func NewJCalDecoder(r io.Reader) *JCalDecoder {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	return &JCalDecoder{d}
+}
+
+// Decode reads the next jCal vcalendar,io.EOF is returned at the end of the input
+func (dec *JCalDecoder) Decode() (Calendar, error) {
+	var raw json.RawMessage
+	if err := dec.d.Decode(&raw); err != nil {
+		return Calendar{}, err
+	}
+	com, err := icalComponent(raw)
+	if err != nil {
+		return Calendar{}, err
+	}
+	if com.Name() != CompCalendar {
+		return Calendar{}, fmt.Errorf("ical:jCal must start with vcalendar,but got %q", strings.ToLower(com.Name()))
+	}
+	return Calendar{*com}, nil
+}
+
+func icalComponent(raw json.RawMessage) (*ComponentObj, error) {
+	var arr []json.RawMessage
+	if err := json.Unmarshal(raw, &arr); err != nil || len(arr) != 3 {
+		return nil, fmt.Errorf("ical:a jCal component must be [name,[properties],[components]]")
+	}
+	var name string
+	var props, subs []json.RawMessage
+	if err := json.Unmarshal(arr[0], &name); err != nil || !isValidName(name) {
+		return nil, fmt.Errorf("ical:invalid jCal component name %s", arr[0])
+	}
+	if err := json.Unmarshal(arr[1], &props); err != nil {
+		return nil, fmt.Errorf("ical:invalid jCal properties of %q: %v", name, err)
+	}
+	if err := json.Unmarshal(arr[2], &subs); err != nil {
+		return nil, fmt.Errorf("ical:invalid jCal components of %q: %v", name, err)
+	}
+	com := &ComponentObj{
+		NameObj:          strings.ToUpper(name),
+		PropertiesObj:    []Property{},
+		SubComponentsObj: []Component{},
+	}
+	for _, rp := range props {
+		p, err := icalProperty(rp)
+		if err != nil {
+			return nil, err
+		}
+		com.PropertiesObj = append(com.PropertiesObj, p)
+	}
+	for _, rs := range subs {
+		sub, err := icalComponent(rs)
+		if err != nil {
+			return nil, err
+		}
+		com.SubComponentsObj = append(com.SubComponentsObj, sub)
+	}
+	return com, nil
+}
+
+func icalProperty(raw json.RawMessage) (Property, error) {
+	var arr []json.RawMessage
+	if err := json.Unmarshal(raw, &arr); err != nil || len(arr) < 4 {
+		return Property{}, fmt.Errorf("ical:a jCal property must be [name,{parameters},type,value...],but got %s", raw)
+	}
+	var name, typ string
+	if err := json.Unmarshal(arr[0], &name); err != nil || !isValidName(name) {
+		return Property{}, fmt.Errorf("ical:invalid jCal property name %s", arr[0])
+	}
+	p := NewProperty(name)
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(arr[1], &params); err != nil {
+		return Property{}, fmt.Errorf("ical:invalid jCal parameters of %q: %v", name, err)
+	}
+	for key, rv := range params {
+		if !isValidName(key) {
+			return Property{}, fmt.Errorf("ical:property %q has invalid parameter name %q", p.Name, key)
+		}
+		var vs []string
+		var v string
+		if err := json.Unmarshal(rv, &v); err == nil {
+			vs = []string{v}
+		} else if err := json.Unmarshal(rv, &vs); err != nil {
+			return Property{}, fmt.Errorf("ical:property %q parameter %q must be a string or an array of strings", p.Name, key)
+		}
+		p.Params[strings.ToUpper(key)] = vs
+	}
+	if err := json.Unmarshal(arr[2], &typ); err != nil || !isValidName(typ) {
+		return Property{}, fmt.Errorf("ical:invalid jCal type %s of %q", arr[2], name)
+	}
+	typ = strings.ToUpper(typ)
+	if typ == strings.ToUpper(jcalUnknown) {
+		typ = VDTdefault
+	} else if DefaultVDT[p.Name] != typ {
+		p.Params.Set(Paramvaluetypeparam, typ)
+	}
+
+	structured := p.Name == PropGeographicPosition && typ == VDTfloat ||
+		p.Name == PropRequestStatus && typ == VDTtext
+	var vals []string
+	for _, rv := range arr[3:] {
+		if structured {
+			var parts []json.RawMessage
+			if err := json.Unmarshal(rv, &parts); err == nil {
+				var ss []string
+				for _, part := range parts {
+					s, err := icalValue(typ, part)
+					if err != nil {
+						return Property{}, fmt.Errorf("ical:property %q: %v", p.Name, err)
+					}
+					ss = append(ss, s)
+				}
+				vals = append(vals, strings.Join(ss, ";"))
+				continue
+			}
+		}
+		s, err := icalValue(typ, rv)
+		if err != nil {
+			return Property{}, fmt.Errorf("ical:property %q: %v", p.Name, err)
+		}
+		vals = append(vals, s)
+	}
+	p.Value = strings.Join(vals, ",")
+	return *p, nil
+}
+
+// icalValue converts one JSON value of type typ to iCalendar text
+func icalValue(typ string, raw json.RawMessage) (string, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return "", err
+	}
+	switch typ {
+	case VDTint, VDTfloat:
+		if n, ok := v.(json.Number); ok {
+			return n.String(), nil
+		}
+	case VDTbool:
+		if b, ok := v.(bool); ok {
+			return strings.ToUpper(strconv.FormatBool(b)), nil
+		}
+	case VDTperiod:
+		var pe []string
+		if err := json.Unmarshal(raw, &pe); err != nil || len(pe) != 2 {
+			break
+		}
+		start, err := icalTime(VDTdatetime, pe[0])
+		if err != nil {
+			return "", err
+		}
+		end := pe[1]
+		if !strings.ContainsRune(end, 'P') {
+			if end, err = icalTime(VDTdatetime, end); err != nil {
+				return "", err
+			}
+		}
+		return start + "/" + end, nil
+	case VDTrecurrence:
+		if _, ok := v.(map[string]interface{}); ok {
+			return icalRecur(raw)
+		}
+	default:
+		s, ok := v.(string)
+		if !ok {
+			break
+		}
+		switch typ {
+		case VDTtext:
+			return ToText(s), nil
+		case VDTdate, VDTdatetime, VDTtime, VDTutcoffset:
+			return icalTime(typ, s)
+		}
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid jCal %s value %s", strings.ToLower(typ), raw)
+}
+
+// icalRecur reads a RECUR object,the parts are written in the order of the object
+func icalRecur(raw json.RawMessage) (string, error) {
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	d.Token() //the '{' was checked by icalValue
+	var parts []string
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+		key := strings.ToUpper(t.(string))
+		var rv json.RawMessage
+		if err := d.Decode(&rv); err != nil {
+			return "", err
+		}
+		var vals []interface{}
+		if err := json.Unmarshal(rv, &vals); err != nil {
+			vals = []interface{}{nil}
+			if err := json.Unmarshal(rv, &vals[0]); err != nil {
+				return "", err
+			}
+		}
+		ss := make([]string, len(vals))
+		for i, v := range vals {
+			switch v := v.(type) {
+			case float64:
+				ss[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			case string:
+				ss[i] = v
+				if key == "UNTIL" {
+					typ := VDTdatetime
+					if len(v) == len(jcalFormats[VDTdate][0][1]) {
+						typ = VDTdate
+					}
+					if ss[i], err = icalTime(typ, v); err != nil {
+						return "", err
+					}
+				}
+			default:
+				return "", fmt.Errorf("invalid jCal recur part %q", strings.ToLower(key))
+			}
+		}
+		parts = append(parts, key+"="+strings.Join(ss, ","))
+	}
+	s := strings.Join(parts, ";")
+	if _, err := ParseRecur(s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
@@ -0,0 +1,127 @@
+package go_ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const jcalCalendarStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VTIMEZONE
+TZID:Europe/Berlin
+BEGIN:STANDARD
+DTSTART:19701025T030000
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:uid1@example.com
+DTSTAMP:19960704T120000Z
+DTSTART;TZID=Europe/Berlin:19960918T143000
+DURATION:PT1H
+RRULE:FREQ=WEEKLY;UNTIL=19961231T000000Z;BYDAY=WE,FR;INTERVAL=2
+EXDATE;TZID=Europe/Berlin:19960925T143000,19961002T143000
+RDATE;VALUE=PERIOD:19961224T100000Z/PT2H,19961225T100000Z/19961225T120000Z
+ATTENDEE;ROLE=REQ-PARTICIPANT;DELEGATED-TO="mailto:a@example.com","mailto:b@example.com":mailto:jdoe@example.com
+GEO:37.386013;-122.082932
+CATEGORIES:BUSINESS,Meeting\, weekly
+DESCRIPTION:Agenda:\nfirst\; second
+PRIORITY:1
+REQUEST-STATUS:2.0;Success
+X-ALLOWED;VALUE=BOOLEAN:TRUE
+X-LEGACY:raw\qvalue
+END:VEVENT
+BEGIN:VTODO
+UID:uid2@example.com
+DTSTAMP:19960704T120000Z
+DUE;VALUE=DATE:19960920
+PERCENT-COMPLETE:40
+END:VTODO
+END:VCALENDAR
+`
+
+func TestJCalEncode(t *testing.T) {
+	cal := decodeString(t, jcalCalendarStr)
+	var buf bytes.Buffer
+	if err := NewJCalEncoder(&buf).Encode(&cal); err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`["vcalendar",[["prodid",{},"text","-//xyz Corp//Scott WORK Calendar Version 1.0//CN"]`,
+		`["tzoffsetfrom",{},"utc-offset","+02:00"]`,
+		`["rrule",{},"recur",{"freq":"YEARLY","bymonth":10,"byday":"-1SU"}]`,
+		`["dtstart",{"tzid":"Europe/Berlin"},"date-time","1996-09-18T14:30:00"]`,
+		`["rrule",{},"recur",{"freq":"WEEKLY","until":"1996-12-31T00:00:00Z","byday":["WE","FR"],"interval":2}]`,
+		`["exdate",{"tzid":"Europe/Berlin"},"date-time","1996-09-25T14:30:00","1996-10-02T14:30:00"]`,
+		`["rdate",{},"period",["1996-12-24T10:00:00Z","PT2H"],["1996-12-25T10:00:00Z","1996-12-25T12:00:00Z"]]`,
+		`"delegated-to":["mailto:a@example.com","mailto:b@example.com"]`,
+		`["geo",{},"float",[37.386013,-122.082932]]`,
+		`["categories",{},"text","BUSINESS","Meeting, weekly"]`,
+		`["description",{},"text","Agenda:\nfirst; second"]`,
+		`["priority",{},"integer",1]`,
+		`["request-status",{},"text",["2.0","Success"]]`,
+		`["x-allowed",{},"boolean",true]`,
+		`["x-legacy",{},"unknown","raw\\qvalue"]`,
+		`["due",{},"date","1996-09-20"]`,
+		`["percent-complete",{},"integer",40]`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("jCal does not contain %s\n%s", want, got)
+		}
+	}
+}
+
+func TestJCalRoundTrip(t *testing.T) {
+	for _, s := range []string{jcalCalendarStr, validCalendarStr} {
+		cal := decodeString(t, s)
+		var want bytes.Buffer
+		if err := NewEncoder(&want).Encode(&cal); err != nil {
+			t.Fatalf("Encode() err: %v", err)
+		}
+
+		var js bytes.Buffer
+		if err := NewJCalEncoder(&js).Encode(&cal); err != nil {
+			t.Fatalf("JCalEncoder.Encode() err: %v", err)
+		}
+		back, err := NewJCalDecoder(bytes.NewReader(js.Bytes())).Decode()
+		if err != nil {
+			t.Fatalf("JCalDecoder.Decode() err: %v", err)
+		}
+		var got bytes.Buffer
+		if err := NewEncoder(&got).Encode(&back); err != nil {
+			t.Fatalf("Encode() err: %v", err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("round trip changed the calendar:\n got %s\nwant %s", got.String(), want.String())
+		}
+
+		var js2 bytes.Buffer
+		if err := NewJCalEncoder(&js2).Encode(&back); err != nil {
+			t.Fatalf("JCalEncoder.Encode() err: %v", err)
+		}
+		if js2.String() != js.String() {
+			t.Errorf("jCal changed:\n got %s\nwant %s", js2.String(), js.String())
+		}
+	}
+}
+
+func TestJCalDecodeErrors(t *testing.T) {
+	for _, s := range []string{
+		`["vevent",[],[]]`,
+		`["vcalendar",[["dtstart",{},"date-time","19960918T143000"]],[]]`,
+		`["vcalendar",[["priority",{},"integer","1"]],[]]`,
+		`["vcalendar",[["rrule",{},"recur",{"freq":"SOMETIMES"}]],[]]`,
+		`["vcalendar",[["summary",{"cn":1},"text","x"]],[]]`,
+		`["vcalendar",[["summary",{},"text"]],[]]`,
+		`["vcalendar",[]]`,
+	} {
+		if _, err := NewJCalDecoder(strings.NewReader(s)).Decode(); err == nil {
+			t.Errorf("Decode(%s) err = nil", s)
+		}
+	}
+}
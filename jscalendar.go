@@ -0,0 +1,1209 @@
+package go_ical
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSCalendar,RFC 8984,is a JSON model of calendar data. Unlike jCal it is not
+// a mapping of iCalendar syntax: an event with its overrides is one object,
+// start times are local times with a time zone name,and attendees,alarms and
+// exceptions are maps of objects. The types below hold the JSCalendar
+// properties which have an iCalendar counterpart.
+
+const (
+	jsLocalFormat = "2006-01-02T15:04:05"
+	jsUTCFormat   = "2006-01-02T15:04:05Z"
+	jsSection     = "RFC 8984"
+)
+
+// JSGroup is a JSCalendar Group,RFC 8984 5.3
+type JSGroup struct {
+	Type    string     `json:"@type"`
+	UID     string     `json:"uid"`
+	ProdID  string     `json:"prodId,omitempty"`
+	Title   string     `json:"title,omitempty"`
+	Entries []*JSEntry `json:"entries"`
+}
+
+// JSEntry is a JSCalendar Event or Task,RFC 8984 5.1 and 5.2
+type JSEntry struct {
+	Type            string                 `json:"@type"`
+	UID             string                 `json:"uid"`
+	Created         string                 `json:"created,omitempty"`
+	Updated         string                 `json:"updated,omitempty"`
+	Sequence        int                    `json:"sequence,omitempty"`
+	Method          string                 `json:"method,omitempty"`
+	Title           string                 `json:"title,omitempty"`
+	Description     string                 `json:"description,omitempty"`
+	Locations       map[string]*JSLocation `json:"locations,omitempty"`
+	Keywords        map[string]bool        `json:"keywords,omitempty"`
+	Color           string                 `json:"color,omitempty"`
+	Start           string                 `json:"start,omitempty"`
+	TimeZone        string                 `json:"timeZone,omitempty"`
+	ShowWithoutTime bool                   `json:"showWithoutTime,omitempty"`
+	//Duration is only used by an Event
+	Duration string `json:"duration,omitempty"`
+	//Due,Progress and PercentComplete are only used by a Task
+	Due             string              `json:"due,omitempty"`
+	Progress        string              `json:"progress,omitempty"`
+	PercentComplete int                 `json:"percentComplete,omitempty"`
+	Status          string              `json:"status,omitempty"`
+	FreeBusyStatus  string              `json:"freeBusyStatus,omitempty"`
+	Privacy         string              `json:"privacy,omitempty"`
+	Priority        int                 `json:"priority,omitempty"`
+	RecurrenceID    string              `json:"recurrenceId,omitempty"`
+	RecurrenceRules []*JSRecurrenceRule `json:"recurrenceRules,omitempty"`
+	//RecurrenceOverrides maps the LocalDateTime of an instance to a PatchObject,
+	//{} adds an instance and {"excluded":true} removes one
+	RecurrenceOverrides map[string]map[string]interface{} `json:"recurrenceOverrides,omitempty"`
+	ReplyTo             map[string]string                 `json:"replyTo,omitempty"`
+	Participants        map[string]*JSParticipant         `json:"participants,omitempty"`
+	Alerts              map[string]*JSAlert               `json:"alerts,omitempty"`
+}
+
+// JSLocation is a JSCalendar Location,RFC 8984 4.2.5
+type JSLocation struct {
+	Type        string `json:"@type"`
+	Name        string `json:"name,omitempty"`
+	Coordinates string `json:"coordinates,omitempty"`
+}
+
+// JSRecurrenceRule is a JSCalendar RecurrenceRule,RFC 8984 4.3.3
+type JSRecurrenceRule struct {
+	Type           string   `json:"@type"`
+	Frequency      string   `json:"frequency"`
+	Interval       int      `json:"interval,omitempty"`
+	FirstDayOfWeek string   `json:"firstDayOfWeek,omitempty"`
+	ByDay          []JSNDay `json:"byDay,omitempty"`
+	ByMonthDay     []int    `json:"byMonthDay,omitempty"`
+	ByMonth        []string `json:"byMonth,omitempty"`
+	ByYearDay      []int    `json:"byYearDay,omitempty"`
+	ByWeekNo       []int    `json:"byWeekNo,omitempty"`
+	ByHour         []int    `json:"byHour,omitempty"`
+	ByMinute       []int    `json:"byMinute,omitempty"`
+	BySecond       []int    `json:"bySecond,omitempty"`
+	BySetPosition  []int    `json:"bySetPosition,omitempty"`
+	Count          int      `json:"count,omitempty"`
+	Until          string   `json:"until,omitempty"`
+}
+
+// JSNDay is a day of the week in a RecurrenceRule
+type JSNDay struct {
+	Type        string `json:"@type"`
+	Day         string `json:"day"`
+	NthOfPeriod int    `json:"nthOfPeriod,omitempty"`
+}
+
+// JSParticipant is a JSCalendar Participant,RFC 8984 4.4.6
+type JSParticipant struct {
+	Type                string            `json:"@type"`
+	Name                string            `json:"name,omitempty"`
+	Email               string            `json:"email,omitempty"`
+	SendTo              map[string]string `json:"sendTo,omitempty"`
+	Kind                string            `json:"kind,omitempty"`
+	Roles               map[string]bool   `json:"roles"`
+	ParticipationStatus string            `json:"participationStatus,omitempty"`
+	ExpectReply         bool              `json:"expectReply,omitempty"`
+}
+
+// JSAlert is a JSCalendar Alert,RFC 8984 4.5.2
+type JSAlert struct {
+	Type    string    `json:"@type"`
+	Trigger JSTrigger `json:"trigger"`
+	Action  string    `json:"action,omitempty"`
+}
+
+// JSTrigger is an OffsetTrigger or an AbsoluteTrigger of an Alert
+type JSTrigger struct {
+	Type       string `json:"@type"`
+	Offset     string `json:"offset,omitempty"`
+	RelativeTo string `json:"relativeTo,omitempty"`
+	When       string `json:"when,omitempty"`
+}
+
+// jsParams are the parameters each property keeps,VALUE is always kept
+var jsParams = map[string][]string{
+	PropDatetimeStart:      {Paramtzid},
+	PropDatetimeEnd:        {Paramtzid},
+	PropDatetimeDue:        {Paramtzid},
+	PropRecurrenceId:       {Paramtzid},
+	PropRecurrenceDatetime: {Paramtzid},
+	PropExceptionDatetime:  {Paramtzid},
+	PropAttendee:           {Paramcn, Paramrole, Parampartstat, Paramrsvp, Paramcutype},
+	PropOrganizer:          {Paramcn},
+	PropTrigger:            {Paramtrigrel},
+}
+
+var jsPrivacy = map[string]string{"PUBLIC": "public", "PRIVATE": "private", "CONFIDENTIAL": "secret"}
+
+var jsRoles = map[string]string{"CHAIR": "chair", "OPT-PARTICIPANT": "optional", "NON-PARTICIPANT": "informational"}
+
+// ParseJSCalendar decodes a JSCalendar Group,or a single Event or Task which
+// is returned in a Group of its own
+func ParseJSCalendar(b []byte) (*JSGroup, error) {
+	var head struct {
+		Type string `json:"@type"`
+	}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return nil, err
+	}
+	switch head.Type {
+	case "Group":
+		g := &JSGroup{}
+		if err := json.Unmarshal(b, g); err != nil {
+			return nil, err
+		}
+		return g, nil
+	case "Event", "Task":
+		e := &JSEntry{}
+		if err := json.Unmarshal(b, e); err != nil {
+			return nil, err
+		}
+		uid, err := newUID()
+		if err != nil {
+			return nil, err
+		}
+		return &JSGroup{Type: "Group", UID: uid, Entries: []*JSEntry{e}}, nil
+	}
+	return nil, fmt.Errorf("ical:unknown JSCalendar type %q", head.Type)
+}
+
+type jsConverter struct {
+	findings []Finding
+}
+
+func (c *jsConverter) lost(path, format string, args ...interface{}) {
+	c.findings = append(c.findings, Finding{
+		Severity: SeverityWarning,
+		Path:     path,
+		Section:  jsSection,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+/*
+ToJSCalendar converts cal to a JSCalendar Group with one Event for each
+VEVENT and one Task for each VTODO.
+
+Components with the same UID become one entry: RRULE becomes
+recurrenceRules,RDATE,EXDATE and the components with RECURRENCE-ID become
+recurrenceOverrides,ATTENDEE and ORGANIZER become participants and VALARM
+becomes alerts. Every property,parameter or component JSCalendar has no
+place for is reported as a warning,the conversion goes on without it.
+VTIMEZONE is dropped,time zones are named by TZID,which must be an IANA name.
+*/
+func ToJSCalendar(cal Calendar) (*JSGroup, []Finding, error) {
+	c := &jsConverter{}
+	g := &JSGroup{Type: "Group", Entries: []*JSEntry{}}
+	method := ""
+	for _, p := range cal.Properties() {
+		path := CompCalendar + "/" + p.Name
+		switch p.Name {
+		case PropProductIdentifier:
+			g.ProdID = FromText(p.Value)
+		case PropVersion:
+		case PropCalendarScale:
+			if !strings.EqualFold(p.Value, "GREGORIAN") {
+				c.lost(path, "CALSCALE %q is lost", p.Value)
+			}
+		case PropMethod:
+			method = strings.ToLower(p.Value)
+		case PropName:
+			g.Title = FromText(p.Value)
+		case PropUID:
+			g.UID = p.Value
+		default:
+			c.lost(path, "%s is lost", p.Name)
+		}
+	}
+	if g.UID == "" {
+		uid, err := newUID()
+		if err != nil {
+			return nil, nil, err
+		}
+		g.UID = uid
+	}
+
+	type group struct {
+		master    *ComponentObj
+		path      string
+		overrides []*ComponentObj
+		paths     []string
+	}
+	groups := map[string]*group{}
+	var order []string
+	counts := map[string]int{}
+	for _, sub := range cal.SubComponents() {
+		com := sub.obj()
+		path := fmt.Sprintf("%s/%s[%d]", CompCalendar, com.Name(), counts[com.Name()])
+		counts[com.Name()]++
+		switch com.Name() {
+		case CompTimezone:
+			if p := com.GetProperty(PropTimeZoneIdentifier); p == nil || !isIANAZone(p.Value) {
+				c.lost(path, "VTIMEZONE is not an IANA time zone,its rules are lost")
+			}
+		case CompEvent, CompTodo:
+			key := path
+			if p := com.GetProperty(PropUID); p != nil {
+				key = com.Name() + "/" + p.Value
+			}
+			gr := groups[key]
+			if gr == nil {
+				gr = &group{}
+				groups[key] = gr
+				order = append(order, key)
+			}
+			if com.GetProperty(PropRecurrenceId) == nil {
+				gr.master, gr.path = com, path
+			} else {
+				gr.overrides = append(gr.overrides, com)
+				gr.paths = append(gr.paths, path)
+			}
+		default:
+			c.lost(path, "%s has no JSCalendar counterpart", com.Name())
+		}
+	}
+
+	for _, key := range order {
+		gr := groups[key]
+		if gr.master == nil {
+			//overrides without their master stay single instances
+			for i, o := range gr.overrides {
+				e, err := c.entry(o, gr.paths[i])
+				if err != nil {
+					return nil, nil, err
+				}
+				e.Method = method
+				g.Entries = append(g.Entries, e)
+			}
+			continue
+		}
+		e, err := c.entry(gr.master, gr.path)
+		if err != nil {
+			return nil, nil, err
+		}
+		e.Method = method
+		for i, o := range gr.overrides {
+			oe, err := c.entry(o, gr.paths[i])
+			if err != nil {
+				return nil, nil, err
+			}
+			rid, err := o.GetProperty(PropRecurrenceId).GetToTime()
+			if err != nil {
+				return nil, nil, err
+			}
+			key, err := jsLocalIn(rid, e.TimeZone, e.ShowWithoutTime)
+			if err != nil {
+				return nil, nil, err
+			}
+			patch, err := jsPatch(e, oe, key)
+			if err != nil {
+				return nil, nil, err
+			}
+			if e.RecurrenceOverrides == nil {
+				e.RecurrenceOverrides = map[string]map[string]interface{}{}
+			}
+			e.RecurrenceOverrides[key] = patch
+		}
+		g.Entries = append(g.Entries, e)
+	}
+	return g, c.findings, nil
+}
+
+func isIANAZone(tz string) bool {
+	_, err := time.LoadLocation(tz)
+	return err == nil && tz != "" && tz != "Local"
+}
+
+// jsTime returns the time of a DTSTART-like property and its JSCalendar time
+// zone: the TZID,"Etc/UTC" for UTC and "" for floating times and dates
+func jsTime(p *Property) (time.Time, string, error) {
+	t, err := p.GetToTime()
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	tz := p.Params.Get(Paramtzid)
+	if tz == "" && !p.IsDate() && strings.HasSuffix(p.Value, "Z") {
+		tz = "Etc/UTC"
+	}
+	return t, tz, nil
+}
+
+// jsLocalIn formats t as a LocalDateTime in the time zone tz
+func jsLocalIn(t time.Time, tz string, date bool) (string, error) {
+	if date {
+		return t.Format("2006-01-02") + "T00:00:00", nil
+	}
+	if tz == "" {
+		return t.Format(jsLocalFormat), nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return "", err
+	}
+	return t.In(loc).Format(jsLocalFormat), nil
+}
+
+func (c *jsConverter) entry(com *ComponentObj, path string) (*JSEntry, error) {
+	e := &JSEntry{Type: "Event"}
+	task := com.Name() == CompTodo
+	if task {
+		e.Type = "Task"
+	}
+	var start time.Time
+	hasStart := false
+	if p := com.GetProperty(PropDatetimeStart); p != nil {
+		t, tz, err := jsTime(p)
+		if err != nil {
+			return nil, err
+		}
+		start, hasStart = t, true
+		e.TimeZone, e.ShowWithoutTime = tz, p.IsDate()
+		if e.Start, err = jsLocalIn(t, tz, p.IsDate()); err != nil {
+			return nil, err
+		}
+	}
+	local := func(t time.Time) string {
+		s, _ := jsLocalIn(t, e.TimeZone, e.ShowWithoutTime)
+		return s
+	}
+	utc := func(p *Property, ppath string) string {
+		t, err := p.GetToDatetime()
+		if err != nil {
+			c.lost(ppath, "invalid %s is lost: %v", p.Name, err)
+			return ""
+		}
+		return t.UTC().Format(jsUTCFormat)
+	}
+	location := func() *JSLocation {
+		if e.Locations == nil {
+			e.Locations = map[string]*JSLocation{"1": {Type: "Location"}}
+		}
+		return e.Locations["1"]
+	}
+
+	counts := map[string]int{}
+	for _, p := range com.Properties() {
+		p := p
+		ppath := path + "/" + p.Name
+		if counts[p.Name] > 0 || len(com.GetProperties(p.Name)) > 1 {
+			ppath = fmt.Sprintf("%s[%d]", ppath, counts[p.Name])
+		}
+		counts[p.Name]++
+		c.params(&p, ppath)
+		switch p.Name {
+		case PropUID:
+			e.UID = p.Value
+		case PropDatetimeStamp:
+			if com.GetProperty(PropLastModified) == nil {
+				e.Updated = utc(&p, ppath)
+			}
+		case PropLastModified:
+			e.Updated = utc(&p, ppath)
+		case PropDatetimeCreated:
+			e.Created = utc(&p, ppath)
+		case PropSequenceNumber:
+			n, err := strconv.Atoi(p.Value)
+			if err != nil {
+				c.lost(ppath, "invalid SEQUENCE %q is lost", p.Value)
+			}
+			e.Sequence = n
+		case PropSummary:
+			e.Title = FromText(p.Value)
+		case PropDescription:
+			e.Description = FromText(p.Value)
+		case PropLocation:
+			location().Name = FromText(p.Value)
+		case PropGeographicPosition:
+			parts := strings.Split(p.Value, ";")
+			if len(parts) != 2 {
+				c.lost(ppath, "invalid GEO %q is lost", p.Value)
+				continue
+			}
+			location().Coordinates = "geo:" + parts[0] + "," + parts[1]
+		case PropCategories:
+			if e.Keywords == nil {
+				e.Keywords = map[string]bool{}
+			}
+			for _, v := range splitValues(p.Value) {
+				e.Keywords[FromText(v)] = true
+			}
+		case PropColor:
+			e.Color = p.Value
+		case PropClassification:
+			if e.Privacy = jsPrivacy[strings.ToUpper(p.Value)]; e.Privacy == "" {
+				c.lost(ppath, "CLASS %q is lost", p.Value)
+			}
+		case PropStatus:
+			switch v := strings.ToUpper(p.Value); {
+			case !task && (v == StatusConfirmed || v == StatusTentative || v == StatusCancelled):
+				e.Status = strings.ToLower(v)
+			case task && (v == StatusNeedsAction || v == StatusInProcess || v == StatusCompleted || v == StatusCancelled):
+				e.Progress = strings.ToLower(v)
+			default:
+				c.lost(ppath, "STATUS %q is lost", p.Value)
+			}
+		case PropTimeTransparency:
+			e.FreeBusyStatus = "busy"
+			if strings.EqualFold(p.Value, TranspTransparent) {
+				e.FreeBusyStatus = "free"
+			}
+		case PropPriority, PropPercentComplete:
+			n, err := strconv.Atoi(p.Value)
+			if err != nil {
+				c.lost(ppath, "invalid %s %q is lost", p.Name, p.Value)
+			} else if p.Name == PropPriority {
+				e.Priority = n
+			} else {
+				e.PercentComplete = n
+			}
+		case PropDatetimeStart:
+		case PropDatetimeEnd, PropDatetimeDue:
+			t, tz, err := jsTime(&p)
+			if err != nil {
+				return nil, err
+			}
+			if !hasStart {
+				if !task {
+					c.lost(ppath, "%s without DTSTART is lost", p.Name)
+					continue
+				}
+				e.TimeZone, e.ShowWithoutTime = tz, p.IsDate()
+			}
+			if task {
+				e.Due = local(t)
+				continue
+			}
+			d := t.Sub(start)
+			if tz == e.TimeZone {
+				//a nominal duration keeps the wall clock across DST changes
+				d = naive(t).Sub(naive(start))
+			}
+			if d < 0 {
+				c.lost(ppath, "DTEND before DTSTART is lost")
+				continue
+			}
+			e.Duration = formatDuration(d)
+		case PropDuration:
+			d, err := parseDuration(p.Value)
+			if err != nil || d < 0 || !hasStart {
+				c.lost(ppath, "DURATION %q is lost", p.Value)
+				continue
+			}
+			if task {
+				e.Due = local(start.Add(d))
+			} else {
+				e.Duration = formatDuration(d)
+			}
+		case PropRecurrenceId:
+			t, _, err := jsTime(&p)
+			if err != nil {
+				return nil, err
+			}
+			e.RecurrenceID = local(t)
+		case PropRecurrenceRule:
+			r, err := ParseRecur(p.Value)
+			if err != nil {
+				c.lost(ppath, "invalid RRULE is lost: %v", err)
+				continue
+			}
+			e.RecurrenceRules = append(e.RecurrenceRules, jsRule(r, e))
+		case PropRecurrenceDatetime, PropExceptionDatetime:
+			ps, err := periodValues(&p)
+			if err != nil {
+				c.lost(ppath, "invalid %s is lost: %v", p.Name, err)
+				continue
+			}
+			if e.RecurrenceOverrides == nil {
+				e.RecurrenceOverrides = map[string]map[string]interface{}{}
+			}
+			for _, pe := range ps {
+				patch := map[string]interface{}{}
+				if p.Name == PropExceptionDatetime {
+					patch["excluded"] = true
+				} else if !pe.End.IsZero() {
+					patch["duration"] = formatDuration(pe.Duration())
+				}
+				e.RecurrenceOverrides[local(pe.Start)] = patch
+			}
+		case PropAttendee, PropOrganizer:
+			c.participant(e, &p)
+		default:
+			c.lost(ppath, "%s is lost", p.Name)
+		}
+	}
+	if !task && e.ShowWithoutTime && e.Duration == "" {
+		//a DATE start lasts one day in iCalendar,RFC 5545 3.6.1
+		e.Duration = "P1D"
+	}
+
+	alarms := 0
+	for _, sub := range com.SubComponents() {
+		if sub.Name() != CompAlarm {
+			c.lost(path+"/"+sub.Name(), "%s has no JSCalendar counterpart", sub.Name())
+			continue
+		}
+		c.alert(e, sub.obj(), fmt.Sprintf("%s/%s[%d]", path, CompAlarm, alarms))
+		alarms++
+	}
+	return e, nil
+}
+
+// params reports the parameters of p which JSCalendar does not keep
+func (c *jsConverter) params(p *Property, path string) {
+	for _, key := range sortedParamKeys(p.Params) {
+		if key == Paramvaluetypeparam || contains(jsParams[p.Name], key) {
+			continue
+		}
+		c.lost(path, "parameter %s is lost", key)
+	}
+}
+
+func jsRule(r *Recur, e *JSEntry) *JSRecurrenceRule {
+	rr := &JSRecurrenceRule{
+		Type:          "RecurrenceRule",
+		Frequency:     strings.ToLower(r.Freq),
+		Count:         r.Count,
+		ByMonthDay:    r.ByMonthDay,
+		ByYearDay:     r.ByYearDay,
+		ByWeekNo:      r.ByWeekNo,
+		ByHour:        r.ByHour,
+		ByMinute:      r.ByMinute,
+		BySecond:      r.BySecond,
+		BySetPosition: r.BySetPos,
+	}
+	if r.Interval > 1 {
+		rr.Interval = r.Interval
+	}
+	if r.WeekStart != time.Monday {
+		rr.FirstDayOfWeek = strings.ToLower(weekdayNames[r.WeekStart])
+	}
+	for _, wn := range r.ByDay {
+		rr.ByDay = append(rr.ByDay, JSNDay{Type: "NDay", Day: strings.ToLower(weekdayNames[wn.Weekday]), NthOfPeriod: wn.N})
+	}
+	for _, m := range r.ByMonth {
+		rr.ByMonth = append(rr.ByMonth, strconv.Itoa(m))
+	}
+	if !r.Until.IsZero() {
+		if r.UntilUTC {
+			rr.Until, _ = jsLocalIn(r.Until, e.TimeZone, false)
+		} else {
+			rr.Until = r.Until.Format(jsLocalFormat)
+		}
+	}
+	return rr
+}
+
+// participantID derives a stable id from an address,so the participants of
+// an override match those of its master
+func participantID(addr string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(addr)))
+	return hex.EncodeToString(sum[:6])
+}
+
+func (c *jsConverter) participant(e *JSEntry, p *Property) {
+	if e.Participants == nil {
+		e.Participants = map[string]*JSParticipant{}
+	}
+	id := participantID(p.Value)
+	pt := e.Participants[id]
+	if pt == nil {
+		pt = &JSParticipant{Type: "Participant", Roles: map[string]bool{}}
+		e.Participants[id] = pt
+	}
+	if cn := p.Params.Get(Paramcn); cn != "" {
+		pt.Name = cn
+	}
+	method := "other"
+	if strings.HasPrefix(strings.ToLower(p.Value), "mailto:") {
+		method = "imip"
+		pt.Email = p.Value[len("mailto:"):]
+	}
+	pt.SendTo = map[string]string{method: p.Value}
+	if p.Name == PropOrganizer {
+		pt.Roles["owner"] = true
+		e.ReplyTo = map[string]string{method: p.Value}
+		return
+	}
+	role := strings.ToUpper(p.Params.Get(Paramrole))
+	if role != "NON-PARTICIPANT" {
+		pt.Roles["attendee"] = true
+	}
+	if r := jsRoles[role]; r != "" {
+		pt.Roles[r] = true
+	}
+	if ps := p.Params.Get(Parampartstat); ps != "" {
+		pt.ParticipationStatus = strings.ToLower(ps)
+	}
+	pt.ExpectReply = strings.EqualFold(p.Params.Get(Paramrsvp), "TRUE")
+	if cu := p.Params.Get(Paramcutype); cu != "" && !strings.EqualFold(cu, "INDIVIDUAL") {
+		pt.Kind = strings.ToLower(cu)
+	}
+}
+
+func (c *jsConverter) alert(e *JSEntry, com *ComponentObj, path string) {
+	a := &JSAlert{Type: "Alert", Action: "display"}
+	for _, p := range com.Properties() {
+		p := p
+		ppath := path + "/" + p.Name
+		c.params(&p, ppath)
+		switch p.Name {
+		case PropAction:
+			switch strings.ToUpper(p.Value) {
+			case ActionDisplay:
+			case ActionEmail:
+				a.Action = "email"
+			default:
+				c.lost(ppath, "ACTION %q is lost,the alert displays", p.Value)
+			}
+		case PropTrigger:
+			if p.GetParamValue() == VDTdatetime {
+				t, err := p.GetToDatetime()
+				if err != nil {
+					c.lost(ppath, "invalid TRIGGER is lost: %v", err)
+					continue
+				}
+				a.Trigger = JSTrigger{Type: "AbsoluteTrigger", When: t.UTC().Format(jsUTCFormat)}
+				continue
+			}
+			d, err := parseDuration(p.Value)
+			if err != nil {
+				c.lost(ppath, "invalid TRIGGER is lost: %v", err)
+				continue
+			}
+			a.Trigger = JSTrigger{Type: "OffsetTrigger", Offset: formatDuration(d)}
+			if strings.EqualFold(p.Params.Get(Paramtrigrel), "END") {
+				a.Trigger.RelativeTo = "end"
+			}
+		case PropDescription, PropSummary:
+			//the text of an alert is the title of the event
+			if FromText(p.Value) != e.Title {
+				c.lost(ppath, "%s of the alarm is lost", p.Name)
+			}
+		default:
+			c.lost(ppath, "%s is lost", p.Name)
+		}
+	}
+	if e.Alerts == nil {
+		e.Alerts = map[string]*JSAlert{}
+	}
+	e.Alerts[strconv.Itoa(len(e.Alerts)+1)] = a
+}
+
+// jsObject returns e as a generic JSON object
+func jsObject(e *JSEntry) (map[string]interface{}, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	return m, json.Unmarshal(b, &m)
+}
+
+// jsInstance returns the instance of master at rid before any patch
+func jsInstance(master *JSEntry, rid string) (map[string]interface{}, error) {
+	m, err := jsObject(master)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range []string{"recurrenceRules", "recurrenceOverrides", "method"} {
+		delete(m, k)
+	}
+	m["start"] = rid
+	return m, nil
+}
+
+// jsPatch returns the PatchObject turning the instance rid of master into
+// override,RFC 8984 4.3.5. Patches replace whole top level properties.
+func jsPatch(master, override *JSEntry, rid string) (map[string]interface{}, error) {
+	base, err := jsInstance(master, rid)
+	if err != nil {
+		return nil, err
+	}
+	o, err := jsObject(override)
+	if err != nil {
+		return nil, err
+	}
+	patch := map[string]interface{}{}
+	for k, v := range o {
+		switch k {
+		case "@type", "uid", "recurrenceId", "recurrenceRules", "recurrenceOverrides":
+			continue
+		}
+		if !reflect.DeepEqual(base[k], v) {
+			patch[k] = v
+		}
+	}
+	for k := range base {
+		if _, ok := o[k]; !ok && k != "uid" && k != "@type" {
+			patch[k] = nil
+		}
+	}
+	return patch, nil
+}
+
+// applyJSPatch sets the paths of patch in m,a null value removes the path
+func applyJSPatch(m map[string]interface{}, patch map[string]interface{}) error {
+	for path, v := range patch {
+		keys := strings.Split(strings.TrimPrefix(path, "/"), "/")
+		obj := m
+		for _, k := range keys[:len(keys)-1] {
+			next, ok := obj[k].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("ical:JSCalendar patch path %q does not exist", path)
+			}
+			obj = next
+		}
+		last := keys[len(keys)-1]
+		if v == nil {
+			delete(obj, last)
+		} else {
+			obj[last] = v
+		}
+	}
+	return nil
+}
+
+/*
+FromJSCalendar converts a JSCalendar Group to a Calendar,the reverse of
+ToJSCalendar.
+
+Every entry gives a VEVENT or VTODO,followed by one component with
+RECURRENCE-ID for each patched instance. recurrenceOverrides which only add
+or exclude an instance become RDATE and EXDATE. Of several locations only the
+first by id is kept. No VTIMEZONE is written,TZID is the IANA name of timeZone.
+*/
+func FromJSCalendar(g *JSGroup) (*Calendar, error) {
+	cal := NewCalendar()
+	if g.ProdID != "" {
+		cal.PutProperty(newPropertyValue(PropProductIdentifier, ToText(g.ProdID)))
+	}
+	if g.UID != "" {
+		cal.AddProperty(newPropertyValue(PropUID, g.UID))
+	}
+	if g.Title != "" {
+		cal.AddProperty(newPropertyValue(PropName, ToText(g.Title)))
+	}
+	for _, e := range g.Entries {
+		if e.Method != "" && cal.GetProperty(PropMethod) == nil {
+			cal.SetMethod(strings.ToUpper(e.Method))
+		}
+		coms, err := jsComponents(e)
+		if err != nil {
+			return nil, err
+		}
+		for _, com := range coms {
+			cal.AddComponent(com)
+		}
+	}
+	return cal, nil
+}
+
+// jsComponents converts an entry to its master and overrides
+func jsComponents(e *JSEntry) ([]*ComponentObj, error) {
+	master, err := jsComponent(e, nil)
+	if err != nil {
+		return nil, err
+	}
+	coms := []*ComponentObj{master}
+	rids := make([]string, 0, len(e.RecurrenceOverrides))
+	for rid := range e.RecurrenceOverrides {
+		rids = append(rids, rid)
+	}
+	sort.Strings(rids)
+	for _, rid := range rids {
+		patch := e.RecurrenceOverrides[rid]
+		excluded, _ := patch["excluded"].(bool)
+		if len(patch) == 0 || excluded && len(patch) == 1 {
+			name := PropRecurrenceDatetime
+			if excluded {
+				name = PropExceptionDatetime
+			}
+			p := NewProperty(name)
+			if err := setJSTime(p, rid, e.TimeZone, e.ShowWithoutTime); err != nil {
+				return nil, err
+			}
+			master.AddProperty(*p)
+			continue
+		}
+		m, err := jsInstance(e, rid)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyJSPatch(m, patch); err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		o := &JSEntry{}
+		if err := json.Unmarshal(b, o); err != nil {
+			return nil, fmt.Errorf("ical:invalid JSCalendar patch of %q: %v", rid, err)
+		}
+		o.RecurrenceID = rid
+		com, err := jsComponent(o, e)
+		if err != nil {
+			return nil, err
+		}
+		coms = append(coms, com)
+	}
+	return coms, nil
+}
+
+// setJSTime sets p to the LocalDateTime local in the time zone tz
+func setJSTime(p *Property, local, tz string, date bool) error {
+	t, err := time.Parse(jsLocalFormat, local)
+	if err != nil {
+		return fmt.Errorf("ical:invalid JSCalendar LocalDateTime %q", local)
+	}
+	switch {
+	case date:
+		p.SetFromDate(t)
+	case tz == "":
+		p.UpdateParamValue(VDTdatetime)
+		p.Value = t.Format(DatetimeFormat)
+	case tz == "Etc/UTC" || tz == "UTC":
+		p.setFromUTC(t)
+	default:
+		if _, err := time.LoadLocation(tz); err != nil {
+			return err
+		}
+		p.UpdateParamValue(VDTdatetime)
+		p.Params.Set(Paramtzid, tz)
+		p.Value = t.Format(DatetimeFormat)
+	}
+	return nil
+}
+
+// jsComponent converts one entry,master is the entry an override belongs to
+func jsComponent(e *JSEntry, master *JSEntry) (*ComponentObj, error) {
+	name := CompEvent
+	switch e.Type {
+	case "Event":
+	case "Task":
+		name = CompTodo
+	default:
+		return nil, fmt.Errorf("ical:unknown JSCalendar type %q", e.Type)
+	}
+	com := &ComponentObj{NameObj: name, PropertiesObj: []Property{}, SubComponentsObj: []Component{}}
+	uid := e.UID
+	if uid == "" {
+		var err error
+		if uid, err = newUID(); err != nil {
+			return nil, err
+		}
+	}
+	com.AddProperty(newPropertyValue(PropUID, uid))
+	addTime := func(pname, local, tz string, date bool) error {
+		if local == "" {
+			return nil
+		}
+		p := NewProperty(pname)
+		if err := setJSTime(p, local, tz, date); err != nil {
+			return err
+		}
+		com.AddProperty(*p)
+		return nil
+	}
+	addUTC := func(pname, s string) error {
+		t, err := time.Parse(jsUTCFormat, s)
+		if err != nil {
+			return fmt.Errorf("ical:invalid JSCalendar UTCDateTime %q", s)
+		}
+		p := NewProperty(pname)
+		p.setFromUTC(t)
+		com.AddProperty(*p)
+		return nil
+	}
+	stamp := e.Updated
+	if stamp == "" {
+		stamp = time.Now().UTC().Format(jsUTCFormat)
+	}
+	if err := addUTC(PropDatetimeStamp, stamp); err != nil {
+		return nil, err
+	}
+	if e.Created != "" {
+		if err := addUTC(PropDatetimeCreated, e.Created); err != nil {
+			return nil, err
+		}
+	}
+	if master != nil {
+		if err := addTime(PropRecurrenceId, e.RecurrenceID, master.TimeZone, master.ShowWithoutTime); err != nil {
+			return nil, err
+		}
+	}
+	if err := addTime(PropDatetimeStart, e.Start, e.TimeZone, e.ShowWithoutTime); err != nil {
+		return nil, err
+	}
+	if name == CompEvent && e.Duration != "" && e.Start != "" {
+		d, err := parseDuration(e.Duration)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("ical:invalid JSCalendar duration %q", e.Duration)
+		}
+		start, _ := time.Parse(jsLocalFormat, e.Start)
+		if !e.ShowWithoutTime || d%(24*time.Hour) == 0 {
+			if err := addTime(PropDatetimeEnd, start.Add(d).Format(jsLocalFormat), e.TimeZone, e.ShowWithoutTime); err != nil {
+				return nil, err
+			}
+		} else {
+			com.AddProperty(newPropertyValue(PropDuration, e.Duration))
+		}
+	}
+	if name == CompTodo {
+		if err := addTime(PropDatetimeDue, e.Due, e.TimeZone, e.ShowWithoutTime); err != nil {
+			return nil, err
+		}
+	}
+	if master == nil {
+		for _, rr := range e.RecurrenceRules {
+			r, err := rr.recur(e)
+			if err != nil {
+				return nil, err
+			}
+			com.AddProperty(newPropertyValue(PropRecurrenceRule, r.String()))
+		}
+	}
+
+	if e.Sequence > 0 {
+		com.AddProperty(newPropertyValue(PropSequenceNumber, strconv.Itoa(e.Sequence)))
+	}
+	if e.Title != "" {
+		com.AddProperty(newPropertyValue(PropSummary, ToText(e.Title)))
+	}
+	if e.Description != "" {
+		com.AddProperty(newPropertyValue(PropDescription, ToText(e.Description)))
+	}
+	if len(e.Locations) > 0 {
+		ids := make([]string, 0, len(e.Locations))
+		for id := range e.Locations {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		loc := e.Locations[ids[0]]
+		if loc.Name != "" {
+			com.AddProperty(newPropertyValue(PropLocation, ToText(loc.Name)))
+		}
+		if geo := strings.TrimPrefix(loc.Coordinates, "geo:"); geo != loc.Coordinates {
+			if i := strings.IndexAny(geo, ";"); i >= 0 {
+				geo = geo[:i]
+			}
+			com.AddProperty(newPropertyValue(PropGeographicPosition, strings.Replace(geo, ",", ";", 1)))
+		}
+	}
+	if len(e.Keywords) > 0 {
+		var kws []string
+		for k, ok := range e.Keywords {
+			if ok {
+				kws = append(kws, k)
+			}
+		}
+		sort.Strings(kws)
+		p := NewProperty(PropCategories)
+		p.SetFromTextlines(kws)
+		com.AddProperty(*p)
+	}
+	if e.Color != "" {
+		com.AddProperty(newPropertyValue(PropColor, e.Color))
+	}
+	for class, privacy := range jsPrivacy {
+		if privacy == e.Privacy {
+			com.AddProperty(newPropertyValue(PropClassification, class))
+		}
+	}
+	switch status := strings.ToUpper(e.Status); {
+	case name == CompEvent && status != "":
+		com.AddProperty(newPropertyValue(PropStatus, status))
+	case name == CompTodo && e.Progress != "":
+		com.AddProperty(newPropertyValue(PropStatus, strings.ToUpper(e.Progress)))
+	}
+	switch e.FreeBusyStatus {
+	case "free":
+		com.AddProperty(newPropertyValue(PropTimeTransparency, TranspTransparent))
+	case "busy":
+		com.AddProperty(newPropertyValue(PropTimeTransparency, TranspOpaque))
+	}
+	if e.Priority > 0 {
+		com.AddProperty(newPropertyValue(PropPriority, strconv.Itoa(e.Priority)))
+	}
+	if e.PercentComplete > 0 {
+		com.AddProperty(newPropertyValue(PropPercentComplete, strconv.Itoa(e.PercentComplete)))
+	}
+
+	organizer := ""
+	ids := make([]string, 0, len(e.Participants))
+	for id := range e.Participants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		pt := e.Participants[id]
+		addr := pt.SendTo["imip"]
+		if addr == "" && pt.Email != "" {
+			addr = "mailto:" + pt.Email
+		}
+		if addr == "" {
+			addr = pt.SendTo["other"]
+		}
+		if addr == "" {
+			continue
+		}
+		if pt.Roles["owner"] {
+			p := NewProperty(PropOrganizer)
+			p.Value = addr
+			if pt.Name != "" {
+				p.Params.Set(Paramcn, pt.Name)
+			}
+			com.AddProperty(*p)
+			organizer = addr
+		}
+		if pt.Roles["owner"] && !pt.Roles["attendee"] && !pt.Roles["informational"] {
+			continue
+		}
+		p := NewProperty(PropAttendee)
+		p.Value = addr
+		if pt.Name != "" {
+			p.Params.Set(Paramcn, pt.Name)
+		}
+		for role, r := range jsRoles {
+			if pt.Roles[r] {
+				p.Params.Set(Paramrole, role)
+			}
+		}
+		if pt.ParticipationStatus != "" {
+			p.Params.Set(Parampartstat, strings.ToUpper(pt.ParticipationStatus))
+		}
+		if pt.ExpectReply {
+			p.Params.Set(Paramrsvp, "TRUE")
+		}
+		if pt.Kind != "" {
+			p.Params.Set(Paramcutype, strings.ToUpper(pt.Kind))
+		}
+		com.AddProperty(*p)
+	}
+	if organizer == "" {
+		if addr := e.ReplyTo["imip"]; addr != "" {
+			organizer = addr
+			com.AddProperty(newPropertyValue(PropOrganizer, addr))
+		}
+	}
+
+	ids = ids[:0]
+	for id := range e.Alerts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		alarm, err := jsAlarm(e.Alerts[id], e.Title, organizer)
+		if err != nil {
+			return nil, err
+		}
+		com.SubComponentsObj = append(com.SubComponentsObj, alarm)
+	}
+	return com, nil
+}
+
+func (rr *JSRecurrenceRule) recur(e *JSEntry) (*Recur, error) {
+	r := &Recur{
+		Freq:       strings.ToUpper(rr.Frequency),
+		Count:      rr.Count,
+		Interval:   rr.Interval,
+		BySecond:   rr.BySecond,
+		ByMinute:   rr.ByMinute,
+		ByHour:     rr.ByHour,
+		ByMonthDay: rr.ByMonthDay,
+		ByYearDay:  rr.ByYearDay,
+		ByWeekNo:   rr.ByWeekNo,
+		BySetPos:   rr.BySetPosition,
+		WeekStart:  time.Monday,
+	}
+	if rr.FirstDayOfWeek != "" {
+		wd, err := parseWeekday(rr.FirstDayOfWeek)
+		if err != nil {
+			return nil, err
+		}
+		r.WeekStart = wd
+	}
+	for _, nd := range rr.ByDay {
+		wd, err := parseWeekday(nd.Day)
+		if err != nil {
+			return nil, err
+		}
+		r.ByDay = append(r.ByDay, WeekdayNum{Weekday: wd, N: nd.NthOfPeriod})
+	}
+	for _, m := range rr.ByMonth {
+		n, err := strconv.Atoi(m)
+		if err != nil {
+			return nil, fmt.Errorf("ical:byMonth %q has no iCalendar counterpart", m)
+		}
+		r.ByMonth = append(r.ByMonth, n)
+	}
+	if rr.Until != "" {
+		t, err := time.Parse(jsLocalFormat, rr.Until)
+		if err != nil {
+			return nil, fmt.Errorf("ical:invalid JSCalendar LocalDateTime %q", rr.Until)
+		}
+		switch {
+		case e.ShowWithoutTime:
+			r.Until, r.UntilDate = t, true
+		case e.TimeZone == "":
+			r.Until = t
+		default:
+			loc, err := time.LoadLocation(e.TimeZone)
+			if err != nil {
+				return nil, err
+			}
+			//UNTIL is in UTC when DTSTART has a time zone,RFC 5545 3.3.10
+			r.Until, r.UntilUTC = wallClock(t, loc).UTC(), true
+		}
+	}
+	//the rule is checked like a decoded one
+	return ParseRecur(r.String())
+}
+
+func jsAlarm(a *JSAlert, title, organizer string) (*ComponentObj, error) {
+	alarm := &ComponentObj{NameObj: CompAlarm, PropertiesObj: []Property{}, SubComponentsObj: []Component{}}
+	action := ActionDisplay
+	if a.Action == "email" && organizer != "" {
+		action = ActionEmail
+	}
+	alarm.AddProperty(newPropertyValue(PropAction, action))
+	trigger := NewProperty(PropTrigger)
+	switch a.Trigger.Type {
+	case "OffsetTrigger":
+		if _, err := parseDuration(a.Trigger.Offset); err != nil {
+			return nil, err
+		}
+		trigger.Value = a.Trigger.Offset
+		if a.Trigger.RelativeTo == "end" {
+			trigger.Params.Set(Paramtrigrel, "END")
+		}
+	case "AbsoluteTrigger":
+		t, err := time.Parse(jsUTCFormat, a.Trigger.When)
+		if err != nil {
+			return nil, fmt.Errorf("ical:invalid JSCalendar UTCDateTime %q", a.Trigger.When)
+		}
+		trigger.setFromUTC(t)
+		trigger.Params.Set(Paramvaluetypeparam, VDTdatetime)
+	default:
+		return nil, fmt.Errorf("ical:unknown JSCalendar trigger %q", a.Trigger.Type)
+	}
+	alarm.AddProperty(*trigger)
+	if title == "" {
+		title = "Reminder"
+	}
+	alarm.AddProperty(newPropertyValue(PropDescription, ToText(title)))
+	if action == ActionEmail {
+		alarm.AddProperty(newPropertyValue(PropSummary, ToText(title)))
+		alarm.AddProperty(newPropertyValue(PropAttendee, organizer))
+	}
+	return alarm, nil
+}
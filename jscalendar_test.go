@@ -0,0 +1,257 @@
+package go_ical
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const jsCalendarStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+METHOD:REQUEST
+X-WR-CALNAME:Work
+BEGIN:VEVENT
+UID:weekly@example.com
+DTSTAMP:20210301T080000Z
+DTSTART;TZID=Europe/Berlin:20210301T090000
+DTEND;TZID=Europe/Berlin:20210301T093000
+RRULE:FREQ=WEEKLY;UNTIL=20210329T070000Z;BYDAY=MO
+EXDATE;TZID=Europe/Berlin:20210308T090000
+SUMMARY:Standup
+LOCATION:Room 1
+GEO:52.52;13.40
+CATEGORIES:TEAM,DAILY
+CLASS:CONFIDENTIAL
+TRANSP:OPAQUE
+ORGANIZER;CN=Boss:mailto:boss@example.com
+ATTENDEE;CN=Ann;ROLE=CHAIR;PARTSTAT=ACCEPTED:mailto:ann@example.com
+ATTENDEE;ROLE=OPT-PARTICIPANT;RSVP=TRUE;X-FOO=1:mailto:bob@example.com
+X-SECRET:keep me
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+DESCRIPTION:Standup
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+UID:weekly@example.com
+DTSTAMP:20210301T080000Z
+RECURRENCE-ID;TZID=Europe/Berlin:20210315T090000
+DTSTART;TZID=Europe/Berlin:20210315T100000
+DTEND;TZID=Europe/Berlin:20210315T103000
+SUMMARY:Standup (moved)
+LOCATION:Room 1
+GEO:52.52;13.40
+CATEGORIES:TEAM,DAILY
+CLASS:CONFIDENTIAL
+TRANSP:OPAQUE
+ORGANIZER;CN=Boss:mailto:boss@example.com
+ATTENDEE;CN=Ann;ROLE=CHAIR;PARTSTAT=ACCEPTED:mailto:ann@example.com
+ATTENDEE;ROLE=OPT-PARTICIPANT;RSVP=TRUE:mailto:bob@example.com
+END:VEVENT
+BEGIN:VTODO
+UID:todo@example.com
+DTSTAMP:20210301T080000Z
+DTSTART;VALUE=DATE:20210301
+DUE;VALUE=DATE:20210305
+STATUS:IN-PROCESS
+PERCENT-COMPLETE:40
+SUMMARY:Report
+END:VTODO
+BEGIN:VJOURNAL
+UID:journal@example.com
+DTSTAMP:20210301T080000Z
+END:VJOURNAL
+END:VCALENDAR
+`
+
+func TestToJSCalendar(t *testing.T) {
+	cal := decodeString(t, jsCalendarStr)
+	g, findings, err := ToJSCalendar(cal)
+	if err != nil {
+		t.Fatalf("ToJSCalendar() err: %v", err)
+	}
+	if len(g.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(g.Entries))
+	}
+	b, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	ann, bob := participantID("mailto:ann@example.com"), participantID("mailto:bob@example.com")
+	for _, want := range []string{
+		`"@type":"Group"`,
+		`"prodId":"-//xyz Corp//Scott WORK Calendar Version 1.0//CN"`,
+		`"@type":"Event","uid":"weekly@example.com","updated":"2021-03-01T08:00:00Z","method":"request","title":"Standup"`,
+		`"locations":{"1":{"@type":"Location","name":"Room 1","coordinates":"geo:52.52,13.40"}}`,
+		`"keywords":{"DAILY":true,"TEAM":true}`,
+		`"start":"2021-03-01T09:00:00","timeZone":"Europe/Berlin","duration":"PT30M"`,
+		`"freeBusyStatus":"busy","privacy":"secret"`,
+		`"recurrenceRules":[{"@type":"RecurrenceRule","frequency":"weekly","byDay":[{"@type":"NDay","day":"mo"}],"until":"2021-03-29T09:00:00"}]`,
+		`"2021-03-08T09:00:00":{"excluded":true}`,
+		`"2021-03-15T09:00:00":{"alerts":null,"start":"2021-03-15T10:00:00","title":"Standup (moved)"}`,
+		`"replyTo":{"imip":"mailto:boss@example.com"}`,
+		`"` + ann + `":{"@type":"Participant","name":"Ann","email":"ann@example.com","sendTo":{"imip":"mailto:ann@example.com"},"roles":{"attendee":true,"chair":true},"participationStatus":"accepted"}`,
+		`"` + bob + `":{"@type":"Participant","email":"bob@example.com","sendTo":{"imip":"mailto:bob@example.com"},"roles":{"attendee":true,"optional":true},"expectReply":true}`,
+		`"alerts":{"1":{"@type":"Alert","trigger":{"@type":"OffsetTrigger","offset":"-PT15M"},"action":"display"}}`,
+		`"@type":"Task","uid":"todo@example.com"`,
+		`"start":"2021-03-01T00:00:00","showWithoutTime":true,"due":"2021-03-05T00:00:00","progress":"in-process","percentComplete":40`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("JSCalendar does not contain %s\n%s", want, got)
+		}
+	}
+
+	lost := map[string]bool{}
+	for _, f := range findings {
+		lost[f.Path+": "+f.Message] = true
+	}
+	for _, want := range []string{
+		"VCALENDAR/X-WR-CALNAME: X-WR-CALNAME is lost",
+		"VCALENDAR/VEVENT[0]/X-SECRET: X-SECRET is lost",
+		"VCALENDAR/VEVENT[0]/ATTENDEE[1]: parameter X-FOO is lost",
+		"VCALENDAR/VJOURNAL[0]: VJOURNAL has no JSCalendar counterpart",
+	} {
+		if !lost[want] {
+			t.Errorf("missing finding %q in %v", want, findings)
+		}
+	}
+	if len(findings) != 4 {
+		t.Errorf("got %d findings, want 4: %v", len(findings), findings)
+	}
+}
+
+func TestFromJSCalendar(t *testing.T) {
+	cal := decodeString(t, jsCalendarStr)
+	g, _, err := ToJSCalendar(cal)
+	if err != nil {
+		t.Fatalf("ToJSCalendar() err: %v", err)
+	}
+	b, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseJSCalendar(b)
+	if err != nil {
+		t.Fatalf("ParseJSCalendar() err: %v", err)
+	}
+	back, err := FromJSCalendar(parsed)
+	if err != nil {
+		t.Fatalf("FromJSCalendar() err: %v", err)
+	}
+	//no VTIMEZONE is written,TZID names an IANA zone
+	for _, f := range Validate(*back) {
+		if !strings.Contains(f.Message, "no VTIMEZONE") {
+			t.Errorf("Validate() finding %v", f)
+		}
+	}
+
+	coms := back.SubComponents()
+	if len(coms) != 3 {
+		t.Fatalf("got %d components, want 3", len(coms))
+	}
+	master, override, todo := coms[0].obj(), coms[1].obj(), coms[2].obj()
+	for _, c := range []struct {
+		com  *ComponentObj
+		name string
+		want string
+	}{
+		{master, PropDatetimeStart, "20210301T090000"},
+		{master, PropDatetimeEnd, "20210301T093000"},
+		{master, PropRecurrenceRule, "FREQ=WEEKLY;UNTIL=20210329T070000Z;BYDAY=MO"},
+		{master, PropExceptionDatetime, "20210308T090000"},
+		{master, PropSummary, "Standup"},
+		{master, PropGeographicPosition, "52.52;13.40"},
+		{master, PropClassification, "CONFIDENTIAL"},
+		{master, PropOrganizer, "mailto:boss@example.com"},
+		{override, PropRecurrenceId, "20210315T090000"},
+		{override, PropDatetimeStart, "20210315T100000"},
+		{override, PropDatetimeEnd, "20210315T103000"},
+		{override, PropSummary, "Standup (moved)"},
+		{todo, PropDatetimeDue, "20210305"},
+		{todo, PropStatus, StatusInProcess},
+	} {
+		p := c.com.GetProperty(c.name)
+		if p == nil || p.Value != c.want {
+			t.Errorf("%s %s = %v, want %s", c.com.Name(), c.name, p, c.want)
+		}
+	}
+	if got := master.GetProperty(PropDatetimeStart).Params.Get(Paramtzid); got != "Europe/Berlin" {
+		t.Errorf("DTSTART TZID = %q", got)
+	}
+	if got := master.GetProperty(PropRecurrenceRule); got.Value != cal.SubComponents()[0].obj().GetProperty(PropRecurrenceRule).Value {
+		t.Errorf("RRULE = %s", got.Value)
+	}
+	attendees := master.GetProperties(PropAttendee)
+	if len(attendees) != 2 {
+		t.Fatalf("got %d attendees, want 2", len(attendees))
+	}
+	for _, a := range attendees {
+		switch a.Value {
+		case "mailto:ann@example.com":
+			if a.Params.Get(Paramrole) != "CHAIR" || a.Params.Get(Parampartstat) != PartstatAccepted || a.Params.Get(Paramcn) != "Ann" {
+				t.Errorf("ATTENDEE = %v", a)
+			}
+		case "mailto:bob@example.com":
+			if a.Params.Get(Paramrole) != "OPT-PARTICIPANT" || a.Params.Get(Paramrsvp) != "TRUE" {
+				t.Errorf("ATTENDEE = %v", a)
+			}
+		default:
+			t.Errorf("unexpected ATTENDEE %v", a)
+		}
+	}
+	alarms := master.SubComponents()
+	if len(alarms) != 1 || alarms[0].obj().GetProperty(PropTrigger).Value != "-PT15M" {
+		t.Errorf("VALARM = %v", alarms)
+	}
+}
+
+func TestParseJSCalendar(t *testing.T) {
+	g, err := ParseJSCalendar([]byte(`{"@type":"Event","uid":"a@example.com","start":"2021-03-01T09:00:00","timeZone":"Etc/UTC","duration":"PT1H",
+		"recurrenceOverrides":{"2021-03-02T09:00:00":{},"2021-03-03T09:00:00":{"locations/1/name":"Room 2"}},
+		"locations":{"1":{"@type":"Location","name":"Room 1"}},
+		"alerts":{"a":{"@type":"Alert","trigger":{"@type":"AbsoluteTrigger","when":"2021-03-01T08:00:00Z"},"action":"email"}}}`))
+	if err != nil {
+		t.Fatalf("ParseJSCalendar() err: %v", err)
+	}
+	cal, err := FromJSCalendar(g)
+	if err != nil {
+		t.Fatalf("FromJSCalendar() err: %v", err)
+	}
+	coms := cal.SubComponents()
+	if len(coms) != 2 {
+		t.Fatalf("got %d components, want 2", len(coms))
+	}
+	master := coms[0].obj()
+	if p := master.GetProperty(PropDatetimeStart); p.Value != "20210301T090000Z" {
+		t.Errorf("DTSTART = %s", p.Value)
+	}
+	if p := master.GetProperty(PropRecurrenceDatetime); p == nil || p.Value != "20210302T090000Z" {
+		t.Errorf("RDATE = %v", p)
+	}
+	if p := coms[1].obj().GetProperty(PropLocation); p == nil || p.Value != "Room 2" {
+		t.Errorf("override LOCATION = %v", p)
+	}
+	//an email alert needs an address,without organizer it displays
+	alarm := master.SubComponents()[0].obj()
+	if alarm.GetProperty(PropAction).Value != ActionDisplay || alarm.GetProperty(PropTrigger).Value != "20210301T080000Z" {
+		t.Errorf("VALARM = %v", alarm.Properties())
+	}
+
+	for _, s := range []string{
+		`{"@type":"Note"}`,
+		`{"@type":"Group","entries":[{"@type":"Event","start":"2021-03-01"}]}`,
+		`{"@type":"Event","start":"2021-03-01T09:00:00","recurrenceRules":[{"@type":"RecurrenceRule","frequency":"sometimes"}]}`,
+		`{"@type":"Event","start":"2021-03-01T09:00:00","recurrenceOverrides":{"2021-03-02T09:00:00":{"locations/1/name":"x"}}}`,
+	} {
+		g, err := ParseJSCalendar([]byte(s))
+		if err == nil {
+			_, err = FromJSCalendar(g)
+		}
+		if err == nil {
+			t.Errorf("%s err = nil", s)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package go_ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MergeWinner tells which side a conflict was resolved for
+type MergeWinner int
+
+const (
+	//MergeUndecided is a conflict SEQUENCE,LAST-MODIFIED and DTSTAMP do not
+	//decide,the local side is kept
+	MergeUndecided MergeWinner = iota
+	MergeLocal
+	MergeRemote
+)
+
+func (w MergeWinner) String() string {
+	switch w {
+	case MergeLocal:
+		return "local"
+	case MergeRemote:
+		return "remote"
+	}
+	return "undecided"
+}
+
+/*
+MergeConflict is a property both sides changed differently,or a component
+one side deleted and the other changed.
+
+Path leads from the merged component to the component of the conflict.
+Name is the property,empty when the conflict is about the component at the
+end of Path. Base,Local and Remote are the versions of the property,nil
+where it is missing.
+*/
+type MergeConflict struct {
+	Path   []ComponentKey
+	Name   string
+	Base   *Property
+	Local  *Property
+	Remote *Property
+	Winner MergeWinner
+}
+
+func (c MergeConflict) String() string {
+	var path []string
+	for _, k := range c.Path {
+		path = append(path, k.String())
+	}
+	s := strings.Join(path, "/")
+	if c.Name != "" {
+		s += ": " + c.Name
+	}
+	value := func(p *Property) string {
+		if p == nil {
+			return "none"
+		}
+		return p.Value
+	}
+	return fmt.Sprintf("%s: base %s, local %s, remote %s, %s wins",
+		s, value(c.Base), value(c.Local), value(c.Remote), c.Winner)
+}
+
+// mergeStamps are the properties which decide conflicts,they are not merged
+// but take the higher SEQUENCE and the later times
+var mergeStamps = []string{PropSequenceNumber, PropLastModified, PropDatetimeStamp}
+
+/*
+MergeComponents merges the changes local and remote made to their common
+ancestor base,which is nil when both sides created the component.
+
+Properties are merged one by one,and for properties which may occur more
+than once,such as ATTENDEE and CATEGORIES,value by value,so PARTSTAT
+changes of different attendees do not conflict. Within a property the value
+and each parameter are merged on their own. Sub-components are matched like
+DiffComponents does,a calendar merges its components by UID and
+RECURRENCE-ID.
+
+When both sides changed the same thing differently,the side with the higher
+SEQUENCE,then the later LAST-MODIFIED,then the later DTSTAMP wins,and the
+conflict is returned. A component deleted on one side and changed on the
+other is kept. The merged component gets the higher SEQUENCE and the later
+LAST-MODIFIED and DTSTAMP of both sides. The inputs are not modified.
+*/
+func MergeComponents(base, local, remote Component) (*ComponentObj, []MergeConflict, error) {
+	l, r := local.obj(), remote.obj()
+	if l.Name() != r.Name() {
+		return nil, nil, fmt.Errorf("ical:cannot merge %s with %s", l.Name(), r.Name())
+	}
+	lk, _ := diffKey(l)
+	rk, _ := diffKey(r)
+	if lk != rk {
+		return nil, nil, fmt.Errorf("ical:cannot merge %s with %s", lk, rk)
+	}
+	b := &ComponentObj{NameObj: l.NameObj}
+	if base != nil {
+		b = base.obj()
+		if bk, _ := diffKey(b); bk != lk {
+			return nil, nil, fmt.Errorf("ical:cannot merge %s into %s", lk, bk)
+		}
+	}
+	m := &merger{}
+	return m.component(nil, b, l, r), m.conflicts, nil
+}
+
+type merger struct {
+	conflicts []MergeConflict
+}
+
+func (m *merger) component(path []ComponentKey, base, local, remote *ComponentObj) *ComponentObj {
+	k, _ := diffKey(local)
+	path = append(path[:len(path):len(path)], k)
+	winner := mergeWinner(local, remote)
+	merged := &ComponentObj{NameObj: local.NameObj}
+	merged.PropertiesObj = m.properties(path, winner, base.PropertiesObj, local.PropertiesObj, remote.PropertiesObj)
+
+	baseOrder, baseCom := componentIDs(base.SubComponentsObj)
+	localOrder, localCom := componentIDs(local.SubComponentsObj)
+	remoteOrder, remoteCom := componentIDs(remote.SubComponentsObj)
+	for _, id := range mergeOrder(localOrder, remoteOrder, baseOrder) {
+		b, l, r := baseCom[id], localCom[id], remoteCom[id]
+		var sub *ComponentObj
+		switch {
+		case l != nil && r != nil:
+			if b == nil {
+				b = &ComponentObj{NameObj: l.NameObj}
+			}
+			sub = m.component(path, b, l, r)
+		case b == nil:
+			//added on one side
+			sub = l
+			if sub == nil {
+				sub = r
+			}
+			sub = sub.Clone()
+		case l == nil && r == nil:
+		default:
+			//deleted on one side,kept when the other changed it
+			kept, w := l, MergeLocal
+			if kept == nil {
+				kept, w = r, MergeRemote
+			}
+			if DiffComponents(b, kept) == nil {
+				continue
+			}
+			k, _ := diffKey(kept)
+			m.conflicts = append(m.conflicts, MergeConflict{Path: append(path[:len(path):len(path)], k), Winner: w})
+			sub = kept.Clone()
+		}
+		if sub != nil {
+			merged.SubComponentsObj = append(merged.SubComponentsObj, sub)
+		}
+	}
+	return merged
+}
+
+// mergeOrder returns the ids of local,then those only remote has,then
+// those only base has
+func mergeOrder(local, remote, base []string) []string {
+	seen := map[string]bool{}
+	var order []string
+	for _, ids := range [][]string{local, remote, base} {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				order = append(order, id)
+			}
+		}
+	}
+	return order
+}
+
+// mergeWinner compares SEQUENCE,LAST-MODIFIED and DTSTAMP of both sides
+func mergeWinner(local, remote *ComponentObj) MergeWinner {
+	seq := func(com *ComponentObj) int {
+		if p := com.GetProperty(PropSequenceNumber); p != nil {
+			n, _ := p.GetToInt()
+			return n
+		}
+		return 0
+	}
+	if l, r := seq(local), seq(remote); l != r {
+		if l > r {
+			return MergeLocal
+		}
+		return MergeRemote
+	}
+	for _, name := range []string{PropLastModified, PropDatetimeStamp} {
+		l, r := stampTime(local, name), stampTime(remote, name)
+		if l.After(r) {
+			return MergeLocal
+		}
+		if r.After(l) {
+			return MergeRemote
+		}
+	}
+	return MergeUndecided
+}
+
+func stampTime(com *ComponentObj, name string) time.Time {
+	if p := com.GetProperty(name); p != nil {
+		if t, err := p.GetToTime(); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// mergeSlot is a property,or a value of a property which may occur more
+// than once,in the three versions
+type mergeSlot struct {
+	base, local, remote *Property
+}
+
+// propertySlots returns the slot ids of ps in order and the property of each
+func propertySlots(ps []Property) ([]string, map[string]*Property) {
+	var order []string
+	byID := map[string]*Property{}
+	for _, p := range splitListProperties(ps) {
+		p := p
+		first := p.Name
+		if contains(multiProperties, p.Name) {
+			first += ":" + propertyIdentity(p)
+		}
+		id := first
+		for n := 1; byID[id] != nil; n++ {
+			id = fmt.Sprintf("%s#%d", first, n)
+		}
+		order = append(order, id)
+		byID[id] = &p
+	}
+	return order, byID
+}
+
+func (m *merger) properties(path []ComponentKey, winner MergeWinner, base, local, remote []Property) []Property {
+	baseOrder, baseProp := propertySlots(base)
+	localOrder, localProp := propertySlots(local)
+	remoteOrder, remoteProp := propertySlots(remote)
+
+	var merged []Property
+	for _, id := range mergeOrder(localOrder, remoteOrder, baseOrder) {
+		s := mergeSlot{baseProp[id], localProp[id], remoteProp[id]}
+		var p *Property
+		if name := slotName(s); contains(mergeStamps, name) {
+			p = mergeStamp(name, s.local, s.remote)
+		} else {
+			p = m.slot(path, winner, s)
+		}
+		if p != nil {
+			merged = append(merged, p.Clone())
+		}
+	}
+	return joinListProperties(merged)
+}
+
+func slotName(s mergeSlot) string {
+	for _, p := range []*Property{s.base, s.local, s.remote} {
+		if p != nil {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// mergeStamp takes the higher SEQUENCE or the later time
+func mergeStamp(name string, local, remote *Property) *Property {
+	if local == nil || remote == nil {
+		if local == nil {
+			return remote
+		}
+		return local
+	}
+	if name == PropSequenceNumber {
+		l, _ := local.GetToInt()
+		r, _ := remote.GetToInt()
+		if r > l {
+			return remote
+		}
+		return local
+	}
+	l, lerr := local.GetToTime()
+	r, rerr := remote.GetToTime()
+	if lerr == nil && rerr == nil && r.After(l) {
+		return remote
+	}
+	return local
+}
+
+// slot merges the versions of a property,nil when it is deleted
+func (m *merger) slot(path []ComponentKey, winner MergeWinner, s mergeSlot) *Property {
+	switch {
+	case propertyEqual(s.local, s.remote) || propertyEqual(s.base, s.remote):
+		return s.local
+	case propertyEqual(s.base, s.local):
+		return s.remote
+	}
+	conflict := MergeConflict{Path: path, Name: slotName(s), Base: s.base, Local: s.local, Remote: s.remote, Winner: winner}
+	if s.local == nil || s.remote == nil {
+		//deleted on one side,changed on the other
+		m.conflicts = append(m.conflicts, conflict)
+		if winner == MergeRemote {
+			return s.remote
+		}
+		return s.local
+	}
+
+	//both changed,merge the value and each parameter on their own
+	base := s.base
+	if base == nil {
+		base = &Property{Params: Parameters{}}
+	}
+	merged := s.local.Clone()
+	conflicted := false
+	pick := func(l, r interface{}) interface{} {
+		conflicted = true
+		if winner == MergeRemote {
+			return r
+		}
+		return l
+	}
+	switch {
+	case s.local.Value == s.remote.Value || base.Value == s.remote.Value:
+	case base.Value == s.local.Value:
+		merged.Value = s.remote.Value
+	default:
+		merged.Value = pick(s.local.Value, s.remote.Value).(string)
+	}
+	for _, k := range sortedParamNames(s.local.Params, s.remote.Params) {
+		l, r := s.local.Params[k], s.remote.Params[k]
+		v := l
+		switch {
+		case equalParam(s.local.Params, s.remote.Params, k) || equalParam(base.Params, s.remote.Params, k):
+		case equalParam(base.Params, s.local.Params, k):
+			v = r
+		default:
+			v = pick(l, r).([]string)
+		}
+		if v == nil {
+			delete(merged.Params, k)
+		} else {
+			merged.Params[k] = append([]string(nil), v...)
+		}
+	}
+	if conflicted {
+		m.conflicts = append(m.conflicts, conflict)
+	}
+	return &merged
+}
+
+// equalParam reports whether the parameter k is the same in a and b,a
+// missing parameter only equals a missing one
+func equalParam(a, b Parameters, k string) bool {
+	av, aok := a[k]
+	bv, bok := b[k]
+	return aok == bok && equalStrings(av, bv)
+}
+
+// propertyEqual reports whether a and b have the same value and parameters,
+// nil only equals nil
+func propertyEqual(a, b *Property) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.Value != b.Value {
+		return false
+	}
+	for _, k := range sortedParamNames(a.Params, b.Params) {
+		if !equalParam(a.Params, b.Params, k) {
+			return false
+		}
+	}
+	return true
+}
+
+// joinListProperties joins the values of list properties with the same
+// parameters into one property again
+func joinListProperties(ps []Property) []Property {
+	var joined []Property
+	for _, p := range ps {
+		if contains(listProperties, p.Name) {
+			i := len(joined) - 1
+			for ; i >= 0; i-- {
+				q := &joined[i]
+				if q.Name == p.Name && propertyEqual(&Property{Params: q.Params}, &Property{Params: p.Params}) {
+					q.Value += "," + p.Value
+					break
+				}
+			}
+			if i >= 0 {
+				continue
+			}
+		}
+		joined = append(joined, p)
+	}
+	return joined
+}
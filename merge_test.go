@@ -0,0 +1,176 @@
+package go_ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const mergeBaseStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+SEQUENCE:1
+DTSTART:20210301T090000Z
+SUMMARY:Standup
+LOCATION:Room 1
+CATEGORIES:WORK,DAILY
+ATTENDEE;PARTSTAT=NEEDS-ACTION:mailto:ann@example.com
+ATTENDEE;PARTSTAT=NEEDS-ACTION:mailto:bob@example.com
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+DESCRIPTION:Standup
+END:VALARM
+END:VEVENT
+END:VCALENDAR
+`
+
+// mergeEdit returns the event of mergeBaseStr after the replacements
+func mergeEdit(t *testing.T, oldnew ...string) *ComponentObj {
+	t.Helper()
+	cal := decodeString(t, strings.NewReplacer(oldnew...).Replace(mergeBaseStr))
+	return cal.SubComponents()[0].obj()
+}
+
+func encodeComponent(t *testing.T, com Component) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(com); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Replace(buf.String(), "\r\n", "\n", -1)
+}
+
+func TestMergeComponents(t *testing.T) {
+	base := mergeEdit(t)
+	local := mergeEdit(t,
+		"SUMMARY:Standup", "SUMMARY:Team Standup",
+		"PARTSTAT=NEEDS-ACTION:mailto:ann", "PARTSTAT=ACCEPTED:mailto:ann",
+		"CATEGORIES:WORK,DAILY", "CATEGORIES:WORK,DAILY,TEAM",
+		"DTSTAMP:20210301T080000Z", "DTSTAMP:20210302T080000Z")
+	remote := mergeEdit(t,
+		"LOCATION:Room 1", "LOCATION:Room 2",
+		"PARTSTAT=NEEDS-ACTION:mailto:bob", "PARTSTAT=DECLINED:mailto:bob",
+		"CATEGORIES:WORK,DAILY", "CATEGORIES:WORK",
+		"TRIGGER:-PT15M", "TRIGGER:-PT5M",
+		"ATTENDEE;PARTSTAT=NEEDS-ACTION:mailto:bob@example.com", "ATTENDEE;PARTSTAT=DECLINED:mailto:bob@example.com\nATTENDEE:mailto:carl@example.com")
+
+	merged, conflicts, err := MergeComponents(base, local, remote)
+	if err != nil {
+		t.Fatalf("MergeComponents() err: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("conflicts = %v", conflicts)
+	}
+	want := `BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210302T080000Z
+SEQUENCE:1
+DTSTART:20210301T090000Z
+SUMMARY:Team Standup
+LOCATION:Room 2
+CATEGORIES:WORK,TEAM
+ATTENDEE;PARTSTAT=ACCEPTED:mailto:ann@example.com
+ATTENDEE;PARTSTAT=DECLINED:mailto:bob@example.com
+ATTENDEE:mailto:carl@example.com
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT5M
+DESCRIPTION:Standup
+END:VALARM
+END:VEVENT
+`
+	if got := encodeComponent(t, merged); got != want {
+		t.Errorf("merged =\n%s\nwant\n%s", got, want)
+	}
+	if got := base.GetProperty(PropSummary).Value; got != "Standup" {
+		t.Errorf("base was modified: SUMMARY = %s", got)
+	}
+}
+
+func TestMergeComponentsConflicts(t *testing.T) {
+	base := mergeEdit(t)
+	//remote has the higher SEQUENCE and wins,the merge takes it
+	local := mergeEdit(t,
+		"SUMMARY:Standup", "SUMMARY:Local standup",
+		"PARTSTAT=NEEDS-ACTION:mailto:ann", "PARTSTAT=ACCEPTED;ROLE=CHAIR:mailto:ann",
+		"LOCATION:Room 1\n", "",
+		"DTSTAMP:20210301T080000Z", "DTSTAMP:20210305T080000Z")
+	remote := mergeEdit(t,
+		"SUMMARY:Standup", "SUMMARY:Remote standup",
+		"PARTSTAT=NEEDS-ACTION:mailto:ann", "PARTSTAT=TENTATIVE:mailto:ann",
+		"LOCATION:Room 1", "LOCATION:Room 3",
+		"SEQUENCE:1", "SEQUENCE:2",
+		"BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER:-PT15M\nDESCRIPTION:Standup\nEND:VALARM\n", "")
+	merged, conflicts, err := MergeComponents(base, local, remote)
+	if err != nil {
+		t.Fatalf("MergeComponents() err: %v", err)
+	}
+	var got []string
+	for _, c := range conflicts {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"VEVENT standup@example.com: SUMMARY: base Standup, local Local standup, remote Remote standup, remote wins",
+		"VEVENT standup@example.com: ATTENDEE: base mailto:ann@example.com, local mailto:ann@example.com, remote mailto:ann@example.com, remote wins",
+		"VEVENT standup@example.com: LOCATION: base Room 1, local none, remote Room 3, remote wins",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("conflicts =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if merged.GetProperty(PropSummary).Value != "Remote standup" || merged.GetProperty(PropLocation).Value != "Room 3" {
+		t.Errorf("merged =\n%s", encodeComponent(t, merged))
+	}
+	//ROLE was only set locally and is kept,PARTSTAT conflicts
+	ann := merged.GetProperties(PropAttendee)[0]
+	if ann.Params.Get(Parampartstat) != "TENTATIVE" || ann.Params.Get(Paramrole) != "CHAIR" {
+		t.Errorf("ATTENDEE = %+v", ann)
+	}
+	//the stamps are the highest of both sides,the unchanged alarm is deleted
+	if merged.GetProperty(PropSequenceNumber).Value != "2" || merged.GetProperty(PropDatetimeStamp).Value != "20210305T080000Z" ||
+		len(merged.SubComponents()) != 0 {
+		t.Errorf("merged =\n%s", encodeComponent(t, merged))
+	}
+
+	//without SEQUENCE,LAST-MODIFIED or DTSTAMP to decide local is kept
+	local = mergeEdit(t, "TRIGGER:-PT15M", "TRIGGER:-PT10M")
+	remote = mergeEdit(t, "TRIGGER:-PT15M", "TRIGGER:-PT30M")
+	merged, conflicts, _ = MergeComponents(base, local, remote)
+	if len(conflicts) != 1 || conflicts[0].Winner != MergeUndecided || len(conflicts[0].Path) != 2 ||
+		conflicts[0].Path[1].Name != CompAlarm || merged.SubComponents()[0].obj().GetProperty(PropTrigger).Value != "-PT10M" {
+		t.Errorf("conflicts = %v", conflicts)
+	}
+
+	//a changed alarm deleted on the other side is kept
+	remote = mergeEdit(t, "BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER:-PT15M\nDESCRIPTION:Standup\nEND:VALARM\n", "")
+	merged, conflicts, _ = MergeComponents(base, local, remote)
+	if len(conflicts) != 1 || conflicts[0].Name != "" || conflicts[0].Winner != MergeLocal || len(merged.SubComponents()) != 1 {
+		t.Errorf("conflicts = %v", conflicts)
+	}
+}
+
+func TestMergeCalendars(t *testing.T) {
+	base := decodeString(t, mergeBaseStr)
+	local := decodeString(t, strings.Replace(mergeBaseStr, "END:VCALENDAR", `BEGIN:VTODO
+UID:review@example.com
+DTSTAMP:20210301T080000Z
+SUMMARY:Review
+END:VTODO
+END:VCALENDAR`, 1))
+	remote := decodeString(t, strings.Replace(mergeBaseStr, "SUMMARY:Standup", "SUMMARY:Daily", 1))
+	merged, conflicts, err := MergeComponents(&base, &local, &remote)
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("MergeComponents() = %v, %v", conflicts, err)
+	}
+	if subs := merged.SubComponents(); len(subs) != 2 || subs[0].obj().GetProperty(PropSummary).Value != "Daily" || subs[1].Name() != CompTodo {
+		t.Errorf("merged =\n%s", encodeComponent(t, merged))
+	}
+
+	other := decodeString(t, strings.Replace(mergeBaseStr, "UID:standup@", "UID:other@", 1))
+	if _, _, err := MergeComponents(nil, base.SubComponents()[0], other.SubComponents()[0]); err == nil {
+		t.Error("MergeComponents() of different UIDs err = nil")
+	}
+}
@@ -0,0 +1,562 @@
+package go_ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+The patch format is modelled on draft-daboo-icalendar-vpatch. A VPATCH holds
+PATCH components,each changes the component PATCH-TARGET points to:
+
+	BEGIN:PATCH
+	PATCH-TARGET:/VCALENDAR/VEVENT[UID=standup@example.com]
+	PATCH-DELETE:#CATEGORIES[=WORK]
+	PATCH-DELETE:/VALARM[0]
+	PATCH-PARAMETER;PARTSTAT=ACCEPTED:#ATTENDEE[=mailto:ann@example.com]
+	SUMMARY:Team Standup
+	END:PATCH
+
+A component path starts at /VCALENDAR,each step names a sub-component by
+[UID=...] with an optional [RID=...],a VTIMEZONE by [TZID=...] and a
+component without UID by its position among those of the same name. A step
+without selector needs a single component of that name. A %, /, [ or ] in a
+UID,TZID or RID is percent-encoded,so [UID=a/b] is written [UID=a%2Fb].
+
+A property path is #NAME,selecting all properties of the name,#NAME[=value]
+selecting those with the value,and either followed by ;PARAM for a
+parameter. For list properties such as CATEGORIES the value is one of the
+list.
+
+PATCH-DELETE paths are relative to the target,PATCH-PARAMETER sets its
+parameters on the properties of its path. The other properties of a PATCH
+replace those of the same name in the target,for properties which may occur
+more than once,such as ATTENDEE,only those of the same value,others are
+added. Sub-components of a PATCH are added,or replace the sub-component of
+the same UID and RECURRENCE-ID.
+*/
+const (
+	CompPatch          = "VPATCH"
+	CompPatchItem      = "PATCH"
+	PropPatchVersion   = "PATCH-VERSION"
+	PropPatchTarget    = "PATCH-TARGET"
+	PropPatchDelete    = "PATCH-DELETE"
+	PropPatchParameter = "PATCH-PARAMETER"
+)
+
+// NewPatch returns a VPATCH with the changes of d,applied to the old
+// calendar of d it gives the new one. now is its DTSTAMP.
+func NewPatch(d *CalendarDiff, now time.Time) (*ComponentObj, error) {
+	uid, err := newUID()
+	if err != nil {
+		return nil, err
+	}
+	vpatch := &ComponentObj{NameObj: CompPatch}
+	vpatch.AddProperty(newPropertyValue(PropUID, uid))
+	vpatch.AddProperty(newPropertyValue(PropDatetimeStamp, now.UTC().Format(DatetimeFormat2)))
+	vpatch.AddProperty(newPropertyValue(PropPatchVersion, "1"))
+
+	root := ComponentDiff{Kind: DiffModified, Key: ComponentKey{Name: CompCalendar}, Old: d.Old, New: d.New,
+		Properties: d.Properties, Components: d.Components}
+	vpatch.SubComponentsObj = patchItems("/"+CompCalendar, root, nil)
+	return vpatch, nil
+}
+
+// patchItems returns the PATCHes of the modified component d,those of its
+// sub-components first as they address them by their old position
+func patchItems(path string, d ComponentDiff, items []Component) []Component {
+	item := &ComponentObj{NameObj: CompPatchItem}
+	item.AddProperty(newPropertyValue(PropPatchTarget, path))
+	patchProperties(item, d.Properties, d.New)
+	for _, sub := range d.Components {
+		switch sub.Kind {
+		case DiffAdded:
+			item.SubComponentsObj = append(item.SubComponentsObj, sub.New.Clone())
+		case DiffRemoved:
+			item.AddProperty(newPropertyValue(PropPatchDelete, "/"+patchStep(sub.Old, d.Old)))
+		default:
+			items = patchItems(path+"/"+patchStep(sub.Old, d.Old), sub, items)
+		}
+	}
+	if len(item.PropertiesObj) > 1 || len(item.SubComponentsObj) > 0 {
+		items = append(items, item)
+	}
+	return items
+}
+
+// patchStep returns the path step of com among the sub-components of
+// parent
+func patchStep(com, parent *ComponentObj) string {
+	k, ok := diffKey(com)
+	switch {
+	case k.Name == CompTimezone && ok:
+		return fmt.Sprintf("%s[TZID=%s]", k.Name, selectorEscaper.Replace(k.UID))
+	case ok && k.RecurrenceID != "":
+		return fmt.Sprintf("%s[UID=%s][RID=%s]", k.Name, selectorEscaper.Replace(k.UID), selectorEscaper.Replace(k.RecurrenceID))
+	case ok:
+		return fmt.Sprintf("%s[UID=%s]", k.Name, selectorEscaper.Replace(k.UID))
+	}
+	n := 0
+	if parent != nil {
+		for _, sub := range parent.SubComponentsObj {
+			if sub.obj() == com {
+				break
+			}
+			if _, keyed := diffKey(sub.obj()); !keyed && sub.Name() == com.Name() {
+				n++
+			}
+		}
+	}
+	return fmt.Sprintf("%s[%d]", k.Name, n)
+}
+
+func patchProperties(item *ComponentObj, changes []PropertyChange, new *ComponentObj) {
+	done := map[string]bool{}
+	for _, c := range changes {
+		if contains(multiProperties, c.Name) {
+			patchMultiProperty(item, c)
+			continue
+		}
+		//the other properties are replaced as a whole
+		if done[c.Name] {
+			continue
+		}
+		done[c.Name] = true
+		ps := new.GetProperties(c.Name)
+		if len(ps) == 0 {
+			item.AddProperty(newPropertyValue(PropPatchDelete, "#"+c.Name))
+		}
+		for _, p := range ps {
+			item.AddProperty(p.Clone())
+		}
+	}
+}
+
+func patchMultiProperty(item *ComponentObj, c PropertyChange) {
+	switch {
+	case c.Kind == DiffAdded:
+		item.AddProperty(c.New.Clone())
+	case c.Kind == DiffRemoved:
+		item.AddProperty(newPropertyValue(PropPatchDelete, propertyPath(c.Old)))
+	case c.Old.Value != c.New.Value || contains(listProperties, c.Name):
+		item.AddProperty(newPropertyValue(PropPatchDelete, propertyPath(c.Old)))
+		item.AddProperty(c.New.Clone())
+	default:
+		set := NewProperty(PropPatchParameter)
+		set.Value = propertyPath(c.Old)
+		for _, pc := range c.Params {
+			if pc.New == nil {
+				item.AddProperty(newPropertyValue(PropPatchDelete, set.Value+";"+pc.Name))
+			} else {
+				set.Params[pc.Name] = append([]string(nil), pc.New...)
+			}
+		}
+		if len(set.Params) > 0 {
+			item.AddProperty(*set)
+		}
+	}
+}
+
+func propertyPath(p *Property) string {
+	return "#" + p.Name + "[=" + p.Value + "]"
+}
+
+/*
+ApplyPatch applies the PATCHes of patch,a VPATCH or a calendar holding
+VPATCHes,to cal in order.
+
+A PATCH-TARGET or PATCH-DELETE path which selects nothing is an error,and
+so is a PATCH-PARAMETER without property. cal is only changed when all the
+PATCHes apply.
+*/
+func ApplyPatch(cal *Calendar, patch Component) error {
+	var vpatches []*ComponentObj
+	switch p := patch.obj(); p.Name() {
+	case CompPatch:
+		vpatches = append(vpatches, p)
+	case CompCalendar:
+		for _, sub := range p.SubComponentsObj {
+			if sub.Name() == CompPatch {
+				vpatches = append(vpatches, sub.obj())
+			}
+		}
+	default:
+		return fmt.Errorf("ical:%s is no %s", p.Name(), CompPatch)
+	}
+
+	work := cal.ComponentObj.Clone()
+	for _, vp := range vpatches {
+		for _, item := range vp.SubComponentsObj {
+			if item.Name() != CompPatchItem {
+				continue
+			}
+			if err := applyPatchItem(work, item.obj()); err != nil {
+				return err
+			}
+		}
+	}
+	cal.ComponentObj = *work
+	return nil
+}
+
+func applyPatchItem(root, item *ComponentObj) error {
+	target := item.GetProperty(PropPatchTarget)
+	if target == nil {
+		return fmt.Errorf("ical:%s without %s", CompPatchItem, PropPatchTarget)
+	}
+	steps, err := parsePatchPath(target.Value)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 || steps[0].name != CompCalendar || steps[0].selector != "" {
+		return fmt.Errorf("ical:%s %s does not start at /%s", PropPatchTarget, target.Value, CompCalendar)
+	}
+	com, _, err := findPatchComponent(root, steps[1:])
+	if err != nil {
+		return fmt.Errorf("ical:%s %s: %v", PropPatchTarget, target.Value, err)
+	}
+
+	//resolve all deletions first,positions refer to the unpatched target
+	var deletes []func()
+	for _, del := range item.GetProperties(PropPatchDelete) {
+		del := del.Value
+		var f func()
+		if strings.HasPrefix(del, "/") {
+			f, err = componentDeletion(com, del)
+		} else {
+			f, err = propertyDeletion(com, del)
+		}
+		if err != nil {
+			return fmt.Errorf("ical:%s %s in %s: %v", PropPatchDelete, del, target.Value, err)
+		}
+		deletes = append(deletes, f)
+	}
+	for _, f := range deletes {
+		f()
+	}
+	for _, set := range item.GetProperties(PropPatchParameter) {
+		ps, err := selectPatchProperties(com, set.Value)
+		if err != nil {
+			return fmt.Errorf("ical:%s %s in %s: %v", PropPatchParameter, set.Value, target.Value, err)
+		}
+		for _, p := range ps {
+			for k, vs := range set.Params {
+				p.Params[k] = append([]string(nil), vs...)
+			}
+		}
+	}
+
+	replaced := map[string]bool{}
+	for _, p := range item.PropertiesObj {
+		switch {
+		case strings.HasPrefix(p.Name, "PATCH-"):
+		case contains(listProperties, p.Name):
+			addListValues(com, p)
+		case contains(multiProperties, p.Name):
+			ps := com.PropertiesObj[:0]
+			for _, q := range com.PropertiesObj {
+				if q.Name != p.Name || propertyIdentity(q) != propertyIdentity(p) {
+					ps = append(ps, q)
+				}
+			}
+			com.PropertiesObj = append(ps, p.Clone())
+		default:
+			if !replaced[p.Name] {
+				replaced[p.Name] = true
+				com.DelProperty(p.Name)
+			}
+			com.AddProperty(p.Clone())
+		}
+	}
+	for _, sub := range item.SubComponentsObj {
+		add := sub.obj().Clone()
+		k, keyed := diffKey(add)
+		i := len(com.SubComponentsObj)
+		if keyed {
+			for j, have := range com.SubComponentsObj {
+				if hk, _ := diffKey(have.obj()); hk == k {
+					i = j
+					break
+				}
+			}
+		}
+		if i < len(com.SubComponentsObj) {
+			com.SubComponentsObj[i] = add
+		} else {
+			com.SubComponentsObj = append(com.SubComponentsObj, add)
+		}
+	}
+	return nil
+}
+
+// addListValues adds the values of p the target does not have,to a
+// property of the same name and parameters when there is one
+func addListValues(com *ComponentObj, p Property) {
+	var add []string
+	for _, v := range splitValues(p.Value) {
+		found := false
+		for _, q := range com.GetProperties(p.Name) {
+			if contains(splitValues(q.Value), v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add = append(add, v)
+		}
+	}
+	if len(add) == 0 {
+		return
+	}
+	for i := range com.PropertiesObj {
+		q := &com.PropertiesObj[i]
+		if q.Name == p.Name && propertyEqual(&Property{Params: q.Params}, &Property{Params: p.Params}) {
+			q.Value += "," + strings.Join(add, ",")
+			return
+		}
+	}
+	c := p.Clone()
+	c.Value = strings.Join(add, ",")
+	com.AddProperty(c)
+}
+
+// selectorEscaper encodes the characters which end a step or a selector
+var selectorEscaper = strings.NewReplacer("%", "%25", "/", "%2F", "[", "%5B", "]", "%5D")
+
+// selectorUnescaper decodes what selectorEscaper encodes,in any case
+var selectorUnescaper = strings.NewReplacer("%25", "%", "%2F", "/", "%2f", "/", "%5B", "[", "%5b", "[", "%5D", "]", "%5d", "]")
+
+// patchStepSel is a step of a component path
+type patchStepSel struct {
+	name     string
+	selector string //UID,TZID or a position,empty for none,decoded
+	rid      string //decoded
+}
+
+func parsePatchPath(path string) ([]patchStepSel, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("ical:component path %q does not start with /", path)
+	}
+	var steps []patchStepSel
+	rest := path[1:]
+	for rest != "" {
+		var s patchStepSel
+		i := strings.IndexAny(rest, "[/")
+		if i < 0 {
+			i = len(rest)
+		}
+		s.name, rest = strings.ToUpper(rest[:i]), rest[i:]
+		for strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("ical:component path %q misses ]", path)
+			}
+			sel := rest[1:end]
+			rest = rest[end+1:]
+			switch {
+			case strings.HasPrefix(sel, "UID="), strings.HasPrefix(sel, "TZID="):
+				s.selector = selectorUnescaper.Replace(sel)
+			case strings.HasPrefix(sel, "RID="):
+				s.rid = selectorUnescaper.Replace(sel[len("RID="):])
+			default:
+				if _, err := strconv.Atoi(sel); err != nil {
+					return nil, fmt.Errorf("ical:component path %q has an invalid selector [%s]", path, sel)
+				}
+				s.selector = sel
+			}
+		}
+		if s.name == "" {
+			return nil, fmt.Errorf("ical:component path %q has an empty step", path)
+		}
+		steps = append(steps, s)
+		rest = strings.TrimPrefix(rest, "/")
+	}
+	return steps, nil
+}
+
+// findPatchComponent follows steps from com,it returns the component and
+// its parent
+func findPatchComponent(com *ComponentObj, steps []patchStepSel) (*ComponentObj, *ComponentObj, error) {
+	var parent *ComponentObj
+	for _, s := range steps {
+		var found []*ComponentObj
+		n := 0
+		for _, sub := range com.SubComponentsObj {
+			if sub.Name() != s.name {
+				continue
+			}
+			k, keyed := diffKey(sub.obj())
+			switch {
+			case strings.HasPrefix(s.selector, "UID="):
+				if keyed && "UID="+k.UID == s.selector && k.RecurrenceID == s.rid {
+					found = append(found, sub.obj())
+				}
+			case strings.HasPrefix(s.selector, "TZID="):
+				if keyed && "TZID="+k.UID == s.selector {
+					found = append(found, sub.obj())
+				}
+			case s.selector != "":
+				if !keyed {
+					if strconv.Itoa(n) == s.selector {
+						found = append(found, sub.obj())
+					}
+					n++
+				}
+			default:
+				found = append(found, sub.obj())
+			}
+		}
+		if len(found) != 1 {
+			step := s.name
+			if s.selector != "" {
+				step += "[" + s.selector + "]"
+			}
+			if s.rid != "" {
+				step += "[RID=" + s.rid + "]"
+			}
+			if len(found) == 0 {
+				return nil, nil, fmt.Errorf("no %s in %s", step, com.Name())
+			}
+			return nil, nil, fmt.Errorf("%s is ambiguous in %s", step, com.Name())
+		}
+		parent, com = com, found[0]
+	}
+	return com, parent, nil
+}
+
+func componentDeletion(com *ComponentObj, path string) (func(), error) {
+	steps, err := parsePatchPath(path)
+	if err != nil {
+		return nil, err
+	}
+	del, parent, err := findPatchComponent(com, steps)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		subs := parent.SubComponentsObj[:0]
+		for _, sub := range parent.SubComponentsObj {
+			if sub.obj() != del {
+				subs = append(subs, sub)
+			}
+		}
+		parent.SubComponentsObj = subs
+	}, nil
+}
+
+// parsePropertyPath splits #NAME[=value];PARAM,hasValue tells whether a
+// value is given
+func parsePropertyPath(path string) (name, value string, hasValue bool, param string, err error) {
+	if !strings.HasPrefix(path, "#") {
+		return "", "", false, "", fmt.Errorf("property path %q does not start with #", path)
+	}
+	rest := path[1:]
+	if i := strings.Index(rest, "[="); i >= 0 {
+		end := strings.LastIndex(rest, "]")
+		if end < i {
+			return "", "", false, "", fmt.Errorf("property path %q misses ]", path)
+		}
+		name, value, hasValue, rest = rest[:i], rest[i+2:end], true, rest[end+1:]
+	} else {
+		i := strings.Index(rest, ";")
+		if i < 0 {
+			i = len(rest)
+		}
+		name, rest = rest[:i], rest[i:]
+	}
+	if rest != "" {
+		if !strings.HasPrefix(rest, ";") || len(rest) == 1 {
+			return "", "", false, "", fmt.Errorf("property path %q is invalid", path)
+		}
+		param = strings.ToUpper(rest[1:])
+	}
+	if name == "" {
+		return "", "", false, "", fmt.Errorf("property path %q has no name", path)
+	}
+	return strings.ToUpper(name), value, hasValue, param, nil
+}
+
+// selectPatchProperties returns the properties of com a path without
+// parameter selects
+func selectPatchProperties(com *ComponentObj, path string) ([]*Property, error) {
+	name, value, hasValue, param, err := parsePropertyPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if param != "" {
+		return nil, fmt.Errorf("property path %q selects a parameter", path)
+	}
+	var ps []*Property
+	for i := range com.PropertiesObj {
+		p := &com.PropertiesObj[i]
+		if p.Name == name && (!hasValue || matchesPatchValue(*p, value)) {
+			ps = append(ps, p)
+		}
+	}
+	if len(ps) == 0 {
+		return nil, fmt.Errorf("no such property")
+	}
+	return ps, nil
+}
+
+func matchesPatchValue(p Property, value string) bool {
+	if contains(listProperties, p.Name) {
+		return contains(splitValues(p.Value), value)
+	}
+	return propertyIdentity(p) == propertyIdentity(Property{Name: p.Name, Value: value})
+}
+
+func propertyDeletion(com *ComponentObj, path string) (func(), error) {
+	name, value, hasValue, param, err := parsePropertyPath(path)
+	if err != nil {
+		return nil, err
+	}
+	selector := path
+	if param != "" {
+		selector = path[:strings.LastIndex(path, ";")]
+	}
+	ps, err := selectPatchProperties(com, selector)
+	if err != nil {
+		return nil, err
+	}
+	if param != "" {
+		for _, p := range ps {
+			if _, ok := p.Params[param]; !ok {
+				return nil, fmt.Errorf("no parameter %s", param)
+			}
+		}
+		//select again,other deletions may have moved the properties
+		return func() {
+			ps, _ := selectPatchProperties(com, selector)
+			for _, p := range ps {
+				delete(p.Params, param)
+			}
+		}, nil
+	}
+	return func() {
+		kept := com.PropertiesObj[:0]
+		for _, p := range com.PropertiesObj {
+			if p.Name != name || (hasValue && !matchesPatchValue(p, value)) {
+				kept = append(kept, p)
+				continue
+			}
+			if !hasValue || !contains(listProperties, name) {
+				continue
+			}
+			//drop the value from the list
+			var vs []string
+			for _, v := range splitValues(p.Value) {
+				if v != value {
+					vs = append(vs, v)
+				}
+			}
+			if len(vs) > 0 {
+				p.Value = strings.Join(vs, ",")
+				kept = append(kept, p)
+			}
+		}
+		com.PropertiesObj = kept
+	}, nil
+}
@@ -0,0 +1,151 @@
+package go_ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPatch(t *testing.T) {
+	old, new := decodeString(t, diffOldStr), decodeString(t, diffNewStr)
+	vpatch, err := NewPatch(DiffCalendars(old, new), itipNow)
+	if err != nil {
+		t.Fatalf("NewPatch() err: %v", err)
+	}
+	if got := vpatch.GetProperty(PropDatetimeStamp).Value; got != "20200301T090000Z" {
+		t.Errorf("NewPatch() DTSTAMP = %s", got)
+	}
+	//unfold the long PATCH-TARGET lines
+	got := strings.Replace(encodeComponent(t, vpatch), "\n ", "", -1)
+	for _, want := range []string{
+		`BEGIN:PATCH
+PATCH-TARGET:/VCALENDAR/VEVENT[UID=standup@example.com]
+SUMMARY:Team Standup
+PATCH-DELETE:#CATEGORIES[=WORK]
+CATEGORIES:MEETING
+PATCH-DELETE:#ATTENDEE[=mailto:ann@example.com]
+ATTENDEE;PARTSTAT=ACCEPTED:MAILTO:ann@example.com
+PATCH-DELETE:#ATTENDEE[=mailto:bob@example.com]
+ATTENDEE:mailto:carl@example.com
+PATCH-DELETE:/VALARM[0]
+END:PATCH
+`,
+		"PATCH-TARGET:/VCALENDAR/VEVENT[UID=standup@example.com][RID=20210308T090000Z]\nRECURRENCE-ID;TZID=Europe/Berlin:20210308T100000\n",
+		"PATCH-TARGET:/VCALENDAR\nMETHOD:PUBLISH\nPATCH-DELETE:/VTODO[UID=review@example.com]\nBEGIN:VJOURNAL\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("NewPatch() =\n%s\nwant it to contain\n%s", got, want)
+		}
+	}
+
+	//the patch goes over the wire in a calendar
+	wire := NewCalendar()
+	wire.AddComponent(vpatch)
+	received := decodeString(t, encodeComponent(t, wire))
+	if err := ApplyPatch(&old, &received); err != nil {
+		t.Fatalf("ApplyPatch() err: %v", err)
+	}
+	if d := DiffCalendars(old, new); !d.Empty() {
+		t.Errorf("patched calendar differs:\n%s", d)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	cal := decodeString(t, diffOldStr)
+	patch := decodeString(t, `BEGIN:VPATCH
+UID:patch@example.com
+DTSTAMP:20210305T080000Z
+PATCH-VERSION:1
+BEGIN:PATCH
+PATCH-TARGET:/VCALENDAR/VEVENT[UID=standup@example.com]
+PATCH-PARAMETER;PARTSTAT=DECLINED;RSVP=FALSE:#ATTENDEE[=MAILTO:bob@example.com]
+PATCH-DELETE:#ATTENDEE[=mailto:ann@example.com];PARTSTAT
+PATCH-DELETE:#RRULE
+CATEGORIES:DAILY,TEAM
+BEGIN:VALARM
+ACTION:AUDIO
+TRIGGER:-PT5M
+END:VALARM
+END:PATCH
+BEGIN:PATCH
+PATCH-TARGET:/VCALENDAR/VEVENT[UID=standup@example.com]/VALARM[0]
+DESCRIPTION:Standup soon
+END:PATCH
+END:VPATCH
+`)
+	if err := ApplyPatch(&cal, &patch); err != nil {
+		t.Fatalf("ApplyPatch() err: %v", err)
+	}
+	want := `BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+DTSTART:20210301T090000Z
+SUMMARY:Standup
+CATEGORIES:WORK,DAILY,TEAM
+ATTENDEE:mailto:ann@example.com
+ATTENDEE;PARTSTAT=DECLINED;RSVP=FALSE:mailto:bob@example.com
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+DESCRIPTION:Standup soon
+END:VALARM
+BEGIN:VALARM
+ACTION:AUDIO
+TRIGGER:-PT5M
+END:VALARM
+END:VEVENT
+`
+	if got := encodeComponent(t, cal.SubComponents()[0]); got != want {
+		t.Errorf("patched event =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPatchSelectorEscape(t *testing.T) {
+	uid := "stand]up/[x]%2F@example.com"
+	old := decodeString(t, strings.Replace(diffOldStr, "standup@example.com", uid, -1))
+	new := decodeString(t, strings.Replace(diffNewStr, "standup@example.com", uid, -1))
+	vpatch, err := NewPatch(DiffCalendars(old, new), time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("NewPatch() err: %v", err)
+	}
+	got := strings.Replace(encodeComponent(t, vpatch), "\n ", "", -1)
+	want := "PATCH-TARGET:/VCALENDAR/VEVENT[UID=stand%5Dup%2F%5Bx%5D%252F@example.com][RID=20210308T090000Z]\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("NewPatch() =\n%s\nwant it to contain\n%s", got, want)
+	}
+	if err := ApplyPatch(&old, vpatch); err != nil {
+		t.Fatalf("ApplyPatch() err: %v", err)
+	}
+	if d := DiffCalendars(old, new); !d.Empty() {
+		t.Errorf("patched calendar differs:\n%s", d)
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	for _, c := range []struct {
+		patch string
+		err   string
+	}{
+		{"PATCH-TARGET:/VCALENDAR/VEVENT[UID=missing@example.com]", "no VEVENT[UID=missing@example.com] in VCALENDAR"},
+		{"PATCH-TARGET:/VCALENDAR/VEVENT", "VEVENT is ambiguous in VCALENDAR"},
+		{"PATCH-TARGET:/VCALENDAR/VTODO/VALARM[0]", "no VALARM[0] in VTODO"},
+		{"PATCH-TARGET:/VEVENT", "does not start at /VCALENDAR"},
+		{"PATCH-TARGET:/VCALENDAR/VTODO\nPATCH-DELETE:#LOCATION", "PATCH-DELETE #LOCATION in /VCALENDAR/VTODO: no such property"},
+		{"PATCH-TARGET:/VCALENDAR/VTODO\nPATCH-DELETE:#SUMMARY;LANGUAGE", "no parameter LANGUAGE"},
+		{"PATCH-TARGET:/VCALENDAR/VTODO\nPATCH-PARAMETER;LANGUAGE=en:#SUMMARY[=Code review]", "PATCH-PARAMETER #SUMMARY[=Code review] in /VCALENDAR/VTODO: no such property"},
+		{"SUMMARY:x", "PATCH without PATCH-TARGET"},
+	} {
+		cal := decodeString(t, diffOldStr)
+		patch := decodeString(t, "BEGIN:VPATCH\nBEGIN:PATCH\n"+c.patch+"\nEND:PATCH\n"+
+			"BEGIN:PATCH\nPATCH-TARGET:/VCALENDAR\nMETHOD:PUBLISH\nEND:PATCH\nEND:VPATCH\n")
+		//the second PATCH is only applied with the first
+		patch.SubComponentsObj[0], patch.SubComponentsObj[1] = patch.SubComponentsObj[1], patch.SubComponentsObj[0]
+		err := ApplyPatch(&cal, &patch)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("ApplyPatch(%s) err = %v, want %s", c.patch, err, c.err)
+		}
+		if cal.GetProperty(PropMethod) != nil {
+			t.Errorf("ApplyPatch(%s) changed the calendar", c.patch)
+		}
+	}
+}
@@ -153,6 +153,13 @@ type Property struct {
 	Value string
 }
 
+//newPropertyValue returns a property with a ready to use value
+func newPropertyValue(name,value string) Property {
+	p := NewProperty(name)
+	p.Value = value
+	return *p
+}
+
 func NewProperty(name string) *Property {
 	return &Property{
 		Name:strings.ToUpper(name),
@@ -160,6 +167,16 @@ func NewProperty(name string) *Property {
 	}
 }
 
+//Clone returns a copy which shares no parameter values with p
+func (p Property) Clone() Property {
+	c := p
+	c.Params = make(Parameters,len(p.Params))
+	for k,vs := range p.Params{
+		c.Params[k] = append([]string(nil),vs...)
+	}
+	return c
+}
+
 //=========================Property Value Type===================================
 //defined in RFC 5545 3.3
 /*
@@ -175,7 +192,7 @@ The properties in an iCalendar object are strongly typed.  The
 
 
 func (p *Property) GetParamValue() string {
-	vdt := p.Params[Paramvaluetypeparam][0]
+	vdt := p.Params.Get(Paramvaluetypeparam)
 	if vdt == VDTdefault{
 		vdt = DefaultVDT[p.Name]
 	}
@@ -237,7 +254,7 @@ func (p *Property) GetToDate() (time.Time,error) {
 		loc = l
 	}
 	vdt := p.GetParamValue()
-	if vdt == VDTdefault || vdt == VDTdatetime{
+	if vdt == VDTdefault || vdt == VDTdate{
 		return time.ParseInLocation(DateFormat,p.Value,loc)
 	} else {
 		return time.Time{},fmt.Errorf("ical:expect date,but got %q",vdt)
@@ -267,11 +284,64 @@ func (p *Property) GetToDatetime() (time.Time,error) {
 	}
 }
 
+//IsDate reports whether the value is a DATE instead of the default DATE-TIME
+func (p *Property) IsDate() bool {
+	return p.GetParamValue() == VDTdate
+}
+
+//GetToTime works for properties which can be DATE or DATE-TIME,
+//such as DTSTART,DTEND,DUE and RECURRENCE-ID
+func (p *Property) GetToTime() (time.Time,error) {
+	if p.IsDate(){
+		return p.GetToDate()
+	}
+	return p.GetToDatetime()
+}
+
+func (p *Property) SetFromDate(t time.Time)  {
+	p.UpdateParamValue(VDTdate)
+	p.Params.Del(Paramtzid)
+	p.Value = t.Format(DateFormat)
+}
+
 func (p *Property) SetFromDatetime(t time.Time)  {
 	p.UpdateParamValue(VDTdatetime)
 	p.Value = t.Format(DatetimeFormat2)
 }
 
+//setFromUTC sets a DATE-TIME in UTC form,t is converted to UTC and TZID is removed
+func (p *Property) setFromUTC(t time.Time)  {
+	p.UpdateParamValue(VDTdatetime)
+	p.Params.Del(Paramtzid)
+	p.Value = t.UTC().Format(DatetimeFormat2)
+}
+
+//SetFromTimeLike sets t in the same form as ref: a DATE,a UTC DATE-TIME,a
+//floating DATE-TIME or a local time in the TZID of ref
+func (p *Property) SetFromTimeLike(ref *Property,t time.Time) error {
+	if ref.IsDate(){
+		p.SetFromDate(t)
+		return nil
+	}
+	if tz := ref.Params.Get(Paramtzid);tz != ""{
+		loc,err := time.LoadLocation(tz)
+		if err != nil{
+			return err
+		}
+		p.UpdateParamValue(VDTdatetime)
+		p.Params.Set(Paramtzid,tz)
+		p.Value = t.In(loc).Format(DatetimeFormat)
+		return nil
+	}
+	if !strings.HasSuffix(ref.Value,"Z"){
+		p.UpdateParamValue(VDTdatetime)
+		p.Value = t.Format(DatetimeFormat)
+		return nil
+	}
+	p.setFromUTC(t)
+	return nil
+}
+
 func (p *Property) SetFromDuration(d time.Duration)  {
 	p.UpdateParamValue(VDTduration)
 	seconds := d.Milliseconds()/1000
@@ -293,97 +363,45 @@ func (p *Property) GetToDuration() (time.Duration,error) {
 	if err := p.expectVDT(VDTduration);err != nil {
 		return 0,err
 	}
-	ds := durstr(p.Value)
-	return ds.parseToDuration()
+	return parseDuration(p.Value)
 }
 
-type durstr string
-func (ds durstr) next(b byte) bool {
-	if len(ds) ==0 || ds[0] != b{
-		return false
+func (p *Property) GetToRecur() (*Recur,error) {
+	if err := p.expectVDT(VDTrecurrence);err != nil {
+		return nil,err
 	}
-	ds = ds[1:]
-	return true
+	return ParseRecur(p.Value)
 }
 
-func (ds durstr) parseToDuration() (time.Duration,error) {
-	//Duration can date or datetime,so need to sigure
-	isTime := false
-	var end time.Duration
-	sign := ds.next('-')
-	if !sign {
-		bl := ds.next('+')
-		if bl{
-			ds = ds[1:]
-		} else {
-			return 0,fmt.Errorf("ical: invalid duration,need '-' or '+'")
-		}
-	} else {
-		ds = ds[1:]
-	}
-
+func (p *Property) SetFromRecur(r *Recur)  {
+	p.UpdateParamValue(VDTrecurrence)
+	p.Value = r.String()
+}
 
-	if !ds.next('P'){
-		return 0,fmt.Errorf("ical: invalide duration,expect 'P'")
-	} else {
-		ds = ds[1:]
+//GetToPeriods works for FREEBUSY and RDATE;VALUE=PERIOD,a list of PERIOD values
+func (p *Property) GetToPeriods() ([]Period,error) {
+	if err := p.expectVDT(VDTperiod);err != nil{
+		return nil,err
 	}
-
-	for len(ds) > 0{
-		if ds.next('T'){
-			ds = ds[1:]
-			isTime = true
-		}
-
-		index := strings.IndexFunc(string(ds), func(r rune) bool {
-			return !(r>='0' && r<='9')
-		})
-
-		if index == 0 {
-			return 0,fmt.Errorf("ical:invalid duration,shoud be digital")
-		}
-		if index < 0{
-			index = len(ds)
-		}
-
-		n,err := strconv.ParseUint(string(ds[:index]),10,64)
+	var ps []Period
+	for _,v := range splitValues(p.Value){
+		pe,err := parsePeriod(v)
 		if err != nil{
-			return 0,err
-		}
-		ds := ds[index:]
-
-		num := time.Duration(n)
-
-		if isTime{
-			if ds.next('H'){
-				ds = ds[1:]
-				end += num * time.Hour
-			} else if ds.next('M'){
-				ds = ds[1:]
-				end += num*time.Minute
-			} else if ds.next('S'){
-				ds = ds[1:]
-				end += num*time.Second
-			} else {
-				return 0,fmt.Errorf("ical:invalid duration: expect 'H','M' or 'S'")
-			}
-		} else {
-			if ds.next('D'){
-				ds = ds[1:]
-				end += num*24*time.Hour
-			} else if ds.next('W'){
-				ds = ds[1:]
-				end += num*7*24*time.Hour
-			} else {
-				return 0,fmt.Errorf("ical:invalid duration: expect 'D' or 'W'")
-			}
+			return nil,err
 		}
+		ps = append(ps,pe)
 	}
+	return ps,nil
+}
 
-	if sign{
-		end = -end
+//SetFromPeriods writes the periods in UTC,as FREEBUSY requires
+func (p *Property) SetFromPeriods(ps []Period)  {
+	p.UpdateParamValue(VDTperiod)
+	vals := make([]string,len(ps))
+	for i,pe := range ps{
+		vals[i] = pe.String()
 	}
-	return end,nil
+	p.Value = strings.Join(vals,",")
 }
 
 func (p *Property) GetToFloat() (float64,error) {
@@ -0,0 +1,656 @@
+package go_ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies defined in RFC 5545 3.3.10
+const (
+	FreqSecondly = "SECONDLY"
+	FreqMinutely = "MINUTELY"
+	FreqHourly   = "HOURLY"
+	FreqDaily    = "DAILY"
+	FreqWeekly   = "WEEKLY"
+	FreqMonthly  = "MONTHLY"
+	FreqYearly   = "YEARLY"
+)
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum is one BYDAY element,e.g. "-1FR" is {Weekday:time.Friday,N:-1}
+// N is zero when the weekday has no ordinal
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+func (wn WeekdayNum) String() string {
+	if wn.N == 0 {
+		return weekdayNames[wn.Weekday]
+	}
+	return strconv.Itoa(wn.N) + weekdayNames[wn.Weekday]
+}
+
+/*
+Recur is a RECUR value,RFC 5545 3.3.10
+
+	recur           = recur-rule-part *( ";" recur-rule-part )
+
+UNTIL is kept in UTC,UntilDate reports a DATE value and UntilUTC a
+DATE-TIME value with the "Z" suffix,a floating UNTIL has neither.
+*/
+type Recur struct {
+	Freq       string
+	Until      time.Time
+	UntilDate  bool
+	UntilUTC   bool
+	Count      int
+	Interval   int
+	BySecond   []int
+	ByMinute   []int
+	ByHour     []int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByYearDay  []int
+	ByWeekNo   []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// ParseRecur parses a RECUR value such as "FREQ=WEEKLY;COUNT=10;BYDAY=MO,WE"
+func ParseRecur(s string) (*Recur, error) {
+	r := &Recur{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		i := strings.IndexByte(part, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("ical:invalid recur rule part %q", part)
+		}
+		name, val := strings.ToUpper(part[:i]), part[i+1:]
+		if seen[name] {
+			return nil, fmt.Errorf("ical:recur rule part %q occurs more than once", name)
+		}
+		seen[name] = true
+		var err error
+		switch name {
+		case "FREQ":
+			switch val = strings.ToUpper(val); val {
+			case FreqSecondly, FreqMinutely, FreqHourly, FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				r.Freq = val
+			default:
+				err = fmt.Errorf("ical:invalid FREQ %q", val)
+			}
+		case "UNTIL":
+			if len(val) == len(DateFormat) {
+				r.Until, err = parseDateValue(val)
+				r.UntilDate = true
+			} else {
+				r.Until, r.UntilUTC, err = parseDateTimeValue(val)
+			}
+		case "COUNT":
+			r.Count, err = parseRecurInt(val, 1, 0)
+		case "INTERVAL":
+			r.Interval, err = parseRecurInt(val, 1, 0)
+		case "BYSECOND":
+			r.BySecond, err = parseRecurInts(val, 0, 60, false)
+		case "BYMINUTE":
+			r.ByMinute, err = parseRecurInts(val, 0, 59, false)
+		case "BYHOUR":
+			r.ByHour, err = parseRecurInts(val, 0, 23, false)
+		case "BYDAY":
+			for _, v := range strings.Split(val, ",") {
+				var wn WeekdayNum
+				if wn, err = parseWeekdayNum(v); err != nil {
+					break
+				}
+				r.ByDay = append(r.ByDay, wn)
+			}
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseRecurInts(val, 1, 31, true)
+		case "BYYEARDAY":
+			r.ByYearDay, err = parseRecurInts(val, 1, 366, true)
+		case "BYWEEKNO":
+			r.ByWeekNo, err = parseRecurInts(val, 1, 53, true)
+		case "BYMONTH":
+			r.ByMonth, err = parseRecurInts(val, 1, 12, false)
+		case "BYSETPOS":
+			r.BySetPos, err = parseRecurInts(val, 1, 366, true)
+		case "WKST":
+			r.WeekStart, err = parseWeekday(val)
+		default:
+			if !strings.HasPrefix(name, "X-") {
+				err = fmt.Errorf("ical:unknown recur rule part %q", name)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.Freq == "" {
+		return nil, fmt.Errorf("ical:recur rule %q has no FREQ", s)
+	}
+	if seen["UNTIL"] && seen["COUNT"] {
+		return nil, fmt.Errorf("ical:recur rule %q has both UNTIL and COUNT", s)
+	}
+	for _, wn := range r.ByDay {
+		if wn.N != 0 && r.Freq != FreqMonthly && r.Freq != FreqYearly {
+			return nil, fmt.Errorf("ical:BYDAY %q with an ordinal is only allowed with FREQ=MONTHLY or YEARLY", wn)
+		}
+		if wn.N != 0 && r.Freq == FreqYearly && len(r.ByWeekNo) > 0 {
+			return nil, fmt.Errorf("ical:BYDAY %q with an ordinal can not be used with BYWEEKNO", wn)
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == FreqWeekly {
+		return nil, fmt.Errorf("ical:BYMONTHDAY can not be used with FREQ=WEEKLY")
+	}
+	if len(r.ByYearDay) > 0 && (r.Freq == FreqDaily || r.Freq == FreqWeekly || r.Freq == FreqMonthly) {
+		return nil, fmt.Errorf("ical:BYYEARDAY can not be used with FREQ=%s", r.Freq)
+	}
+	if len(r.ByWeekNo) > 0 && r.Freq != FreqYearly {
+		return nil, fmt.Errorf("ical:BYWEEKNO can only be used with FREQ=YEARLY")
+	}
+	if len(r.BySetPos) > 0 && !r.hasByRule() {
+		return nil, fmt.Errorf("ical:BYSETPOS needs another BYxxx rule part")
+	}
+	return r, nil
+}
+
+func (r *Recur) hasByRule() bool {
+	return len(r.BySecond) > 0 || len(r.ByMinute) > 0 || len(r.ByHour) > 0 || len(r.ByDay) > 0 ||
+		len(r.ByMonthDay) > 0 || len(r.ByYearDay) > 0 || len(r.ByWeekNo) > 0 || len(r.ByMonth) > 0
+}
+
+// String formats the rule,parts are written in the order of the RFC grammar
+func (r *Recur) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if !r.Until.IsZero() {
+		switch {
+		case r.UntilDate:
+			parts = append(parts, "UNTIL="+r.Until.Format(DateFormat))
+		case r.UntilUTC:
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format(DatetimeFormat2))
+		default:
+			parts = append(parts, "UNTIL="+r.Until.Format(DatetimeFormat))
+		}
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	addInts := func(name string, vals []int) {
+		if len(vals) == 0 {
+			return
+		}
+		s := make([]string, len(vals))
+		for i, v := range vals {
+			s[i] = strconv.Itoa(v)
+		}
+		parts = append(parts, name+"="+strings.Join(s, ","))
+	}
+	addInts("BYSECOND", r.BySecond)
+	addInts("BYMINUTE", r.ByMinute)
+	addInts("BYHOUR", r.ByHour)
+	if len(r.ByDay) > 0 {
+		s := make([]string, len(r.ByDay))
+		for i, wn := range r.ByDay {
+			s[i] = wn.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(s, ","))
+	}
+	addInts("BYMONTHDAY", r.ByMonthDay)
+	addInts("BYYEARDAY", r.ByYearDay)
+	addInts("BYWEEKNO", r.ByWeekNo)
+	addInts("BYMONTH", r.ByMonth)
+	addInts("BYSETPOS", r.BySetPos)
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+func parseRecurInt(s string, min, max int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || (max > 0 && n > max) {
+		return 0, fmt.Errorf("ical:invalid recur number %q", s)
+	}
+	return n, nil
+}
+
+// parseRecurInts parses a list like "1,-1,15",signed allows negative values
+func parseRecurInts(s string, min, max int, signed bool) ([]int, error) {
+	var vals []int
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(v)
+		abs := n
+		if signed && n < 0 {
+			abs = -n
+		}
+		if err != nil || abs < min || abs > max {
+			return nil, fmt.Errorf("ical:invalid recur value %q", v)
+		}
+		vals = append(vals, n)
+	}
+	return vals, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for i, name := range weekdayNames {
+		if strings.EqualFold(s, name) {
+			return time.Weekday(i), nil
+		}
+	}
+	return 0, fmt.Errorf("ical:invalid weekday %q", s)
+}
+
+// weekdaynum = [[plus / minus] ordwk] weekday
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("ical:invalid BYDAY value %q", s)
+	}
+	wd, err := parseWeekday(s[len(s)-2:])
+	if err != nil {
+		return WeekdayNum{}, err
+	}
+	wn := WeekdayNum{Weekday: wd}
+	if ord := s[:len(s)-2]; ord != "" {
+		n, err := strconv.Atoi(ord)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("ical:invalid BYDAY value %q", s)
+		}
+		wn.N = n
+	}
+	return wn, nil
+}
+
+// maxEmptyPeriods stops the expansion of a rule which can never match,
+// such as BYMONTH=2;BYMONTHDAY=30
+const maxEmptyPeriods = 10000
+
+/*
+RecurIterator walks the start times a Recur generates,in order.
+
+The times are computed on the wall clock of DTSTART: a daily event at 9:00
+stays at 9:00 across a change of daylight saving time. DTSTART itself is
+returned only when it matches the rule,callers building a recurrence set add
+it themselves,see Expand.
+*/
+type RecurIterator struct {
+	r       *Recur
+	start   time.Time
+	loc     *time.Location
+	byMonth []int
+	byMDay  []int
+	byDay   []WeekdayNum
+	until   time.Time
+	period  time.Time
+	buf     []time.Time
+	count   int
+	done    bool
+}
+
+// Iterator returns an iterator over the occurrences of r for a first
+// instance at dtstart
+func (r *Recur) Iterator(dtstart time.Time) *RecurIterator {
+	it := &RecurIterator{r: r, start: dtstart, loc: dtstart.Location(),
+		byMonth: r.ByMonth, byMDay: r.ByMonthDay, byDay: r.ByDay}
+	//the day of DTSTART is used when the rule does not name days,RFC 5545 3.3.10
+	if len(r.ByWeekNo) == 0 && len(r.ByYearDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		switch r.Freq {
+		case FreqYearly:
+			if len(r.ByMonth) == 0 {
+				it.byMonth = []int{int(dtstart.Month())}
+			}
+			it.byMDay = []int{dtstart.Day()}
+		case FreqMonthly:
+			it.byMDay = []int{dtstart.Day()}
+		case FreqWeekly:
+			it.byDay = []WeekdayNum{{Weekday: dtstart.Weekday()}}
+		}
+	}
+	if !r.Until.IsZero() {
+		switch {
+		case r.UntilDate:
+			y, m, d := r.Until.Date()
+			it.until = time.Date(y, m, d, 23, 59, 59, 0, it.loc)
+		case r.UntilUTC:
+			it.until = r.Until
+		default:
+			it.until = wallClock(r.Until, it.loc)
+		}
+	}
+	it.period = it.firstPeriod(naive(dtstart))
+	return it
+}
+
+// Next returns the next occurrence,ok is false when there is none
+func (it *RecurIterator) Next() (t time.Time, ok bool) {
+	for len(it.buf) == 0 {
+		if it.done {
+			return time.Time{}, false
+		}
+		it.fill()
+	}
+	t = it.buf[0]
+	it.buf = it.buf[1:]
+	return t, true
+}
+
+// Between returns the occurrences of r for a first instance at dtstart
+// which start in [from,to)
+func (r *Recur) Between(dtstart, from, to time.Time) []time.Time {
+	var ts []time.Time
+	it := r.Iterator(dtstart)
+	for {
+		t, ok := it.Next()
+		if !ok || !t.Before(to) {
+			return ts
+		}
+		if !t.Before(from) {
+			ts = append(ts, t)
+		}
+	}
+}
+
+// fill computes the occurrences of the current period and moves to the next
+// one,it gives up after maxEmptyPeriods periods without an occurrence
+func (it *RecurIterator) fill() {
+	for empty := 0; empty < maxEmptyPeriods; empty++ {
+		if it.period.Year() > 9999 {
+			break
+		}
+		set := it.periodSet()
+		it.advance()
+		var out []time.Time
+		for _, n := range set {
+			t := wallClock(n, it.loc)
+			if t.Before(it.start) {
+				continue
+			}
+			if !it.until.IsZero() && t.After(it.until) {
+				it.done = true
+				break
+			}
+			out = append(out, t)
+			it.count++
+			if it.r.Count > 0 && it.count >= it.r.Count {
+				it.done = true
+				break
+			}
+		}
+		if len(out) > 0 || it.done {
+			it.buf = out
+			return
+		}
+	}
+	it.done = true
+}
+
+// firstPeriod returns the start of the period which holds t
+func (it *RecurIterator) firstPeriod(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch it.r.Freq {
+	case FreqYearly:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	case FreqMonthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case FreqWeekly:
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) - int(it.r.WeekStart) + 7) % 7))
+	case FreqDaily:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	case FreqHourly:
+		return t.Truncate(time.Hour)
+	case FreqMinutely:
+		return t.Truncate(time.Minute)
+	}
+	return t.Truncate(time.Second)
+}
+
+func (it *RecurIterator) advance() {
+	n := it.r.Interval
+	if n < 1 {
+		n = 1
+	}
+	switch it.r.Freq {
+	case FreqYearly:
+		it.period = it.period.AddDate(n, 0, 0)
+	case FreqMonthly:
+		it.period = it.period.AddDate(0, n, 0)
+	case FreqWeekly:
+		it.period = it.period.AddDate(0, 0, 7*n)
+	case FreqDaily:
+		it.period = it.period.AddDate(0, 0, n)
+	case FreqHourly:
+		it.period = it.skipDay(it.period.Add(time.Duration(n)*time.Hour), time.Duration(n)*time.Hour)
+	case FreqMinutely:
+		it.period = it.skipDay(it.period.Add(time.Duration(n)*time.Minute), time.Duration(n)*time.Minute)
+	default:
+		it.period = it.skipDay(it.period.Add(time.Duration(n)*time.Second), time.Duration(n)*time.Second)
+	}
+}
+
+// skipDay jumps over the periods of a day no BYxxx day rule matches
+func (it *RecurIterator) skipDay(p time.Time, step time.Duration) time.Time {
+	for i := 0; i < 366*maxEmptyPeriods && p.Year() <= 9999 && !it.dayMatches(dayOf(p)); i++ {
+		next := dayOf(p).AddDate(0, 0, 1)
+		k := (next.Sub(p) + step - 1) / step
+		p = p.Add(k * step)
+	}
+	return p
+}
+
+// periodSet returns the sorted occurrences of the current period,as naive
+// times,after BYSETPOS
+func (it *RecurIterator) periodSet() []time.Time {
+	var days []time.Time
+	p := it.period
+	switch it.r.Freq {
+	case FreqYearly:
+		first, last := p, p.AddDate(1, 0, 0)
+		if len(it.r.ByWeekNo) > 0 {
+			first, last = weekOne(p.Year(), it.r.WeekStart), weekOne(p.Year()+1, it.r.WeekStart)
+		}
+		for d := first; d.Before(last); d = d.AddDate(0, 0, 1) {
+			days = append(days, d)
+		}
+	case FreqMonthly:
+		for d := p; d.Month() == p.Month(); d = d.AddDate(0, 0, 1) {
+			days = append(days, d)
+		}
+	case FreqWeekly:
+		for i := 0; i < 7; i++ {
+			days = append(days, p.AddDate(0, 0, i))
+		}
+	default:
+		days = append(days, dayOf(p))
+	}
+
+	hours := it.timeValues(it.r.ByHour, FreqHourly, p.Hour(), it.start.Hour())
+	minutes := it.timeValues(it.r.ByMinute, FreqMinutely, p.Minute(), it.start.Minute())
+	seconds := it.timeValues(it.r.BySecond, FreqSecondly, p.Second(), it.start.Second())
+
+	var set []time.Time
+	for _, d := range days {
+		if !it.dayMatches(d) {
+			continue
+		}
+		for _, h := range hours {
+			for _, mi := range minutes {
+				for _, s := range seconds {
+					set = append(set, time.Date(d.Year(), d.Month(), d.Day(), h, mi, s, 0, time.UTC))
+				}
+			}
+		}
+	}
+	sortTimes(set)
+	if len(it.r.BySetPos) == 0 {
+		return set
+	}
+	var sel []time.Time
+	for _, pos := range it.r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(set) + pos
+		}
+		if i >= 0 && i < len(set) {
+			sel = append(sel, set[i])
+		}
+	}
+	sortTimes(sel)
+	return dedupTimes(sel)
+}
+
+/*
+timeValues returns the hours,minutes or seconds of the occurrences in the
+current period. For a rule at least as fine as freq the value is the one of
+the period,kept only if the BYxxx list allows it,otherwise the list expands
+the value,which defaults to the one of DTSTART.
+*/
+func (it *RecurIterator) timeValues(by []int, freq string, period, start int) []int {
+	fine := false
+	for _, f := range []string{FreqSecondly, FreqMinutely, FreqHourly} {
+		if it.r.Freq == f {
+			fine = true
+		}
+		if f == freq {
+			break
+		}
+	}
+	if fine {
+		if len(by) == 0 || containsInt(by, period) {
+			return []int{period}
+		}
+		return nil
+	}
+	if len(by) == 0 {
+		return []int{start}
+	}
+	vals := append([]int(nil), by...)
+	sort.Ints(vals)
+	return vals
+}
+
+// dayMatches applies the BYMONTH,BYWEEKNO,BYYEARDAY,BYMONTHDAY and BYDAY rule parts
+func (it *RecurIterator) dayMatches(d time.Time) bool {
+	r := it.r
+	if len(it.byMonth) > 0 && !containsInt(it.byMonth, int(d.Month())) {
+		return false
+	}
+	if len(r.ByWeekNo) > 0 && !it.weekNoMatches(d) {
+		return false
+	}
+	if len(r.ByYearDay) > 0 {
+		yd := d.YearDay()
+		n := daysIn(d.Year())
+		if !containsInt(r.ByYearDay, yd) && !containsInt(r.ByYearDay, yd-n-1) {
+			return false
+		}
+	}
+	if len(it.byMDay) > 0 {
+		n := daysInMonth(d.Year(), d.Month())
+		if !containsInt(it.byMDay, d.Day()) && !containsInt(it.byMDay, d.Day()-n-1) {
+			return false
+		}
+	}
+	if len(it.byDay) > 0 {
+		found := false
+		for _, wn := range it.byDay {
+			if wn.Weekday != d.Weekday() {
+				continue
+			}
+			if wn.N == 0 {
+				found = true
+				break
+			}
+			//ordinals count in the month,or in the year of a YEARLY rule without BYMONTH
+			pos, n := d.Day(), daysInMonth(d.Year(), d.Month())
+			if r.Freq == FreqYearly && len(r.ByMonth) == 0 {
+				pos, n = d.YearDay(), daysIn(d.Year())
+			}
+			if wn.N > 0 && (pos-1)/7+1 == wn.N || wn.N < 0 && -((n-pos)/7+1) == wn.N {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// weekNoMatches checks BYWEEKNO,weeks are numbered in the year of the period
+func (it *RecurIterator) weekNoMatches(d time.Time) bool {
+	y := it.period.Year()
+	first, next := weekOne(y, it.r.WeekStart), weekOne(y+1, it.r.WeekStart)
+	if d.Before(first) || !d.Before(next) {
+		return false
+	}
+	week := int(d.Sub(first).Hours()/24)/7 + 1
+	weeks := int(next.Sub(first).Hours()/24) / 7
+	return containsInt(it.r.ByWeekNo, week) || containsInt(it.r.ByWeekNo, week-weeks-1)
+}
+
+// weekOne returns the first day of week 1 of year,the first week with at
+// least four days in the year
+func weekOne(year int, wkst time.Weekday) time.Time {
+	jan1 := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := (int(jan1.Weekday()) - int(wkst) + 7) % 7
+	if d <= 3 {
+		return jan1.AddDate(0, 0, -d)
+	}
+	return jan1.AddDate(0, 0, 7-d)
+}
+
+// naive returns the wall clock of t as a UTC time,for calendar arithmetic
+func naive(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// wallClock puts the wall clock of the naive time n in loc
+func wallClock(n time.Time, loc *time.Location) time.Time {
+	return time.Date(n.Year(), n.Month(), n.Day(), n.Hour(), n.Minute(), n.Second(), 0, loc)
+}
+
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysIn(year int) int {
+	return time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+func sortTimes(ts []time.Time) {
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+}
+
+// dedupTimes removes repeated times from a sorted slice
+func dedupTimes(ts []time.Time) []time.Time {
+	out := ts[:0]
+	for i, t := range ts {
+		if i == 0 || !t.Equal(ts[i-1]) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,131 @@
+package go_ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecurIterator(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	//examples of RFC 5545 3.8.5.3,DTSTART in America/New_York
+	tests := []struct {
+		rule  string
+		start string
+		limit int
+		want  string
+	}{
+		{"FREQ=DAILY;COUNT=10", "19970902T090000", 0,
+			"19970902T090000 19970903T090000 19970904T090000 19970905T090000 19970906T090000 " +
+				"19970907T090000 19970908T090000 19970909T090000 19970910T090000 19970911T090000"},
+		{"FREQ=DAILY;UNTIL=19971224T000000Z", "19971220T090000", 0,
+			"19971220T090000 19971221T090000 19971222T090000 19971223T090000"},
+		{"FREQ=DAILY;INTERVAL=10;COUNT=5", "19970902T090000", 0,
+			"19970902T090000 19970912T090000 19970922T090000 19971002T090000 19971012T090000"},
+		{"FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=TU,TH;COUNT=8", "19970902T090000", 0,
+			"19970902T090000 19970904T090000 19970916T090000 19970918T090000 19970930T090000 " +
+				"19971002T090000 19971014T090000 19971016T090000"},
+		{"FREQ=MONTHLY;COUNT=6;BYDAY=-2MO", "19970922T090000", 0,
+			"19970922T090000 19971020T090000 19971117T090000 19971222T090000 19980119T090000 19980216T090000"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-3", "19970928T090000", 4,
+			"19970928T090000 19971029T090000 19971128T090000 19971229T090000"},
+		{"FREQ=YEARLY;BYDAY=20MO", "19970519T090000", 3,
+			"19970519T090000 19980518T090000 19990517T090000"},
+		{"FREQ=YEARLY;BYWEEKNO=20;BYDAY=MO", "19970512T090000", 3,
+			"19970512T090000 19980511T090000 19990517T090000"},
+		{"FREQ=YEARLY;BYMONTH=3;BYDAY=TH", "19970313T090000", 4,
+			"19970313T090000 19970320T090000 19970327T090000 19980305T090000"},
+		{"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", "19970902T090000", 3,
+			"19980213T090000 19980313T090000 19981113T090000"},
+		{"FREQ=MONTHLY;COUNT=3;BYDAY=TU,WE,TH;BYSETPOS=3", "19970904T090000", 0,
+			"19970904T090000 19971007T090000 19971106T090000"},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-2", "19970929T090000", 3,
+			"19970929T090000 19971030T090000 19971127T090000"},
+		{"FREQ=HOURLY;INTERVAL=3;UNTIL=19970902T170000Z", "19970902T090000", 0,
+			"19970902T090000 19970902T120000"},
+		{"FREQ=MINUTELY;INTERVAL=20;BYHOUR=9,10", "19970902T090000", 7,
+			"19970902T090000 19970902T092000 19970902T094000 19970902T100000 19970902T102000 19970902T104000 19970903T090000"},
+		{"FREQ=YEARLY;INTERVAL=4;BYMONTH=11;BYDAY=TU;BYMONTHDAY=2,3,4,5,6,7,8", "19961105T090000", 3,
+			"19961105T090000 20001107T090000 20041102T090000"},
+		{"FREQ=MONTHLY;BYMONTHDAY=15,30;COUNT=5", "20070115T090000", 0,
+			"20070115T090000 20070130T090000 20070215T090000 20070315T090000 20070330T090000"},
+		//daylight saving time starts on 19980405,the wall clock is kept
+		{"FREQ=DAILY;COUNT=3", "19980404T090000", 0, "19980404T090000 19980405T090000 19980406T090000"},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "19970101T090000", 0, ""},
+	}
+	for _, test := range tests {
+		r, err := ParseRecur(test.rule)
+		if err != nil {
+			t.Fatalf("ParseRecur(%q) err: %v", test.rule, err)
+		}
+		start, err := time.ParseInLocation(DatetimeFormat, test.start, ny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		it := r.Iterator(start)
+		var got []string
+		for test.limit == 0 || len(got) < test.limit {
+			occ, ok := it.Next()
+			if !ok {
+				break
+			}
+			if occ.Location() != ny {
+				t.Errorf("%s: occurrence in %v, want %v", test.rule, occ.Location(), ny)
+			}
+			got = append(got, occ.Format(DatetimeFormat))
+		}
+		if s := strings.Join(got, " "); s != test.want {
+			t.Errorf("%s:\n got %s\nwant %s", test.rule, s, test.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	cal := decodeString(t, `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VEVENT
+UID:daily@example.com
+DTSTAMP:20200101T000000Z
+DTSTART:20200302T100000Z
+DURATION:PT1H
+RRULE:FREQ=DAILY
+EXDATE:20200303T100000Z
+RDATE;VALUE=PERIOD:20200303T200000Z/PT30M
+END:VEVENT
+BEGIN:VEVENT
+UID:daily@example.com
+DTSTAMP:20200101T000000Z
+RECURRENCE-ID:20200304T100000Z
+DTSTART:20200304T150000Z
+DTEND:20200304T160000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:allday@example.com
+DTSTAMP:20200101T000000Z
+DTSTART;VALUE=DATE:20200303
+END:VEVENT
+END:VCALENDAR
+`)
+	from := time.Date(2020, 3, 2, 10, 30, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 5, 0, 0, 0, 0, time.UTC)
+	occs, err := Expand(cal, from, to)
+	if err != nil {
+		t.Fatalf("Expand() err: %v", err)
+	}
+	var got []string
+	for _, o := range occs {
+		got = append(got, o.Start.UTC().Format(DatetimeFormat2)+"/"+o.End.UTC().Format(DatetimeFormat2))
+	}
+	want := "20200302T100000Z/20200302T110000Z 20200303T000000Z/20200304T000000Z " +
+		"20200303T200000Z/20200303T203000Z 20200304T150000Z/20200304T160000Z"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("Expand():\n got %s\nwant %s", s, want)
+	}
+	if rid := occs[3].RecurrenceID; !rid.Equal(time.Date(2020, 3, 4, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("RecurrenceID of the override = %v", rid)
+	}
+}
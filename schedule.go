@@ -0,0 +1,356 @@
+package go_ical
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// significantProps are the properties whose change reschedules an event,
+// RFC 6638 3.2.8: the attendees must answer again after such a change
+var significantProps = []string{PropDatetimeStart, PropDatetimeEnd, PropDuration, PropDatetimeDue,
+	PropRecurrenceRule, PropRecurrenceDatetime, PropExceptionDatetime}
+
+// Schedule status codes of RFC 6638 3.2.9 which a Schedule sets itself
+const (
+	ScheduleStatusPending   = "1.0"
+	ScheduleStatusSent      = "1.1"
+	ScheduleStatusDelivered = "1.2"
+)
+
+// ScheduleMessage is an iTIP message to send to some of the attendees
+type ScheduleMessage struct {
+	Recipients []string
+	Message    *Calendar
+}
+
+// AttendeeState is what a Schedule knows about one attendee
+type AttendeeState struct {
+	Address        string
+	CN             string
+	Partstat       string
+	RSVP           bool
+	ScheduleStatus string
+	//Outstanding is true while a reply to the last REQUEST is expected
+	Outstanding bool
+}
+
+/*
+Schedule tracks the scheduling of an organizer's event across its revisions.
+
+It keeps the organizer's copy of the event,with PARTSTAT and SCHEDULE-STATUS
+of every attendee,and tells which iTIP messages to send after each change:
+Invite for the first REQUEST,Update for every later edit,Cancel to call the
+event off. Replies are recorded with Receive. Attendees with SCHEDULE-AGENT
+CLIENT or NONE are kept in the event,but get no messages,and the organizer
+never sends a message to itself.
+*/
+type Schedule struct {
+	cal *Calendar
+}
+
+// NewSchedule starts tracking a copy of ev,which must have UID and ORGANIZER
+func NewSchedule(ev *VEvent) (*Schedule, error) {
+	if ev.GetProperty(PropUID) == nil || ev.GetProperty(PropOrganizer) == nil {
+		return nil, fmt.Errorf("ical:a scheduled event needs UID and ORGANIZER")
+	}
+	cal := NewCalendar()
+	cal.AddComponent(ev.Clone())
+	return &Schedule{cal: cal}, nil
+}
+
+// Event returns a copy of the organizer's event
+func (s *Schedule) Event() *VEvent {
+	return &VEvent{*s.master().Clone()}
+}
+
+// Calendar returns a copy of the organizer's calendar: the event and the
+// overrides made by replies for single occurrences
+func (s *Schedule) Calendar() *Calendar {
+	return &Calendar{*s.cal.Clone()}
+}
+
+func (s *Schedule) master() *ComponentObj {
+	return s.cal.SubComponentsObj[0].obj()
+}
+
+// Attendees returns the state of every attendee of the event,the organizer included
+func (s *Schedule) Attendees() []AttendeeState {
+	var states []AttendeeState
+	master := s.master()
+	for _, p := range master.GetProperties(PropAttendee) {
+		st := AttendeeState{
+			Address:        p.Value,
+			CN:             p.Params.Get(Paramcn),
+			Partstat:       p.Params.Get(Parampartstat),
+			RSVP:           strings.EqualFold(p.Params.Get(Paramrsvp), "TRUE"),
+			ScheduleStatus: p.Params.Get(Paramschedulestatus),
+		}
+		if st.Partstat == "" {
+			st.Partstat = PartstatNeedsAction
+		}
+		st.Outstanding = st.RSVP && st.Partstat == PartstatNeedsAction
+		states = append(states, st)
+	}
+	return states
+}
+
+// Outstanding returns the addresses of the attendees whose reply is still expected
+func (s *Schedule) Outstanding() []string {
+	var addrs []string
+	for _, st := range s.Attendees() {
+		if st.Outstanding {
+			addrs = append(addrs, st.Address)
+		}
+	}
+	return addrs
+}
+
+// SetScheduleStatus records the delivery status of the last message to an
+// attendee,such as ScheduleStatusDelivered or a "3.7" or "5.1" failure
+func (s *Schedule) SetScheduleStatus(addr, status string) error {
+	p := findAttendee(s.master(), addr)
+	if p == nil {
+		return fmt.Errorf("ical:%s is not an attendee", addr)
+	}
+	if p.Params == nil {
+		p.Params = Parameters{}
+	}
+	p.Params.Set(Paramschedulestatus, status)
+	return nil
+}
+
+// Invite returns the REQUEST inviting every attendee. Every attendee but the
+// organizer is asked for a reply.
+func (s *Schedule) Invite(now time.Time) ([]ScheduleMessage, error) {
+	master := s.master().Clone()
+	for _, addr := range s.recipients(master) {
+		resetAttendee(findAttendee(master, addr))
+	}
+	msgs, err := s.request(master, now)
+	if err != nil {
+		return nil, err
+	}
+	s.cal.SubComponentsObj[0] = master
+	s.markSent(msgs)
+	return msgs, nil
+}
+
+/*
+Update replaces the organizer's event with the edited ev and returns the
+messages the edit calls for.
+
+ev keeps the UID of the event,its SEQUENCE and the PARTSTAT of the attendees
+are taken from the tracked event. After a significant change,see
+IsSignificantChange,SEQUENCE is bumped and every attendee must answer again.
+Added attendees are asked for a reply,removed attendees get a CANCEL and the
+others get a REQUEST with the new version. The tracked event is only
+replaced when all the messages could be built.
+*/
+func (s *Schedule) Update(ev *VEvent, now time.Time) ([]ScheduleMessage, error) {
+	old := s.master()
+	if uid := ev.GetProperty(PropUID); uid == nil || uid.Value != old.GetProperty(PropUID).Value {
+		return nil, fmt.Errorf("ical:Update can not change the UID of a scheduled event")
+	}
+	significant := IsSignificantChange(&VEvent{*old}, ev)
+
+	next := ev.Clone()
+	next.DelProperty(PropSequenceNumber)
+	seq := sequenceOf(old)
+	if significant {
+		seq++
+	}
+	if seq > 0 {
+		next.AddProperty(newPropertyValue(PropSequenceNumber, fmt.Sprint(seq)))
+	}
+	touch(next, now)
+
+	for i := range next.PropertiesObj {
+		p := &next.PropertiesObj[i]
+		if p.Name != PropAttendee {
+			continue
+		}
+		prev := findAttendee(old, p.Value)
+		if prev == nil {
+			resetAttendee(p)
+			continue
+		}
+		if p.Params == nil {
+			p.Params = Parameters{}
+		}
+		for _, key := range []string{Parampartstat, Paramrsvp, Paramschedulestatus, Paramdelto} {
+			if vs, ok := prev.Params[key]; ok {
+				p.Params[key] = append([]string(nil), vs...)
+			}
+		}
+	}
+
+	var removed []string
+	for _, addr := range s.recipients(old) {
+		if findAttendee(next, addr) == nil {
+			removed = append(removed, addr)
+		}
+	}
+
+	var msgs []ScheduleMessage
+	if len(removed) > 0 {
+		com := old.Clone()
+		com.DelProperty(PropAttendee)
+		for _, addr := range removed {
+			com.AddProperty(findAttendee(old, addr).Clone())
+		}
+		com.PutProperty(newPropertyValue(PropStatus, StatusCancelled))
+		com.PutProperty(newPropertyValue(PropSequenceNumber, fmt.Sprint(seq)))
+		setStamp(com, now)
+		stripForMethod(MethodCancel, com)
+		stripScheduleParams(com)
+		msg, err := checkMessage(newMethodCalendar(MethodCancel, com))
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, ScheduleMessage{Recipients: removed, Message: msg})
+	}
+
+	//overrides made by replies keep their RECURRENCE-ID,after a
+	//significant change their answers no longer hold
+	subs := []Component{next}
+	for _, sub := range s.cal.SubComponentsObj[1:] {
+		o := sub.obj().Clone()
+		if significant {
+			for i := range o.PropertiesObj {
+				if o.PropertiesObj[i].Name == PropAttendee && findAttendee(next, o.PropertiesObj[i].Value) != nil {
+					resetAttendee(&o.PropertiesObj[i])
+				}
+			}
+		}
+		subs = append(subs, o)
+	}
+
+	if significant {
+		for _, addr := range s.recipients(next) {
+			resetAttendee(findAttendee(next, addr))
+		}
+	}
+	reqs, err := s.request(next, now)
+	if err != nil {
+		return nil, err
+	}
+	s.cal.SubComponentsObj = subs
+	s.markSent(reqs)
+	return append(msgs, reqs...), nil
+}
+
+// Cancel calls the whole event off and returns the CANCEL for every attendee
+func (s *Schedule) Cancel(now time.Time) ([]ScheduleMessage, error) {
+	master := s.master()
+	recipients := s.recipients(master)
+	//a clone,so that master is untouched when BuildCancel fails
+	ev := &VEvent{*master.Clone()}
+	msg, err := BuildCancel(ev, now)
+	if err != nil {
+		return nil, err
+	}
+	*master = ev.ComponentObj
+	stripScheduleParams(msg.SubComponentsObj[0].obj())
+	msgs := []ScheduleMessage{{Recipients: recipients, Message: msg}}
+	s.markSent(msgs)
+	return msgs, nil
+}
+
+// Receive records a REPLY,see ApplyReply
+func (s *Schedule) Receive(reply Calendar) error {
+	return ApplyReply(s.cal, reply)
+}
+
+// request returns the REQUEST of master for all its recipients,master is
+// not changed
+func (s *Schedule) request(master *ComponentObj, now time.Time) ([]ScheduleMessage, error) {
+	recipients := s.recipients(master)
+	if len(recipients) == 0 {
+		return nil, nil
+	}
+	msg, err := BuildRequest(&VEvent{*master}, now)
+	if err != nil {
+		return nil, err
+	}
+	stripScheduleParams(msg.SubComponentsObj[0].obj())
+	return []ScheduleMessage{{Recipients: recipients, Message: msg}}, nil
+}
+
+// recipients returns the attendees of com the organizer sends messages to
+func (s *Schedule) recipients(com *ComponentObj) []string {
+	organizer := ""
+	if p := com.GetProperty(PropOrganizer); p != nil {
+		organizer = p.Value
+	}
+	var addrs []string
+	for _, p := range com.GetProperties(PropAttendee) {
+		if sameAddress(p.Value, organizer) {
+			continue
+		}
+		switch strings.ToUpper(p.Params.Get(Paramscheduleagent)) {
+		case "", ScheduleAgentServer:
+			addrs = append(addrs, p.Value)
+		}
+	}
+	return addrs
+}
+
+// markSent records that msgs went out to their recipients
+func (s *Schedule) markSent(msgs []ScheduleMessage) {
+	for _, m := range msgs {
+		for _, addr := range m.Recipients {
+			s.SetScheduleStatus(addr, ScheduleStatusSent)
+		}
+	}
+}
+
+// resetAttendee asks the attendee for a new answer
+func resetAttendee(p *Property) {
+	if p.Params == nil {
+		p.Params = Parameters{}
+	}
+	p.Params.Set(Parampartstat, PartstatNeedsAction)
+	p.Params.Set(Paramrsvp, "TRUE")
+	p.Params.Del(Paramdelto)
+}
+
+// stripScheduleParams removes the RFC 6638 parameters,which are only for the
+// organizer's copy and never sent in iTIP messages
+func stripScheduleParams(com *ComponentObj) {
+	for i := range com.PropertiesObj {
+		p := &com.PropertiesObj[i]
+		if p.Name == PropAttendee || p.Name == PropOrganizer {
+			p.Params.Del(Paramscheduleagent)
+			p.Params.Del(Paramschedulestatus)
+			p.Params.Del(Paramscheduleforcesend)
+		}
+	}
+}
+
+// IsSignificantChange reports whether going from old to new reschedules the
+// event: a change of its time or recurrence,RFC 6638 3.2.8
+func IsSignificantChange(old, new *VEvent) bool {
+	for _, name := range significantProps {
+		if timeValues(old, name) != timeValues(new, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// timeValues is a canonical form of the properties named name,for comparing
+func timeValues(ev *VEvent, name string) string {
+	var vals []string
+	for _, p := range ev.GetProperties(name) {
+		if t, err := p.GetToTime(); err == nil && name != PropRecurrenceRule && name != PropDuration &&
+			!strings.Contains(p.Value, ",") {
+			vals = append(vals, t.UTC().Format(DatetimeFormat2)+p.GetParamValue())
+			continue
+		}
+		vals = append(vals, p.Params.Get(Paramtzid)+";"+p.GetParamValue()+":"+strings.ToUpper(p.Value))
+	}
+	sort.Strings(vals)
+	return strings.Join(vals, "\n")
+}
@@ -0,0 +1,195 @@
+package go_ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func recipientsOf(msgs []ScheduleMessage, method string) []string {
+	for _, m := range msgs {
+		if methodOf(*m.Message) == method {
+			return m.Recipients
+		}
+	}
+	return nil
+}
+
+func TestSchedule(t *testing.T) {
+	ev := prepareOrganizerEvent()
+	ev.SetProperty(PropAttendee, "mailto:c@example.com", NewParamItem(Paramscheduleagent, []string{ScheduleAgentClient}))
+	s, err := NewSchedule(ev)
+	if err != nil {
+		t.Fatalf("NewSchedule() err: %v", err)
+	}
+
+	msgs, err := s.Invite(itipNow)
+	if err != nil {
+		t.Fatalf("Invite() err: %v", err)
+	}
+	if got := recipientsOf(msgs, MethodRequest); len(got) != 1 || got[0] != "mailto:b@example.com" {
+		t.Fatalf("Invite() sends REQUEST to %v, want only mailto:b@example.com", got)
+	}
+	var buf strings.Builder
+	if err := NewEncoder(&buf).Encode(msgs[0].Message); err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	if strings.Contains(buf.String(), Paramschedulestatus) || strings.Contains(buf.String(), Paramscheduleagent) {
+		t.Errorf("REQUEST carries RFC 6638 parameters:\n%s", buf.String())
+	}
+	if got := s.Outstanding(); len(got) != 1 || got[0] != "mailto:b@example.com" {
+		t.Errorf("Outstanding() = %v", got)
+	}
+
+	//b accepts
+	reply, err := BuildReply(*msgs[0].Message, "mailto:b@example.com", PartstatAccepted, "", itipNow)
+	if err != nil {
+		t.Fatalf("BuildReply() err: %v", err)
+	}
+	if err := s.Receive(*reply); err != nil {
+		t.Fatalf("Receive() err: %v", err)
+	}
+	if got := s.Outstanding(); len(got) != 0 {
+		t.Errorf("Outstanding() after the reply = %v", got)
+	}
+
+	//a new SUMMARY is not significant: no new answers are needed
+	edited := s.Event()
+	edited.PutProperty(newPropertyValue(PropSummary, "Weekly sync (room 2)"))
+	msgs, err = s.Update(edited, itipNow.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Update() err: %v", err)
+	}
+	if len(recipientsOf(msgs, MethodRequest)) != 1 {
+		t.Errorf("insignificant Update() messages: %v", msgs)
+	}
+	if got := propValue(s.Event().obj(), PropSequenceNumber); got != "" {
+		t.Errorf("insignificant Update() set SEQUENCE %q", got)
+	}
+	if got := s.Outstanding(); len(got) != 0 {
+		t.Errorf("Outstanding() after an insignificant change = %v", got)
+	}
+	for _, st := range s.Attendees() {
+		if st.Address == "mailto:b@example.com" && (st.Partstat != PartstatAccepted || st.ScheduleStatus != ScheduleStatusSent) {
+			t.Errorf("state of b = %+v", st)
+		}
+	}
+
+	//moving the event is significant,dropping c sends no CANCEL as c schedules itself,
+	//adding d invites d
+	edited = s.Event()
+	edited.PutProperty(newPropertyValue(PropDatetimeStart, "20200302T120000Z"))
+	edited.PutProperty(newPropertyValue(PropDatetimeEnd, "20200302T130000Z"))
+	edited.DelProperty(PropAttendee)
+	edited.SetProperty(PropAttendee, "mailto:a@example.com")
+	edited.SetProperty(PropAttendee, "mailto:B@example.com")
+	edited.SetProperty(PropAttendee, "mailto:d@example.com")
+	msgs, err = s.Update(edited, itipNow.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Update() err: %v", err)
+	}
+	if got := recipientsOf(msgs, MethodCancel); len(got) != 0 {
+		t.Errorf("CANCEL sent to %v", got)
+	}
+	if got := recipientsOf(msgs, MethodRequest); len(got) != 2 {
+		t.Errorf("REQUEST sent to %v, want b and d", got)
+	}
+	if got := propValue(s.Event().obj(), PropSequenceNumber); got != "1" {
+		t.Errorf("SEQUENCE = %q, want 1", got)
+	}
+	if got := s.Outstanding(); len(got) != 2 {
+		t.Errorf("Outstanding() after a significant change = %v", got)
+	}
+
+	//dropping d cancels for d
+	edited = s.Event()
+	edited.DelProperty(PropAttendee)
+	edited.SetProperty(PropAttendee, "mailto:b@example.com")
+	msgs, err = s.Update(edited, itipNow.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("Update() err: %v", err)
+	}
+	if got := recipientsOf(msgs, MethodCancel); len(got) != 1 || got[0] != "mailto:d@example.com" {
+		t.Errorf("CANCEL sent to %v, want d", got)
+	}
+
+	msgs, err = s.Cancel(itipNow.Add(4 * time.Hour))
+	if err != nil {
+		t.Fatalf("Cancel() err: %v", err)
+	}
+	if got := recipientsOf(msgs, MethodCancel); len(got) != 1 {
+		t.Errorf("Cancel() sent to %v", got)
+	}
+	if got := propValue(s.Event().obj(), PropStatus); got != StatusCancelled {
+		t.Errorf("STATUS = %q after Cancel()", got)
+	}
+}
+
+func TestScheduleCancelError(t *testing.T) {
+	s, err := NewSchedule(prepareOrganizerEvent())
+	if err != nil {
+		t.Fatalf("NewSchedule() err: %v", err)
+	}
+	//a CANCEL without ORGANIZER fails validation
+	s.master().DelProperty(PropOrganizer)
+	want := encodedSchedule(t, s)
+	if _, err := s.Cancel(itipNow); err == nil {
+		t.Fatal("Cancel() without ORGANIZER succeeded")
+	}
+	if got := encodedSchedule(t, s); got != want {
+		t.Errorf("a failed Cancel() changed the event:\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestScheduleUpdateError(t *testing.T) {
+	s, err := NewSchedule(prepareOrganizerEvent())
+	if err != nil {
+		t.Fatalf("NewSchedule() err: %v", err)
+	}
+	if _, err := s.Invite(itipNow); err != nil {
+		t.Fatalf("Invite() err: %v", err)
+	}
+	//b answered for one occurrence
+	override := s.master().Clone()
+	override.AddProperty(newPropertyValue(PropRecurrenceId, "20200309T100000Z"))
+	findAttendee(override, "mailto:b@example.com").Params.Set(Parampartstat, PartstatAccepted)
+	s.cal.SubComponentsObj = append(s.cal.SubComponentsObj, override)
+
+	//a REQUEST without DTSTART fails validation
+	edited := s.Event()
+	edited.DelProperty(PropDatetimeStart)
+	want := encodedSchedule(t, s)
+	if _, err := s.Update(edited, itipNow.Add(time.Hour)); err == nil {
+		t.Fatal("Update() without DTSTART succeeded")
+	}
+	if got := encodedSchedule(t, s); got != want {
+		t.Errorf("a failed Update() changed the calendar:\n%s\nwant\n%s", got, want)
+	}
+}
+
+// encodedSchedule returns the tracked calendar of s as text
+func encodedSchedule(t *testing.T, s *Schedule) string {
+	t.Helper()
+	var buf strings.Builder
+	if err := NewEncoder(&buf).Encode(s.cal); err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	return buf.String()
+}
+
+func TestIsSignificantChange(t *testing.T) {
+	a := prepareOrganizerEvent()
+	b := &VEvent{*a.Clone()}
+	b.PutProperty(newPropertyValue(PropLocation, "Room 2"))
+	if IsSignificantChange(a, b) {
+		t.Errorf("a new LOCATION is significant")
+	}
+	b.PutProperty(Property{Name: PropDatetimeStart, Params: Parameters{Paramtzid: {"UTC"}}, Value: "20200302T100000"})
+	if IsSignificantChange(a, b) {
+		t.Errorf("the same DTSTART written with TZID is significant")
+	}
+	b.PutProperty(newPropertyValue(PropRecurrenceRule, "FREQ=WEEKLY;COUNT=5"))
+	if !IsSignificantChange(a, b) {
+		t.Errorf("a new RRULE is not significant")
+	}
+}
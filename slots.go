@@ -0,0 +1,182 @@
+package go_ical
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SlotAttendee is the free/busy time and the working hours of one attendee
+type SlotAttendee struct {
+	Address  string
+	FreeBusy *VFreeBusy
+	//Location is the time zone of the attendee,UTC when nil
+	Location *time.Location
+	//WorkStart and WorkEnd are the working hours as times of day in Location,
+	//both zero means the whole day
+	WorkStart time.Duration
+	WorkEnd   time.Duration
+	//WorkDays defaults to Monday to Friday
+	WorkDays []time.Weekday
+}
+
+// SlotQuery describes the meeting FindSlots looks for
+type SlotQuery struct {
+	From     time.Time
+	To       time.Time
+	Duration time.Duration
+	//Step is the distance between candidate starts,15 minutes by default
+	Step      time.Duration
+	Attendees []SlotAttendee
+	//NotBefore and NotAfter limit the meeting to a time of day in the time
+	//zone of every attendee,e.g. NotBefore: 9*time.Hour. Zero means no limit
+	NotBefore time.Duration
+	NotAfter  time.Duration
+	//AllowTentative accepts slots where attendees are BUSY-TENTATIVE,such
+	//slots rank after the ones where everyone is free
+	AllowTentative bool
+	//Max limits the number of slots returned,zero returns all
+	Max int
+}
+
+// Slot is a candidate meeting time
+type Slot struct {
+	Period
+	//Tentative holds the attendees who are BUSY-TENTATIVE during the slot
+	Tentative []string
+}
+
+/*
+FindSlots returns the times in [q.From,q.To) when every attendee could meet
+for q.Duration,judging by their VFREEBUSY and working hours.
+
+A slot must fit in the working hours and NotBefore/NotAfter of every attendee,
+in the attendee's time zone. BUSY and BUSY-UNAVAILABLE time,and time outside
+the DTSTART/DTEND range of a VFREEBUSY,is never used. Slots are ranked by the
+number of tentatively busy attendees,then by start.
+*/
+func FindSlots(q SlotQuery) ([]Slot, error) {
+	if q.Duration <= 0 {
+		return nil, fmt.Errorf("ical:FindSlots needs a positive duration")
+	}
+	step := q.Step
+	if step <= 0 {
+		step = 15 * time.Minute
+	}
+	type attendee struct {
+		SlotAttendee
+		busy      []FreeBusyPeriod
+		known     Period
+		hasWindow bool
+	}
+	var as []attendee
+	for _, a := range q.Attendees {
+		at := attendee{SlotAttendee: a}
+		if at.Location == nil {
+			at.Location = time.UTC
+		}
+		if len(at.WorkDays) == 0 {
+			at.WorkDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		}
+		if a.FreeBusy != nil {
+			fbs, err := a.FreeBusy.Periods()
+			if err != nil {
+				return nil, err
+			}
+			at.busy = MergeFreeBusy(fbs, q.From, q.To)
+			start, end := a.FreeBusy.GetProperty(PropDatetimeStart), a.FreeBusy.GetProperty(PropDatetimeEnd)
+			if start != nil && end != nil {
+				s, err := start.GetToTime()
+				if err != nil {
+					return nil, err
+				}
+				e, err := end.GetToTime()
+				if err != nil {
+					return nil, err
+				}
+				at.known, at.hasWindow = Period{Start: s, End: e}, true
+			}
+		}
+		as = append(as, at)
+	}
+
+	var slots []Slot
+	first := q.From.Truncate(step)
+	if first.Before(q.From) {
+		first = first.Add(step)
+	}
+	for s := first; !s.Add(q.Duration).After(q.To); s = s.Add(step) {
+		slot := Slot{Period: Period{Start: s.UTC(), End: s.Add(q.Duration).UTC()}}
+		ok := true
+		for _, a := range as {
+			if !a.fits(slot.Period, q.NotBefore, q.NotAfter) ||
+				a.hasWindow && (slot.Start.Before(a.known.Start) || slot.End.After(a.known.End)) {
+				ok = false
+				break
+			}
+			tentative := false
+			for _, b := range a.busy {
+				if !b.Start.Before(slot.End) || !b.End.After(slot.Start) {
+					continue
+				}
+				if b.Type != FBTypeBusyTentative || !q.AllowTentative {
+					ok = false
+					break
+				}
+				tentative = true
+			}
+			if !ok {
+				break
+			}
+			if tentative {
+				slot.Tentative = append(slot.Tentative, a.Address)
+			}
+		}
+		if ok {
+			slots = append(slots, slot)
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return len(slots[i].Tentative) < len(slots[j].Tentative) })
+	if q.Max > 0 && len(slots) > q.Max {
+		slots = slots[:q.Max]
+	}
+	return slots, nil
+}
+
+// fits checks the slot against the working days and hours of a,intersected
+// with the notBefore and notAfter times of day
+func (a SlotAttendee) fits(pe Period, notBefore, notAfter time.Duration) bool {
+	start, end := pe.Start.In(a.Location), pe.End.In(a.Location)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, a.Location)
+	found := false
+	for _, wd := range a.WorkDays {
+		if wd == start.Weekday() {
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+	lo, hi := a.WorkStart, a.WorkEnd
+	if lo == 0 && hi == 0 {
+		hi = 24 * time.Hour
+	}
+	if notBefore > lo {
+		lo = notBefore
+	}
+	if notAfter > 0 && notAfter < hi {
+		hi = notAfter
+	}
+	//times of day are wall clock times,daylight saving time changes are respected
+	open := wallClock(naive(day).Add(lo), a.Location)
+	shut := wallClock(naive(day).Add(hi), a.Location)
+	return !start.Before(open) && !end.After(shut)
+}
+
+func (s Slot) String() string {
+	if len(s.Tentative) == 0 {
+		return s.Period.String()
+	}
+	return s.Period.String() + " (tentative: " + strings.Join(s.Tentative, ", ") + ")"
+}
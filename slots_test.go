@@ -0,0 +1,79 @@
+package go_ical
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindSlots(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	at := func(h, m int) time.Time { return time.Date(2020, 3, 3, h, m, 0, 0, time.UTC) }
+	from, to := at(0, 0), at(23, 0)
+	newFreeBusy := func(fbs ...FreeBusyPeriod) *VFreeBusy {
+		fb := &VFreeBusy{ComponentObj{NameObj: CompFreebusy}}
+		for _, x := range []struct {
+			name string
+			t    time.Time
+		}{{PropDatetimeStart, from}, {PropDatetimeEnd, to}} {
+			p := NewProperty(x.name)
+			p.setFromUTC(x.t)
+			fb.AddProperty(*p)
+		}
+		fb.SetPeriods(fbs)
+		return fb
+	}
+	q := SlotQuery{
+		From:     from,
+		To:       to,
+		Duration: 30 * time.Minute,
+		Step:     30 * time.Minute,
+		Attendees: []SlotAttendee{
+			//working hours 08:00-16:00 UTC
+			{Address: "mailto:a@example.com", Location: berlin, WorkStart: 9 * time.Hour, WorkEnd: 17 * time.Hour,
+				FreeBusy: newFreeBusy(FreeBusyPeriod{Period{at(14, 0), at(15, 0)}, FBTypeBusy})},
+			//working hours 14:00-22:00 UTC
+			{Address: "mailto:b@example.com", Location: newYork, WorkStart: 9 * time.Hour, WorkEnd: 17 * time.Hour,
+				FreeBusy: newFreeBusy(FreeBusyPeriod{Period{at(15, 0), at(15, 30)}, FBTypeBusyTentative})},
+		},
+	}
+	check := func(want ...string) {
+		t.Helper()
+		slots, err := FindSlots(q)
+		if err != nil {
+			t.Fatalf("FindSlots() err: %v", err)
+		}
+		var got []string
+		for _, s := range slots {
+			got = append(got, s.String())
+		}
+		if len(got) != len(want) {
+			t.Fatalf("FindSlots() = %v, want %v", got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("slot %d = %s, want %s", i, got[i], want[i])
+			}
+		}
+	}
+
+	check("20200303T153000Z/20200303T160000Z")
+
+	q.AllowTentative = true
+	check("20200303T153000Z/20200303T160000Z",
+		"20200303T150000Z/20200303T153000Z (tentative: mailto:b@example.com)")
+
+	//not before 10:30 local,which is 15:30 UTC in New York
+	q.NotBefore = 10*time.Hour + 30*time.Minute
+	check("20200303T153000Z/20200303T160000Z")
+
+	//Sunday is not a working day
+	q.From, q.To = q.From.AddDate(0, 0, 5), q.To.AddDate(0, 0, 5)
+	check()
+}
@@ -0,0 +1,86 @@
+package go_ical
+
+// splitProperties are the calendar properties every split calendar gets
+var splitProperties = []string{PropVersion, PropProductIdentifier, PropCalendarScale, PropMethod}
+
+/*
+SplitCalendar splits cal into a calendar per UID,as CalDAV and vdir store
+them.
+
+Each calendar holds the components of one UID,the master and its
+RECURRENCE-ID overrides,in the order of cal,after the VTIMEZONEs they
+reference by TZID. VERSION,PRODID,CALSCALE and METHOD are copied. Components
+without UID get a calendar each. The calendars share nothing with cal.
+*/
+func SplitCalendar(cal Calendar) []*Calendar {
+	zones := map[string]*ComponentObj{}
+	var zoneOrder []string
+	for _, sub := range cal.SubComponentsObj {
+		if sub.Name() != CompTimezone {
+			continue
+		}
+		if p := sub.obj().GetProperty(PropTimeZoneIdentifier); p != nil {
+			if _, dup := zones[p.Value]; !dup {
+				zones[p.Value] = sub.obj()
+				zoneOrder = append(zoneOrder, p.Value)
+			}
+		}
+	}
+
+	var groups [][]*ComponentObj
+	byUID := map[string]int{}
+	for _, sub := range cal.SubComponentsObj {
+		if sub.Name() == CompTimezone {
+			continue
+		}
+		com := sub.obj()
+		uid := com.GetProperty(PropUID)
+		if uid == nil {
+			groups = append(groups, []*ComponentObj{com})
+			continue
+		}
+		if i, ok := byUID[uid.Value]; ok {
+			groups[i] = append(groups[i], com)
+			continue
+		}
+		byUID[uid.Value] = len(groups)
+		groups = append(groups, []*ComponentObj{com})
+	}
+
+	cals := make([]*Calendar, 0, len(groups))
+	for _, group := range groups {
+		split := &Calendar{ComponentObj{NameObj: CompCalendar, PropertiesObj: []Property{}, SubComponentsObj: []Component{}}}
+		for _, p := range cal.PropertiesObj {
+			if contains(splitProperties, p.Name) {
+				split.AddProperty(p.Clone())
+			}
+		}
+		used := map[string]bool{}
+		for _, com := range group {
+			referencedTZIDs(com, used)
+		}
+		for _, tzid := range zoneOrder {
+			if used[tzid] {
+				split.AddComponent(zones[tzid].Clone())
+			}
+		}
+		for _, com := range group {
+			split.AddComponent(com.Clone())
+		}
+		cals = append(cals, split)
+	}
+	return cals
+}
+
+// referencedTZIDs adds the TZID parameters of com and its sub-components to
+// used
+func referencedTZIDs(com *ComponentObj, used map[string]bool) {
+	for _, p := range com.PropertiesObj {
+		if tzid := p.Params.Get(Paramtzid); tzid != "" {
+			used[tzid] = true
+		}
+	}
+	for _, sub := range com.SubComponentsObj {
+		referencedTZIDs(sub.obj(), used)
+	}
+}
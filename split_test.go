@@ -0,0 +1,115 @@
+package go_ical
+
+import (
+	"strings"
+	"testing"
+)
+
+const splitCalendarStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+CALSCALE:GREGORIAN
+METHOD:PUBLISH
+X-WR-CALNAME:Work
+BEGIN:VTIMEZONE
+TZID:Office
+BEGIN:STANDARD
+DTSTART:19700101T000000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0100
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VTIMEZONE
+TZID:Home
+BEGIN:STANDARD
+DTSTART:19700101T000000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0200
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+DTSTART;TZID=Office:20210301T090000
+RRULE:FREQ=WEEKLY
+SUMMARY:Standup
+END:VEVENT
+BEGIN:VTODO
+UID:review@example.com
+DTSTAMP:20210301T080000Z
+SUMMARY:Review
+END:VTODO
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+RECURRENCE-ID;TZID=Office:20210308T090000
+DTSTART;TZID=Home:20210308T100000
+SUMMARY:Standup from home
+END:VEVENT
+BEGIN:VJOURNAL
+DTSTAMP:20210301T080000Z
+SUMMARY:No UID
+END:VJOURNAL
+END:VCALENDAR
+`
+
+func TestSplitCalendar(t *testing.T) {
+	cal := decodeString(t, splitCalendarStr)
+	cals := SplitCalendar(cal)
+	if len(cals) != 3 {
+		t.Fatalf("SplitCalendar() = %d calendars", len(cals))
+	}
+	want := []string{
+		"PRODID,VERSION,CALSCALE,METHOD VTIMEZONE Office,VTIMEZONE Home,VEVENT standup@example.com,VEVENT standup@example.com 20210308T090000",
+		"PRODID,VERSION,CALSCALE,METHOD VTODO review@example.com",
+		"PRODID,VERSION,CALSCALE,METHOD VJOURNAL",
+	}
+	for i, c := range cals {
+		var props []string
+		for _, p := range c.Properties() {
+			props = append(props, p.Name)
+		}
+		got := strings.Join(props, ",") + " " + strings.Join(splitKeys(c), ",")
+		if got != want[i] {
+			t.Errorf("calendar %d = %s, want %s", i, got, want[i])
+		}
+	}
+
+	//the calendars are independent of cal and each other
+	cals[0].SubComponents()[2].obj().PutProperty(newPropertyValue(PropSummary, "Changed"))
+	if cal.SubComponents()[2].obj().GetProperty(PropSummary).Value != "Standup" {
+		t.Error("SplitCalendar() shares components with cal")
+	}
+	if d := DiffCalendars(cal, *CombineCalendars(CombinePolicy{}, derefCalendars(SplitCalendar(cal))...)); len(d.Components) != 0 {
+		t.Errorf("combining the split calendars changes them:\n%s", d)
+	}
+}
+
+// splitKeys describes the components of cal by name,UID and the value of
+// RECURRENCE-ID
+func splitKeys(cal *Calendar) []string {
+	var keys []string
+	for _, sub := range cal.SubComponents() {
+		com := sub.obj()
+		s := com.Name()
+		if p := com.GetProperty(PropTimeZoneIdentifier); p != nil {
+			s += " " + p.Value
+		}
+		if p := com.GetProperty(PropUID); p != nil {
+			s += " " + p.Value
+		}
+		if p := com.GetProperty(PropRecurrenceId); p != nil {
+			s += " " + p.Value
+		}
+		keys = append(keys, s)
+	}
+	return keys
+}
+
+func derefCalendars(cals []*Calendar) []Calendar {
+	var vs []Calendar
+	for _, c := range cals {
+		vs = append(vs, *c)
+	}
+	return vs
+}
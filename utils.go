@@ -1,6 +1,11 @@
 package go_ical
 
-import "strings"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
 
 
 var textEscaper = strings.NewReplacer(
@@ -26,3 +31,12 @@ func ToText(s string) string {
 func FromText(s string) string {
 	return textUnescaper.Replace(s)
 }
+
+//newUID returns a random UID,RFC 5545 3.8.4.7 recommends a globally unique value
+func newUID() (string,error) {
+	b := make([]byte,16)
+	if _,err := rand.Read(b);err != nil{
+		return "",fmt.Errorf("ical:can not make a UID: %v",err)
+	}
+	return hex.EncodeToString(b)+"@go-ical",nil
+}
@@ -0,0 +1,908 @@
+package go_ical
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity tells how serious a Finding is
+type Severity int
+
+const (
+	//SeverityError is a violation of a MUST in the RFC
+	SeverityError Severity = iota
+	//SeverityWarning is a violation of a SHOULD,or data the validator does not know
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "severity(" + strconv.Itoa(int(s)) + ")"
+}
+
+/*
+Finding is one problem found by Validate.
+
+Path points into the component tree,e.g. "VCALENDAR/VEVENT[0]/DTEND",
+component indexes count the siblings with the same name and a property gets
+an index only when it occurs more than once. Section is the RFC section
+defining the broken rule,e.g. "RFC 5545 3.8.2.2".
+*/
+type Finding struct {
+	Severity Severity
+	Path     string
+	Section  string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%v: %s: %s (%s)", f.Severity, f.Path, f.Message, f.Section)
+}
+
+// HasErrors reports whether any finding has SeverityError
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidationError carries the findings which made an operation refuse a calendar
+type ValidationError struct {
+	Findings []Finding
+}
+
+func (e *ValidationError) Error() string {
+	var errs []Finding
+	for _, f := range e.Findings {
+		if f.Severity == SeverityError {
+			errs = append(errs, f)
+		}
+	}
+	if len(errs) == 0 {
+		return "ical: invalid calendar"
+	}
+	if len(errs) == 1 {
+		return "ical: " + errs[0].String()
+	}
+	return fmt.Sprintf("ical: %v (and %d more errors)", errs[0], len(errs)-1)
+}
+
+// RFC 5545 section of each component
+var compSections = map[string]string{
+	CompCalendar:         "3.4",
+	CompEvent:            "3.6.1",
+	CompTodo:             "3.6.2",
+	CompJournal:          "3.6.3",
+	CompFreebusy:         "3.6.4",
+	CompTimezone:         "3.6.5",
+	CompTimezoneStandard: "3.6.5",
+	CompTimezoneDaylight: "3.6.5",
+	CompAlarm:            "3.6.6",
+	CompAvailability:     "RFC 7953 3.1",
+	CompAvailable:        "RFC 7953 3.1",
+}
+
+// RFC section of each property,RFC 7986 properties carry their RFC number
+var propSections = map[string]string{
+	PropCalendarScale:      "3.7.1",
+	PropMethod:             "3.7.2",
+	PropProductIdentifier:  "3.7.3",
+	PropVersion:            "3.7.4",
+	PropAttachment:         "3.8.1.1",
+	PropCategories:         "3.8.1.2",
+	PropClassification:     "3.8.1.3",
+	PropComment:            "3.8.1.4",
+	PropDescription:        "3.8.1.5",
+	PropGeographicPosition: "3.8.1.6",
+	PropLocation:           "3.8.1.7",
+	PropPercentComplete:    "3.8.1.8",
+	PropPriority:           "3.8.1.9",
+	PropResources:          "3.8.1.10",
+	PropStatus:             "3.8.1.11",
+	PropSummary:            "3.8.1.12",
+	PropDatetimeCompleted:  "3.8.2.1",
+	PropDatetimeEnd:        "3.8.2.2",
+	PropDatetimeDue:        "3.8.2.3",
+	PropDatetimeStart:      "3.8.2.4",
+	PropDuration:           "3.8.2.5",
+	PropFreeBusy:           "3.8.2.6",
+	PropTimeTransparency:   "3.8.2.7",
+	PropTimeZoneIdentifier: "3.8.3.1",
+	PropTimeZoneName:       "3.8.3.2",
+	PropTimeZoneOffsetFrom: "3.8.3.3",
+	PropTimeZoneOffsetTo:   "3.8.3.4",
+	PropTimeZoneURL:        "3.8.3.5",
+	PropAttendee:           "3.8.4.1",
+	PropContact:            "3.8.4.2",
+	PropOrganizer:          "3.8.4.3",
+	PropRecurrenceId:       "3.8.4.4",
+	PropRelatedTo:          "3.8.4.5",
+	PropURL:                "3.8.4.6",
+	PropUID:                "3.8.4.7",
+	PropExceptionDatetime:  "3.8.5.1",
+	PropRecurrenceDatetime: "3.8.5.2",
+	PropRecurrenceRule:     "3.8.5.3",
+	PropAction:             "3.8.6.1",
+	PropRepeatCount:        "3.8.6.2",
+	PropTrigger:            "3.8.6.3",
+	PropDatetimeCreated:    "3.8.7.1",
+	PropDatetimeStamp:      "3.8.7.2",
+	PropLastModified:       "3.8.7.3",
+	PropSequenceNumber:     "3.8.7.4",
+	PropRequestStatus:      "3.8.8.3",
+	PropName:               "RFC 7986 5.1",
+	PropRefreshInterval:    "RFC 7986 5.7",
+	PropSource:             "RFC 7986 5.8",
+	PropColor:              "RFC 7986 5.9",
+	PropImage:              "RFC 7986 5.10",
+	PropConference:         "RFC 7986 5.11",
+	PropBusyType:           "RFC 7953 3.2",
+}
+
+var paramSections = map[string]string{
+	Paramaltrep:         "3.2.1",
+	Paramcn:             "3.2.2",
+	Paramcutype:         "3.2.3",
+	Paramdelfrom:        "3.2.4",
+	Paramdelto:          "3.2.5",
+	Paramdir:            "3.2.6",
+	Paramencoding:       "3.2.7",
+	Paramfmttype:        "3.2.8",
+	Paramfbtype:         "3.2.9",
+	Paramlanguage:       "3.2.10",
+	Parammember:         "3.2.11",
+	Parampartstat:       "3.2.12",
+	Paramrange:          "3.2.13",
+	Paramtrigrel:        "3.2.14",
+	Paramreltype:        "3.2.15",
+	Paramrole:           "3.2.16",
+	Paramrsvp:           "3.2.17",
+	Paramsentby:         "3.2.18",
+	Paramtzid:           "3.2.19",
+	Paramvaluetypeparam: "3.2.20",
+}
+
+var vdtSections = map[string]string{
+	VDTbinary:          "3.3.1",
+	VDTbool:            "3.3.2",
+	VDTcalendaraddress: "3.3.3",
+	VDTdate:            "3.3.4",
+	VDTdatetime:        "3.3.5",
+	VDTduration:        "3.3.6",
+	VDTfloat:           "3.3.7",
+	VDTint:             "3.3.8",
+	VDTperiod:          "3.3.9",
+	VDTrecurrence:      "3.3.10",
+	VDTtext:            "3.3.11",
+	VDTtime:            "3.3.12",
+	VDTuri:             "3.3.13",
+	VDTutcoffset:       "3.3.14",
+}
+
+// value types a property can use besides its DefaultVDT
+var alternateVDT = map[string][]string{
+	PropAttachment:         {VDTbinary},
+	PropImage:              {VDTbinary},
+	PropDatetimeEnd:        {VDTdate},
+	PropDatetimeDue:        {VDTdate},
+	PropDatetimeStart:      {VDTdate},
+	PropRecurrenceId:       {VDTdate},
+	PropExceptionDatetime:  {VDTdate},
+	PropRecurrenceDatetime: {VDTdate, VDTperiod},
+	PropTrigger:            {VDTdatetime},
+}
+
+// properties whose value is a comma separated list
+var multiValueProps = map[string]bool{
+	PropCategories:         true,
+	PropResources:          true,
+	PropExceptionDatetime:  true,
+	PropRecurrenceDatetime: true,
+	PropFreeBusy:           true,
+}
+
+// RFC 5545 parameters each property accepts,VALUE is accepted everywhere
+// and x-param / iana-param are never checked
+var allowedParams = map[string][]string{
+	PropAttachment:         {Paramfmttype, Paramencoding},
+	PropCategories:         {Paramlanguage},
+	PropComment:            {Paramaltrep, Paramlanguage},
+	PropDescription:        {Paramaltrep, Paramlanguage},
+	PropLocation:           {Paramaltrep, Paramlanguage},
+	PropResources:          {Paramaltrep, Paramlanguage},
+	PropSummary:            {Paramaltrep, Paramlanguage},
+	PropContact:            {Paramaltrep, Paramlanguage},
+	PropDatetimeEnd:        {Paramtzid},
+	PropDatetimeDue:        {Paramtzid},
+	PropDatetimeStart:      {Paramtzid},
+	PropFreeBusy:           {Paramfbtype},
+	PropTimeZoneName:       {Paramlanguage},
+	PropAttendee:           {Paramcutype, Parammember, Paramrole, Parampartstat, Paramrsvp, Paramdelto, Paramdelfrom, Paramsentby, Paramcn, Paramdir, Paramlanguage},
+	PropOrganizer:          {Paramcn, Paramdir, Paramsentby, Paramlanguage},
+	PropRecurrenceId:       {Paramtzid, Paramrange},
+	PropRelatedTo:          {Paramreltype},
+	PropExceptionDatetime:  {Paramtzid},
+	PropRecurrenceDatetime: {Paramtzid},
+	PropTrigger:            {Paramtrigrel},
+	PropRequestStatus:      {Paramlanguage},
+	PropName:               {Paramaltrep, Paramlanguage},
+	PropImage:              {Paramfmttype, Paramencoding},
+	PropConference:         {Paramfmttype, Paramlanguage},
+}
+
+// properties whose DATE-TIME value MUST be in UTC
+var utcOnlyProps = map[string]bool{
+	PropDatetimeCompleted: true,
+	PropDatetimeCreated:   true,
+	PropDatetimeStamp:     true,
+	PropLastModified:      true,
+	PropTrigger:           true,
+}
+
+var statusValues = map[string][]string{
+	CompEvent:   {StatusTentative, StatusConfirmed, StatusCancelled},
+	CompTodo:    {StatusNeedsAction, StatusCompleted, StatusInProcess, StatusCancelled},
+	CompJournal: {StatusDraft, StatusFinal, StatusCancelled},
+}
+
+/*
+Validate checks a calendar against RFC 5545 and returns every problem it
+finds,an empty result means the calendar is valid.
+
+It checks for each component the required,optional and repeatable
+properties and the allowed sub-components,the value of each property
+against its value type (DefaultVDT or the VALUE parameter),the parameters
+each property accepts,and the constraints between properties such as
+DTEND being later than DTSTART.
+*/
+func Validate(cal Calendar) []Finding {
+	v := &validator{tzids: map[string]bool{}}
+	for _, p := range cal.Properties() {
+		if p.Name == PropMethod {
+			v.method = p.Value
+		}
+	}
+	for _, sub := range cal.SubComponents() {
+		if sub.Name() != CompTimezone {
+			continue
+		}
+		for _, p := range sub.Properties() {
+			if p.Name == PropTimeZoneIdentifier {
+				v.tzids[p.Value] = true
+			}
+		}
+	}
+	v.component(&cal, nil, cal.Name())
+	return v.findings
+}
+
+type validator struct {
+	findings []Finding
+	method   string
+	tzids    map[string]bool
+}
+
+func (v *validator) add(sev Severity, path, section, format string, args ...interface{}) {
+	if !strings.HasPrefix(section, "RFC ") {
+		section = "RFC 5545 " + section
+	}
+	v.findings = append(v.findings, Finding{
+		Severity: sev,
+		Path:     path,
+		Section:  section,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) component(com, parent Component, path string) {
+	name := com.Name()
+	section, known := compSections[name]
+	if !known {
+		if !strings.HasPrefix(name, "X-") {
+			v.add(SeverityWarning, path, "3.6", "unknown component %q", name)
+		}
+		return
+	}
+
+	//properties
+	counts := map[string]int{}
+	for _, p := range com.Properties() {
+		counts[p.Name]++
+	}
+	for _, pn := range OnlyOnePropMap[name] {
+		if counts[pn] == 0 {
+			v.add(SeverityError, path, section, "%s is required in %s", pn, name)
+		} else if counts[pn] > 1 {
+			v.add(SeverityError, path, section, "%s MUST occur only once in %s,but got %d", pn, name, counts[pn])
+		}
+	}
+	for _, pn := range OneOrZeroPropMap[name] {
+		if counts[pn] > 1 {
+			v.add(SeverityError, path, section, "%s MUST NOT occur more than once in %s,but got %d", pn, name, counts[pn])
+		}
+	}
+	if counts[PropRecurrenceRule] > 1 && name != CompTimezoneStandard && name != CompTimezoneDaylight {
+		v.add(SeverityWarning, path, section, "%s SHOULD NOT occur more than once in %s", PropRecurrenceRule, name)
+	}
+	seen := map[string]int{}
+	for i := range com.Properties() {
+		p := &com.Properties()[i]
+		ppath := path + "/" + p.Name
+		if counts[p.Name] > 1 {
+			ppath += "[" + strconv.Itoa(seen[p.Name]) + "]"
+		}
+		seen[p.Name]++
+		if !propAllowed(name, p.Name) {
+			if strings.HasPrefix(p.Name, "X-") {
+				continue
+			}
+			if ps, ok := propSections[p.Name]; ok {
+				v.add(SeverityError, ppath, ps, "%s is not allowed in %s", p.Name, name)
+			} else {
+				v.add(SeverityWarning, ppath, "3.8.8.1", "unknown property %q", p.Name)
+				continue
+			}
+		}
+		v.property(name, p, ppath)
+	}
+
+	//sub-components
+	subSeen := map[string]int{}
+	for _, sub := range com.SubComponents() {
+		spath := path + "/" + sub.Name() + "[" + strconv.Itoa(subSeen[sub.Name()]) + "]"
+		subSeen[sub.Name()]++
+		if !contains(SubComponentMap[name], sub.Name()) && !strings.HasPrefix(sub.Name(), "X-") {
+			v.add(SeverityError, spath, section, "%s is not allowed in %s", sub.Name(), name)
+			continue
+		}
+		v.component(sub, com, spath)
+	}
+
+	switch name {
+	case CompCalendar:
+		if len(com.SubComponents()) == 0 {
+			v.add(SeverityError, path, section, "VCALENDAR MUST contain at least one component")
+		}
+		for _, p := range com.Properties() {
+			if p.Name == PropVersion && p.Value != "2.0" {
+				v.add(SeverityError, path+"/"+PropVersion, propSections[PropVersion], "VERSION MUST be 2.0,but got %q", p.Value)
+			}
+		}
+		v.uniqueTZIDs(com, path)
+	case CompEvent, CompTodo, CompJournal:
+		v.schedulingComponent(com, path, section)
+	case CompFreebusy:
+		v.freebusy(com, path, section)
+	case CompTimezone:
+		if subSeen[CompTimezoneStandard]+subSeen[CompTimezoneDaylight] == 0 {
+			v.add(SeverityError, path, section, "VTIMEZONE MUST contain at least one STANDARD or DAYLIGHT component")
+		}
+	case CompTimezoneStandard, CompTimezoneDaylight:
+		v.observance(com, path, section)
+	case CompAlarm:
+		v.alarm(com, parent, path, section)
+	case CompAvailability, CompAvailable:
+		v.schedulingComponent(com, path, section)
+		v.availability(com, path)
+	}
+}
+
+func propAllowed(comp, prop string) bool {
+	return contains(OnlyOnePropMap[comp], prop) || contains(OneOrZeroPropMap[comp], prop) || contains(MultiPropMap[comp], prop)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) property(comp string, p *Property, path string) {
+	section := propSections[p.Name]
+	for key, vals := range p.Params {
+		psec, std := paramSections[key]
+		if !std {
+			continue
+		}
+		if key != Paramvaluetypeparam && !contains(allowedParams[p.Name], key) {
+			v.add(SeverityError, path, section, "parameter %s is not allowed on %s", key, p.Name)
+			continue
+		}
+		for _, val := range vals {
+			if err := checkParamValue(key, val); err != nil {
+				v.add(SeverityError, path, psec, "%v", err)
+			}
+		}
+	}
+
+	vdt := DefaultVDT[p.Name]
+	if t := p.Params.Get(Paramvaluetypeparam); t != "" {
+		vdt = strings.ToUpper(t)
+		if vdt != DefaultVDT[p.Name] && !contains(alternateVDT[p.Name], vdt) {
+			v.add(SeverityError, path, section, "value type %s is not allowed on %s", vdt, p.Name)
+			return
+		}
+	}
+	//GEO is two FLOAT values,checkPropValue takes care of it
+	if vsec, ok := vdtSections[vdt]; ok && p.Name != PropGeographicPosition {
+		vals := []string{p.Value}
+		if multiValueProps[p.Name] && vdt != VDTtext {
+			vals = splitValues(p.Value)
+		}
+		for _, val := range vals {
+			if err := checkValue(vdt, val); err != nil {
+				v.add(SeverityError, path, vsec, "%s: %v", p.Name, err)
+				return
+			}
+		}
+	}
+
+	tzid := p.Params.Get(Paramtzid)
+	switch {
+	case tzid != "" && vdt == VDTdate:
+		v.add(SeverityError, path, paramSections[Paramtzid], "TZID can not be used with a DATE value")
+	case tzid != "" && strings.HasSuffix(p.Value, "Z"):
+		v.add(SeverityError, path, paramSections[Paramtzid], "TZID can not be used with a UTC time")
+	case tzid != "" && !v.tzids[tzid]:
+		v.add(SeverityError, path, paramSections[Paramtzid], "no VTIMEZONE defines TZID %q", tzid)
+	}
+	if utcOnlyProps[p.Name] && vdt == VDTdatetime && !strings.HasSuffix(p.Value, "Z") {
+		v.add(SeverityError, path, section, "%s MUST be in UTC", p.Name)
+	}
+	if err := checkPropValue(comp, p); err != nil {
+		v.add(SeverityError, path, section, "%v", err)
+	}
+}
+
+// checkParamValue checks the parameters with a closed set of values
+func checkParamValue(key, val string) error {
+	var allowed []string
+	switch key {
+	case Paramrsvp:
+		allowed = []string{"TRUE", "FALSE"}
+	case Paramencoding:
+		allowed = []string{"8BIT", "BASE64"}
+	case Paramrange:
+		allowed = []string{"THISANDFUTURE"}
+	case Paramtrigrel:
+		allowed = []string{"START", "END"}
+	case Paramdelfrom, Paramdelto, Parammember, Paramsentby:
+		if err := checkValue(VDTcalendaraddress, val); err != nil {
+			return fmt.Errorf("parameter %s: %v", key, err)
+		}
+		return nil
+	default:
+		return nil
+	}
+	if !contains(allowed, strings.ToUpper(val)) {
+		return fmt.Errorf("invalid %s parameter value %q", key, val)
+	}
+	return nil
+}
+
+// checkValue checks that val is valid for the value type vdt
+func checkValue(vdt, val string) error {
+	var err error
+	switch vdt {
+	case VDTbinary:
+		_, err = base64.StdEncoding.DecodeString(val)
+	case VDTbool:
+		if u := strings.ToUpper(val); u != "TRUE" && u != "FALSE" {
+			err = fmt.Errorf("invalid boolean %q", val)
+		}
+	case VDTcalendaraddress, VDTuri:
+		var u *url.URL
+		if u, err = url.Parse(val); err == nil && u.Scheme == "" {
+			err = fmt.Errorf("invalid %s %q,no scheme", strings.ToLower(vdt), val)
+		}
+	case VDTdate:
+		_, err = parseDateValue(val)
+	case VDTdatetime:
+		_, _, err = parseDateTimeValue(val)
+	case VDTduration:
+		_, err = parseDuration(val)
+	case VDTfloat:
+		_, err = strconv.ParseFloat(val, 64)
+	case VDTint:
+		_, err = strconv.Atoi(val)
+	case VDTperiod:
+		_, err = parsePeriod(val)
+	case VDTrecurrence:
+		_, err = ParseRecur(val)
+	case VDTtext:
+		err = checkText(val)
+	case VDTtime:
+		_, err = parseTimeValue(val)
+	case VDTutcoffset:
+		_, err = parseUTCOffset(val)
+	}
+	return err
+}
+
+// ESCAPED-CHAR = ("\\" / "\;" / "\," / "\N" / "\n")
+func checkText(s string) error {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			continue
+		}
+		i++
+		if i >= len(s) || !strings.ContainsRune(`\;,nN`, rune(s[i])) {
+			return fmt.Errorf("invalid escape sequence in text %q", s)
+		}
+	}
+	return nil
+}
+
+// checkPropValue checks the restrictions a property puts on its own value
+func checkPropValue(comp string, p *Property) error {
+	switch p.Name {
+	case PropStatus:
+		if allowed, ok := statusValues[comp]; ok && !contains(allowed, p.Value) {
+			return fmt.Errorf("invalid STATUS %q for %s", p.Value, comp)
+		}
+	case PropTimeTransparency:
+		if p.Value != TranspOpaque && p.Value != TranspTransparent {
+			return fmt.Errorf("invalid TRANSP %q", p.Value)
+		}
+	case PropPriority:
+		if n, err := strconv.Atoi(p.Value); err == nil && (n < 0 || n > 9) {
+			return fmt.Errorf("PRIORITY MUST be between 0 and 9,but got %d", n)
+		}
+	case PropPercentComplete:
+		if n, err := strconv.Atoi(p.Value); err == nil && (n < 0 || n > 100) {
+			return fmt.Errorf("PERCENT-COMPLETE MUST be between 0 and 100,but got %d", n)
+		}
+	case PropSequenceNumber, PropRepeatCount:
+		if n, err := strconv.Atoi(p.Value); err == nil && n < 0 {
+			return fmt.Errorf("%s MUST NOT be negative", p.Name)
+		}
+	case PropCalendarScale:
+		if p.Value != "GREGORIAN" {
+			return fmt.Errorf("unsupported CALSCALE %q", p.Value)
+		}
+	case PropGeographicPosition:
+		parts := strings.Split(p.Value, ";")
+		if len(parts) != 2 {
+			return fmt.Errorf("GEO MUST be two floats separated by ';',but got %q", p.Value)
+		}
+		lat, err1 := strconv.ParseFloat(parts[0], 64)
+		lon, err2 := strconv.ParseFloat(parts[1], 64)
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return fmt.Errorf("invalid GEO %q", p.Value)
+		}
+	case PropRequestStatus:
+		code := p.Value
+		if i := strings.IndexByte(code, ';'); i >= 0 {
+			code = code[:i]
+		} else {
+			return fmt.Errorf("REQUEST-STATUS needs a status code and a description,but got %q", p.Value)
+		}
+		for _, n := range strings.Split(code, ".") {
+			if _, err := strconv.Atoi(n); err != nil || len(strings.Split(code, ".")) < 2 {
+				return fmt.Errorf("invalid REQUEST-STATUS code %q", code)
+			}
+		}
+	case PropFreeBusy:
+		for _, val := range splitValues(p.Value) {
+			if i := strings.IndexByte(val, '/'); i < 0 || !strings.HasSuffix(val[:i], "Z") {
+				return fmt.Errorf("FREEBUSY periods MUST be in UTC,but got %q", val)
+			}
+		}
+	}
+	return nil
+}
+
+// timeProp holds what the cross-property checks need to know about a DATE or DATE-TIME
+type timeProp struct {
+	prop *Property
+	date bool
+	utc  bool
+	tzid string
+	t    time.Time
+}
+
+func getTimeProp(com Component, name string) *timeProp {
+	for i := range com.Properties() {
+		p := &com.Properties()[i]
+		if p.Name != name {
+			continue
+		}
+		return newTimeProp(p, p.Value)
+	}
+	return nil
+}
+
+func newTimeProp(p *Property, val string) *timeProp {
+	tp := &timeProp{prop: p, tzid: p.Params.Get(Paramtzid), date: p.IsDate()}
+	var err error
+	if tp.date {
+		tp.t, err = parseDateValue(val)
+	} else {
+		tp.t, tp.utc, err = parseDateTimeValue(val)
+	}
+	if err != nil {
+		return nil
+	}
+	return tp
+}
+
+// absolute returns the time as an instant,ok is false when the TZID is unknown
+func (tp *timeProp) absolute() (time.Time, bool) {
+	if tp.tzid == "" || tp.utc || tp.date {
+		return tp.t, true
+	}
+	loc, err := time.LoadLocation(tp.tzid)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(tp.t.Year(), tp.t.Month(), tp.t.Day(), tp.t.Hour(), tp.t.Minute(), tp.t.Second(), 0, loc), true
+}
+
+// compare returns -1,0 or 1,ok is false when the two times can not be compared
+func (tp *timeProp) compare(o *timeProp) (int, bool) {
+	a, b := tp.t, o.t
+	if tp.tzid != o.tzid || tp.utc != o.utc {
+		var ok1, ok2 bool
+		a, ok1 = tp.absolute()
+		b, ok2 = o.absolute()
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+	}
+	switch {
+	case a.Before(b):
+		return -1, true
+	case a.After(b):
+		return 1, true
+	}
+	return 0, true
+}
+
+func hasProp(com Component, name string) bool {
+	for _, p := range com.Properties() {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) schedulingComponent(com Component, path, section string) {
+	name := com.Name()
+	start := getTimeProp(com, PropDatetimeStart)
+	if !hasProp(com, PropDatetimeStart) && name == CompEvent && v.method == "" {
+		v.add(SeverityError, path, section, "DTSTART is required in VEVENT when VCALENDAR has no METHOD")
+	}
+
+	endName := PropDatetimeEnd
+	if name == CompTodo {
+		endName = PropDatetimeDue
+	}
+	if name != CompJournal {
+		if hasProp(com, endName) && hasProp(com, PropDuration) {
+			v.add(SeverityError, path, section, "%s and DURATION MUST NOT occur together in %s", endName, name)
+		}
+	}
+	if (name == CompTodo || name == CompAvailability) && hasProp(com, PropDuration) && start == nil {
+		v.add(SeverityError, path, section, "DURATION in %s needs DTSTART", name)
+	}
+
+	if start != nil {
+		if end := getTimeProp(com, endName); end != nil && name != CompJournal {
+			epath := path + "/" + endName
+			if end.date != start.date {
+				v.add(SeverityError, epath, propSections[endName], "%s MUST have the same value type as DTSTART", endName)
+			} else if c, ok := end.compare(start); ok {
+				if name != CompTodo && c <= 0 {
+					v.add(SeverityError, epath, propSections[endName], "DTEND MUST be later than DTSTART")
+				} else if name == CompTodo && c < 0 {
+					v.add(SeverityError, epath, propSections[endName], "DUE MUST NOT be earlier than DTSTART")
+				}
+			}
+		}
+		for _, p := range com.Properties() {
+			if p.Name == PropDuration && start.date && strings.Contains(p.Value, "T") {
+				v.add(SeverityError, path+"/"+PropDuration, propSections[PropDuration], "DURATION MUST be in days or weeks when DTSTART is a DATE")
+			}
+		}
+	}
+
+	for i := range com.Properties() {
+		p := &com.Properties()[i]
+		switch p.Name {
+		case PropRecurrenceId, PropExceptionDatetime, PropRecurrenceDatetime:
+			if start == nil {
+				v.add(SeverityError, path+"/"+p.Name, propSections[p.Name], "%s needs DTSTART", p.Name)
+				continue
+			}
+			vdt := p.GetParamValue()
+			if vdt == VDTperiod {
+				if start.date {
+					v.add(SeverityError, path+"/"+p.Name, propSections[p.Name], "RDATE periods can not be used when DTSTART is a DATE")
+				}
+				continue
+			}
+			if (vdt == VDTdate) != start.date {
+				v.add(SeverityError, path+"/"+p.Name, propSections[p.Name], "%s MUST have the same value type as DTSTART", p.Name)
+			}
+		case PropRecurrenceRule:
+			if start == nil {
+				v.add(SeverityError, path+"/"+p.Name, propSections[p.Name], "RRULE needs DTSTART")
+				continue
+			}
+			v.recurUntil(p, start, path, false)
+		}
+	}
+}
+
+// availability checks BUSYTYPE of a VAVAILABILITY,RFC 7953 3.2
+func (v *validator) availability(com Component, path string) {
+	for _, p := range com.Properties() {
+		if p.Name != PropBusyType {
+			continue
+		}
+		switch strings.ToUpper(p.Value) {
+		case FBTypeBusy, FBTypeBusyUnavailable, FBTypeBusyTentative:
+		default:
+			if !strings.HasPrefix(strings.ToUpper(p.Value), "X-") {
+				v.add(SeverityWarning, path+"/"+p.Name, propSections[p.Name], "unknown BUSYTYPE %q", p.Value)
+			}
+		}
+	}
+}
+
+// UNTIL MUST have the same value type as DTSTART,and be in UTC when DTSTART
+// is in UTC or has a TZID,RFC 5545 3.3.10
+func (v *validator) recurUntil(p *Property, start *timeProp, path string, mustUTC bool) {
+	r, err := ParseRecur(p.Value)
+	if err != nil || r.Until.IsZero() {
+		return
+	}
+	rpath := path + "/" + p.Name
+	switch {
+	case start.date && !r.UntilDate:
+		v.add(SeverityError, rpath, vdtSections[VDTrecurrence], "UNTIL MUST be a DATE when DTSTART is a DATE")
+	case !start.date && r.UntilDate:
+		v.add(SeverityError, rpath, vdtSections[VDTrecurrence], "UNTIL MUST be a DATE-TIME when DTSTART is a DATE-TIME")
+	case (mustUTC || start.utc || start.tzid != "") && !start.date && !r.UntilUTC:
+		v.add(SeverityError, rpath, vdtSections[VDTrecurrence], "UNTIL MUST be in UTC")
+	}
+}
+
+func (v *validator) freebusy(com Component, path, section string) {
+	start := getTimeProp(com, PropDatetimeStart)
+	end := getTimeProp(com, PropDatetimeEnd)
+	for _, tp := range []*timeProp{start, end} {
+		if tp != nil && !tp.utc {
+			v.add(SeverityError, path+"/"+tp.prop.Name, propSections[tp.prop.Name], "%s in VFREEBUSY MUST be in UTC", tp.prop.Name)
+		}
+	}
+	if start != nil && end != nil {
+		if c, ok := end.compare(start); ok && c <= 0 {
+			v.add(SeverityError, path+"/"+PropDatetimeEnd, section, "DTEND MUST be later than DTSTART")
+		}
+	}
+}
+
+func (v *validator) observance(com Component, path, section string) {
+	start := getTimeProp(com, PropDatetimeStart)
+	if start != nil && (start.date || start.utc || start.tzid != "") {
+		v.add(SeverityError, path+"/"+PropDatetimeStart, section, "DTSTART in %s MUST be a local DATE-TIME", com.Name())
+	}
+	for i := range com.Properties() {
+		p := &com.Properties()[i]
+		if p.Name == PropRecurrenceRule && start != nil {
+			v.recurUntil(p, start, path, true)
+		}
+	}
+}
+
+func (v *validator) uniqueTZIDs(com Component, path string) {
+	seen := map[string]bool{}
+	for _, sub := range com.SubComponents() {
+		if sub.Name() != CompTimezone {
+			continue
+		}
+		for _, p := range sub.Properties() {
+			if p.Name != PropTimeZoneIdentifier {
+				continue
+			}
+			if seen[p.Value] {
+				v.add(SeverityError, path, compSections[CompTimezone], "more than one VTIMEZONE defines TZID %q", p.Value)
+			}
+			seen[p.Value] = true
+		}
+	}
+}
+
+func (v *validator) alarm(com, parent Component, path, section string) {
+	counts := map[string]int{}
+	action := ""
+	related := ""
+	for _, p := range com.Properties() {
+		counts[p.Name]++
+		switch p.Name {
+		case PropAction:
+			action = p.Value
+		case PropTrigger:
+			related = strings.ToUpper(p.Params.Get(Paramtrigrel))
+		}
+	}
+	if (counts[PropDuration] == 0) != (counts[PropRepeatCount] == 0) {
+		v.add(SeverityError, path, section, "DURATION and REPEAT MUST both occur or neither")
+	}
+
+	switch action {
+	case ActionAudio:
+		if counts[PropAttachment] > 1 {
+			v.add(SeverityError, path, section, "an AUDIO VALARM MUST NOT have more than one ATTACH")
+		}
+		for _, pn := range []string{PropDescription, PropSummary, PropAttendee} {
+			if counts[pn] > 0 {
+				v.add(SeverityError, path, section, "%s is not allowed in an AUDIO VALARM", pn)
+			}
+		}
+	case ActionDisplay:
+		if counts[PropDescription] == 0 {
+			v.add(SeverityError, path, section, "a DISPLAY VALARM MUST have DESCRIPTION")
+		}
+		for _, pn := range []string{PropSummary, PropAttendee, PropAttachment} {
+			if counts[pn] > 0 {
+				v.add(SeverityError, path, section, "%s is not allowed in a DISPLAY VALARM", pn)
+			}
+		}
+	case ActionEmail:
+		for _, pn := range []string{PropDescription, PropSummary, PropAttendee} {
+			if counts[pn] == 0 {
+				v.add(SeverityError, path, section, "an EMAIL VALARM MUST have %s", pn)
+			}
+		}
+	case "":
+	default:
+		if !strings.HasPrefix(action, "X-") {
+			v.add(SeverityWarning, path+"/"+PropAction, propSections[PropAction], "unknown ACTION %q", action)
+		}
+	}
+
+	if parent == nil {
+		return
+	}
+	switch related {
+	case "END":
+		if parent.Name() == CompEvent && !hasProp(parent, PropDatetimeEnd) && !hasProp(parent, PropDuration) {
+			v.add(SeverityError, path+"/"+PropTrigger, propSections[PropTrigger], "RELATED=END needs DTEND or DURATION in VEVENT")
+		}
+		if parent.Name() == CompTodo && !hasProp(parent, PropDatetimeDue) && !hasProp(parent, PropDuration) {
+			v.add(SeverityError, path+"/"+PropTrigger, propSections[PropTrigger], "RELATED=END needs DUE or DURATION in VTODO")
+		}
+	default:
+		if counts[PropTrigger] > 0 && !hasProp(parent, PropDatetimeStart) && !isAbsoluteTrigger(com) {
+			v.add(SeverityError, path+"/"+PropTrigger, propSections[PropTrigger], "a relative TRIGGER needs DTSTART in %s", parent.Name())
+		}
+	}
+}
+
+func isAbsoluteTrigger(com Component) bool {
+	for i := range com.Properties() {
+		p := &com.Properties()[i]
+		if p.Name == PropTrigger {
+			return p.GetParamValue() == VDTdatetime
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package go_ical
+
+import (
+	"strings"
+	"testing"
+)
+
+func decodeString(t *testing.T, s string) Calendar {
+	t.Helper()
+	cal, err := NewDecoder(strings.NewReader(toCRLF(s))).Decode()
+	if err != nil {
+		t.Fatalf("Decode() err: %v", err)
+	}
+	return cal
+}
+
+const validCalendarStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VTIMEZONE
+TZID:Europe/Berlin
+BEGIN:STANDARD
+DTSTART:19701025T030000
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+TZNAME:CET
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:19700329T020000
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+TZNAME:CEST
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:uid1@example.com
+DTSTAMP:19960704T120000Z
+DTSTART;TZID=Europe/Berlin:19960918T143000
+DTEND;TZID=Europe/Berlin:19960918T153000
+RRULE:FREQ=WEEKLY;UNTIL=19961231T000000Z;BYDAY=WE
+EXDATE;TZID=Europe/Berlin:19960925T143000
+ATTENDEE;ROLE=REQ-PARTICIPANT;RSVP=TRUE;CN="Doe, John":mailto:jdoe@example.com
+ORGANIZER;CN=Jane:mailto:jsmith@example.com
+GEO:37.386013;-122.082932
+SUMMARY:Weekly sync
+BEGIN:VALARM
+ACTION:DISPLAY
+DESCRIPTION:Reminder
+TRIGGER:-PT15M
+END:VALARM
+END:VEVENT
+BEGIN:VTODO
+UID:uid2@example.com
+DTSTAMP:19960704T120000Z
+DTSTART;VALUE=DATE:19960918
+DUE;VALUE=DATE:19960920
+STATUS:NEEDS-ACTION
+END:VTODO
+END:VCALENDAR
+`
+
+func TestValidateValid(t *testing.T) {
+	cal := decodeString(t, validCalendarStr)
+	if findings := Validate(cal); len(findings) != 0 {
+		t.Errorf("Validate() found problems in a valid calendar:\n%v", findings)
+	}
+}
+
+func TestValidateFindings(t *testing.T) {
+	cal := decodeString(t, `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VEVENT
+UID:uid1@example.com
+DTSTART:19960918T143000Z
+DTEND;VALUE=DATE:19960917
+DURATION:PT1H
+PRIORITY:12
+RRULE:FREQ=DAILY;UNTIL=19961231
+SUMMARY;RSVP=TRUE:Lunch
+DTSTAMP:19960704T120000
+BEGIN:VALARM
+ACTION:EMAIL
+TRIGGER;RELATED=END:PT0S
+REPEAT:2
+END:VALARM
+END:VEVENT
+BEGIN:VTODO
+UID:uid2@example.com
+DTSTAMP:19960704T120000Z
+DTSTART;TZID=Nowhere/City:19960918T143000
+DURATION:1H
+COMPLETED:19960918
+END:VTODO
+END:VCALENDAR
+`)
+	findings := Validate(cal)
+
+	want := []struct {
+		path, section, message string
+	}{
+		{"VCALENDAR/VEVENT[0]", "RFC 5545 3.6.1", "DTEND and DURATION MUST NOT occur together"},
+		{"VCALENDAR/VEVENT[0]/DTEND", "RFC 5545 3.8.2.2", "same value type as DTSTART"},
+		{"VCALENDAR/VEVENT[0]/PRIORITY", "RFC 5545 3.8.1.9", "between 0 and 9"},
+		{"VCALENDAR/VEVENT[0]/RRULE", "RFC 5545 3.3.10", "UNTIL MUST be a DATE-TIME"},
+		{"VCALENDAR/VEVENT[0]/SUMMARY", "RFC 5545 3.8.1.12", "parameter RSVP is not allowed"},
+		{"VCALENDAR/VEVENT[0]/DTSTAMP", "RFC 5545 3.8.7.2", "MUST be in UTC"},
+		{"VCALENDAR/VEVENT[0]/VALARM[0]", "RFC 5545 3.6.6", "DURATION and REPEAT"},
+		{"VCALENDAR/VEVENT[0]/VALARM[0]", "RFC 5545 3.6.6", "MUST have DESCRIPTION"},
+		{"VCALENDAR/VTODO[0]/DTSTART", "RFC 5545 3.2.19", `no VTIMEZONE defines TZID "Nowhere/City"`},
+		{"VCALENDAR/VTODO[0]/DURATION", "RFC 5545 3.3.6", "invalid duration"},
+		{"VCALENDAR/VTODO[0]/COMPLETED", "RFC 5545 3.3.5", "invalid date-time"},
+	}
+	for _, w := range want {
+		found := false
+		for _, f := range findings {
+			if f.Path == w.path && f.Section == w.section && strings.Contains(f.Message, w.message) {
+				found = true
+				if f.Severity != SeverityError {
+					t.Errorf("finding %v has severity %v, want error", f, f.Severity)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("no finding at %s (%s) containing %q", w.path, w.section, w.message)
+		}
+	}
+	if t.Failed() {
+		for _, f := range findings {
+			t.Log(f)
+		}
+	}
+}
+
+func TestValidateEmptyCalendar(t *testing.T) {
+	findings := Validate(Calendar{ComponentObj{NameObj: CompCalendar}})
+	if !HasErrors(findings) {
+		t.Fatalf("Validate() of an empty VCALENDAR has no errors")
+	}
+	want := map[string]bool{
+		"error: VCALENDAR: PRODID is required in VCALENDAR (RFC 5545 3.4)":               false,
+		"error: VCALENDAR: VERSION is required in VCALENDAR (RFC 5545 3.4)":              false,
+		"error: VCALENDAR: VCALENDAR MUST contain at least one component (RFC 5545 3.4)": false,
+	}
+	for _, f := range findings {
+		if _, ok := want[f.String()]; ok {
+			want[f.String()] = true
+		}
+	}
+	for s, ok := range want {
+		if !ok {
+			t.Errorf("missing finding %q in %v", s, findings)
+		}
+	}
+}
+
+func TestValidateAlarmOnce(t *testing.T) {
+	cal := decodeString(t, `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+BEGIN:VEVENT
+UID:uid1@example.com
+DTSTAMP:19960704T120000Z
+DTSTART:19960918T143000Z
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+DESCRIPTION:Lunch
+DURATION:PT5M
+DURATION:PT10M
+REPEAT:2
+REPEAT:3
+END:VALARM
+END:VEVENT
+END:VCALENDAR
+`)
+	found := map[string]bool{}
+	for _, f := range Validate(cal) {
+		if f.Path == "VCALENDAR/VEVENT[0]/VALARM[0]" && strings.Contains(f.Message, "MUST NOT occur more than once") {
+			found[strings.Fields(f.Message)[0]] = true
+		}
+	}
+	if !found[PropDuration] || !found[PropRepeatCount] {
+		t.Errorf("Validate() accepts a VALARM with two DURATION and REPEAT,found %v", found)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]string{
+		"P15DT5H0M20S": "P15DT5H20S",
+		"P7W":          "P7W",
+		"-PT15M":       "-PT15M",
+		"+P1D":         "P1D",
+		"PT0S":         "PT0S",
+	}
+	for in, want := range tests {
+		d, err := parseDuration(in)
+		if err != nil {
+			t.Errorf("parseDuration(%q) err: %v", in, err)
+			continue
+		}
+		if got := formatDuration(d); got != want {
+			t.Errorf("formatDuration(parseDuration(%q)) = %q, want %q", in, got, want)
+		}
+	}
+	for _, in := range []string{"PT", "P1H", "1D", "P1W2D", "PT1S1H", "P"} {
+		if _, err := parseDuration(in); err == nil {
+			t.Errorf("parseDuration(%q) succeeded, want error", in)
+		}
+	}
+}
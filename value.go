@@ -0,0 +1,270 @@
+package go_ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//parsers for the value types defined in RFC 5545 3.3 ,they only look at the
+//text of one value and never at the parameters of the property
+
+// date = date-value = date-fullyear date-month date-mday ,RFC 5545 3.3.4
+func parseDateValue(s string) (time.Time, error) {
+	if len(s) != len(DateFormat) {
+		return time.Time{}, fmt.Errorf("ical:invalid date %q", s)
+	}
+	return time.ParseInLocation(DateFormat, s, time.UTC)
+}
+
+// date-time = date "T" time ,RFC 5545 3.3.5
+// the returned time is in UTC,utc reports whether the value had the "Z" suffix
+func parseDateTimeValue(s string) (t time.Time, utc bool, err error) {
+	switch len(s) {
+	case len(DatetimeFormat):
+		t, err = time.ParseInLocation(DatetimeFormat, s, time.UTC)
+	case len(DatetimeFormat2):
+		t, err = time.ParseInLocation(DatetimeFormat2, s, time.UTC)
+		utc = true
+	default:
+		err = fmt.Errorf("ical:invalid date-time %q", s)
+	}
+	return t, utc, err
+}
+
+// time = time-hour time-minute time-second [time-utc] ,RFC 5545 3.3.12
+func parseTimeValue(s string) (time.Time, error) {
+	v := strings.TrimSuffix(s, "Z")
+	if len(v) != 6 {
+		return time.Time{}, fmt.Errorf("ical:invalid time %q", s)
+	}
+	return time.ParseInLocation("150405", v, time.UTC)
+}
+
+/*
+dur-value  = (["+"] / "-") "P" (dur-date / dur-time / dur-week)
+dur-date   = dur-day [dur-time]
+dur-time   = "T" (dur-hour / dur-minute / dur-second)
+dur-week   = 1*DIGIT "W"
+dur-hour   = 1*DIGIT "H" [dur-minute]
+dur-minute = 1*DIGIT "M" [dur-second]
+dur-second = 1*DIGIT "S"
+dur-day    = 1*DIGIT "D"
+*/
+func parseDuration(s string) (time.Duration, error) {
+	ds := s
+	sign := false
+	if strings.HasPrefix(ds, "-") {
+		sign = true
+		ds = ds[1:]
+	} else if strings.HasPrefix(ds, "+") {
+		ds = ds[1:]
+	}
+	if !strings.HasPrefix(ds, "P") {
+		return 0, fmt.Errorf("ical:invalid duration %q,expect 'P'", s)
+	}
+	ds = ds[1:]
+	if ds == "" {
+		return 0, fmt.Errorf("ical:invalid duration %q,no value", s)
+	}
+
+	var d time.Duration
+	isTime := false
+	//designators have to appear in this order,each at most once
+	order := "WDTHMS"
+	last := -1
+	for len(ds) > 0 {
+		if ds[0] == 'T' {
+			if isTime || len(ds) == 1 {
+				return 0, fmt.Errorf("ical:invalid duration %q", s)
+			}
+			isTime = true
+			last = strings.IndexByte(order, 'T')
+			ds = ds[1:]
+			continue
+		}
+		index := strings.IndexFunc(ds, func(r rune) bool {
+			return !(r >= '0' && r <= '9')
+		})
+		if index <= 0 {
+			return 0, fmt.Errorf("ical:invalid duration %q,should be digital", s)
+		}
+		n, err := strconv.ParseInt(ds[:index], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("ical:invalid duration %q: %v", s, err)
+		}
+		unit := ds[index]
+		ds = ds[index+1:]
+
+		pos := strings.IndexByte(order, unit)
+		if pos <= last || (isTime && pos < 3) || (!isTime && pos > 2) {
+			return 0, fmt.Errorf("ical:invalid duration %q,unexpected %q", s, unit)
+		}
+		last = pos
+		num := time.Duration(n)
+		switch unit {
+		case 'W':
+			if len(ds) > 0 {
+				return 0, fmt.Errorf("ical:invalid duration %q,weeks can not be combined", s)
+			}
+			d += num * 7 * 24 * time.Hour
+		case 'D':
+			d += num * 24 * time.Hour
+		case 'H':
+			d += num * time.Hour
+		case 'M':
+			d += num * time.Minute
+		case 'S':
+			d += num * time.Second
+		}
+	}
+	if sign {
+		d = -d
+	}
+	return d, nil
+}
+
+// formatDuration is the reverse of parseDuration,it uses the largest units
+// that represent d exactly
+func formatDuration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteByte('P')
+	const day = 24 * time.Hour
+	if d != 0 && d%(7*day) == 0 {
+		fmt.Fprintf(&b, "%dW", d/(7*day))
+		return b.String()
+	}
+	if d >= day {
+		fmt.Fprintf(&b, "%dD", d/day)
+		d %= day
+	}
+	if d > 0 || b.Len() <= 2 {
+		b.WriteByte('T')
+		h, m, sec := d/time.Hour, (d%time.Hour)/time.Minute, (d%time.Minute)/time.Second
+		if h > 0 {
+			fmt.Fprintf(&b, "%dH", h)
+		}
+		if m > 0 {
+			fmt.Fprintf(&b, "%dM", m)
+		}
+		if sec > 0 || (h == 0 && m == 0) {
+			fmt.Fprintf(&b, "%dS", sec)
+		}
+	}
+	return b.String()
+}
+
+// Period is a PERIOD value,RFC 5545 3.3.9 .A period given with a duration
+// has End set to Start plus the duration
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the period
+func (pe Period) Duration() time.Duration {
+	return pe.End.Sub(pe.Start)
+}
+
+// String formats the period in the period-explicit form
+func (pe Period) String() string {
+	return pe.Start.UTC().Format(DatetimeFormat2) + "/" + pe.End.UTC().Format(DatetimeFormat2)
+}
+
+/*
+period     = period-explicit / period-start
+period-explicit = date-time "/" date-time
+period-start = date-time "/" dur-value
+*/
+func parsePeriod(s string) (Period, error) {
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		return Period{}, fmt.Errorf("ical:invalid period %q,expect '/'", s)
+	}
+	start, _, err := parseDateTimeValue(s[:i])
+	if err != nil {
+		return Period{}, err
+	}
+	rest := s[i+1:]
+	if strings.HasPrefix(rest, "P") || strings.HasPrefix(rest, "+P") {
+		d, err := parseDuration(rest)
+		if err != nil {
+			return Period{}, err
+		}
+		if d < 0 {
+			return Period{}, fmt.Errorf("ical:invalid period %q,duration must be positive", s)
+		}
+		return Period{Start: start, End: start.Add(d)}, nil
+	}
+	end, _, err := parseDateTimeValue(rest)
+	if err != nil {
+		return Period{}, err
+	}
+	if end.Before(start) {
+		return Period{}, fmt.Errorf("ical:invalid period %q,end is before start", s)
+	}
+	return Period{Start: start, End: end}, nil
+}
+
+// utc-offset = time-numzone = ("+" / "-") time-hour time-minute [time-second]
+// the offset is returned in seconds east of UTC
+func parseUTCOffset(s string) (int, error) {
+	if (len(s) != 5 && len(s) != 7) || (s[0] != '+' && s[0] != '-') {
+		return 0, fmt.Errorf("ical:invalid utc-offset %q", s)
+	}
+	if s == "-0000" || s == "-000000" {
+		return 0, fmt.Errorf("ical:invalid utc-offset %q,negative zero is not allowed", s)
+	}
+	var parts [3]int
+	for i := 0; 1+2*i < len(s); i++ {
+		n, err := strconv.Atoi(s[1+2*i : 3+2*i])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("ical:invalid utc-offset %q", s)
+		}
+		parts[i] = n
+	}
+	if parts[0] > 23 || parts[1] > 59 || parts[2] > 59 {
+		return 0, fmt.Errorf("ical:invalid utc-offset %q", s)
+	}
+	off := parts[0]*3600 + parts[1]*60 + parts[2]
+	if s[0] == '-' {
+		off = -off
+	}
+	return off, nil
+}
+
+// formatUTCOffset is the reverse of parseUTCOffset
+func formatUTCOffset(off int) string {
+	sign := byte('+')
+	if off < 0 {
+		sign = '-'
+		off = -off
+	}
+	s := fmt.Sprintf("%c%02d%02d", sign, off/3600, off/60%60)
+	if off%60 != 0 {
+		s += fmt.Sprintf("%02d", off%60)
+	}
+	return s
+}
+
+// splitValues splits a multi-valued property value on the commas that are
+// not escaped with a backslash
+func splitValues(s string) []string {
+	var vals []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			vals = append(vals, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(vals, s[start:])
+}
@@ -0,0 +1,402 @@
+package go_ical
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	//ErrVdirNotFound is returned for an item the directory does not have
+	ErrVdirNotFound = errors.New("ical:vdir item not found")
+	//ErrVdirPreconditionFailed is returned when an item exists which should
+	//not,or does not have the expected ETag
+	ErrVdirPreconditionFailed = errors.New("ical:vdir item changed")
+)
+
+// vdirExt is the extension of the items of a vdir
+const vdirExt = ".ics"
+
+// VdirItem is an item of a vdir: a file holding one calendar object. Items
+// are cached by the store,Calendar must not be modified.
+type VdirItem struct {
+	//Href is the file name in the directory
+	Href string
+	//ETag is derived from the content of the file
+	ETag     string
+	ModTime  time.Time
+	Calendar *Calendar
+}
+
+// VdirChanges lists the hrefs of the items which changed since the last
+// scan
+type VdirChanges struct {
+	Added    []string
+	Modified []string
+	Removed  []string
+}
+
+// VdirBrokenItem is an item Items or Query skipped as it can not be read
+// or expanded
+type VdirBrokenItem struct {
+	Href string
+	Err  error
+}
+
+// VdirOccurrence is an occurrence of a component of the item Href
+type VdirOccurrence struct {
+	Href string
+	Occurrence
+}
+
+/*
+VdirStore reads and writes a vdir,the storage format of vdirsyncer and khal:
+a directory of .ics files holding one calendar object each. Files whose name
+starts with a dot are ignored.
+
+Items are written to a temporary file which is renamed over the item,so
+readers see the old or the new content. Decoded items are cached and only
+read again when their modification time or size changes. A VdirStore is
+safe for concurrent use,other programs may change the directory at any time.
+*/
+type VdirStore struct {
+	Dir string
+
+	mu    sync.Mutex
+	cache map[string]vdirEntry
+	//scanned is the state of the files at the last Scan
+	scanned map[string]vdirStamp
+}
+
+// vdirStamp tells whether a file changed
+type vdirStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func fileStamp(fi os.FileInfo) vdirStamp {
+	return vdirStamp{fi.ModTime(), fi.Size()}
+}
+
+type vdirEntry struct {
+	vdirStamp
+	item *VdirItem
+}
+
+// NewVdirStore returns a store of the directory dir,which is created when
+// it does not exist
+func NewVdirStore(dir string) (*VdirStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &VdirStore{Dir: dir, cache: map[string]vdirEntry{}, scanned: map[string]vdirStamp{}}, nil
+}
+
+// checkHref rejects names which are not a file of the directory
+func checkHref(href string) error {
+	if href == "" || strings.HasPrefix(href, ".") || strings.ContainsAny(href, `/\`) || !strings.HasSuffix(href, vdirExt) {
+		return fmt.Errorf("ical:invalid vdir href %q", href)
+	}
+	return nil
+}
+
+// load returns the item of href from the cache,or reads it when the file
+// changed. The caller holds mu.
+func (s *VdirStore) load(href string, fi os.FileInfo) (*VdirItem, error) {
+	if e, ok := s.cache[href]; ok && e.vdirStamp == fileStamp(fi) {
+		return e.item, nil
+	}
+	b, err := ioutil.ReadFile(filepath.Join(s.Dir, href))
+	if os.IsNotExist(err) {
+		return nil, ErrVdirNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	cal, err := NewDecoder(bytes.NewReader(b)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("ical:vdir item %s: %v", href, err)
+	}
+	item := &VdirItem{Href: href, ETag: contentETag(b), ModTime: fi.ModTime(), Calendar: &cal}
+	s.cache[href] = vdirEntry{fileStamp(fi), item}
+	return item, nil
+}
+
+// files returns the items of the directory by href. The caller holds mu.
+func (s *VdirStore) files() (map[string]os.FileInfo, error) {
+	fis, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]os.FileInfo{}
+	for _, fi := range fis {
+		if fi.Mode().IsRegular() && checkHref(fi.Name()) == nil {
+			files[fi.Name()] = fi
+		}
+	}
+	return files, nil
+}
+
+// Items returns all items sorted by href. Items which can not be read or
+// decoded are skipped and returned as broken,sorted by href too,err is only
+// set when the directory can not be listed.
+func (s *VdirStore) Items() ([]*VdirItem, []VdirBrokenItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := s.files()
+	if err != nil {
+		return nil, nil, err
+	}
+	items := make([]*VdirItem, 0, len(files))
+	var broken []VdirBrokenItem
+	for href, fi := range files {
+		item, err := s.load(href, fi)
+		if err == ErrVdirNotFound {
+			//deleted since the listing
+			continue
+		} else if err != nil {
+			broken = append(broken, VdirBrokenItem{Href: href, Err: err})
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Href < items[j].Href })
+	sort.Slice(broken, func(i, j int) bool { return broken[i].Href < broken[j].Href })
+	return items, broken, nil
+}
+
+// Get returns the item href,ErrVdirNotFound when there is none
+func (s *VdirStore) Get(href string) (*VdirItem, error) {
+	if err := checkHref(href); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(href)
+}
+
+func (s *VdirStore) get(href string) (*VdirItem, error) {
+	fi, err := os.Stat(filepath.Join(s.Dir, href))
+	if os.IsNotExist(err) {
+		return nil, ErrVdirNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return s.load(href, fi)
+}
+
+// Scan reports the items added,modified or removed since the last scan,by
+// the store or by other programs. The first scan reports all items as added.
+func (s *VdirStore) Scan() (*VdirChanges, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	changes := &VdirChanges{}
+	for href, fi := range files {
+		stamp, known := s.scanned[href]
+		if known && stamp == fileStamp(fi) {
+			continue
+		}
+		s.scanned[href] = fileStamp(fi)
+		if known {
+			changes.Modified = append(changes.Modified, href)
+		} else {
+			changes.Added = append(changes.Added, href)
+		}
+	}
+	for href := range s.scanned {
+		if _, ok := files[href]; !ok {
+			delete(s.scanned, href)
+			delete(s.cache, href)
+			changes.Removed = append(changes.Removed, href)
+		}
+	}
+	for _, hrefs := range [][]string{changes.Added, changes.Modified, changes.Removed} {
+		sort.Strings(hrefs)
+	}
+	return changes, nil
+}
+
+// Create writes cal to a new item named after its UID and returns the href
+// and ETag
+func (s *VdirStore) Create(cal *Calendar) (string, string, error) {
+	uid, err := vdirUID(cal)
+	if err != nil {
+		return "", "", err
+	}
+	href := uid + vdirExt
+	if checkHref(href) != nil || strings.ContainsAny(uid, ` :*?"<>|`) {
+		//not every UID is a file name
+		href = contentETag([]byte(uid)) + vdirExt
+	}
+	etag, err := s.Put(href, cal, "")
+	return href, etag, err
+}
+
+// vdirUID returns the UID of the calendar object cal
+func vdirUID(cal *Calendar) (string, error) {
+	uid := ""
+	for _, sub := range cal.SubComponents() {
+		if sub.Name() == CompTimezone {
+			continue
+		}
+		p := sub.obj().GetProperty(PropUID)
+		if p == nil {
+			return "", fmt.Errorf("ical:vdir item has a %s without UID", sub.Name())
+		}
+		if uid != "" && p.Value != uid {
+			return "", fmt.Errorf("ical:vdir item has UIDs %s and %s,split it first", uid, p.Value)
+		}
+		uid = p.Value
+	}
+	if uid == "" {
+		return "", fmt.Errorf("ical:vdir item has no component")
+	}
+	return uid, nil
+}
+
+/*
+Put writes cal to the item href and returns its new ETag.
+
+With an empty etag the item must not exist yet,else it must exist with that
+ETag,otherwise ErrVdirPreconditionFailed is returned. The check and the
+write are atomic for users of the store,not for other programs.
+*/
+func (s *VdirStore) Put(href string, cal *Calendar, etag string) (string, error) {
+	if err := checkHref(href); err != nil {
+		return "", err
+	}
+	if _, err := vdirUID(cal); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(cal); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkETag(href, etag); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(filepath.Join(s.Dir, href), buf.Bytes()); err != nil {
+		return "", err
+	}
+	//the next read decodes the file,the store keeps no reference to cal
+	delete(s.cache, href)
+	if _, err := s.get(href); err != nil {
+		return "", err
+	}
+	return contentETag(buf.Bytes()), nil
+}
+
+// checkETag checks the precondition etag of Put. The caller holds mu.
+func (s *VdirStore) checkETag(href, etag string) error {
+	have, err := s.fileETag(href)
+	switch {
+	case err == ErrVdirNotFound:
+		if etag != "" {
+			return ErrVdirPreconditionFailed
+		}
+		return nil
+	case err != nil:
+		return err
+	case have != etag:
+		return ErrVdirPreconditionFailed
+	}
+	return nil
+}
+
+// fileETag returns the ETag of the file href without decoding it,so items
+// which are no calendar can be replaced or deleted. The caller holds mu.
+func (s *VdirStore) fileETag(href string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.Dir, href))
+	if os.IsNotExist(err) {
+		return "", ErrVdirNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return contentETag(b), nil
+}
+
+// writeFileAtomic writes b to a temporary file in the directory of path and
+// renames it to path
+func writeFileAtomic(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// Delete removes the item href,which must have the ETag etag unless it is
+// empty
+func (s *VdirStore) Delete(href, etag string) error {
+	if err := checkHref(href); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	have, err := s.fileETag(href)
+	if err != nil {
+		return err
+	}
+	if etag != "" && have != etag {
+		return ErrVdirPreconditionFailed
+	}
+	if err := os.Remove(filepath.Join(s.Dir, href)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(s.cache, href)
+	return nil
+}
+
+// Query returns the occurrences of all items which overlap [from,to),
+// sorted by start,see Expand. Like Items it skips and returns the items it
+// can not read or expand.
+func (s *VdirStore) Query(from, to time.Time) ([]VdirOccurrence, []VdirBrokenItem, error) {
+	items, broken, err := s.Items()
+	if err != nil {
+		return nil, nil, err
+	}
+	var occs []VdirOccurrence
+	for _, item := range items {
+		found, err := Expand(*item.Calendar, from, to)
+		if err != nil {
+			broken = append(broken, VdirBrokenItem{Href: item.Href, Err: fmt.Errorf("ical:vdir item %s: %v", item.Href, err)})
+			continue
+		}
+		for _, o := range found {
+			occs = append(occs, VdirOccurrence{Href: item.Href, Occurrence: o})
+		}
+	}
+	sort.SliceStable(occs, func(i, j int) bool { return occs[i].Start.Before(occs[j].Start) })
+	sort.Slice(broken, func(i, j int) bool { return broken[i].Href < broken[j].Href })
+	return occs, broken, nil
+}
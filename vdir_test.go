@@ -0,0 +1,193 @@
+package go_ical
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestVdir(t *testing.T) (*VdirStore, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "vdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewVdirStore(filepath.Join(dir, "work"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestVdirStore(t *testing.T) {
+	s, done := newTestVdir(t)
+	defer done()
+
+	ev := decodeString(t, caldavEventStr)
+	href, etag, err := s.Create(&ev)
+	if err != nil || href != "standup@example.com.ics" || etag == "" {
+		t.Fatalf("Create() = %s, %s, %v", href, etag, err)
+	}
+	if _, _, err := s.Create(&ev); err != ErrVdirPreconditionFailed {
+		t.Errorf("Create() of an existing item err = %v", err)
+	}
+	item, err := s.Get(href)
+	if err != nil || item.ETag != etag || item.Calendar.SubComponents()[0].obj().GetProperty(PropSummary).Value != "Standup" {
+		t.Fatalf("Get() = %+v, %v", item, err)
+	}
+
+	ev.SubComponents()[0].obj().PutProperty(newPropertyValue(PropSummary, "Standup!"))
+	if _, err := s.Put(href, &ev, "stale"); err != ErrVdirPreconditionFailed {
+		t.Errorf("Put() with a stale ETag err = %v", err)
+	}
+	next, err := s.Put(href, &ev, etag)
+	if err != nil || next == etag {
+		t.Fatalf("Put() = %s, %v", next, err)
+	}
+	if item, _ := s.Get(href); item.ETag != next || item.Calendar.SubComponents()[0].obj().GetProperty(PropSummary).Value != "Standup!" {
+		t.Errorf("Get() after Put() = %+v", item)
+	}
+
+	//temporary and foreign files are not items,a leftover temp file is ignored
+	ioutil.WriteFile(filepath.Join(s.Dir, ".tmp-123"), []byte("partial"), 0644)
+	ioutil.WriteFile(filepath.Join(s.Dir, "README"), []byte("hello"), 0644)
+	todo := decodeString(t, caldavTodoStr)
+	if _, err := s.Put("report.ics", &todo, ""); err != nil {
+		t.Fatal(err)
+	}
+	items, broken, err := s.Items()
+	if err != nil || len(items) != 2 || items[0].Href != "report.ics" || items[1].Href != href || len(broken) != 0 {
+		t.Errorf("Items() = %+v, %+v, %v", items, broken, err)
+	}
+
+	if err := s.Delete(href, etag); err != ErrVdirPreconditionFailed {
+		t.Errorf("Delete() with a stale ETag err = %v", err)
+	}
+	if err := s.Delete(href, next); err != nil {
+		t.Errorf("Delete() err: %v", err)
+	}
+	if _, err := s.Get(href); err != ErrVdirNotFound {
+		t.Errorf("Get() of a deleted item err = %v", err)
+	}
+
+	for _, bad := range []string{"../escape.ics", ".hidden.ics", "noext", "a/b.ics"} {
+		if _, err := s.Put(bad, &todo, ""); err == nil {
+			t.Errorf("Put(%s) err = nil", bad)
+		}
+	}
+	two := decodeString(t, filterCalendarStr)
+	if _, _, err := s.Create(&two); err == nil {
+		t.Error("Create() of two UIDs err = nil")
+	}
+}
+
+func TestVdirStoreScan(t *testing.T) {
+	s, done := newTestVdir(t)
+	defer done()
+	ev, todo := decodeString(t, caldavEventStr), decodeString(t, caldavTodoStr)
+	s.Put("standup.ics", &ev, "")
+	s.Put("report.ics", &todo, "")
+
+	changes, err := s.Scan()
+	if err != nil || strings.Join(changes.Added, ",") != "report.ics,standup.ics" || len(changes.Modified)+len(changes.Removed) != 0 {
+		t.Fatalf("first Scan() = %+v, %v", changes, err)
+	}
+	if changes, _ := s.Scan(); len(changes.Added)+len(changes.Modified)+len(changes.Removed) != 0 {
+		t.Errorf("Scan() without changes = %+v", changes)
+	}
+
+	//another program rewrites standup.ics and deletes report.ics
+	path := filepath.Join(s.Dir, "standup.ics")
+	b, _ := ioutil.ReadFile(path)
+	b = []byte(strings.Replace(string(b), "SUMMARY:Standup", "SUMMARY:Daily", 1))
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	os.Remove(filepath.Join(s.Dir, "report.ics"))
+
+	changes, err = s.Scan()
+	if err != nil || len(changes.Added) != 0 || strings.Join(changes.Modified, ",") != "standup.ics" ||
+		strings.Join(changes.Removed, ",") != "report.ics" {
+		t.Errorf("Scan() = %+v, %v", changes, err)
+	}
+	item, err := s.Get("standup.ics")
+	if err != nil || item.Calendar.SubComponents()[0].obj().GetProperty(PropSummary).Value != "Daily" || item.ETag != contentETag(b) {
+		t.Errorf("Get() of the rewritten item = %+v, %v", item, err)
+	}
+}
+
+func TestVdirStoreQuery(t *testing.T) {
+	s, done := newTestVdir(t)
+	defer done()
+	standup, todo := decodeString(t, filterCalendarStr), decodeString(t, caldavTodoStr)
+	//filterCalendarStr holds two UIDs,the vdir gets one item each
+	for _, cal := range SplitCalendar(standup) {
+		if _, _, err := s.Create(cal); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Put("report.ics", &todo, "")
+
+	from := time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC)
+	occs, broken, err := s.Query(from, from.Add(4*24*time.Hour))
+	if err != nil || len(broken) != 0 {
+		t.Fatalf("Query() = %+v, %v", broken, err)
+	}
+	var got []string
+	for _, o := range occs {
+		got = append(got, o.Href+" "+o.Start.UTC().Format(DatetimeFormat2))
+	}
+	//the instance of 3/15 is moved to 3/16
+	if want := "standup@example.com.ics 20210316T100000Z"; strings.Join(got, ",") != want {
+		t.Errorf("Query() = %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestVdirStoreBrokenItem(t *testing.T) {
+	s, done := newTestVdir(t)
+	defer done()
+	ev := decodeString(t, caldavEventStr)
+	if _, _, err := s.Create(&ev); err != nil {
+		t.Fatal(err)
+	}
+	//another program leaves a file which is no calendar
+	corrupt := []byte("BEGIN:VCALENDAR\r\nnot a line\r\n")
+	if err := ioutil.WriteFile(filepath.Join(s.Dir, "corrupt.ics"), corrupt, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	items, broken, err := s.Items()
+	if err != nil || len(items) != 1 || items[0].Href != "standup@example.com.ics" ||
+		len(broken) != 1 || broken[0].Href != "corrupt.ics" || broken[0].Err == nil {
+		t.Errorf("Items() = %+v, %+v, %v", items, broken, err)
+	}
+	from := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	occs, broken, err := s.Query(from, from.AddDate(0, 1, 0))
+	if err != nil || len(occs) == 0 || len(broken) != 1 || broken[0].Href != "corrupt.ics" {
+		t.Errorf("Query() = %d occurrences, %+v, %v", len(occs), broken, err)
+	}
+
+	//the ETag of the raw file is enough to replace or delete it
+	if _, err := s.Put("corrupt.ics", &ev, "stale"); err != ErrVdirPreconditionFailed {
+		t.Errorf("Put() over the broken item with a stale ETag err = %v", err)
+	}
+	etag, err := s.Put("corrupt.ics", &ev, contentETag(corrupt))
+	if err != nil {
+		t.Fatalf("Put() over the broken item err: %v", err)
+	}
+	if _, broken, _ := s.Items(); len(broken) != 0 {
+		t.Errorf("Items() after Put() broken = %+v", broken)
+	}
+	ioutil.WriteFile(filepath.Join(s.Dir, "corrupt.ics"), corrupt, 0644)
+	if err := s.Delete("corrupt.ics", etag); err != ErrVdirPreconditionFailed {
+		t.Errorf("Delete() of the broken item with a stale ETag err = %v", err)
+	}
+	if err := s.Delete("corrupt.ics", contentETag(corrupt)); err != nil {
+		t.Errorf("Delete() of the broken item err: %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package go_ical
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PropPublishedTTL is the refresh interval feeds published before RFC 7986
+// declare,e.g. "X-PUBLISHED-TTL:PT1H"
+const PropPublishedTTL = "X-PUBLISHED-TTL"
+
+// DefaultFeedRefresh is how long a feed without REFRESH-INTERVAL or
+// X-PUBLISHED-TTL stays fresh
+const DefaultFeedRefresh = 24 * time.Hour
+
+/*
+Subscription is a subscribed calendar feed and what the last fetch learned
+about it.
+
+The zero value of the state fields makes the first Fetch load the feed
+unconditionally. Subscriptions are kept between runs by saving URL,ETag,
+LastModified,Refresh and Fetched,Calendar is only needed for the change
+summary. Without Calendar the next Fetch loads the whole feed again.
+*/
+type Subscription struct {
+	//URL is the feed,webcal:// and webcals:// are fetched with https
+	URL string
+	//Client sends the requests,http.DefaultClient when nil
+	Client *http.Client
+	//DefaultRefresh replaces DefaultFeedRefresh when not zero
+	DefaultRefresh time.Duration
+	//Now returns the time Fetched is set to,time.Now when nil
+	Now func() time.Time
+
+	ETag         string
+	LastModified string
+	//Refresh is the REFRESH-INTERVAL or X-PUBLISHED-TTL of the feed,zero
+	//when it has none
+	Refresh  time.Duration
+	Fetched  time.Time
+	Calendar *Calendar
+}
+
+// ComponentKey names a component of a calendar: the master of a UID has an
+// empty RecurrenceID,an override its RECURRENCE-ID in UTC
+type ComponentKey struct {
+	Name         string
+	UID          string
+	RecurrenceID string
+}
+
+func (k ComponentKey) String() string {
+	s := k.Name
+	for _, v := range []string{k.UID, k.RecurrenceID} {
+		if v != "" {
+			s += " " + v
+		}
+	}
+	return s
+}
+
+// componentKey returns the key of com,ok is false without UID
+func componentKey(com *ComponentObj) (ComponentKey, bool) {
+	uid := com.GetProperty(PropUID)
+	if uid == nil {
+		return ComponentKey{}, false
+	}
+	k := ComponentKey{Name: com.Name(), UID: uid.Value}
+	if rid := com.GetProperty(PropRecurrenceId); rid != nil {
+		k.RecurrenceID = rid.Value
+		if t, err := rid.GetToTime(); err == nil {
+			k.RecurrenceID = t.UTC().Format(DatetimeFormat2)
+			if rid.IsDate() {
+				k.RecurrenceID = t.Format(DateFormat)
+			}
+		}
+	}
+	return k, true
+}
+
+// FeedChanges summarizes what a fetch changed in the feed,by component
+type FeedChanges struct {
+	//NotModified is set when the server answered 304 Not Modified
+	NotModified bool
+	Added       []ComponentKey
+	Updated     []ComponentKey
+	Removed     []ComponentKey
+}
+
+// Empty reports whether the feed did not change
+func (c *FeedChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Updated) == 0 && len(c.Removed) == 0
+}
+
+// NewSubscription returns a subscription to the feed at rawurl
+func NewSubscription(rawurl string) (*Subscription, error) {
+	if _, err := feedURL(rawurl); err != nil {
+		return nil, err
+	}
+	return &Subscription{URL: rawurl}, nil
+}
+
+// feedURL maps webcal:// to https://,other schemes than http and https fail
+func feedURL(rawurl string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "webcal", "webcals":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return "", fmt.Errorf("ical:feed URL %q is not webcal,http or https", rawurl)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("ical:feed URL %q has no host", rawurl)
+	}
+	return u.String(), nil
+}
+
+// Due reports whether the feed should be fetched again at now
+func (s *Subscription) Due(now time.Time) bool {
+	if s.Fetched.IsZero() {
+		return true
+	}
+	refresh := s.Refresh
+	if refresh <= 0 {
+		refresh = s.DefaultRefresh
+	}
+	if refresh <= 0 {
+		refresh = DefaultFeedRefresh
+	}
+	return !now.Before(s.Fetched.Add(refresh))
+}
+
+func (s *Subscription) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+/*
+Fetch loads the feed with a conditional GET and returns the calendar and what
+changed since the last fetch.
+
+The request carries If-None-Match and If-Modified-Since from the last
+response when the calendar of the last fetch is kept,after a 304 answer that
+calendar is returned. gzip bodies are decompressed,also
+when the file itself is gzipped. Redirects are followed,when all of them are
+permanent URL is updated. Components are compared by ComponentKey and
+without DTSTAMP,which many feeds set to the time of the download. On error
+the subscription is left unchanged.
+*/
+func (s *Subscription) Fetch() (*Calendar, *FeedChanges, error) {
+	target, err := feedURL(s.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "text/calendar, */*;q=0.5")
+	req.Header.Set("Accept-Encoding", "gzip")
+	//a 304 is of no use without the calendar it refers to
+	if s.ETag != "" && s.Calendar != nil {
+		req.Header.Set("If-None-Match", s.ETag)
+	}
+	if s.LastModified != "" && s.Calendar != nil {
+		req.Header.Set("If-Modified-Since", s.LastModified)
+	}
+
+	client := http.Client{}
+	if s.Client != nil {
+		client = *s.Client
+	}
+	permanent := true
+	check := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if code := req.Response.StatusCode; code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			permanent = false
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("ical:feed %s redirects more than 10 times", s.URL)
+		}
+		return nil
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	changes := &FeedChanges{}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if s.Calendar == nil {
+			return nil, nil, fmt.Errorf("ical:feed %s is not modified but no calendar is kept", s.URL)
+		}
+		s.Fetched = s.now()
+		changes.NotModified = true
+		return s.Calendar, changes, nil
+	default:
+		return nil, nil, fmt.Errorf("ical:fetching feed %s: %s", s.URL, resp.Status)
+	}
+	body, err := feedBody(resp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ical:reading feed %s: %v", s.URL, err)
+	}
+	cal, err := NewDecoder(body).Decode()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ical:decoding feed %s: %v", s.URL, err)
+	}
+	refresh, err := feedRefresh(&cal)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ical:feed %s: %v", s.URL, err)
+	}
+
+	if final := resp.Request.URL.String(); permanent && final != target {
+		s.URL = final
+	}
+	changes.Added, changes.Updated, changes.Removed = feedChanges(s.Calendar, &cal)
+	s.ETag = resp.Header.Get("ETag")
+	s.LastModified = resp.Header.Get("Last-Modified")
+	s.Refresh = refresh
+	s.Fetched = s.now()
+	s.Calendar = &cal
+	return &cal, changes, nil
+}
+
+// feedBody returns the decompressed body of resp. The request asks for gzip
+// itself,so the transport leaves it to us,and a .ics.gz file may be served
+// as it is.
+func feedBody(resp *http.Response) (io.Reader, error) {
+	var r io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	}
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(zr)
+	}
+	b, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// feedRefresh returns REFRESH-INTERVAL,RFC 7986 5.7,or X-PUBLISHED-TTL
+func feedRefresh(cal *Calendar) (time.Duration, error) {
+	for _, name := range []string{PropRefreshInterval, PropPublishedTTL} {
+		p := cal.GetProperty(name)
+		if p == nil {
+			continue
+		}
+		d, err := parseDuration(p.Value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %v", name, err)
+		}
+		if d > 0 {
+			return d, nil
+		}
+	}
+	return 0, nil
+}
+
+// feedChanges compares the components of old and new by key,old may be nil
+func feedChanges(old, new *Calendar) (added, updated, removed []ComponentKey) {
+	before := feedFingerprints(old)
+	after := feedFingerprints(new)
+	for k, fp := range after {
+		prev, ok := before[k]
+		if !ok {
+			added = append(added, k)
+		} else if prev != fp {
+			updated = append(updated, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	for _, keys := range [][]ComponentKey{added, updated, removed} {
+		sortComponentKeys(keys)
+	}
+	return
+}
+
+func sortComponentKeys(keys []ComponentKey) {
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+}
+
+// feedFingerprints maps the keyed components of cal to their encoding
+// without DTSTAMP
+func feedFingerprints(cal *Calendar) map[ComponentKey]string {
+	fps := map[ComponentKey]string{}
+	if cal == nil {
+		return fps
+	}
+	for _, sub := range cal.SubComponents() {
+		k, ok := componentKey(sub.obj())
+		if !ok {
+			continue
+		}
+		c := sub.obj().Clone()
+		c.DelProperty(PropDatetimeStamp)
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).Encode(c); err != nil {
+			//a component which does not encode is compared by its value
+			buf.WriteString(fmt.Sprint(c))
+		}
+		fps[k] = buf.String()
+	}
+	return fps
+}
@@ -0,0 +1,187 @@
+package go_ical
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const feedStr = `BEGIN:VCALENDAR
+PRODID:-//xyz Corp//Scott WORK Calendar Version 1.0//CN
+VERSION:2.0
+X-PUBLISHED-TTL:PT1H
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210301T080000Z
+DTSTART:20210301T090000Z
+RRULE:FREQ=WEEKLY
+SUMMARY:Team Standup
+END:VEVENT
+BEGIN:VEVENT
+UID:review@example.com
+DTSTAMP:20210301T080000Z
+DTSTART:20210302T090000Z
+SUMMARY:Review
+END:VEVENT
+END:VCALENDAR
+`
+
+// feedServer serves body at /feed.ics with an ETag of its version,gzipped
+// when the client accepts it,and redirects /old.ics and /moved.ics there
+type feedServer struct {
+	body    string
+	version string
+}
+
+func (f *feedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/old.ics":
+		http.Redirect(w, r, "/feed.ics", http.StatusMovedPermanently)
+		return
+	case "/moved.ics":
+		http.Redirect(w, r, "/feed.ics", http.StatusFound)
+		return
+	case "/feed.ics":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	etag := `"` + f.version + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar")
+	if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Write([]byte(f.body))
+		return
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	zw := gzip.NewWriter(w)
+	zw.Write([]byte(f.body))
+	zw.Close()
+}
+
+func TestSubscriptionFetch(t *testing.T) {
+	feed := &feedServer{body: toCRLF(feedStr), version: "1"}
+	srv := httptest.NewServer(feed)
+	defer srv.Close()
+
+	s, err := NewSubscription(srv.URL + "/old.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Client = srv.Client()
+	now := time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)
+	s.Now = func() time.Time { return now }
+	if !s.Due(now) {
+		t.Error("Due() before the first fetch = false")
+	}
+	cal, changes, err := s.Fetch()
+	if err != nil {
+		t.Fatalf("Fetch() err: %v", err)
+	}
+	if len(cal.SubComponents()) != 2 || len(changes.Added) != 2 || len(changes.Updated)+len(changes.Removed) != 0 {
+		t.Errorf("first Fetch() = %d components, %+v", len(cal.SubComponents()), changes)
+	}
+	if s.URL != srv.URL+"/feed.ics" || s.ETag != `"1"` || s.Refresh != time.Hour {
+		t.Errorf("after Fetch() URL = %s, ETag = %s, Refresh = %v", s.URL, s.ETag, s.Refresh)
+	}
+	if !s.Fetched.Equal(now) || s.Due(now.Add(59*time.Minute)) || !s.Due(now.Add(time.Hour)) {
+		t.Errorf("Due() does not follow X-PUBLISHED-TTL,Fetched = %v", s.Fetched)
+	}
+
+	now = now.Add(2 * time.Hour)
+	cal, changes, err = s.Fetch()
+	if err != nil || !changes.NotModified || !changes.Empty() || cal != s.Calendar || !s.Fetched.Equal(now) {
+		t.Errorf("conditional Fetch() = %+v, %v, Fetched = %v", changes, err, s.Fetched)
+	}
+
+	//a subscription restored without its calendar loads the whole feed
+	restored := &Subscription{URL: s.URL, Client: s.Client, ETag: s.ETag, Refresh: s.Refresh, Fetched: s.Fetched}
+	cal, changes, err = restored.Fetch()
+	if err != nil || changes.NotModified || cal == nil || len(changes.Added) != 2 || restored.Calendar != cal {
+		t.Errorf("Fetch() without a calendar = %+v, %v", changes, err)
+	}
+
+	//a new DTSTAMP alone is no change,REFRESH-INTERVAL wins over the TTL
+	body := strings.Replace(feedStr, "DTSTAMP:20210301T080000Z", "DTSTAMP:20210401T080000Z", -1)
+	body = strings.Replace(body, "X-PUBLISHED-TTL:PT1H", "X-PUBLISHED-TTL:PT1H\nREFRESH-INTERVAL;VALUE=DURATION:P1D", 1)
+	body = strings.Replace(body, "SUMMARY:Review", "SUMMARY:Code review", 1)
+	body = strings.Replace(body, "END:VCALENDAR", `BEGIN:VEVENT
+UID:standup@example.com
+DTSTAMP:20210401T080000Z
+RECURRENCE-ID:20210308T090000Z
+DTSTART:20210308T100000Z
+SUMMARY:Late standup
+END:VEVENT
+END:VCALENDAR`, 1)
+	feed.body, feed.version = toCRLF(body), "2"
+	_, changes, err = s.Fetch()
+	if err != nil {
+		t.Fatalf("Fetch() err: %v", err)
+	}
+	if len(changes.Added) != 1 || changes.Added[0] != (ComponentKey{CompEvent, "standup@example.com", "20210308T090000Z"}) ||
+		len(changes.Updated) != 1 || changes.Updated[0].UID != "review@example.com" || len(changes.Removed) != 0 {
+		t.Errorf("Fetch() changes = %+v", changes)
+	}
+	if s.Refresh != 24*time.Hour {
+		t.Errorf("Refresh = %v", s.Refresh)
+	}
+
+	feed.body, feed.version = toCRLF(strings.Replace(body, "UID:review@example.com", "UID:retro@example.com", 1)), "3"
+	_, changes, err = s.Fetch()
+	if err != nil || len(changes.Added) != 1 || len(changes.Removed) != 1 || changes.Removed[0].UID != "review@example.com" {
+		t.Errorf("Fetch() changes = %+v, %v", changes, err)
+	}
+}
+
+func TestSubscriptionFetchErrors(t *testing.T) {
+	feed := &feedServer{body: "not a calendar", version: "1"}
+	srv := httptest.NewServer(feed)
+	defer srv.Close()
+
+	for _, u := range []string{"ftp://example.com/feed.ics", "webcal:///feed.ics"} {
+		if _, err := NewSubscription(u); err == nil {
+			t.Errorf("NewSubscription(%s) err = nil", u)
+		}
+	}
+	s := &Subscription{URL: srv.URL + "/missing.ics", Client: srv.Client()}
+	if _, _, err := s.Fetch(); err == nil {
+		t.Error("Fetch() of a missing feed err = nil")
+	}
+	s.URL = srv.URL + "/moved.ics"
+	if _, _, err := s.Fetch(); err == nil {
+		t.Error("Fetch() of an invalid feed err = nil")
+	}
+	if s.ETag != "" || !s.Fetched.IsZero() || s.URL != srv.URL+"/moved.ics" {
+		t.Errorf("failed Fetch() changed the subscription: %+v", s)
+	}
+
+	//a temporary redirect keeps the URL,a gzipped file is decompressed
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(toCRLF(feedStr)))
+	zw.Close()
+	feed.body = buf.String()
+	if _, _, err := s.Fetch(); err != nil || s.URL != srv.URL+"/moved.ics" {
+		t.Errorf("Fetch() = %v, URL = %s", err, s.URL)
+	}
+}
+
+func TestWebcalURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"webcal://example.com/feed.ics":  "https://example.com/feed.ics",
+		"WEBCALS://example.com/feed.ics": "https://example.com/feed.ics",
+		"http://example.com/feed.ics":    "http://example.com/feed.ics",
+	} {
+		if got, err := feedURL(in); err != nil || got != want {
+			t.Errorf("feedURL(%s) = %s, %v", in, got, err)
+		}
+	}
+}
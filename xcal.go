@@ -0,0 +1,512 @@
+package go_ical
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xCal,RFC 6321,is the XML form of iCalendar. Every component has a
+// <properties> and a <components> element,every property holds its
+// <parameters> and one element per value,named after the value type:
+//
+//	<dtstart>
+//	  <parameters><tzid><text>Europe/Berlin</text></tzid></parameters>
+//	  <date-time>1996-09-18T14:30:00</date-time>
+//	</dtstart>
+//
+// Values use the same extended date and time formats as jCal.
+
+// XCalNamespace is the XML namespace of xCal
+const XCalNamespace = "urn:ietf:params:xml:ns:icalendar-2.0"
+
+// xcalParamTypes are the parameters whose value is not TEXT,RFC 6321 3.5
+var xcalParamTypes = map[string]string{
+	Paramaltrep:  VDTuri,
+	Paramdir:     VDTuri,
+	Paramdelfrom: VDTcalendaraddress,
+	Paramdelto:   VDTcalendaraddress,
+	Parammember:  VDTcalendaraddress,
+	Paramsentby:  VDTcalendaraddress,
+	Paramrsvp:    VDTbool,
+}
+
+// xcalRequestStatus names the parts of a REQUEST-STATUS,RFC 6321 3.4.1.3
+var xcalRequestStatus = []string{"code", "description", "data"}
+
+// xcalGeo names the parts of a GEO,RFC 6321 3.4.1.2
+var xcalGeo = []string{"latitude", "longitude"}
+
+// xcalNode is any xCal element,either with text or with child elements
+type xcalNode struct {
+	XMLName xml.Name
+	Text    string     `xml:",chardata"`
+	Nodes   []xcalNode `xml:",any"`
+}
+
+func newXCalNode(name string, nodes ...xcalNode) xcalNode {
+	return xcalNode{XMLName: xml.Name{Local: name}, Nodes: nodes}
+}
+
+func newXCalText(name, text string) xcalNode {
+	return xcalNode{XMLName: xml.Name{Local: name}, Text: text}
+}
+
+// dropForeign removes the elements of other namespaces below n,xCal has no
+// meaning for them
+func (n *xcalNode) dropForeign() {
+	nodes := n.Nodes[:0]
+	for _, c := range n.Nodes {
+		if c.XMLName.Space == XCalNamespace {
+			c.dropForeign()
+			nodes = append(nodes, c)
+		}
+	}
+	n.Nodes = nodes
+}
+
+// child returns the first child element named name
+func (n *xcalNode) child(name string) *xcalNode {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == name {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+type XCalEncoder struct {
+	w io.Writer
+}
+
+func NewXCalEncoder(w io.Writer) *XCalEncoder {
+	return &XCalEncoder{w}
+}
+
+// Encode writes com as an xCal document with an <icalendar> root. Values which
+// do not match their value type are an error,the same as for Encoder.
+func (enc *XCalEncoder) Encode(com Component) error {
+	n, err := xcalComponent(com.obj())
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(enc.w, xml.Header); err != nil {
+		return err
+	}
+	xe := xml.NewEncoder(enc.w)
+	xe.Indent("", " ")
+	//the namespace is declared once,encoding/xml would repeat it on every element
+	root := xml.StartElement{
+		Name: xml.Name{Local: "icalendar"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: XCalNamespace}},
+	}
+	if err := xe.EncodeToken(root); err != nil {
+		return err
+	}
+	if err := n.encode(xe); err != nil {
+		return err
+	}
+	if err := xe.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	if err := xe.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(enc.w, "\n")
+	return err
+}
+
+func (n *xcalNode) encode(xe *xml.Encoder) error {
+	start := xml.StartElement{Name: n.XMLName}
+	if err := xe.EncodeToken(start); err != nil {
+		return err
+	}
+	if n.Text != "" {
+		if err := xe.EncodeToken(xml.CharData(n.Text)); err != nil {
+			return err
+		}
+	}
+	for i := range n.Nodes {
+		if err := n.Nodes[i].encode(xe); err != nil {
+			return err
+		}
+	}
+	return xe.EncodeToken(start.End())
+}
+
+func xcalComponent(com *ComponentObj) (xcalNode, error) {
+	if err := com.IsAvailable(); err != nil {
+		return xcalNode{}, err
+	}
+	n := newXCalNode(strings.ToLower(com.Name()))
+	if len(com.PropertiesObj) > 0 {
+		props := newXCalNode("properties")
+		for i := range com.PropertiesObj {
+			p, err := xcalProperty(&com.PropertiesObj[i])
+			if err != nil {
+				return xcalNode{}, err
+			}
+			props.Nodes = append(props.Nodes, p)
+		}
+		n.Nodes = append(n.Nodes, props)
+	}
+	if len(com.SubComponentsObj) > 0 {
+		subs := newXCalNode("components")
+		for _, sub := range com.SubComponentsObj {
+			s, err := xcalComponent(sub.obj())
+			if err != nil {
+				return xcalNode{}, err
+			}
+			subs.Nodes = append(subs.Nodes, s)
+		}
+		n.Nodes = append(n.Nodes, subs)
+	}
+	return n, nil
+}
+
+func xcalProperty(p *Property) (xcalNode, error) {
+	if !isValidName(p.Name) {
+		return xcalNode{}, fmt.Errorf("ical:invalid property name %q", p.Name)
+	}
+	n := newXCalNode(strings.ToLower(p.Name))
+	if err := checkValueChars(p.Value); err != nil {
+		return xcalNode{}, fmt.Errorf("ical:property %q: %v", p.Name, err)
+	}
+
+	params := newXCalNode("parameters")
+	for _, key := range sortedParamKeys(p.Params) {
+		if strings.EqualFold(key, Paramvaluetypeparam) {
+			continue
+		}
+		if !isValidName(key) {
+			return xcalNode{}, fmt.Errorf("ical:property %q has invalid parameter name %q", p.Name, key)
+		}
+		typ := xcalParamTypes[strings.ToUpper(key)]
+		if typ == "" {
+			typ = VDTtext
+		}
+		pn := newXCalNode(strings.ToLower(key))
+		for _, v := range p.Params[key] {
+			if err := checkValueChars(v); err != nil {
+				return xcalNode{}, fmt.Errorf("ical:property %q parameter %q: %v", p.Name, key, err)
+			}
+			if typ == VDTbool {
+				v = strings.ToLower(v)
+			}
+			pn.Nodes = append(pn.Nodes, newXCalText(strings.ToLower(typ), v))
+		}
+		params.Nodes = append(params.Nodes, pn)
+	}
+	if len(params.Nodes) > 0 {
+		n.Nodes = append(n.Nodes, params)
+	}
+
+	typ := strings.ToUpper(p.GetParamValue())
+	if typ == VDTdefault {
+		n.Nodes = append(n.Nodes, newXCalText(jcalUnknown, p.Value))
+		return n, nil
+	}
+	if names := xcalStructured(p.Name, typ); names != nil {
+		parts := splitStructured(p.Value)
+		if len(parts) > len(names) {
+			return xcalNode{}, fmt.Errorf("ical:property %q has %d parts,at most %d are allowed", p.Name, len(parts), len(names))
+		}
+		for i, s := range parts {
+			if typ == VDTtext {
+				s = FromText(s)
+			}
+			n.Nodes = append(n.Nodes, newXCalText(names[i], s))
+		}
+		return n, nil
+	}
+	vals := []string{p.Value}
+	if jcalLists[typ] {
+		vals = splitValues(p.Value)
+	}
+	for _, s := range vals {
+		v, err := xcalValue(typ, s)
+		if err != nil {
+			return xcalNode{}, fmt.Errorf("ical:property %q: %v", p.Name, err)
+		}
+		n.Nodes = append(n.Nodes, v)
+	}
+	return n, nil
+}
+
+// xcalStructured returns the part names of GEO and REQUEST-STATUS,whose
+// parts are written without a value type element
+func xcalStructured(name, typ string) []string {
+	switch {
+	case name == PropGeographicPosition && typ == VDTfloat:
+		return xcalGeo
+	case name == PropRequestStatus && typ == VDTtext:
+		return xcalRequestStatus
+	}
+	return nil
+}
+
+// xcalValue converts one value of type typ to its element
+func xcalValue(typ, s string) (xcalNode, error) {
+	name := strings.ToLower(typ)
+	switch typ {
+	case VDTtext:
+		return newXCalText(name, FromText(s)), nil
+	case VDTbool, VDTint, VDTfloat:
+		v, err := jcalValue(typ, s)
+		if err != nil {
+			return xcalNode{}, err
+		}
+		return newXCalText(name, fmt.Sprint(v)), nil
+	case VDTdate, VDTdatetime, VDTtime, VDTutcoffset:
+		v, err := jcalTime(typ, s)
+		if err != nil {
+			return xcalNode{}, err
+		}
+		return newXCalText(name, v), nil
+	case VDTperiod:
+		i := strings.IndexByte(s, '/')
+		if i < 0 {
+			return xcalNode{}, fmt.Errorf("invalid period %q", s)
+		}
+		start, err := jcalTime(VDTdatetime, s[:i])
+		if err != nil {
+			return xcalNode{}, err
+		}
+		end := newXCalText("duration", s[i+1:])
+		if !strings.ContainsRune(end.Text, 'P') {
+			v, err := jcalTime(VDTdatetime, end.Text)
+			if err != nil {
+				return xcalNode{}, err
+			}
+			end = newXCalText("end", v)
+		}
+		return newXCalNode(name, newXCalText("start", start), end), nil
+	case VDTrecurrence:
+		obj, err := jcalRecur(s)
+		if err != nil {
+			return xcalNode{}, err
+		}
+		n := newXCalNode(name)
+		for _, m := range obj {
+			vals, ok := m.value.([]interface{})
+			if !ok {
+				vals = []interface{}{m.value}
+			}
+			for _, v := range vals {
+				n.Nodes = append(n.Nodes, newXCalText(m.key, fmt.Sprint(v)))
+			}
+		}
+		return n, nil
+	}
+	return newXCalText(name, s), nil
+}
+
+// XCalDecoder reads xCal documents
+type XCalDecoder struct {
+	r    io.Reader
+	cals []Calendar
+	read bool
+}
+
+func NewXCalDecoder(r io.Reader) *XCalDecoder {
+	return &XCalDecoder{r: r}
+}
+
+// Decode returns the next vcalendar of the document,io.EOF is returned
+// after the last one
+func (dec *XCalDecoder) Decode() (Calendar, error) {
+	if !dec.read {
+		dec.read = true
+		var root xcalNode
+		if err := xml.NewDecoder(dec.r).Decode(&root); err != nil {
+			return Calendar{}, err
+		}
+		if root.XMLName.Space != XCalNamespace || root.XMLName.Local != "icalendar" {
+			return Calendar{}, fmt.Errorf("ical:xCal root must be icalendar in namespace %s,but got %q in %q",
+				XCalNamespace, root.XMLName.Local, root.XMLName.Space)
+		}
+		root.dropForeign()
+		for i := range root.Nodes {
+			com, err := icalComponentXML(&root.Nodes[i])
+			if err != nil {
+				return Calendar{}, err
+			}
+			if com.Name() != CompCalendar {
+				return Calendar{}, fmt.Errorf("ical:icalendar holds vcalendar,but got %q", root.Nodes[i].XMLName.Local)
+			}
+			dec.cals = append(dec.cals, Calendar{*com})
+		}
+	}
+	if len(dec.cals) == 0 {
+		return Calendar{}, io.EOF
+	}
+	cal := dec.cals[0]
+	dec.cals = dec.cals[1:]
+	return cal, nil
+}
+
+func icalComponentXML(n *xcalNode) (*ComponentObj, error) {
+	if !isValidName(n.XMLName.Local) {
+		return nil, fmt.Errorf("ical:invalid xCal component name %q", n.XMLName.Local)
+	}
+	com := &ComponentObj{
+		NameObj:          strings.ToUpper(n.XMLName.Local),
+		PropertiesObj:    []Property{},
+		SubComponentsObj: []Component{},
+	}
+	for i := range n.Nodes {
+		c := &n.Nodes[i]
+		switch c.XMLName.Local {
+		case "properties":
+			for j := range c.Nodes {
+				p, err := icalPropertyXML(&c.Nodes[j])
+				if err != nil {
+					return nil, err
+				}
+				com.PropertiesObj = append(com.PropertiesObj, p)
+			}
+		case "components":
+			for j := range c.Nodes {
+				sub, err := icalComponentXML(&c.Nodes[j])
+				if err != nil {
+					return nil, err
+				}
+				com.SubComponentsObj = append(com.SubComponentsObj, sub)
+			}
+		default:
+			return nil, fmt.Errorf("ical:unexpected element %q in %q", c.XMLName.Local, n.XMLName.Local)
+		}
+	}
+	return com, nil
+}
+
+func icalPropertyXML(n *xcalNode) (Property, error) {
+	if !isValidName(n.XMLName.Local) {
+		return Property{}, fmt.Errorf("ical:invalid xCal property name %q", n.XMLName.Local)
+	}
+	p := NewProperty(n.XMLName.Local)
+	var vals []*xcalNode
+	for i := range n.Nodes {
+		c := &n.Nodes[i]
+		if c.XMLName.Local != "parameters" {
+			vals = append(vals, c)
+			continue
+		}
+		for _, pn := range c.Nodes {
+			key := strings.ToUpper(pn.XMLName.Local)
+			if !isValidName(key) {
+				return Property{}, fmt.Errorf("ical:property %q has invalid parameter name %q", p.Name, key)
+			}
+			for _, v := range pn.Nodes {
+				s := v.Text
+				if v.XMLName.Local == "boolean" {
+					s = strings.ToUpper(s)
+				}
+				p.Params.Add(key, s)
+			}
+		}
+	}
+	if len(vals) == 0 {
+		return Property{}, fmt.Errorf("ical:xCal property %q has no value", p.Name)
+	}
+
+	if names := xcalStructured(p.Name, DefaultVDT[p.Name]); names != nil && vals[0].XMLName.Local == names[0] {
+		var parts []string
+		for i, v := range vals {
+			if i >= len(names) || v.XMLName.Local != names[i] {
+				return Property{}, fmt.Errorf("ical:unexpected element %q in %q", v.XMLName.Local, n.XMLName.Local)
+			}
+			s := v.Text
+			if p.Name == PropRequestStatus {
+				s = ToText(s)
+			}
+			parts = append(parts, s)
+		}
+		p.Value = strings.Join(parts, ";")
+		return *p, nil
+	}
+
+	typ := strings.ToUpper(vals[0].XMLName.Local)
+	if typ == strings.ToUpper(jcalUnknown) {
+		typ = VDTdefault
+	} else if DefaultVDT[p.Name] != typ {
+		p.Params.Set(Paramvaluetypeparam, typ)
+	}
+	var ss []string
+	for _, v := range vals {
+		if !strings.EqualFold(v.XMLName.Local, vals[0].XMLName.Local) {
+			return Property{}, fmt.Errorf("ical:property %q mixes values %q and %q", p.Name, vals[0].XMLName.Local, v.XMLName.Local)
+		}
+		s, err := icalValueXML(typ, v)
+		if err != nil {
+			return Property{}, fmt.Errorf("ical:property %q: %v", p.Name, err)
+		}
+		ss = append(ss, s)
+	}
+	p.Value = strings.Join(ss, ",")
+	return *p, nil
+}
+
+// icalValueXML converts one value element of type typ to iCalendar text
+func icalValueXML(typ string, n *xcalNode) (string, error) {
+	switch typ {
+	case VDTtext:
+		return ToText(n.Text), nil
+	case VDTbool:
+		return strings.ToUpper(strings.TrimSpace(n.Text)), nil
+	case VDTdate, VDTdatetime, VDTtime, VDTutcoffset:
+		return icalTime(typ, strings.TrimSpace(n.Text))
+	case VDTperiod:
+		start, end := n.child("start"), n.child("end")
+		if start == nil {
+			return "", fmt.Errorf("period without start")
+		}
+		s, err := icalTime(VDTdatetime, strings.TrimSpace(start.Text))
+		if err != nil {
+			return "", err
+		}
+		if end != nil {
+			e, err := icalTime(VDTdatetime, strings.TrimSpace(end.Text))
+			if err != nil {
+				return "", err
+			}
+			return s + "/" + e, nil
+		}
+		if d := n.child("duration"); d != nil {
+			return s + "/" + strings.TrimSpace(d.Text), nil
+		}
+		return "", fmt.Errorf("period without end or duration")
+	case VDTrecurrence:
+		var parts []string
+		for _, c := range n.Nodes {
+			key := strings.ToUpper(c.XMLName.Local)
+			v := strings.TrimSpace(c.Text)
+			if key == "UNTIL" {
+				t := VDTdatetime
+				if !strings.ContainsRune(v, 'T') {
+					t = VDTdate
+				}
+				var err error
+				if v, err = icalTime(t, v); err != nil {
+					return "", err
+				}
+			}
+			//repeated elements of a part are one comma separated list
+			if l := len(parts); l > 0 && strings.HasPrefix(parts[l-1], key+"=") {
+				parts[l-1] += "," + v
+				continue
+			}
+			parts = append(parts, key+"="+v)
+		}
+		s := strings.Join(parts, ";")
+		if _, err := ParseRecur(s); err != nil {
+			return "", err
+		}
+		return s, nil
+	}
+	if len(n.Nodes) > 0 {
+		return "", fmt.Errorf("unexpected element %q in %q", n.Nodes[0].XMLName.Local, n.XMLName.Local)
+	}
+	return n.Text, nil
+}
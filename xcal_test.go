@@ -0,0 +1,135 @@
+package go_ical
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestXCalRoundTrip(t *testing.T) {
+	for _, s := range []string{jcalCalendarStr, validCalendarStr} {
+		cal := decodeString(t, s)
+		var want bytes.Buffer
+		if err := NewEncoder(&want).Encode(&cal); err != nil {
+			t.Fatalf("Encode() err: %v", err)
+		}
+
+		var x bytes.Buffer
+		if err := NewXCalEncoder(&x).Encode(&cal); err != nil {
+			t.Fatalf("XCalEncoder.Encode() err: %v", err)
+		}
+		dec := NewXCalDecoder(bytes.NewReader(x.Bytes()))
+		back, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("XCalDecoder.Decode() err: %v\n%s", err, x.String())
+		}
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("second Decode() err = %v, want io.EOF", err)
+		}
+		var got bytes.Buffer
+		if err := NewEncoder(&got).Encode(&back); err != nil {
+			t.Fatalf("Encode() err: %v", err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("round trip changed the calendar:\n got %s\nwant %s\nxCal:\n%s", got.String(), want.String(), x.String())
+		}
+	}
+}
+
+func TestXCalEncode(t *testing.T) {
+	cal := decodeString(t, jcalCalendarStr)
+	var x bytes.Buffer
+	if err := NewXCalEncoder(&x).Encode(&cal); err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	got := strings.Join(strings.Fields(x.String()), "")
+	for _, want := range []string{
+		`<icalendarxmlns="urn:ietf:params:xml:ns:icalendar-2.0"><vcalendar><properties><prodid><text>`,
+		`<tzoffsetfrom><utc-offset>+02:00</utc-offset></tzoffsetfrom>`,
+		`<dtstart><parameters><tzid><text>Europe/Berlin</text></tzid></parameters><date-time>1996-09-18T14:30:00</date-time></dtstart>`,
+		`<rrule><recur><freq>WEEKLY</freq><until>1996-12-31T00:00:00Z</until><byday>WE</byday><byday>FR</byday><interval>2</interval></recur></rrule>`,
+		`<rdate><period><start>1996-12-24T10:00:00Z</start><duration>PT2H</duration></period><period><start>1996-12-25T10:00:00Z</start><end>1996-12-25T12:00:00Z</end></period></rdate>`,
+		`<delegated-to><cal-address>mailto:a@example.com</cal-address><cal-address>mailto:b@example.com</cal-address></delegated-to>`,
+		`<geo><latitude>37.386013</latitude><longitude>-122.082932</longitude></geo>`,
+		`<categories><text>BUSINESS</text><text>Meeting,weekly</text></categories>`,
+		`<request-status><code>2.0</code><description>Success</description></request-status>`,
+		`<x-allowed><boolean>true</boolean></x-allowed>`,
+		`<x-legacy><unknown>raw\qvalue</unknown></x-legacy>`,
+		`<due><date>1996-09-20</date></due>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("xCal does not contain %s\n%s", want, x.String())
+		}
+	}
+}
+
+// the example of RFC 6321 appendix B.1,shortened
+const xcalExampleStr = `<?xml version="1.0" encoding="utf-8"?>
+<icalendar xmlns="urn:ietf:params:xml:ns:icalendar-2.0">
+ <vcalendar>
+  <properties>
+   <calscale><text>GREGORIAN</text></calscale>
+   <prodid><text>-//Example Inc.//Example Calendar//EN</text></prodid>
+   <version><text>2.0</text></version>
+  </properties>
+  <components>
+   <vevent>
+    <properties>
+     <dtstamp><date-time>2008-02-05T19:12:24Z</date-time></dtstamp>
+     <dtstart><date>2008-10-06</date></dtstart>
+     <summary><text>Planning meeting</text></summary>
+     <uid><text>4088E990AD89CB3DBB484909</text></uid>
+    </properties>
+   </vevent>
+  </components>
+ </vcalendar>
+</icalendar>
+`
+
+func TestXCalDecode(t *testing.T) {
+	cal, err := NewXCalDecoder(strings.NewReader(xcalExampleStr)).Decode()
+	if err != nil {
+		t.Fatalf("Decode() err: %v", err)
+	}
+	var got bytes.Buffer
+	if err := NewEncoder(&got).Encode(&cal); err != nil {
+		t.Fatalf("Encode() err: %v", err)
+	}
+	want := toCRLF(`BEGIN:VCALENDAR
+CALSCALE:GREGORIAN
+PRODID:-//Example Inc.//Example Calendar//EN
+VERSION:2.0
+BEGIN:VEVENT
+DTSTAMP:20080205T191224Z
+DTSTART;VALUE=DATE:20081006
+SUMMARY:Planning meeting
+UID:4088E990AD89CB3DBB484909
+END:VEVENT
+END:VCALENDAR
+`)
+	if got.String() != want {
+		t.Errorf("Decode():\n got %s\nwant %s", got.String(), want)
+	}
+
+	//elements of other namespaces are ignored
+	foreign := strings.Replace(xcalExampleStr, "<properties>", `<properties><x:summary xmlns:x="urn:example">Other</x:summary>`, 1)
+	if c, err := NewXCalDecoder(strings.NewReader(foreign)).Decode(); err != nil || len(c.GetProperties(PropSummary)) != 0 {
+		t.Errorf("Decode() with a foreign element = %v, %v", c.GetProperties(PropSummary), err)
+	}
+
+	const ns = `<icalendar xmlns="urn:ietf:params:xml:ns:icalendar-2.0">`
+	for _, s := range []string{
+		`<calendar/>`,
+		`<icalendar><vcalendar><properties><version><text>2.0</text></version></properties></vcalendar></icalendar>`,
+		`<icalendar xmlns="urn:example"><vcalendar/></icalendar>`,
+		ns + `<vevent/></icalendar>`,
+		ns + `<vcalendar><properties><dtstart><date-time>2008</date-time></dtstart></properties></vcalendar></icalendar>`,
+		ns + `<vcalendar><properties><rrule><recur><freq>SOMETIMES</freq></recur></rrule></properties></vcalendar></icalendar>`,
+		ns + `<vcalendar><properties><summary/></properties></vcalendar></icalendar>`,
+	} {
+		if _, err := NewXCalDecoder(strings.NewReader(s)).Decode(); err == nil {
+			t.Errorf("Decode(%s) err = nil", s)
+		}
+	}
+}